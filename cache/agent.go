@@ -3,6 +3,7 @@ package cache
 import (
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"omo.msa.assignment/proxy/nosql"
 	"time"
 )
@@ -34,9 +35,13 @@ type AgentInfo struct {
 }
 
 func (mine *cacheContext) CreateAgent(info *pb.ReqAgentAdd) (*AgentInfo, error) {
+	id, err := mine.nextID(nosql.TableAgent)
+	if err != nil {
+		return nil, err
+	}
 	db := new(nosql.Agent)
 	db.UID = primitive.NewObjectID()
-	db.ID = nosql.GetAgentNextID()
+	db.ID = id
 	db.CreatedTime = time.Now()
 	db.UpdatedTime = time.Now()
 	db.Operator = info.Operator
@@ -62,7 +67,7 @@ func (mine *cacheContext) CreateAgent(info *pb.ReqAgentAdd) (*AgentInfo, error)
 	db.Attaches = make([]string, 0, 1)
 	db.Attaches = append(db.Attaches, info.Owner)
 	db.Tags = make([]string, 0, 1)
-	err := nosql.CreateAgent(db)
+	err = mine.agents.CreateAgent(db)
 	if err == nil {
 		tmp := new(AgentInfo)
 		tmp.initInfo(db)
@@ -72,7 +77,7 @@ func (mine *cacheContext) CreateAgent(info *pb.ReqAgentAdd) (*AgentInfo, error)
 }
 
 func (mine *cacheContext) GetAgent(uid string) (*AgentInfo, error) {
-	db, err := nosql.GetAgent(uid)
+	db, err := mine.agents.GetAgent(uid)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +87,7 @@ func (mine *cacheContext) GetAgent(uid string) (*AgentInfo, error) {
 }
 
 func (mine *cacheContext) GetAgentByUser(uid string) (*AgentInfo, error) {
-	db, err := nosql.GetAgentByUser(uid)
+	db, err := mine.agents.GetAgentByUser(uid)
 	if err != nil {
 		return nil, err
 	}
@@ -91,37 +96,39 @@ func (mine *cacheContext) GetAgentByUser(uid string) (*AgentInfo, error) {
 	return info, nil
 }
 
-func (mine *cacheContext) GetAgentsByOwner(uid string) []*AgentInfo {
-	list := make([]*AgentInfo, 0, 5)
-	dbs, err := nosql.GetAgentsByOwner(uid)
+func (mine *cacheContext) GetAgentsByOwner(uid string, page *PageInfo) (uint32, uint32, []*AgentInfo) {
+	dbs, num, err := mine.agents.QueryAgents(page.query(true, proxy.Equal("owner", uid)))
 	if err != nil {
-		return list
+		return 0, 0, make([]*AgentInfo, 0, 1)
 	}
+	list := make([]*AgentInfo, 0, len(dbs))
 	for _, db := range dbs {
 		info := new(AgentInfo)
 		info.initInfo(db)
 		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
-func (mine *cacheContext) GetAgentsByRegion(region string) []*AgentInfo {
-	list := make([]*AgentInfo, 0, 5)
-	dbs, err := nosql.GetAgentsByRegion(region)
+func (mine *cacheContext) GetAgentsByRegion(region string, page *PageInfo) (uint32, uint32, []*AgentInfo) {
+	dbs, num, err := mine.agents.QueryAgents(page.query(true, proxy.Equal("regions", region)))
 	if err != nil {
-		return list
+		return 0, 0, make([]*AgentInfo, 0, 1)
 	}
+	list := make([]*AgentInfo, 0, len(dbs))
 	for _, db := range dbs {
 		info := new(AgentInfo)
 		info.initInfo(db)
 		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
 func (mine *cacheContext) GetAgentsByAttach(scene string) []*AgentInfo {
 	list := make([]*AgentInfo, 0, 5)
-	dbs, err := nosql.GetAgentsByAttach(scene)
+	dbs, err := mine.agents.GetAgentsByAttach(scene)
 	if err != nil {
 		return list
 	}
@@ -133,35 +140,38 @@ func (mine *cacheContext) GetAgentsByAttach(scene string) []*AgentInfo {
 	return list
 }
 
-func (mine *cacheContext) GetAgentsByWay(owner, way string) []*AgentInfo {
-	list := make([]*AgentInfo, 0, 5)
-	dbs, err := nosql.GetAgentsByWay(owner, way)
+func (mine *cacheContext) GetAgentsByWay(owner, way string, page *PageInfo) (uint32, uint32, []*AgentInfo) {
+	dbs, num, err := mine.agents.QueryAgents(page.query(true, proxy.Equal("owner", owner), proxy.Equal("way", way)))
 	if err != nil {
-		return list
+		return 0, 0, make([]*AgentInfo, 0, 1)
 	}
+	list := make([]*AgentInfo, 0, len(dbs))
 	for _, db := range dbs {
 		info := new(AgentInfo)
 		info.initInfo(db)
 		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
-func (mine *cacheContext) GetAgentsByArray(array []string) []*AgentInfo {
-	list := make([]*AgentInfo, 0, len(array))
-	for _, uid := range array {
-		db, err := nosql.GetAgentByUser(uid)
-		if err == nil {
-			info := new(AgentInfo)
-			info.initInfo(db)
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetAgentsByArray(array []string, page *PageInfo) (uint32, uint32, []*AgentInfo) {
+	dbs, num, err := mine.agents.QueryAgents(page.query(false, proxy.In("user", array)))
+	if err != nil {
+		return 0, 0, make([]*AgentInfo, 0, 1)
 	}
-	return list
+	list := make([]*AgentInfo, 0, len(dbs))
+	for _, db := range dbs {
+		info := new(AgentInfo)
+		info.initInfo(db)
+		list = append(list, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
 func (mine *cacheContext) RemoveAgent(uid, operator string) error {
-	return nosql.RemoveAgent(uid, operator)
+	return mine.agents.RemoveAgent(uid, operator)
 }
 
 func (mine *AgentInfo) initInfo(db *nosql.Agent) {
@@ -191,7 +201,7 @@ func (mine *AgentInfo) UpdateBase(name, remark, operator string) error {
 	if len(remark) < 1 {
 		remark = mine.Remark
 	}
-	err := nosql.UpdateAgentBase(mine.UID, name, remark, operator)
+	err := cacheCtx.agents.UpdateAgentBase(mine.UID, name, remark, operator)
 	if err == nil {
 		mine.Name = name
 		mine.Remark = remark
@@ -201,7 +211,7 @@ func (mine *AgentInfo) UpdateBase(name, remark, operator string) error {
 }
 
 func (mine *AgentInfo) UpdateStatus(operator string, st uint32) error {
-	err := nosql.UpdateAgentStatus(mine.UID, operator, uint8(st))
+	err := cacheCtx.agents.UpdateAgentStatus(mine.UID, operator, uint8(st))
 	if err == nil {
 		mine.Status = uint8(st)
 		mine.Operator = operator
@@ -210,7 +220,7 @@ func (mine *AgentInfo) UpdateStatus(operator string, st uint32) error {
 }
 
 func (mine *AgentInfo) UpdateEntity(entity, operator string) error {
-	err := nosql.UpdateAgentEntity(mine.UID, entity, operator)
+	err := cacheCtx.agents.UpdateAgentEntity(mine.UID, entity, operator)
 	if err == nil {
 		mine.Entity = entity
 		mine.Operator = operator
@@ -219,7 +229,7 @@ func (mine *AgentInfo) UpdateEntity(entity, operator string) error {
 }
 
 func (mine *AgentInfo) UpdateTags(operator string, tags []string) error {
-	err := nosql.UpdateAgentTags(mine.UID, operator, tags)
+	err := cacheCtx.agents.UpdateAgentTags(mine.UID, operator, tags)
 	if err == nil {
 		mine.Tags = tags
 		mine.Operator = operator
@@ -228,7 +238,7 @@ func (mine *AgentInfo) UpdateTags(operator string, tags []string) error {
 }
 
 func (mine *AgentInfo) UpdateRegions(operator string, list []string) error {
-	err := nosql.UpdateAgentRegions(mine.UID, operator, list)
+	err := cacheCtx.agents.UpdateAgentRegions(mine.UID, operator, list)
 	if err == nil {
 		mine.Regions = list
 		mine.Operator = operator
@@ -237,7 +247,7 @@ func (mine *AgentInfo) UpdateRegions(operator string, list []string) error {
 }
 
 func (mine *AgentInfo) UpdateAttaches(operator string, list []string) error {
-	err := nosql.UpdateAgentAttaches(mine.UID, operator, list)
+	err := cacheCtx.agents.UpdateAgentAttaches(mine.UID, operator, list)
 	if err == nil {
 		mine.Regions = list
 		mine.Operator = operator
@@ -258,7 +268,7 @@ func (mine *AgentInfo)AddAttach(uid string) error {
 	if mine.hadAttach(uid){
 		return nil
 	}
-	err := nosql.AppendAgentAttach(mine.UID, uid)
+	err := cacheCtx.agents.AppendAgentAttach(mine.UID, uid)
 	if err == nil {
 		mine.Attaches = append(mine.Attaches, uid)
 	}
@@ -269,7 +279,7 @@ func (mine *AgentInfo)RemoveAttach(uid string) error {
 	if !mine.hadAttach(uid){
 		return nil
 	}
-	err := nosql.SubtractAgentAttach(mine.UID, uid)
+	err := cacheCtx.agents.SubtractAgentAttach(mine.UID, uid)
 	if err == nil {
 		for i := 0;i < len(mine.Attaches);i += 1 {
 			if mine.Attaches[i] == uid {
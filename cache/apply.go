@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"omo.msa.assignment/proxy/nosql"
 	"time"
 )
@@ -29,6 +32,30 @@ const (
 	搁置
 	**/
 	ApplyStatusStay = 6
+
+	/**
+	超时未审核
+	**/
+	ApplyStatusExpired = 7
+)
+
+const (
+	/*
+		其他，通过后不修改成员
+	*/
+	ApplyTypeOther = 0
+	/*
+		加入小组(TeamInfo)
+	*/
+	ApplyTypeTeam = 1
+	/*
+		加入圈子(CoterieInfo)
+	*/
+	ApplyTypeCoterie = 2
+	/*
+		加入家庭(FamilyInfo)
+	*/
+	ApplyTypeFamily = 3
 )
 
 type ApplyInfo struct {
@@ -54,71 +81,80 @@ type ApplyInfo struct {
 	Group  string
 	Reason string
 	Remark string
+	/**
+	审核人以及审核时间
+	*/
+	Decider    string
+	DecideTime time.Time
+	RemindTime time.Time
 }
 
-func (mine *cacheContext) GetAppliesByUser(uid string) []*ApplyInfo {
-	list := make([]*ApplyInfo, 0, 5)
-	array, err := nosql.GetAppliesByApplicant(uid)
-	if err == nil {
-		for _, item := range array {
-			info := new(ApplyInfo)
-			info.initInfo(item)
-
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetAppliesByUser(uid string, page *PageInfo) (uint32, uint32, []*ApplyInfo) {
+	array, num, err := mine.applies.QueryApplies(page.query(true, proxy.Equal("applicant", uid)))
+	if err != nil {
+		return 0, 0, make([]*ApplyInfo, 0, 1)
+	}
+	list := make([]*ApplyInfo, 0, len(array))
+	for _, item := range array {
+		info := new(ApplyInfo)
+		info.initInfo(item)
+		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
-func (mine *cacheContext) GetAppliesByCreator(uid string) []*ApplyInfo {
-	list := make([]*ApplyInfo, 0, 5)
-	array, err := nosql.GetAppliesByCreator(uid)
-	if err == nil {
-		for _, item := range array {
-			info := new(ApplyInfo)
-			info.initInfo(item)
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetAppliesByCreator(uid string, page *PageInfo) (uint32, uint32, []*ApplyInfo) {
+	array, num, err := mine.applies.QueryApplies(page.query(true, proxy.Equal("creator", uid)))
+	if err != nil {
+		return 0, 0, make([]*ApplyInfo, 0, 1)
+	}
+	list := make([]*ApplyInfo, 0, len(array))
+	for _, item := range array {
+		info := new(ApplyInfo)
+		info.initInfo(item)
+		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
-func (mine *cacheContext) GetAppliesByGroup(uid string) []*ApplyInfo {
-	list := make([]*ApplyInfo, 0, 5)
-	array, err := nosql.GetAppliesByGroup(uid)
-	if err == nil {
-		for _, item := range array {
-			info := new(ApplyInfo)
-			info.initInfo(item)
-
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetAppliesByGroup(uid string, page *PageInfo) (uint32, uint32, []*ApplyInfo) {
+	array, num, err := mine.applies.QueryApplies(page.query(true, proxy.Equal("group", uid)))
+	if err != nil {
+		return 0, 0, make([]*ApplyInfo, 0, 1)
+	}
+	list := make([]*ApplyInfo, 0, len(array))
+	for _, item := range array {
+		info := new(ApplyInfo)
+		info.initInfo(item)
+		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
-func (mine *cacheContext) GetAppliesByOwner(scene string, tp int32) []*ApplyInfo {
-	var array []*nosql.Apply
-	var err error
-	if tp < 0 {
-		array, err = nosql.GetAppliesByScene1(scene)
-	} else {
-		array, err = nosql.GetAppliesByScene(scene, uint8(tp))
+func (mine *cacheContext) GetAppliesByOwner(scene string, tp int32, page *PageInfo) (uint32, uint32, []*ApplyInfo) {
+	conditions := []proxy.Condition{proxy.Equal("scene", scene)}
+	if tp >= 0 {
+		conditions = append(conditions, proxy.Equal("type", uint8(tp)))
+	}
+	array, num, err := mine.applies.QueryApplies(page.query(true, conditions...))
+	if err != nil {
+		return 0, 0, make([]*ApplyInfo, 0, 1)
 	}
-
 	list := make([]*ApplyInfo, 0, len(array))
-	if err == nil {
-		for _, item := range array {
-			info := new(ApplyInfo)
-			info.initInfo(item)
-			list = append(list, info)
-		}
+	for _, item := range array {
+		info := new(ApplyInfo)
+		info.initInfo(item)
+		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
 func (mine *cacheContext) GetApply(uid string) (*ApplyInfo, error) {
-	db, err := nosql.GetApply(uid)
+	db, err := mine.applies.GetApply(uid)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +164,7 @@ func (mine *cacheContext) GetApply(uid string) (*ApplyInfo, error) {
 }
 
 func (mine *cacheContext) RemoveApply(uid, operator string) error {
-	err := nosql.RemoveApply(uid, operator)
+	err := mine.applies.RemoveApply(uid, operator)
 	if err != nil {
 		return err
 	}
@@ -136,11 +172,29 @@ func (mine *cacheContext) RemoveApply(uid, operator string) error {
 }
 
 func (mine *cacheContext) CreateApply(creator, scene, group, applicant, inviter, remark string, tp uint8) (*ApplyInfo, error) {
+	db, err := mine.newApply(creator, scene, group, applicant, inviter, remark, tp)
+	if err != nil {
+		return nil, err
+	}
+	info := new(ApplyInfo)
+	info.initInfo(db)
+	err = mine.applies.CreateApply(db)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (mine *cacheContext) newApply(creator, scene, group, applicant, inviter, remark string, tp uint8) (*nosql.Apply, error) {
+	id, err := mine.nextID(nosql.TableApply)
+	if err != nil {
+		return nil, err
+	}
 	var db = new(nosql.Apply)
 	db.UID = primitive.NewObjectID()
 	db.CreatedTime = time.Now()
 	db.UpdatedTime = time.Now()
-	db.ID = nosql.GetApplyNextID()
+	db.ID = id
 	db.Creator = creator
 	db.Applicant = applicant
 	db.Inviter = inviter
@@ -150,14 +204,7 @@ func (mine *cacheContext) CreateApply(creator, scene, group, applicant, inviter,
 	db.Type = tp
 	db.Remark = remark
 	db.Status = ApplyStatusPending
-
-	info := new(ApplyInfo)
-	info.initInfo(db)
-	err := nosql.CreateApply(db)
-	if err != nil {
-		return nil, err
-	}
-	return info, nil
+	return db, nil
 }
 
 func (mine *ApplyInfo) initInfo(db *nosql.Apply) bool {
@@ -179,21 +226,118 @@ func (mine *ApplyInfo) initInfo(db *nosql.Apply) bool {
 	mine.Group = db.Group
 	mine.Remark = db.Remark
 	mine.Reason = db.Reason
+	mine.Decider = db.Decider
+	mine.DecideTime = db.DecidedTime
+	mine.RemindTime = db.RemindTime
 	return true
 }
 
+// CheckDecision 只有待审核和搁置的申请可以审核，审核结果为通过、被拒或者搁置
+func (mine *ApplyInfo) CheckDecision(dist uint8) error {
+	if dist != ApplyStatusPass && dist != ApplyStatusRefused && dist != ApplyStatusStay {
+		return errors.New(fmt.Sprintf("the apply status(%d) is not a decision", dist))
+	}
+	if mine.Status == dist {
+		return nil
+	}
+	if mine.Status != ApplyStatusPending && mine.Status != ApplyStatusStay {
+		return errors.New(fmt.Sprintf("the apply had been decided(%d) by %s", mine.Status, mine.Decider))
+	}
+	return nil
+}
+
+// SetStatus 审核申请，通过时把申请人加入对应的小组或者圈子；
+// 重复提交相同的结果不会再修改，通过的申请会再确认一次成员，用于修复加入失败的情况
 func (mine *ApplyInfo) SetStatus(dist uint8, reason, operator string) error {
-	//if mine.Status != ApplyStatusPending {
-	//	return errors.New("the apply now status not pending")
-	//}
 	if dist == mine.Status {
-		//return errors.New("the apply dist status is pending")
+		if dist == ApplyStatusPass {
+			return mine.join()
+		}
 		return nil
 	}
-	err := nosql.UpdateApply(mine.UID, reason, operator, dist)
-	if err == nil {
+	err := mine.CheckDecision(dist)
+	if err != nil {
+		return err
+	}
+	if dist == ApplyStatusRefused && len(reason) < 1 {
+		return errors.New("the reason of refusal is empty")
+	}
+	if dist == ApplyStatusPass {
+		err = mine.checkGroup()
+		if err != nil {
+			return err
+		}
+	}
+	ok, err := cacheCtx.applies.DecideApply(mine.UID, operator, reason, []uint8{mine.Status}, dist)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 已经被其他人审核了，以数据库中的结果为准
+		db, er := cacheCtx.applies.GetApply(mine.UID)
+		if er != nil {
+			return er
+		}
+		mine.initInfo(db)
+		if !db.DeleteTime.IsZero() {
+			return errors.New("the apply had been removed")
+		}
+		if mine.Status != dist {
+			return errors.New(fmt.Sprintf("the apply had been decided(%d) by %s", mine.Status, mine.Decider))
+		}
+	} else {
 		mine.Status = dist
-		mine.UpdateTime = time.Now()
+		mine.Reason = reason
+		mine.Operator = operator
+		mine.Decider = operator
+		mine.DecideTime = time.Now()
+		mine.UpdateTime = mine.DecideTime
+	}
+	if dist == ApplyStatusPass {
+		return mine.join()
+	}
+	return nil
+}
+
+// 通过之前确认申请加入的小组或者圈子存在
+func (mine *ApplyInfo) checkGroup() error {
+	var err error
+	switch mine.Type {
+	case ApplyTypeTeam:
+		_, err = cacheCtx.GetTeam(mine.Group)
+	case ApplyTypeCoterie:
+		_, err = cacheCtx.GetCoterie(mine.Group)
+	case ApplyTypeFamily:
+		_, err = cacheCtx.GetFamily(mine.Group)
 	}
-	return err
+	if err != nil {
+		return errors.New(fmt.Sprintf("the group(%s) of apply not found: %s", mine.Group, err.Error()))
+	}
+	return nil
+}
+
+// 把申请人加入小组或者圈子，已经是成员时不修改；小组满员时加入候补
+func (mine *ApplyInfo) join() error {
+	switch mine.Type {
+	case ApplyTypeTeam:
+		team, err := cacheCtx.GetTeam(mine.Group)
+		if err != nil {
+			return err
+		}
+		_, err = team.Join(mine.Applicant, mine.Decider)
+		return err
+	case ApplyTypeCoterie:
+		coterie, err := cacheCtx.GetCoterie(mine.Group)
+		if err != nil {
+			return err
+		}
+		return coterie.AppendMember(mine.Applicant, "", mine.Remark)
+	case ApplyTypeFamily:
+		family, err := cacheCtx.GetFamily(mine.Group)
+		if err != nil {
+			return err
+		}
+		return family.AppendMember(mine.Applicant, "", mine.Remark)
+	}
+	return nil
 }
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestApplySetStatus(t *testing.T) {
+	type decision struct {
+		dist   uint8
+		reason string
+	}
+	cases := []struct {
+		name    string
+		before  []decision
+		dist    uint8
+		reason  string
+		missing bool
+		wantErr bool
+		status  uint8
+		joined  bool
+	}{
+		{name: "pass", dist: ApplyStatusPass, status: ApplyStatusPass, joined: true},
+		{name: "pass again", before: []decision{{dist: ApplyStatusPass}}, dist: ApplyStatusPass, status: ApplyStatusPass, joined: true},
+		{name: "refuse", dist: ApplyStatusRefused, reason: "full", status: ApplyStatusRefused},
+		{name: "refuse without reason", dist: ApplyStatusRefused, wantErr: true, status: ApplyStatusPending},
+		{name: "stay then pass", before: []decision{{dist: ApplyStatusStay}}, dist: ApplyStatusPass, status: ApplyStatusPass, joined: true},
+		{name: "refused then pass", before: []decision{{dist: ApplyStatusRefused, reason: "no"}}, dist: ApplyStatusPass,
+			wantErr: true, status: ApplyStatusRefused},
+		{name: "not a decision", dist: ApplyStatusExpired, wantErr: true, status: ApplyStatusPending},
+		{name: "group missing", missing: true, dist: ApplyStatusPass, wantErr: true, status: ApplyStatusPending},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			team := newTestTeam(t, ctx, "scene", 0, "a")
+			group := team.UID
+			if item.missing {
+				group = "5f0fbf01b780dd269d83eb79"
+			}
+			info, err := ctx.CreateApply("admin", "scene", group, "user", "", "", ApplyTypeTeam)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range item.before {
+				if err = info.SetStatus(step.dist, step.reason, "admin"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = info.SetStatus(item.dist, item.reason, "decider")
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			db, _ := ctx.GetApply(info.UID)
+			if db.Status != item.status {
+				t.Errorf("the status = %d, want %d", db.Status, item.status)
+			}
+			if err == nil && len(item.before) < 1 && db.Decider != "decider" {
+				t.Errorf("the decider = %s, want decider", db.Decider)
+			}
+			team, _ = ctx.GetTeam(team.UID)
+			if team.HadMember("user") != item.joined {
+				t.Errorf("the applicant joined = %v, want %v", team.HadMember("user"), item.joined)
+			}
+		})
+	}
+}
+
+func TestApplySetStatusChanged(t *testing.T) {
+	ctx := newTestContext(t)
+	team := newTestTeam(t, ctx, "scene", 0)
+	info, err := ctx.CreateApply("admin", "scene", team.UID, "user", "", "", ApplyTypeTeam)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, _ := ctx.GetApply(info.UID)
+	if err = info.SetStatus(ApplyStatusRefused, "no", "first"); err != nil {
+		t.Fatal(err)
+	}
+	// 已经被其他人审核时以数据库中的结果为准
+	if err = stale.SetStatus(ApplyStatusPass, "", "second"); err == nil {
+		t.Fatal("the apply should not be decided twice")
+	}
+	if stale.Status != ApplyStatusRefused || stale.Decider != "first" {
+		t.Errorf("the status = %d, the decider = %s", stale.Status, stale.Decider)
+	}
+	if err = stale.SetStatus(ApplyStatusRefused, "no", "second"); err != nil {
+		t.Errorf("the same decision should be ignored: %v", err)
+	}
+	team, _ = ctx.GetTeam(team.UID)
+	if team.HadMember("user") {
+		t.Error("the refused applicant should not join the team")
+	}
+}
+
+func TestDecideApply(t *testing.T) {
+	steps := []struct {
+		name string
+		from []uint8
+		dist uint8
+		want bool
+	}{
+		{name: "stay", from: []uint8{ApplyStatusPending}, dist: ApplyStatusStay, want: true},
+		{name: "status changed", from: []uint8{ApplyStatusPending}, dist: ApplyStatusPass},
+		{name: "pass", from: []uint8{ApplyStatusPending, ApplyStatusStay}, dist: ApplyStatusPass, want: true},
+		{name: "decided", from: []uint8{ApplyStatusPending, ApplyStatusStay}, dist: ApplyStatusRefused},
+	}
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			if err := InitDataWith(backend.stores(t)); err != nil {
+				t.Fatal(err)
+			}
+			info, err := cacheCtx.CreateApply("admin", "scene", "group", "user", "", "", ApplyTypeOther)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range steps {
+				ok, err := cacheCtx.applies.DecideApply(info.UID, step.name, "", step.from, step.dist)
+				if err != nil || ok != step.want {
+					t.Errorf("%s: the result = %v, the error = %v, want %v", step.name, ok, err, step.want)
+				}
+			}
+			db, _ := cacheCtx.GetApply(info.UID)
+			if db.Status != ApplyStatusPass || db.Decider != "pass" || db.DecideTime.IsZero() {
+				t.Errorf("the status = %d, the decider = %s, the decided = %v", db.Status, db.Decider, db.DecideTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"omo.msa.assignment/tool"
+	"time"
+)
+
+const (
+	AnswerNone      uint8 = 0
+	AnswerAccept    uint8 = 1
+	AnswerDecline   uint8 = 2
+	AnswerTentative uint8 = 3
+)
+
+type MeetingNotifyHandler func(info *MeetingInfo, users []string)
+
+var meetingNotifyHandlers = make([]MeetingNotifyHandler, 0, 1)
+
+// WatchMeetingNotify 注册回调，邀请成员参加会议时通知，users为新邀请的成员
+func WatchMeetingNotify(handler MeetingNotifyHandler) {
+	if handler != nil {
+		meetingNotifyHandlers = append(meetingNotifyHandlers, handler)
+	}
+}
+
+// AttendanceItem 成员的出勤情况，Expected表示应该参加(小组的成员或者被邀请)
+type AttendanceItem struct {
+	proxy.AttendInfo
+	Expected  bool
+	Signed    bool
+	LeftEarly bool
+}
+
+// AttendanceReport 会议的出勤统计，Extra为签到了但不在应到名单中的人数
+type AttendanceReport struct {
+	Expected  int
+	Signed    int
+	Late      int
+	Absent    int
+	LeftEarly int
+	Submitted int
+	Notified  int
+	Acked     int
+	Extra     int
+	Items     []*AttendanceItem
+}
+
+// 以前只保存了用户的签到、提交和通知，没有对应的记录时补充一条没有时间的记录
+func (mine *MeetingInfo) initAttends(list []proxy.AttendInfo) {
+	mine.Attends = make([]proxy.AttendInfo, 0, len(list)+1)
+	mine.Attends = append(mine.Attends, list...)
+	for _, arr := range [][]string{mine.Signs, mine.Submits, mine.Notifies} {
+		for _, user := range arr {
+			if mine.attendIndex(user) < 0 {
+				mine.Attends = append(mine.Attends, proxy.AttendInfo{User: user, Distance: -1})
+			}
+		}
+	}
+}
+
+func (mine *MeetingInfo) attendIndex(user string) int {
+	for i := 0; i < len(mine.Attends); i += 1 {
+		if mine.Attends[i].User == user {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetAttend 成员的参加情况，没有时返回空的记录
+func (mine *MeetingInfo) GetAttend(user string) proxy.AttendInfo {
+	i := mine.attendIndex(user)
+	if i < 0 {
+		return proxy.AttendInfo{User: user, Distance: -1}
+	}
+	return mine.Attends[i]
+}
+
+// 一次更新保存成员的参加情况，field为需要加入成员的数组，record为签到记录
+func (mine *MeetingInfo) saveAttend(attend proxy.AttendInfo, field string, record *proxy.SignInfo, operator string) error {
+	change := &nosql.AttendChange{Attend: &attend, Record: record, Field: field, Operator: operator}
+	err := cacheCtx.meetings.SaveMeetingAttend(mine.UID, change)
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		mine.Records = append(mine.Records, *record)
+	}
+	switch field {
+	case "signs":
+		mine.Signs = append(mine.Signs, attend.User)
+	case "submits":
+		mine.Submits = append(mine.Submits, attend.User)
+	case "notifies":
+		mine.Notifies = append(mine.Notifies, attend.User)
+	}
+	if len(operator) > 0 {
+		mine.Operator = operator
+	}
+	i := mine.attendIndex(attend.User)
+	if i < 0 {
+		mine.Attends = append(mine.Attends, attend)
+	} else {
+		mine.Attends[i] = attend
+	}
+	return nil
+}
+
+func (mine *MeetingInfo) HadNotified(user string) bool {
+	for _, item := range mine.Notifies {
+		if item == user {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify 邀请成员参加会议，已经邀请过的不再通知；返回新邀请的成员
+func (mine *MeetingInfo) Notify(users []string, operator string) ([]string, error) {
+	list := make([]string, 0, len(users))
+	now := time.Now()
+	for _, user := range users {
+		if len(user) < 1 || mine.HadNotified(user) {
+			continue
+		}
+		attend := mine.GetAttend(user)
+		attend.NotifiedTime = now
+		err := mine.saveAttend(attend, "notifies", nil, operator)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, user)
+	}
+	mine.Operator = operator
+	if len(list) > 0 {
+		for _, handler := range meetingNotifyHandlers {
+			handler(mine, list)
+		}
+	}
+	return list, nil
+}
+
+// Acknowledge 被邀请的成员回复是否参加，可以修改回复
+func (mine *MeetingInfo) Acknowledge(user string, answer uint8) error {
+	if answer < AnswerAccept || answer > AnswerTentative {
+		return errors.New("the answer should be accept, decline or tentative")
+	}
+	if !mine.HadNotified(user) {
+		return errors.New("the user had not been invited")
+	}
+	attend := mine.GetAttend(user)
+	attend.AckedTime = time.Now()
+	attend.Answer = answer
+	return mine.saveAttend(attend, "", nil, mine.Operator)
+}
+
+// Leave 记录签到的成员离开的时间，早于结束时间为早退
+func (mine *MeetingInfo) Leave(user string) error {
+	if !mine.HadSigned(user) {
+		return errors.New("the user had not signed")
+	}
+	attend := mine.GetAttend(user)
+	if !attend.LeftTime.IsZero() {
+		return nil
+	}
+	attend.LeftTime = time.Now()
+	return mine.saveAttend(attend, "", nil, mine.Operator)
+}
+
+func (mine *MeetingInfo) leftEarly(attend proxy.AttendInfo) bool {
+	if attend.LeftTime.IsZero() {
+		return false
+	}
+	return mine.StopTime.IsZero() || attend.LeftTime.Before(mine.StopTime)
+}
+
+// GetAttendance 会议的出勤统计，应到的为所属小组的成员以及被邀请的成员
+func (mine *MeetingInfo) GetAttendance() *AttendanceReport {
+	report := new(AttendanceReport)
+	expected := make([]string, 0, 10)
+	team, er := cacheCtx.GetTeam(mine.Group)
+	if er == nil {
+		expected = append(expected, team.Members...)
+	}
+	expected = append(expected, mine.Notifies...)
+	report.Items = make([]*AttendanceItem, 0, len(expected)+len(mine.Attends))
+	added := make(map[string]bool, len(expected)+len(mine.Attends))
+	appendItem := func(user string, expect bool) {
+		if len(user) < 1 || added[user] {
+			return
+		}
+		added[user] = true
+		item := &AttendanceItem{AttendInfo: mine.GetAttend(user), Expected: expect, Signed: mine.HadSigned(user)}
+		item.LeftEarly = item.Signed && mine.leftEarly(item.AttendInfo)
+		report.Items = append(report.Items, item)
+		if expect {
+			report.Expected += 1
+			if !item.Signed {
+				report.Absent += 1
+			}
+		} else if item.Signed {
+			report.Extra += 1
+		}
+		if item.Signed {
+			report.Signed += 1
+			if item.Late {
+				report.Late += 1
+			}
+		}
+		if item.LeftEarly {
+			report.LeftEarly += 1
+		}
+		if !item.SubmittedTime.IsZero() || tool.HasItem(mine.Submits, user) {
+			report.Submitted += 1
+		}
+		if mine.HadNotified(user) {
+			report.Notified += 1
+		}
+		if !item.AckedTime.IsZero() {
+			report.Acked += 1
+		}
+	}
+	for _, user := range expected {
+		appendItem(user, true)
+	}
+	for _, attend := range mine.Attends {
+		appendItem(attend.User, false)
+	}
+	return report
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy/nosql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetAttendance(t *testing.T) {
+	ctx := newTestContext(t)
+	team := newTestTeam(t, ctx, "scene", 0, "a", "b", "c")
+	start := time.Now().Add(-time.Hour)
+	info := newTestMeeting(t, ctx, "scene", team.UID, "", start)
+	if err := info.updateDate(start.Unix(), time.Now().Add(time.Hour).Unix(), "admin"); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "never late", run: func() error { return info.UpdateSignRule(0, -1, -1, "admin") }},
+		{name: "sign a", run: func() error { _, err := info.Sign("a", "a", "", ""); return err }},
+		{name: "submit a", run: func() error { return info.Submit("a", "a") }},
+		{name: "leave a", run: func() error { return info.Leave("a") }},
+		{name: "late", run: func() error { return info.UpdateSignRule(0, -1, 60, "admin") }},
+		{name: "sign b", run: func() error { _, err := info.Sign("b", "b", "", ""); return err }},
+		{name: "notify d", run: func() error { _, err := info.Notify([]string{"d", ""}, "admin"); return err }},
+		{name: "ack d", run: func() error { return info.Acknowledge("d", AnswerAccept) }},
+		{name: "sign e", run: func() error { _, err := info.Sign("e", "e", "", ""); return err }},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+	}
+	db, _ := ctx.GetMeeting(info.UID)
+	report := db.GetAttendance()
+	want := AttendanceReport{Expected: 4, Signed: 3, Late: 2, Absent: 2, LeftEarly: 1, Submitted: 1, Notified: 1, Acked: 1, Extra: 1}
+	got := *report
+	got.Items = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the report = %+v, want %+v", got, want)
+	}
+	cases := []struct {
+		user      string
+		expected  bool
+		signed    bool
+		late      bool
+		leftEarly bool
+		answer    uint8
+	}{
+		{user: "a", expected: true, signed: true, leftEarly: true},
+		{user: "b", expected: true, signed: true, late: true},
+		{user: "c", expected: true},
+		{user: "d", expected: true, answer: AnswerAccept},
+		{user: "e", signed: true, late: true},
+	}
+	if len(report.Items) != len(cases) {
+		t.Fatalf("the items = %d, want %d", len(report.Items), len(cases))
+	}
+	items := make(map[string]*AttendanceItem, len(report.Items))
+	for _, item := range report.Items {
+		items[item.User] = item
+	}
+	for _, item := range cases {
+		t.Run(item.user, func(t *testing.T) {
+			got, ok := items[item.user]
+			if !ok {
+				t.Fatal("the user is not in the report")
+			}
+			if got.Expected != item.expected || got.Signed != item.signed || got.Late != item.late ||
+				got.LeftEarly != item.leftEarly || got.Answer != item.answer {
+				t.Errorf("the item = %+v, want %+v", *got, item)
+			}
+		})
+	}
+}
+
+func TestAttendanceLegacy(t *testing.T) {
+	ctx := newTestContext(t)
+	// 以前只保存了签到、提交和通知的用户
+	db := &nosql.Meeting{UID: primitive.NewObjectID(), Name: "meeting", CreatedTime: time.Now(),
+		Signs: []string{"a", "b"}, Submits: []string{"a"}, Notifies: []string{"b", "c"}}
+	if err := ctx.meetings.CreateMeeting(db); err != nil {
+		t.Fatal(err)
+	}
+	info, err := ctx.GetMeeting(db.UID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Attends) != 3 || info.GetAttend("a").Distance != -1 {
+		t.Errorf("the attends = %+v", info.Attends)
+	}
+	got := *info.GetAttendance()
+	got.Items = nil
+	want := AttendanceReport{Expected: 2, Signed: 2, Absent: 1, Submitted: 1, Notified: 2, Extra: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the report = %+v, want %+v", got, want)
+	}
+}
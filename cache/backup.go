@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"omo.msa.assignment/proxy/nosql"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const backupSuffix = ".jsonl"
+
+func checkBackupTables(tables []string) ([]string, error) {
+	if len(tables) < 1 {
+		return nosql.BackupTables, nil
+	}
+	list := make([]string, 0, len(tables))
+	// 按照BackupTables的顺序，先恢复序号
+	for _, table := range nosql.BackupTables {
+		for _, item := range tables {
+			if item == table {
+				list = append(list, table)
+				break
+			}
+		}
+	}
+	for _, item := range tables {
+		if _, err := nosql.NewDocument(item); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Backup 备份到dir下以时间命名的目录中，每个集合一个文件，tables为空时备份全部集合，返回备份的目录
+func (mine *cacheContext) Backup(dir string, tables []string) (string, error) {
+	if mine.backups == nil {
+		return "", errors.New("the database not support backup")
+	}
+	list, err := checkBackupTables(tables)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, time.Now().Format("20060102150405"))
+	err = os.MkdirAll(path, 0755)
+	if err != nil {
+		return "", err
+	}
+	for _, table := range list {
+		docs, err := mine.backups.DumpTable(table)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("dump the table %s failed: %s", table, err.Error()))
+		}
+		err = nosql.WriteBackupFile(filepath.Join(path, table+backupSuffix), table, docs)
+		if err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// Restore 从备份目录恢复，会清空集合中原来的数据；tables为空时恢复目录中存在的全部集合，
+// 先读取并校验所有的文件，有文件损坏时不修改任何数据
+func (mine *cacheContext) Restore(path string, tables []string) ([]string, error) {
+	if mine.backups == nil {
+		return nil, errors.New("the database not support backup")
+	}
+	list, err := checkBackupTables(tables)
+	if err != nil {
+		return nil, err
+	}
+	type backupFile struct {
+		table string
+		docs  []interface{}
+	}
+	files := make([]*backupFile, 0, len(list))
+	for _, table := range list {
+		file := filepath.Join(path, table+backupSuffix)
+		if _, err := os.Stat(file); err != nil {
+			if len(tables) < 1 && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		header, docs, err := nosql.ReadBackupFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if header.Table != table {
+			return nil, errors.New(fmt.Sprintf("the backup file %s is for the table %s", file, header.Table))
+		}
+		files = append(files, &backupFile{table: table, docs: docs})
+	}
+	if len(files) < 1 {
+		return nil, errors.New("not found any backup file in " + path)
+	}
+	restored := make([]string, 0, len(files))
+	for _, item := range files {
+		err = mine.backups.RestoreTable(item.table, item.docs)
+		if err != nil {
+			return restored, errors.New(fmt.Sprintf("restore the table %s failed: %s", item.table, err.Error()))
+		}
+		restored = append(restored, item.table)
+	}
+	// 只恢复了部分集合时，序号可能小于集合中的id
+	err = mine.checkSequences()
+	if err != nil {
+		return restored, err
+	}
+	// 以前的备份中可能是明文的密码
+	return restored, mine.migratePasswords()
+}
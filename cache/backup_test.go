@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/memory"
+	"omo.msa.assignment/proxy/nosql"
+	"testing"
+	"time"
+)
+
+func TestBackupRestore(t *testing.T) {
+	cases := []struct {
+		name   string
+		tables []string
+		// 恢复后存在的小组，为备份前后创建的顺序
+		alive []bool
+	}{
+		{name: "all", alive: []bool{true, false, false}},
+		{name: "teams", tables: []string{nosql.TableTeam}, alive: []bool{true, false, false}},
+		{name: "sequences", tables: []string{nosql.TableSequence}, alive: []bool{true, true, true}},
+	}
+	for _, backend := range testBackends {
+		for _, item := range cases {
+			t.Run(backend.name+"/"+item.name, func(t *testing.T) {
+				if err := InitDataWith(backend.stores(t)); err != nil {
+					t.Fatal(err)
+				}
+				ctx := cacheCtx
+				teams := []*TeamInfo{newTestTeam(t, ctx, "scene", 0)}
+				path, err := ctx.Backup(t.TempDir(), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				teams = append(teams, newTestTeam(t, ctx, "scene", 0), newTestTeam(t, ctx, "scene", 0))
+				if _, err = ctx.Restore(path, item.tables); err != nil {
+					t.Fatal(err)
+				}
+				var max uint64
+				for i, team := range teams {
+					db, err := ctx.GetTeam(team.UID)
+					if (err == nil) != item.alive[i] {
+						t.Errorf("the team %d is alive = %v, want %v", i, err == nil, item.alive[i])
+					}
+					if err == nil && db.ID > max {
+						max = db.ID
+					}
+				}
+				// 只恢复了序号时序号会修正为集合中最大的id
+				if team := newTestTeam(t, ctx, "scene", 0); team.ID <= max {
+					t.Errorf("the id of new team = %d, want more than %d", team.ID, max)
+				}
+			})
+		}
+	}
+}
+
+func TestInitStore(t *testing.T) {
+	stores := memory.NewStores()
+	coterie := &nosql.Coterie{UID: primitive.NewObjectID(), ID: 1, Name: "coterie", Passwords: "123456",
+		CreatedTime: time.Now(), UpdatedTime: time.Now()}
+	task := &nosql.Task{UID: primitive.NewObjectID(), ID: 1, Name: "task", CreatedTime: time.Now(), UpdatedTime: time.Now(),
+		Records: []proxy.RecordInfo{{Name: "record"}}}
+	if err := stores.Coterie.CreateCoterie(coterie); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Task.CreateTask(task); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		init    func(stores *nosql.Stores) error
+		migrate bool
+	}{
+		{name: "store only", init: initStores},
+		{name: "data", init: InitDataWith, migrate: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			if err := item.init(stores); err != nil {
+				t.Fatal(err)
+			}
+			db, _ := stores.Coterie.GetCoterie(coterie.UID.Hex())
+			if isHashedPasswords(db.Passwords) != item.migrate {
+				t.Errorf("the passwords is hashed = %v, want %v", isHashedPasswords(db.Passwords), item.migrate)
+			}
+			record, _ := stores.Task.GetTask(task.UID.Hex())
+			if (len(record.Records[0].UID) > 0) != item.migrate {
+				t.Errorf("the record uid = %q, want filled = %v", record.Records[0].UID, item.migrate)
+			}
+		})
+	}
+}
@@ -0,0 +1,507 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"omo.msa.assignment/proxy/nosql"
+	"omo.msa.assignment/tool"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	BankFormatJson = "json"
+	BankFormatCsv  = "csv"
+	BankFormatXlsx = "xlsx"
+)
+
+// 分类路径的分隔符，路径从导出的分类开始，例如 历史/近代史
+const bankPathSep = "/"
+
+// csv和xlsx的表头，options之后的每一列都是一个选项，格式为 id:描述
+var bankHeader = []string{"category", "title", "remark", "quote", "cd", "answers", "assets", "options"}
+
+// BankData 题库导入导出的数据，categories包含没有题目的分类
+type BankData struct {
+	Categories []string        `json:"categories"`
+	Questions  []*BankQuestion `json:"questions"`
+}
+
+type BankQuestion struct {
+	Category string        `json:"category"`
+	Title    string        `json:"title"`
+	Remark   string        `json:"remark"`
+	Quote    string        `json:"quote"`
+	Cd       uint32        `json:"cd"`
+	Answers  []uint32      `json:"answers"`
+	Assets   []string      `json:"assets"`
+	Options  []*BankOption `json:"options"`
+}
+
+type BankOption struct {
+	ID   uint32 `json:"id"`
+	Desc string `json:"desc"`
+}
+
+// BankReport 导入的结果，有不合法的数据时不会导入任何数据；
+// Failed为创建失败的题目，失败后不再导入之后的题目，Skipped为没有导入的数量
+type BankReport struct {
+	Total      uint32
+	Created    uint32
+	Duplicated uint32
+	Invalid    uint32
+	Failed     uint32
+	Skipped    uint32
+	// 新建的分类数量
+	Categories uint32
+	Messages   []string
+}
+
+func (mine *BankReport) addMessage(msg string) {
+	mine.Messages = append(mine.Messages, msg)
+}
+
+// ExportQuestionBank 导出分类及其所有子分类下的题目
+func (mine *cacheContext) ExportQuestionBank(category, format string) ([]byte, error) {
+	root, err := mine.GetOneCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, errors.New("not found the category of " + category)
+	}
+	data := new(BankData)
+	data.Categories = make([]string, 0, 10)
+	data.Questions = make([]*BankQuestion, 0, 50)
+	list := []*CategoryInfo{root}
+	paths := map[string]string{root.UID: root.Name}
+	for i := 0; i < len(list); i++ {
+		item := list[i]
+		data.Categories = append(data.Categories, paths[item.UID])
+		_, _, children, err := mine.GetCategoriesByParent(item.UID, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if _, ok := paths[child.UID]; !ok {
+				paths[child.UID] = paths[item.UID] + bankPathSep + child.Name
+				list = append(list, child)
+			}
+		}
+		_, _, questions, err := mine.GetQuestionsByCategory(item.UID, nil)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(questions, func(a, b int) bool {
+			return questions[a].ID < questions[b].ID
+		})
+		for _, question := range questions {
+			data.Questions = append(data.Questions, switchBankQuestion(paths[item.UID], question))
+		}
+	}
+	return data.Encode(format)
+}
+
+func switchBankQuestion(path string, info *QuestionInfo) *BankQuestion {
+	tmp := new(BankQuestion)
+	tmp.Category = path
+	tmp.Title = info.Name
+	tmp.Remark = info.Remark
+	tmp.Quote = info.Quote
+	tmp.Cd = uint32(info.Cd)
+	tmp.Answers = info.Answers
+	tmp.Assets = info.Assets
+	tmp.Options = make([]*BankOption, 0, len(info.Options))
+	for _, option := range info.Options {
+		id, _ := strconv.ParseUint(option.Key, 10, 32)
+		tmp.Options = append(tmp.Options, &BankOption{ID: uint32(id), Desc: option.Value})
+	}
+	return tmp
+}
+
+// Encode 按照格式编码，csv和xlsx中只有分类没有标题的行表示空的分类
+func (mine *BankData) Encode(format string) ([]byte, error) {
+	var buf bytes.Buffer
+	if format == BankFormatJson {
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(mine)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	rows := make([][]string, 0, len(mine.Questions)+len(mine.Categories)+1)
+	rows = append(rows, bankHeader)
+	used := make(map[string]bool, len(mine.Categories))
+	for _, item := range mine.Questions {
+		used[item.Category] = true
+	}
+	for _, item := range mine.Categories {
+		if !used[item] {
+			rows = append(rows, []string{item})
+		}
+	}
+	for _, item := range mine.Questions {
+		row := []string{item.Category, item.Title, item.Remark, item.Quote, strconv.FormatUint(uint64(item.Cd), 10),
+			joinUint32(item.Answers), strings.Join(item.Assets, ",")}
+		for _, option := range item.Options {
+			row = append(row, fmt.Sprintf("%d:%s", option.ID, option.Desc))
+		}
+		rows = append(rows, row)
+	}
+	switch format {
+	case BankFormatCsv:
+		writer := csv.NewWriter(&buf)
+		err := writer.WriteAll(rows)
+		if err != nil {
+			return nil, err
+		}
+	case BankFormatXlsx:
+		err := tool.WriteXLSX(&buf, "questions", rows)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("the format not supported: " + format)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeBankData 解析导入的数据，只检查格式，内容的校验在导入时进行
+func DecodeBankData(format string, data []byte) (*BankData, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case BankFormatJson:
+		info := new(BankData)
+		err = json.Unmarshal(data, info)
+		if err != nil {
+			return nil, err
+		}
+		return info, nil
+	case BankFormatCsv:
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		rows, err = reader.ReadAll()
+	case BankFormatXlsx:
+		rows, err = tool.ReadXLSX(data)
+	default:
+		return nil, errors.New("the format not supported: " + format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 1 || len(rows[0]) < 1 || strings.TrimSpace(rows[0][0]) != bankHeader[0] {
+		return nil, errors.New("the header is error, it should be " + strings.Join(bankHeader, ","))
+	}
+	info := new(BankData)
+	info.Categories = make([]string, 0, 5)
+	info.Questions = make([]*BankQuestion, 0, len(rows))
+	for i, row := range rows[1:] {
+		cells := make([]string, len(bankHeader)-1)
+		copy(cells, row)
+		if len(strings.TrimSpace(cells[0])) < 1 {
+			continue
+		}
+		if len(strings.TrimSpace(cells[1])) < 1 {
+			info.Categories = append(info.Categories, cells[0])
+			continue
+		}
+		item := &BankQuestion{Category: cells[0], Title: cells[1], Remark: cells[2], Quote: cells[3]}
+		if len(cells[4]) > 0 {
+			cd, err := strconv.ParseUint(strings.TrimSpace(cells[4]), 10, 32)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("row %d: the cd is error: %s", i+2, cells[4]))
+			}
+			item.Cd = uint32(cd)
+		}
+		item.Answers, err = splitUint32(cells[5])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("row %d: the answers is error: %s", i+2, cells[5]))
+		}
+		item.Assets = make([]string, 0, 1)
+		for _, asset := range strings.Split(cells[6], ",") {
+			if asset = strings.TrimSpace(asset); len(asset) > 0 {
+				item.Assets = append(item.Assets, asset)
+			}
+		}
+		item.Options = make([]*BankOption, 0, 4)
+		if len(row) > len(cells) {
+			for _, cell := range row[len(cells):] {
+				if len(strings.TrimSpace(cell)) < 1 {
+					continue
+				}
+				index := strings.Index(cell, ":")
+				if index < 1 {
+					return nil, errors.New(fmt.Sprintf("row %d: the option should be id:desc: %s", i+2, cell))
+				}
+				id, err := strconv.ParseUint(strings.TrimSpace(cell[:index]), 10, 32)
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("row %d: the option id is error: %s", i+2, cell))
+				}
+				item.Options = append(item.Options, &BankOption{ID: uint32(id), Desc: cell[index+1:]})
+			}
+		}
+		info.Questions = append(info.Questions, item)
+	}
+	return info, nil
+}
+
+func joinUint32(list []uint32) string {
+	arr := make([]string, 0, len(list))
+	for _, item := range list {
+		arr = append(arr, strconv.FormatUint(uint64(item), 10))
+	}
+	return strings.Join(arr, ",")
+}
+
+func splitUint32(src string) ([]uint32, error) {
+	list := make([]uint32, 0, 4)
+	for _, item := range strings.Split(src, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 1 {
+			continue
+		}
+		num, err := strconv.ParseUint(item, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, uint32(num))
+	}
+	return list, nil
+}
+
+// 校验题目的内容，答案必须是已有选项的id
+func (mine *BankQuestion) check() error {
+	if len(mine.Title) < 1 {
+		return errors.New("the title is empty")
+	}
+	if len(mine.Options) < 1 {
+		return errors.New("the options is empty")
+	}
+	if len(mine.Answers) < 1 {
+		return errors.New("the answers is empty")
+	}
+	if mine.Cd > 65535 {
+		return errors.New(fmt.Sprintf("the cd %d is out of range", mine.Cd))
+	}
+	ids := make(map[uint32]bool, len(mine.Options))
+	for _, option := range mine.Options {
+		if ids[option.ID] {
+			return errors.New(fmt.Sprintf("the option id %d is repeated", option.ID))
+		}
+		ids[option.ID] = true
+	}
+	for _, answer := range mine.Answers {
+		if !ids[answer] {
+			return errors.New(fmt.Sprintf("the answer %d not in the options", answer))
+		}
+	}
+	return nil
+}
+
+func splitBankPath(path string) ([]string, error) {
+	arr := strings.Split(path, bankPathSep)
+	for i := range arr {
+		arr[i] = strings.TrimSpace(arr[i])
+		if len(arr[i]) < 1 {
+			return nil, errors.New("the category path is error: " + path)
+		}
+	}
+	return arr, nil
+}
+
+// 导入时按照路径查找分类，不存在的分类在非预演时创建
+type bankResolver struct {
+	parent   string
+	operator string
+	dry      bool
+	report   *BankReport
+	// 路径对应的分类uid，预演时新分类的uid为空
+	paths map[string]string
+}
+
+func (mine *bankResolver) resolve(path string) (string, error) {
+	names, err := splitBankPath(path)
+	if err != nil {
+		return "", err
+	}
+	parent := mine.parent
+	current := ""
+	for _, name := range names {
+		if len(current) > 0 {
+			current += bankPathSep
+		}
+		current += name
+		if uid, ok := mine.paths[current]; ok {
+			parent = uid
+			continue
+		}
+		uid := ""
+		if len(parent) > 0 {
+			_, _, children, err := cacheCtx.GetCategoriesByParent(parent, nil)
+			if err != nil {
+				return "", err
+			}
+			for _, child := range children {
+				if child.Name == name {
+					uid = child.UID
+					break
+				}
+			}
+		}
+		if len(uid) < 1 {
+			if !mine.dry {
+				info, err := cacheCtx.NewCategory(name, parent, "", mine.operator, 0)
+				if err != nil {
+					return "", err
+				}
+				uid = info.UID
+			}
+			mine.report.Categories += 1
+		}
+		mine.paths[current] = uid
+		parent = uid
+	}
+	return parent, nil
+}
+
+// ImportQuestionBank 导入题库，分类路径从parent下开始查找，parent为空时为顶级分类；
+// 同一分类下标题相同的题目视为重复，跳过不导入；dry为true时只返回导入的结果，不修改数据；
+// 中途失败时已经创建的分类和题目保留，返回的report中为实际创建的数量
+func (mine *cacheContext) ImportQuestionBank(parent, operator string, data *BankData, dry bool) (*BankReport, error) {
+	if data == nil {
+		return nil, errors.New("the import data is empty")
+	}
+	if len(parent) < 1 {
+		parent = DefaultParent
+	} else {
+		info, err := mine.GetOneCategory(parent)
+		if err != nil || info == nil {
+			return nil, errors.New("not found the parent category of " + parent)
+		}
+	}
+	report := new(BankReport)
+	report.Messages = make([]string, 0, 5)
+	report.Total = uint32(len(data.Questions))
+	invalids := make(map[int]bool)
+	for i, item := range data.Questions {
+		err := item.check()
+		if err == nil {
+			_, err = splitBankPath(item.Category)
+		}
+		if err != nil {
+			invalids[i] = true
+			report.Invalid += 1
+			report.addMessage(fmt.Sprintf("question %d(%s): %s", i+1, item.Title, err.Error()))
+		}
+	}
+	for _, path := range data.Categories {
+		if _, err := splitBankPath(path); err != nil {
+			report.Invalid += 1
+			report.addMessage(err.Error())
+		}
+	}
+	// 有不合法的数据时整体按照预演处理，避免只导入了一部分
+	if report.Invalid > 0 {
+		dry = true
+	}
+	resolver := &bankResolver{parent: parent, operator: operator, dry: dry, report: report, paths: make(map[string]string)}
+	for _, path := range data.Categories {
+		if _, err := splitBankPath(path); err != nil {
+			continue
+		}
+		_, err := resolver.resolve(path)
+		if err != nil {
+			report.Created = 0
+			report.Skipped = report.Total - report.Invalid
+			report.addMessage(fmt.Sprintf("category %s: %s", path, err.Error()))
+			return report, err
+		}
+	}
+	titles := make(map[string]bool, len(data.Questions))
+	pending := make([]*BankQuestion, 0, len(data.Questions))
+	categories := make([]string, 0, len(data.Questions))
+	for i, item := range data.Questions {
+		if invalids[i] {
+			continue
+		}
+		category, err := resolver.resolve(item.Category)
+		if err != nil {
+			report.Created = 0
+			report.Skipped = report.Total - report.Invalid - report.Duplicated
+			report.addMessage(fmt.Sprintf("question %d(%s): %s", i+1, item.Title, err.Error()))
+			return report, err
+		}
+		key := item.Category + "\n" + item.Title
+		if titles[key] {
+			report.Duplicated += 1
+			report.addMessage(fmt.Sprintf("question %d(%s): repeated in the import data", i+1, item.Title))
+			continue
+		}
+		titles[key] = true
+		if len(category) > 0 {
+			list, err := mine.questions.GetQuestionsByTitle(item.Title, category)
+			if err != nil {
+				report.Created = 0
+				report.Skipped = report.Total - report.Invalid - report.Duplicated
+				report.addMessage(fmt.Sprintf("question %d(%s): %s", i+1, item.Title, err.Error()))
+				return report, err
+			}
+			if len(list) > 0 {
+				report.Duplicated += 1
+				report.addMessage(fmt.Sprintf("question %d(%s): existed in the category %s", i+1, item.Title, item.Category))
+				continue
+			}
+		}
+		report.Created += 1
+		pending = append(pending, item)
+		categories = append(categories, category)
+	}
+	if dry || len(pending) < 1 {
+		return report, nil
+	}
+	// 下面按照实际创建的数量统计
+	report.Created = 0
+	// 一次预留全部题目的id
+	first, err := mine.reserveIDs(nosql.TableQuestion, uint64(len(pending)))
+	if err != nil {
+		report.Skipped = uint32(len(pending))
+		report.addMessage("reserve the question ids failed: " + err.Error())
+		return report, err
+	}
+	for i, item := range pending {
+		err = mine.importQuestion(report, item, first+uint64(i), categories[i], operator)
+		if err != nil {
+			report.Failed += 1
+			report.Skipped = uint32(len(pending) - i - 1)
+			report.addMessage(fmt.Sprintf("question(%s): %s", item.Title, err.Error()))
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// 创建题目，题目创建之后修改资源失败时也记为已创建
+func (mine *cacheContext) importQuestion(report *BankReport, item *BankQuestion, id uint64, category, operator string) error {
+	options := make([]*pb.QuestionOption, 0, len(item.Options))
+	for _, option := range item.Options {
+		options = append(options, &pb.QuestionOption{Id: option.ID, Desc: option.Desc})
+	}
+	question, err := mine.createQuestion(id, item.Title, item.Remark, category, item.Quote, operator, int(item.Cd), item.Answers, options)
+	if err != nil {
+		return err
+	}
+	report.Created += 1
+	if len(item.Assets) > 0 {
+		return question.UpdateAssets(operator, item.Assets)
+	}
+	return nil
+}
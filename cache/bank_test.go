@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"reflect"
+	"testing"
+)
+
+// 第fail次创建题目时返回错误的存储，fail为0时不会失败
+type stubQuestions struct {
+	nosql.QuestionStore
+	fail  int
+	count int
+}
+
+func (mine *stubQuestions) CreateQuestion(info *nosql.Question) error {
+	mine.count += 1
+	if mine.count == mine.fail {
+		return errors.New("the question store is broken")
+	}
+	return mine.QuestionStore.CreateQuestion(info)
+}
+
+func newBankQuestion(category, title string) *BankQuestion {
+	return &BankQuestion{Category: category, Title: title, Answers: []uint32{1},
+		Options: []*BankOption{{ID: 1, Desc: "yes"}, {ID: 2, Desc: "no"}}}
+}
+
+func TestImportQuestionBank(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    []*BankQuestion
+		dry     bool
+		fail    int
+		want    BankReport
+		stored  int64
+		wantErr bool
+	}{
+		{name: "created", data: []*BankQuestion{newBankQuestion("a", "1"), newBankQuestion("a/b", "2"), newBankQuestion("c", "3")},
+			want: BankReport{Total: 3, Created: 3, Categories: 2}, stored: 4},
+		{name: "repeated", data: []*BankQuestion{newBankQuestion("a", "1"), newBankQuestion("a", "1"), newBankQuestion("a", "old")},
+			want: BankReport{Total: 3, Created: 1, Duplicated: 2}, stored: 2},
+		{name: "invalid", data: []*BankQuestion{newBankQuestion("a", "1"), newBankQuestion("a", "")},
+			want: BankReport{Total: 2, Created: 1, Invalid: 1}, stored: 1},
+		{name: "dry", data: []*BankQuestion{newBankQuestion("a", "1"), newBankQuestion("b", "2")}, dry: true,
+			want: BankReport{Total: 2, Created: 2, Categories: 1}, stored: 1},
+		{name: "failed", data: []*BankQuestion{newBankQuestion("a", "1"), newBankQuestion("b", "2"), newBankQuestion("b", "3"),
+			newBankQuestion("b", "4")}, fail: 2, want: BankReport{Total: 4, Created: 1, Failed: 1, Skipped: 2, Categories: 1},
+			stored: 2, wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			// 已经存在的分类a以及其中的题目old
+			if _, err := ctx.ImportQuestionBank("", "admin", &BankData{Questions: []*BankQuestion{newBankQuestion("a", "old")}}, false); err != nil {
+				t.Fatal(err)
+			}
+			ctx.questions = &stubQuestions{QuestionStore: ctx.questions, fail: item.fail}
+			report, err := ctx.ImportQuestionBank("", "admin", &BankData{Questions: item.data}, item.dry)
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			report.Messages = nil
+			if !reflect.DeepEqual(*report, item.want) {
+				t.Errorf("the report = %+v, want %+v", *report, item.want)
+			}
+			_, stored, _ := ctx.questions.QueryQuestions(proxy.NewQuery(true))
+			if stored != item.stored {
+				t.Errorf("the stored questions = %d, want %d", stored, item.stored)
+			}
+		})
+	}
+}
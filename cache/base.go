@@ -1,13 +1,13 @@
 package cache
 
 import (
+	"errors"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
-	"math"
 	"omo.msa.assignment/config"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/memory"
 	"omo.msa.assignment/proxy/nosql"
-	"reflect"
-	"strconv"
-	"strings"
+	"omo.msa.assignment/proxy/sqldb"
 	"time"
 )
 
@@ -23,32 +23,118 @@ type baseInfo struct {
 	UpdateTime time.Time
 }
 
-type Vector struct {
-	X float64 // 纬度latitude
-	Y float64 // 经度longitude
-	Z float64
-}
-
 type cacheContext struct {
+	tasks       nosql.TaskStore
+	agents      nosql.AgentStore
+	teams       nosql.TeamStore
+	families    nosql.FamilyStore
+	coteries    nosql.CoterieStore
+	applies     nosql.ApplyStore
+	invitations nosql.InvitationStore
+	meetings    nosql.MeetingStore
+	questions   nosql.QuestionStore
+	categories  nosql.CategoryStore
+	quizzes     nosql.QuizStore
+	sequences   nosql.SequenceStore
+	backups     nosql.BackupStore
 }
 
 var cacheCtx *cacheContext
 
+// InitData 连接数据库并修复旧的数据，服务启动时调用
 func InitData() error {
-	cacheCtx = &cacheContext{}
+	return initData(true)
+}
+
+// InitStore 只连接数据库，不修改已有的数据，备份和恢复时调用
+func InitStore() error {
+	return initData(false)
+}
+
+func initData(migrate bool) error {
+	var stores *nosql.Stores
+	conf := config.Schema.Database
+	if conf.Type == "memory" {
+		stores = memory.NewStores()
+	} else if sqldb.IsSupported(conf.Type) {
+		err := sqldb.InitDB(conf.Type, conf.IP, conf.Port, conf.Name, conf.User, conf.Password)
+		if nil != err {
+			return err
+		}
+		stores = sqldb.NewStores()
+	} else {
+		err := nosql.InitDB(conf.IP, conf.Port, conf.Name, conf.Type)
+		if nil != err {
+			return err
+		}
+		if migrate {
+			err = nosql.Migrate()
+			if nil != err {
+				return err
+			}
+		}
+		stores = nosql.NewStores()
+	}
+	if !migrate {
+		return initStores(stores)
+	}
+	return InitDataWith(stores)
+}
+
+// InitDataWith 使用指定的存储实现初始化缓存层，并修复旧的数据
+func InitDataWith(stores *nosql.Stores) error {
+	err := initStores(stores)
+	if err != nil {
+		return err
+	}
+	return cacheCtx.migrate()
+}
 
-	err := nosql.InitDB(config.Schema.Database.IP, config.Schema.Database.Port, config.Schema.Database.Name, config.Schema.Database.Type)
-	if nil != err {
+func initStores(stores *nosql.Stores) error {
+	if stores == nil {
+		return errors.New("the stores is nil")
+	}
+	cacheCtx = &cacheContext{
+		tasks:       stores.Task,
+		agents:      stores.Agent,
+		teams:       stores.Team,
+		families:    stores.Family,
+		coteries:    stores.Coterie,
+		applies:     stores.Apply,
+		invitations: stores.Invitation,
+		meetings:    stores.Meeting,
+		questions:   stores.Question,
+		categories:  stores.Category,
+		quizzes:     stores.Quiz,
+		sequences:   stores.Sequence,
+		backups:     stores.Backup,
+	}
+	initTransitions(config.Schema.Task.Transitions)
+	return nil
+}
+
+// 启动时修复旧的数据：记录的uid、序号、明文密码以及分类的场景
+func (mine *cacheContext) migrate() error {
+	err := mine.checkRecordUIDs()
+	if err != nil {
 		return err
 	}
-	//dbs, _ := nosql.GetAllFamilies()
+	err = mine.checkSequences()
+	if err != nil {
+		return err
+	}
+	err = mine.migratePasswords()
+	if err != nil {
+		return err
+	}
+	//dbs, _ := cacheCtx.families.GetAllFamilies()
 	//for _, db := range dbs {
 	//	fmt.Printf(db.Name)
 	//}
-	dbs, _ := nosql.GetAllCategories()
+	dbs, _ := mine.categories.GetAllCategories()
 	for _, db := range dbs {
 		if len(db.Owner) < 2 {
-			_ = nosql.UpdateCategoryOwner(db.UID.Hex(), DefaultOwner)
+			_ = mine.categories.UpdateCategoryOwner(db.UID.Hex(), DefaultOwner)
 		}
 	}
 	return nil
@@ -58,35 +144,73 @@ func Context() *cacheContext {
 	return cacheCtx
 }
 
-func checkPage(page, number uint32, all interface{}) (uint32, uint32, interface{}) {
-	if number < 1 {
-		number = 10
+// 集合的下一个自增序号，读取失败时返回错误，不会返回0
+func (mine *cacheContext) nextID(table string) (uint64, error) {
+	num, err := mine.sequences.GetSequenceNext(table)
+	if err != nil {
+		return 0, err
 	}
-	array := reflect.ValueOf(all)
-	total := uint32(array.Len())
-	maxPage := total / number
-	if total%number != 0 {
-		maxPage = total/number + 1
+	if num < 1 {
+		return 0, errors.New("the sequence of " + table + " is invalid")
 	}
-	if page < 1 {
-		return total, maxPage, all
+	return num, nil
+}
+
+// PageInfo 列表的分页参数，Page为0并且没有游标时返回全部数据；
+// Cursor为上一页最后一条数据的uid，不为空时按照uid的顺序返回其后的Number条数据
+type PageInfo struct {
+	Page   uint32
+	Number uint32
+	Cursor string
+}
+
+func (mine *PageInfo) number() uint32 {
+	if mine == nil || mine.Number < 1 {
+		return 10
 	}
-	if page > maxPage {
-		page = maxPage
+	return mine.Number
+}
+
+// 生成数据库的查询条件，alive表示只查询未删除的数据
+func (mine *PageInfo) query(alive bool, list ...proxy.Condition) *proxy.Query {
+	query := proxy.NewQuery(alive, list...)
+	if mine == nil {
+		return query
 	}
+	if len(mine.Cursor) > 0 {
+		query.Cursor = mine.Cursor
+		query.Limit = int64(mine.number())
+	} else {
+		query.Skip, query.Limit = pageRange(mine.Page, mine.number())
+	}
+	return query
+}
+
+// 返回总数以及最大页数
+func (mine *PageInfo) result(total int64) (uint32, uint32) {
+	return uint32(total), maxPageOf(uint32(total), mine.number())
+}
 
-	var start = (page - 1) * number
-	var end = start + number
-	if end > total {
-		end = total
+// 数据库分页的skip和limit，page为0时不分页
+func pageRange(page, number uint32) (int64, int64) {
+	if page < 1 {
+		return 0, 0
 	}
+	return int64(page-1) * int64(number), int64(number)
+}
 
-	list := array.Slice(int(start), int(end))
-	return total, maxPage, list.Interface()
+func maxPageOf(total, number uint32) uint32 {
+	if number < 1 {
+		return 0
+	}
+	if total%number != 0 {
+		return total/number + 1
+	}
+	return total / number
 }
 
 func switchOldFamilyToCoterie() {
-	dbs, _ := nosql.GetAllFamilies()
+	dbs, _ := cacheCtx.families.GetAllFamilies()
 	for _, db := range dbs {
 		if len(db.Children) > 0 {
 			in := new(pb.ReqCoterieAdd)
@@ -112,23 +236,6 @@ func switchOldFamilyToCoterie() {
 	}
 }
 
-func (mine *cacheContext) checkDistance(center, loc string) bool {
-	if len(center) < 1 {
-		return true
-	}
-	if len(loc) < 1 {
-		return false
-	}
-	from := parseLocation(loc)
-	to := parseLocation(center)
-	dis := geoDistance(from, to, "M")
-	if dis < 100 {
-		return true
-	} else {
-		return false
-	}
-}
-
 func (mine *cacheContext) formatTime(from string) (time.Time, error) {
 	t, err := time.ParseInLocation("2006-01-02 15:04", from, time.Local)
 	if err == nil {
@@ -146,47 +253,3 @@ func (mine *cacheContext) formatDate(from string) (time.Time, error) {
 		return time.Now(), err
 	}
 }
-
-// GeoDistance 计算地理距离，依次为两个坐标的纬度、经度、单位（默认：英里，K => 公里，N => 海里）
-func geoDistance(from, to Vector, unit string) float64 {
-	const PI float64 = 3.141592653589793
-
-	radlat1 := PI * from.X / 180
-	radlat2 := PI * to.X / 180
-
-	theta := to.Y - from.Y
-	radTheta := PI * theta / 180
-
-	dist := math.Sin(radlat1)*math.Sin(radlat2) + math.Cos(radlat1)*math.Cos(radlat2)*math.Cos(radTheta)
-
-	if dist > 1 {
-		dist = 1
-	}
-
-	dist = math.Acos(dist)
-	dist = dist * 180 / PI
-	dist = dist * 60 * 1.1515
-
-	if unit == "K" {
-		dist = dist * 1.609344
-	} else if unit == "N" {
-		dist = dist * 0.8684
-	} else if unit == "M" {
-
-	}
-
-	return dist
-}
-
-func parseLocation(local string) Vector {
-	if len(local) < 1 {
-		return Vector{X: 0, Y: 0, Z: 0}
-	}
-	arr := strings.Split(local, "|")
-	if arr == nil || len(arr) < 3 {
-		return Vector{X: 0, Y: 0, Z: 0}
-	}
-	x, _ := strconv.ParseFloat(arr[2], 64)
-	y, _ := strconv.ParseFloat(arr[1], 64)
-	return Vector{X: x, Y: y, Z: 0}
-}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"errors"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"omo.msa.assignment/proxy/memory"
+	"omo.msa.assignment/proxy/nosql"
+	"omo.msa.assignment/proxy/sqldb"
+	"path/filepath"
+	"testing"
+)
+
+// 需要在不同的存储上验证的测试，sqlite每次使用新的数据库文件
+var testBackends = []struct {
+	name   string
+	stores func(t *testing.T) *nosql.Stores
+}{
+	{name: "memory", stores: func(t *testing.T) *nosql.Stores {
+		return memory.NewStores()
+	}},
+	{name: "sqlite", stores: func(t *testing.T) *nosql.Stores {
+		err := sqldb.InitDB(sqldb.KindSqlite, "", "", filepath.Join(t.TempDir(), "test.db"), "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sqldb.NewStores()
+	}},
+}
+
+// 每个测试使用新的内存存储
+func newTestContext(t *testing.T) *cacheContext {
+	t.Helper()
+	err := InitDataWith(memory.NewStores())
+	if err != nil {
+		t.Fatalf("init the cache failed: %v", err)
+	}
+	return cacheCtx
+}
+
+// 序号固定返回num和err的存储
+type stubSequences struct {
+	nosql.SequenceStore
+	num uint64
+	err error
+}
+
+func (mine *stubSequences) GetSequenceNext(name string) (uint64, error) {
+	return mine.num, mine.err
+}
+
+func TestNextID(t *testing.T) {
+	cases := []struct {
+		name    string
+		num     uint64
+		err     error
+		want    uint64
+		wantErr bool
+	}{
+		{name: "next", num: 5, want: 5},
+		{name: "store error", err: errors.New("the sequence store is broken"), wantErr: true},
+		{name: "zero", num: 0, wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			ctx.sequences = &stubSequences{SequenceStore: ctx.sequences, num: item.num, err: item.err}
+			num, err := ctx.nextID(nosql.TableTeam)
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if num != item.want {
+				t.Errorf("the id = %d, want %d", num, item.want)
+			}
+		})
+	}
+}
+
+func TestCreateWithoutSequence(t *testing.T) {
+	cases := []struct {
+		name   string
+		create func(ctx *cacheContext) error
+	}{
+		{name: "team", create: func(ctx *cacheContext) error {
+			_, err := ctx.CreateTeam(&pb.ReqTeamAdd{Name: "team", Owner: "scene"})
+			return err
+		}},
+		{name: "task", create: func(ctx *cacheContext) error {
+			_, err := ctx.CreateTask(&pb.ReqTaskAdd{Name: "task", Owner: "scene", Duration: &pb.DateInfo{}})
+			return err
+		}},
+		{name: "meeting", create: func(ctx *cacheContext) error {
+			_, err := ctx.CreateMeeting(&pb.ReqMeetingAdd{Name: "meeting", Owner: "scene", Appointed: "2030-01-02 09:00"})
+			return err
+		}},
+		{name: "family", create: func(ctx *cacheContext) error {
+			_, err := ctx.CreateFamily(&pb.ReqFamilyAdd{Name: "family"})
+			return err
+		}},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			ctx.sequences = &stubSequences{SequenceStore: ctx.sequences, err: errors.New("the sequence store is broken")}
+			if err := item.create(ctx); err == nil {
+				t.Fatal("create should fail when the sequence is broken")
+			}
+		})
+	}
+}
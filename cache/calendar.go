@@ -0,0 +1,498 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	calendarProduct = "-//omo//omo.msa.assignment//CN"
+	// 导出的会议的UID后缀，导入时据此识别本服务的会议
+	calendarDomain = "@omo.msa.assignment"
+	// 成员的日历地址
+	calendarUserPrefix = "urn:omo:user:"
+	// 每行最多75个字节，超过时折行
+	calendarLineSize = 75
+)
+
+// CalendarEvent 日历中的一个事件(VEVENT)，时间都已转为绝对时间
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	// GEO为 纬度,经度，没有时为空
+	Geo       string
+	Start     time.Time
+	End       time.Time
+	Organizer string
+	Attendees []string
+	Cancelled bool
+}
+
+// CalendarReport 导入的结果，有不合法的事件时不会导入任何数据；
+// Failed为创建失败的事件，失败后不再导入之后的事件，Skipped为没有导入的数量
+type CalendarReport struct {
+	Total      uint32
+	Created    uint32
+	Duplicated uint32
+	Cancelled  uint32
+	Invalid    uint32
+	Failed     uint32
+	Skipped    uint32
+	Messages   []string
+	Meetings   []*MeetingInfo
+}
+
+func (mine *CalendarReport) addMessage(msg string) {
+	mine.Messages = append(mine.Messages, msg)
+}
+
+func escapeCalendarText(str string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\r\n", "\\n", "\n", "\\n", "\r", "")
+	return replacer.Replace(str)
+}
+
+func unescapeCalendarText(str string) string {
+	buf := &strings.Builder{}
+	for i := 0; i < len(str); i += 1 {
+		if str[i] == '\\' && i+1 < len(str) {
+			i += 1
+			switch str[i] {
+			case 'n', 'N':
+				buf.WriteByte('\n')
+			default:
+				buf.WriteByte(str[i])
+			}
+			continue
+		}
+		buf.WriteByte(str[i])
+	}
+	return buf.String()
+}
+
+// 按照75个字节折行，不拆开utf8字符，续行以空格开始
+func writeCalendarLine(buf *bytes.Buffer, line string) {
+	size := calendarLineSize
+	for len(line) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut -= 1
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// 续行的空格占一个字节
+		size = calendarLineSize - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+func calendarTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func partStatOf(answer uint8) string {
+	switch answer {
+	case AnswerAccept:
+		return "ACCEPTED"
+	case AnswerDecline:
+		return "DECLINED"
+	case AnswerTentative:
+		return "TENTATIVE"
+	}
+	return "NEEDS-ACTION"
+}
+
+// ExportCalendar 导出会议为RFC 5545的VCALENDAR，参会人为应到的成员，没有开始时间的会议不导出
+func ExportCalendar(name string, list []*MeetingInfo) []byte {
+	buf := new(bytes.Buffer)
+	now := calendarTime(time.Now())
+	writeCalendarLine(buf, "BEGIN:VCALENDAR")
+	writeCalendarLine(buf, "VERSION:2.0")
+	writeCalendarLine(buf, "PRODID:"+calendarProduct)
+	writeCalendarLine(buf, "CALSCALE:GREGORIAN")
+	writeCalendarLine(buf, "METHOD:PUBLISH")
+	if len(name) > 0 {
+		writeCalendarLine(buf, "X-WR-CALNAME:"+escapeCalendarText(name))
+	}
+	for _, info := range list {
+		if info.StartTime.IsZero() {
+			continue
+		}
+		writeCalendarLine(buf, "BEGIN:VEVENT")
+		writeCalendarLine(buf, "UID:"+info.UID+calendarDomain)
+		writeCalendarLine(buf, "DTSTAMP:"+now)
+		writeCalendarLine(buf, "DTSTART:"+calendarTime(info.StartTime))
+		if info.StopTime.After(info.StartTime) {
+			writeCalendarLine(buf, "DTEND:"+calendarTime(info.StopTime))
+		}
+		writeCalendarLine(buf, "SUMMARY:"+escapeCalendarText(info.Name))
+		if len(info.Remark) > 0 {
+			writeCalendarLine(buf, "DESCRIPTION:"+escapeCalendarText(info.Remark))
+		}
+		if len(info.Location) > 0 {
+			writeCalendarLine(buf, "LOCATION:"+escapeCalendarText(info.Location))
+			if info.Type == Outside {
+				if point, err := ParseGeoPoint(info.Location); err == nil {
+					writeCalendarLine(buf, fmt.Sprintf("GEO:%s;%s", strconv.FormatFloat(point.Latitude, 'f', -1, 64),
+						strconv.FormatFloat(point.Longitude, 'f', -1, 64)))
+				}
+			}
+		}
+		if len(info.Creator) > 0 {
+			writeCalendarLine(buf, "ORGANIZER:"+calendarUserPrefix+info.Creator)
+		}
+		for _, item := range info.GetAttendance().Items {
+			if !item.Expected {
+				continue
+			}
+			writeCalendarLine(buf, fmt.Sprintf("ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=%s:%s%s",
+				partStatOf(item.Answer), calendarUserPrefix, item.User))
+		}
+		if info.Status == Close {
+			writeCalendarLine(buf, "STATUS:CANCELLED")
+		} else {
+			writeCalendarLine(buf, "STATUS:CONFIRMED")
+		}
+		writeCalendarLine(buf, "END:VEVENT")
+	}
+	writeCalendarLine(buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// 一行的属性，参数名都转为大写
+type calendarProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// 展开折行，续行以空格或者tab开始
+func unfoldCalendar(data []byte) []string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+	list := make([]string, 0, 100)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(list) > 0 {
+			list[len(list)-1] += line[1:]
+			continue
+		}
+		if len(line) > 0 {
+			list = append(list, line)
+		}
+	}
+	return list
+}
+
+// 参数的值可以带引号，引号中可以有:;,
+func parseCalendarProperty(line string) (*calendarProperty, error) {
+	prop := &calendarProperty{Params: make(map[string]string)}
+	quoted := false
+	colon := -1
+	for i := 0; i < len(line); i += 1 {
+		if line[i] == '"' {
+			quoted = !quoted
+		} else if line[i] == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 1 {
+		return nil, errors.New("the line is error: " + line)
+	}
+	prop.Value = line[colon+1:]
+	parts := strings.Split(line[:colon], ";")
+	prop.Name = strings.ToUpper(parts[0])
+	for _, part := range parts[1:] {
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) == 2 {
+			prop.Params[strings.ToUpper(pair[0])] = strings.Trim(pair[1], "\"")
+		}
+	}
+	return prop, nil
+}
+
+// 支持UTC时间、带TZID的时间、没有时区的本地时间以及只有日期(当天0点)
+func parseCalendarTime(prop *calendarProperty) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.Value)
+	if prop.Params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.ParseInLocation("20060102T150405Z", value, time.UTC)
+		return t, false, err
+	}
+	loc := time.Local
+	if tz, ok := prop.Params["TZID"]; ok {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// 只支持 P1D、PT1H30M 这样的时长
+func parseCalendarDuration(value string) (time.Duration, error) {
+	str := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if len(str) == len(value) || len(str) < 2 {
+		return 0, errors.New("the DURATION is error: " + value)
+	}
+	var total time.Duration
+	num := 0
+	inTime := false
+	for _, c := range str {
+		switch {
+		case c >= '0' && c <= '9':
+			num = num*10 + int(c-'0')
+			continue
+		case c == 'T':
+			inTime = true
+		case c == 'W' && !inTime:
+			total += time.Duration(num) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			total += time.Duration(num) * 24 * time.Hour
+		case c == 'H' && inTime:
+			total += time.Duration(num) * time.Hour
+		case c == 'M' && inTime:
+			total += time.Duration(num) * time.Minute
+		case c == 'S' && inTime:
+			total += time.Duration(num) * time.Second
+		default:
+			return 0, errors.New("the DURATION is error: " + value)
+		}
+		num = 0
+	}
+	return total, nil
+}
+
+// ParseCalendar 解析.ics文件中的VEVENT，忽略VTIMEZONE、VALARM等其他组件；DTEND为空时使用DURATION，都没有时全天的事件为一天
+func ParseCalendar(data []byte) ([]*CalendarEvent, error) {
+	lines := unfoldCalendar(data)
+	if len(lines) < 1 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, errors.New("the content is not a VCALENDAR")
+	}
+	list := make([]*CalendarEvent, 0, 10)
+	var event *CalendarEvent
+	var duration time.Duration
+	allDay := false
+	// 嵌套的组件，例如VEVENT中的VALARM
+	depth := 0
+	for i, line := range lines {
+		prop, err := parseCalendarProperty(line)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("line %d: %s", i+1, err.Error()))
+		}
+		value := strings.ToUpper(prop.Value)
+		if prop.Name == "BEGIN" && value == "VEVENT" && event == nil {
+			event = &CalendarEvent{Attendees: make([]string, 0, 5)}
+			duration = 0
+			allDay = false
+			depth = 0
+			continue
+		}
+		if event == nil {
+			continue
+		}
+		if prop.Name == "BEGIN" {
+			depth += 1
+			continue
+		}
+		if prop.Name == "END" && value != "VEVENT" {
+			depth -= 1
+			continue
+		}
+		if prop.Name == "END" {
+			if event.Start.IsZero() {
+				return nil, errors.New(fmt.Sprintf("line %d: the DTSTART of the event(%s) is empty", i+1, event.UID))
+			}
+			if event.End.IsZero() {
+				if duration > 0 {
+					event.End = event.Start.Add(duration)
+				} else if allDay {
+					event.End = event.Start.AddDate(0, 0, 1)
+				}
+			}
+			list = append(list, event)
+			event = nil
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+		switch prop.Name {
+		case "UID":
+			event.UID = prop.Value
+		case "SUMMARY":
+			event.Summary = unescapeCalendarText(prop.Value)
+		case "DESCRIPTION":
+			event.Description = unescapeCalendarText(prop.Value)
+		case "LOCATION":
+			event.Location = unescapeCalendarText(prop.Value)
+		case "GEO":
+			arr := strings.Split(prop.Value, ";")
+			if len(arr) == 2 {
+				if point, er := ParseGeoPoint(arr[0] + "," + arr[1]); er == nil {
+					event.Geo = point.String()
+				}
+			}
+		case "DTSTART":
+			event.Start, allDay, err = parseCalendarTime(prop)
+		case "DTEND":
+			event.End, _, err = parseCalendarTime(prop)
+		case "DURATION":
+			duration, err = parseCalendarDuration(prop.Value)
+		case "ORGANIZER":
+			event.Organizer = prop.Value
+		case "ATTENDEE":
+			event.Attendees = append(event.Attendees, prop.Value)
+		case "STATUS":
+			event.Cancelled = value == "CANCELLED"
+		}
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("line %d: the %s is error", i+1, prop.Name))
+		}
+	}
+	if event != nil {
+		return nil, errors.New("the VEVENT is not ended")
+	}
+	return list, nil
+}
+
+// 日历地址是本服务的成员时返回成员的uid
+func calendarUser(address string) string {
+	if strings.HasPrefix(strings.ToLower(address), calendarUserPrefix) {
+		return address[len(calendarUserPrefix):]
+	}
+	return ""
+}
+
+// 本服务导出的会议在小组中已经存在，或者小组中已经有相同名称和开始时间的会议
+func (mine *cacheContext) hadCalendarEvent(group string, event *CalendarEvent) bool {
+	if strings.HasSuffix(event.UID, calendarDomain) {
+		uid := strings.TrimSuffix(event.UID, calendarDomain)
+		if _, err := primitive.ObjectIDFromHex(uid); err == nil {
+			if db, _ := mine.meetings.GetMeeting(uid); db != nil && db.DeleteTime.IsZero() && db.Group == group {
+				return true
+			}
+		}
+	}
+	array, _, err := mine.meetings.QueryMeetings(proxy.NewQuery(true, proxy.Equal("group", group),
+		proxy.Equal("name", event.Summary), proxy.Equal("startAt", event.Start.UTC())))
+	return err == nil && len(array) > 0
+}
+
+// ImportCalendar 导入日历中的事件为小组的会议，DTSTART和DTEND为开始和结束时间，有GEO时为户外的会议；
+// 取消的和已经存在的跳过，与已有的会议冲突时不导入，参会人中本服务的成员会被邀请；dry为true时只预演；
+// 创建失败时停止导入，返回的report中包括已经创建的会议以及失败的事件
+func (mine *cacheContext) ImportCalendar(group, owner, operator string, events []*CalendarEvent, dry bool) (*CalendarReport, error) {
+	report := &CalendarReport{Messages: make([]string, 0, 5), Meetings: make([]*MeetingInfo, 0, len(events))}
+	report.Total = uint32(len(events))
+	valid := make([]*CalendarEvent, 0, len(events))
+	indexes := make([]int, 0, len(events))
+	for i, event := range events {
+		if len(event.Summary) < 1 {
+			report.Invalid += 1
+			report.addMessage(fmt.Sprintf("event %d(%s): the SUMMARY is empty", i+1, event.UID))
+			continue
+		}
+		if !event.End.IsZero() && event.End.Before(event.Start) {
+			report.Invalid += 1
+			report.addMessage(fmt.Sprintf("event %d(%s): the DTEND is before the DTSTART", i+1, event.UID))
+			continue
+		}
+		if event.Cancelled {
+			report.Cancelled += 1
+			continue
+		}
+		if mine.hadCalendarEvent(group, event) {
+			report.Duplicated += 1
+			continue
+		}
+		kind := InRoom
+		if len(event.Geo) > 0 {
+			kind = Outside
+		}
+		err := mine.checkConflicts(&MeetingPlan{Owner: owner, Group: group, Type: kind, Location: event.Location, Start: event.Start, Stop: event.End})
+		if list := ConflictsOf(err); list != nil {
+			report.Invalid += 1
+			report.addMessage(fmt.Sprintf("event %d(%s): %s", i+1, event.UID, err.Error()))
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		valid = append(valid, event)
+		indexes = append(indexes, i)
+	}
+	if report.Invalid > 0 || dry {
+		report.Created = uint32(len(valid))
+		if report.Invalid > 0 {
+			report.Created = 0
+		}
+		return report, nil
+	}
+	for i, event := range valid {
+		err := mine.importEvent(report, event, group, owner, operator)
+		if err != nil {
+			// 已经创建的会议保留，之后的事件不再导入
+			report.Failed += 1
+			report.Skipped = uint32(len(valid) - i - 1)
+			report.addMessage(fmt.Sprintf("event %d(%s): %s", indexes[i]+1, event.UID, err.Error()))
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// 创建事件对应的会议，会议创建之后的修改失败时也记为已创建
+func (mine *cacheContext) importEvent(report *CalendarReport, event *CalendarEvent, group, owner, operator string) error {
+	in := &pb.ReqMeetingAdd{Name: event.Summary, Remark: event.Description, Group: group, Owner: owner,
+		Operator: operator, Location: event.Location, Type: uint32(InRoom),
+		Appointed: event.Start.In(time.Local).Format("2006-01-02 15:04")}
+	if len(event.Geo) > 0 {
+		in.Type = uint32(Outside)
+		in.Location = event.Geo
+	}
+	// 冲突已经按照事件的时间检查过
+	info, err := mine.createMeeting(in, false)
+	if err != nil {
+		return err
+	}
+	report.Created += 1
+	report.Meetings = append(report.Meetings, info)
+	// 没有结束时间时不自动结束
+	stop := time.Time{}
+	if !event.End.IsZero() {
+		stop = event.End.UTC()
+	}
+	err = mine.meetings.UpdateMeetingDate(info.UID, operator, event.Start.UTC(), stop)
+	if err != nil {
+		return err
+	}
+	info.StartTime = event.Start.UTC()
+	info.StopTime = stop
+	info.reschedule()
+	users := make([]string, 0, len(event.Attendees))
+	for _, address := range event.Attendees {
+		if user := calendarUser(address); len(user) > 0 {
+			users = append(users, user)
+		}
+	}
+	if len(users) > 0 {
+		_, err = info.Notify(users, operator)
+	}
+	return err
+}
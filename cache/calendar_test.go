@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarRoundTrip(t *testing.T) {
+	ctx := newTestContext(t)
+	team := newTestTeam(t, ctx, "scene", 0, "a", "b")
+	start := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC)
+	type source struct {
+		name     string
+		remark   string
+		location string
+		kind     LocationType
+		stop     time.Duration
+		notify   []string
+		close    bool
+	}
+	cases := []struct {
+		name  string
+		src   source
+		event CalendarEvent
+	}{
+		{name: "escaped", src: source{name: "weekly", remark: "a;b,c\\d\nnext line", location: "room 1", stop: time.Hour},
+			event: CalendarEvent{Summary: "weekly", Description: "a;b,c\\d\nnext line", Location: "room 1",
+				Attendees: []string{"urn:omo:user:a", "urn:omo:user:b"}}},
+		// 超过75个字节时折行，不拆开utf8字符
+		{name: "folded", src: source{name: strings.Repeat("会议", 30), location: "39.9,116.4", kind: Outside, notify: []string{"c"}},
+			event: CalendarEvent{Summary: strings.Repeat("会议", 30), Location: "39.9,116.4", Geo: "39.9,116.4",
+				Attendees: []string{"urn:omo:user:a", "urn:omo:user:b", "urn:omo:user:c"}}},
+		{name: "cancelled", src: source{name: "closed", close: true},
+			event: CalendarEvent{Summary: "closed", Cancelled: true, Attendees: []string{"urn:omo:user:a", "urn:omo:user:b"}}},
+	}
+	list := make([]*MeetingInfo, 0, len(cases))
+	for i, item := range cases {
+		begin := start.Add(time.Duration(i) * 24 * time.Hour)
+		info := newTestMeeting(t, ctx, "scene", team.UID, "", begin)
+		var err error
+		if err = info.UpdateBase(item.src.name, item.src.remark, "admin"); err == nil && len(item.src.location) > 0 {
+			err = info.UpdateLocation(item.src.location, "admin", item.src.kind)
+		}
+		if err == nil && item.src.stop > 0 {
+			err = info.updateDate(begin.Unix(), begin.Add(item.src.stop).Unix(), "admin")
+		}
+		if err == nil && len(item.src.notify) > 0 {
+			_, err = info.Notify(item.src.notify, "admin")
+		}
+		if err == nil && item.src.close {
+			err = info.Close("admin")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, _ = ctx.GetMeeting(info.UID)
+		list = append(list, info)
+		cases[i].event.UID = info.UID + calendarDomain
+		cases[i].event.Start = info.StartTime.UTC()
+		if info.StopTime.After(info.StartTime) {
+			cases[i].event.End = info.StopTime.UTC().Truncate(time.Second)
+		}
+		cases[i].event.Organizer = "urn:omo:user:admin"
+	}
+	data := ExportCalendar("calendar", list)
+	for _, line := range strings.Split(string(data), "\r\n") {
+		if len(line) > calendarLineSize {
+			t.Errorf("the line is longer than %d: %q", calendarLineSize, line)
+		}
+	}
+	events, err := ParseCalendar(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != len(cases) {
+		t.Fatalf("the events = %d, want %d", len(events), len(cases))
+	}
+	for i, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			got := events[i]
+			if got.UID != item.event.UID || got.Summary != item.event.Summary || got.Description != item.event.Description ||
+				got.Location != item.event.Location || got.Geo != item.event.Geo || !got.Start.Equal(item.event.Start) ||
+				!got.End.Equal(item.event.End) || got.Organizer != item.event.Organizer || got.Cancelled != item.event.Cancelled {
+				t.Errorf("the event = %+v, want %+v", *got, item.event)
+			}
+			if !sameUsers(got.Attendees, item.event.Attendees) {
+				t.Errorf("the attendees = %v, want %v", got.Attendees, item.event.Attendees)
+			}
+		})
+	}
+
+	other := newTestTeam(t, ctx, "scene", 0)
+	imports := []struct {
+		name  string
+		group string
+		want  CalendarReport
+	}{
+		{name: "other group", group: other.UID, want: CalendarReport{Total: 3, Created: 2, Cancelled: 1}},
+		// 再次导入时按照名称和开始时间识别
+		{name: "again", group: other.UID, want: CalendarReport{Total: 3, Duplicated: 2, Cancelled: 1}},
+		// 导入到导出的小组时按照UID识别
+		{name: "same group", group: team.UID, want: CalendarReport{Total: 3, Duplicated: 2, Cancelled: 1}},
+	}
+	for _, item := range imports {
+		report, err := ctx.ImportCalendar(item.group, "scene", "admin", events, false)
+		if err != nil {
+			t.Fatalf("%s: %v", item.name, err)
+		}
+		if report.Total != item.want.Total || report.Created != item.want.Created || report.Duplicated != item.want.Duplicated ||
+			report.Cancelled != item.want.Cancelled || report.Invalid != item.want.Invalid {
+			t.Errorf("%s: the report = %+v, want %+v", item.name, *report, item.want)
+		}
+	}
+	_, _, imported := ctx.GetMeetingsByGroup(other.UID, nil)
+	if len(imported) != 2 {
+		t.Fatalf("the imported meetings = %d, want 2", len(imported))
+	}
+	// 导入的会议再次导出后内容相同
+	again, err := ParseCalendar(ExportCalendar("", imported))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range again {
+		var want *CalendarEvent
+		for _, item := range events {
+			if item.Summary == event.Summary {
+				want = item
+			}
+		}
+		if want == nil || !event.Start.Equal(want.Start) || !event.End.Equal(want.End) || event.Location != want.Location ||
+			event.Geo != want.Geo || event.Description != want.Description || len(event.Attendees) != len(want.Attendees) {
+			t.Errorf("the imported event = %+v, want %+v", *event, want)
+		}
+	}
+}
+
+func TestParseCalendar(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("the time zone data is missing")
+	}
+	wrap := func(lines ...string) []byte {
+		buf := new(bytes.Buffer)
+		buf.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\n")
+		for _, line := range lines {
+			buf.WriteString(line + "\r\n")
+		}
+		buf.WriteString("END:VCALENDAR\r\n")
+		return buf.Bytes()
+	}
+	cases := []struct {
+		name    string
+		data    []byte
+		start   time.Time
+		end     time.Time
+		summary string
+		wantErr bool
+	}{
+		{name: "utc", data: wrap("BEGIN:VEVENT", "SUMMARY:a", "DTSTART:20300101T090000Z", "DTEND:20300101T100000Z", "END:VEVENT"),
+			summary: "a", start: time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC), end: time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{name: "time zone", data: wrap("BEGIN:VEVENT", "SUMMARY:a", "DTSTART;TZID=Asia/Shanghai:20300101T090000", "END:VEVENT"),
+			summary: "a", start: time.Date(2030, 1, 1, 9, 0, 0, 0, shanghai)},
+		{name: "all day", data: wrap("BEGIN:VEVENT", "SUMMARY:a", "DTSTART;VALUE=DATE:20300101", "END:VEVENT"),
+			summary: "a", start: time.Date(2030, 1, 1, 0, 0, 0, 0, time.Local), end: time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local)},
+		{name: "duration", data: wrap("BEGIN:VEVENT", "SUMMARY:a", "DTSTART:20300101T090000Z", "DURATION:PT1H30M", "END:VEVENT"),
+			summary: "a", start: time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC), end: time.Date(2030, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{name: "folded", data: wrap("BEGIN:VEVENT", "SUMMARY:long", "  summary", "DTSTART:20300101T090000Z", "END:VEVENT"),
+			summary: "long summary", start: time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "alarm", data: wrap("BEGIN:VEVENT", "SUMMARY:a", "DTSTART:20300101T090000Z", "BEGIN:VALARM",
+			"DESCRIPTION:ring", "TRIGGER:-PT15M", "END:VALARM", "END:VEVENT"),
+			summary: "a", start: time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "no start", data: wrap("BEGIN:VEVENT", "SUMMARY:a", "END:VEVENT"), wantErr: true},
+		{name: "bad duration", data: wrap("BEGIN:VEVENT", "DTSTART:20300101T090000Z", "DURATION:1H", "END:VEVENT"), wantErr: true},
+		{name: "not ended", data: wrap("BEGIN:VEVENT", "DTSTART:20300101T090000Z"), wantErr: true},
+		{name: "not calendar", data: []byte("BEGIN:VEVENT\r\nEND:VEVENT\r\n"), wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			events, err := ParseCalendar(item.data)
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("the events = %d, want 1", len(events))
+			}
+			got := events[0]
+			if got.Summary != item.summary || !got.Start.Equal(item.start) || !got.End.Equal(item.end) {
+				t.Errorf("the event = %+v, want %s from %v to %v", *got, item.summary, item.start, item.end)
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"omo.msa.assignment/proxy/nosql"
+	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -19,9 +22,13 @@ type CategoryInfo struct {
 }
 
 func (mine *cacheContext) NewCategory(name, parent, source, operator string, weight uint32) (*CategoryInfo, error) {
+	id, err := mine.nextID(nosql.TableQuestion)
+	if err != nil {
+		return nil, err
+	}
 	db := new(nosql.Category)
 	db.UID = primitive.NewObjectID()
-	db.ID = nosql.GetQuestionNextID()
+	db.ID = id
 	db.CreatedTime = time.Now()
 	db.Operator = operator
 	db.Name = name
@@ -33,7 +40,7 @@ func (mine *cacheContext) NewCategory(name, parent, source, operator string, wei
 	db.Quote = source
 	db.Weight = weight
 	if db.Weight > 0 {
-		categoryList, err := nosql.GetCategoryListByParent(db.Parent)
+		categoryList, err := mine.categories.GetCategoryListByParent(db.Parent)
 		if err != nil {
 			return nil, err
 		}
@@ -42,14 +49,14 @@ func (mine *cacheContext) NewCategory(name, parent, source, operator string, wei
 		}
 		for _, v := range categoryList {
 			if v.Weight >= db.Weight {
-				err1 := nosql.UpdateCategoryInt("weight", "", v.UID.Hex(), int64(v.Weight+1))
+				err1 := mine.categories.UpdateCategoryInt("weight", "", v.UID.Hex(), int64(v.Weight+1))
 				if err1 != nil {
 					return nil, err1
 				}
 			}
 		}
 	}
-	err := nosql.CreateCategory(db)
+	err = mine.categories.CreateCategory(db)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +66,7 @@ func (mine *cacheContext) NewCategory(name, parent, source, operator string, wei
 }
 
 func (mine *cacheContext) GetOneCategory(uid string) (*CategoryInfo, error) {
-	category, err := nosql.GetOneCategory(uid)
+	category, err := mine.categories.GetOneCategory(uid)
 	if err != nil {
 		return nil, err
 	}
@@ -72,13 +79,13 @@ func (mine *cacheContext) GetOneCategory(uid string) (*CategoryInfo, error) {
 	return info, nil
 }
 
-func (mine *cacheContext) GetCategoriesByParent(parent string) ([]*CategoryInfo, error) {
+func (mine *cacheContext) GetCategoriesByParent(parent string, page *PageInfo) (uint32, uint32, []*CategoryInfo, error) {
 	if parent == "" {
-		return nil, errors.New("parent is null")
+		return 0, 0, nil, errors.New("parent is null")
 	}
-	array, err := nosql.GetCategoryListByParent(parent)
+	array, num, err := mine.categories.QueryCategories(page.query(true, proxy.Equal("parent", parent)))
 	if err != nil {
-		return nil, err
+		return 0, 0, nil, err
 	}
 	list := make([]*CategoryInfo, 0, len(array))
 	for _, v := range array {
@@ -86,20 +93,17 @@ func (mine *cacheContext) GetCategoriesByParent(parent string) ([]*CategoryInfo,
 		info.initInfo(v)
 		list = append(list, info)
 	}
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetTopCategoriesByScene(owner string) ([]*CategoryInfo, error) {
-	var array []*nosql.Category
-	var err error
-	if len(owner) > 2 {
-		array, err = nosql.GetCategoryListByOwner(owner, DefaultParent)
-	} else {
-		array, err = nosql.GetCategoryListByOwner(DefaultOwner, DefaultParent)
+func (mine *cacheContext) GetTopCategoriesByScene(owner string, page *PageInfo) (uint32, uint32, []*CategoryInfo, error) {
+	if len(owner) < 3 {
+		owner = DefaultOwner
 	}
-
+	array, num, err := mine.categories.QueryCategories(page.query(true, proxy.Equal("owner", owner), proxy.Equal("parent", DefaultParent)))
 	if err != nil {
-		return nil, err
+		return 0, 0, nil, err
 	}
 	list := make([]*CategoryInfo, 0, len(array))
 	for _, v := range array {
@@ -107,7 +111,8 @@ func (mine *cacheContext) GetTopCategoriesByScene(owner string) ([]*CategoryInfo
 		info.initInfo(v)
 		list = append(list, info)
 	}
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
 func (mine *CategoryInfo) initInfo(db *nosql.Category) {
@@ -121,11 +126,26 @@ func (mine *CategoryInfo) initInfo(db *nosql.Category) {
 	mine.Weight = db.Weight
 	mine.Remark = db.Remark
 }
-func (mine *CategoryInfo) Export(dir string) {
 
+// Export 导出分类及其子分类下的题目到目录中，文件名为分类名称，返回文件的路径
+func (mine *CategoryInfo) Export(dir, format string) (string, error) {
+	data, err := cacheCtx.ExportQuestionBank(mine.UID, format)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, mine.Name+"."+format)
+	err = os.WriteFile(file, data, 0644)
+	if err != nil {
+		return "", err
+	}
+	return file, nil
 }
 func (mine *CategoryInfo) Update(name, remark, quote, operator string, weight uint32) error {
-	err := nosql.UpdateCategoryBase(mine.UID, name, remark, quote, operator)
+	err := cacheCtx.categories.UpdateCategoryBase(mine.UID, name, remark, quote, operator)
 	if err != nil {
 		return err
 	}
@@ -134,7 +154,7 @@ func (mine *CategoryInfo) Update(name, remark, quote, operator string, weight ui
 	mine.Name = name
 	mine.Operator = operator
 	if weight != 0 {
-		arry, err := nosql.GetCategoryListByParent(mine.Parent)
+		arry, err := cacheCtx.categories.GetCategoryListByParent(mine.Parent)
 		if err != nil {
 			return err
 		}
@@ -145,13 +165,13 @@ func (mine *CategoryInfo) Update(name, remark, quote, operator string, weight ui
 		if weight > mine.Weight {
 			for _, v := range arry {
 				if v.Weight > mine.Weight && v.Weight <= weight {
-					err := nosql.UpdateCategoryInt("weight", "", v.UID.Hex(), int64(v.Weight-1))
+					err := cacheCtx.categories.UpdateCategoryInt("weight", "", v.UID.Hex(), int64(v.Weight-1))
 					if err != nil {
 						return errors.New("the newWeight is exceed the limit")
 					}
 				}
 			}
-			err = nosql.UpdateCategoryInt("weight", "", mine.UID, int64(weight))
+			err = cacheCtx.categories.UpdateCategoryInt("weight", "", mine.UID, int64(weight))
 			if err != nil {
 				return errors.New("the newWeight update is err")
 			}
@@ -161,13 +181,13 @@ func (mine *CategoryInfo) Update(name, remark, quote, operator string, weight ui
 			//大变小
 			for _, v := range arry {
 				if v.Weight >= weight && v.Weight < mine.Weight {
-					err := nosql.UpdateCategoryInt("weight", "", v.UID.Hex(), int64(v.Weight+1))
+					err := cacheCtx.categories.UpdateCategoryInt("weight", "", v.UID.Hex(), int64(v.Weight+1))
 					if err != nil {
 						return errors.New("the newWeight is exceed the limit")
 					}
 				}
 			}
-			err = nosql.UpdateCategoryInt("weight", "", mine.UID, int64(weight))
+			err = cacheCtx.categories.UpdateCategoryInt("weight", "", mine.UID, int64(weight))
 			if err != nil {
 				return errors.New("the newWeight update is err")
 			}
@@ -203,7 +223,7 @@ func (mine *CategoryInfo) Update(name, remark, quote, operator string, weight ui
 	return nil
 }
 func (mine *CategoryInfo) Delete(operator string) error {
-	list, err := nosql.GetCategoryListByParent(mine.UID)
+	list, err := cacheCtx.categories.GetCategoryListByParent(mine.UID)
 	if err != nil {
 		return err
 	}
@@ -211,27 +231,27 @@ func (mine *CategoryInfo) Delete(operator string) error {
 		return errors.New(fmt.Sprintf("the children count = %d", len(list)))
 	}
 	if mine.Parent == DefaultParent {
-		err = nosql.DeleteCategory(mine.UID, operator)
+		err = cacheCtx.categories.DeleteCategory(mine.UID, operator)
 		if err != nil {
 			return err
 		}
 		return nil
 	}
-	num := nosql.GetQuestionCount(mine.UID)
+	num := cacheCtx.questions.GetQuestionCount(mine.UID)
 	if num > 0 {
 		return errors.New(fmt.Sprintf("the children question count = %d", num))
 	}
-	err = nosql.DeleteCategory(mine.UID, operator)
+	err = cacheCtx.categories.DeleteCategory(mine.UID, operator)
 	if err != nil {
 		return nil
 	}
-	infos, err := nosql.GetCategoryListByParent(mine.Parent)
+	infos, err := cacheCtx.categories.GetCategoryListByParent(mine.Parent)
 	if err != nil {
 		return nil
 	}
 	for _, v := range infos {
 		if v.Weight > mine.Weight {
-			err1 := nosql.UpdateCategoryInt("weight", "", v.UID.Hex(), int64(v.Weight-1))
+			err1 := cacheCtx.categories.UpdateCategoryInt("weight", "", v.UID.Hex(), int64(v.Weight-1))
 			if err1 != nil {
 				return err1
 			}
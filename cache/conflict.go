@@ -0,0 +1,372 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"omo.msa.assignment/config"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/tool"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// 同一个小组的会议
+	ConflictGroup = "group"
+	// 同一个会议室(室内的会议地点相同)
+	ConflictRoom = "room"
+	// 有相同的参会人
+	ConflictAttendee = "attendee"
+)
+
+// 空闲时间的开始按照15分钟对齐
+const slotAlign = 15 * time.Minute
+
+// 查找空闲时间时最多返回的数量
+const maxSlotCount = 50
+
+// MeetingConflict 时间重叠的会议以及冲突的原因，Users为同时参加的成员
+type MeetingConflict struct {
+	Meeting *MeetingInfo
+	Reasons []string
+	Users   []string
+}
+
+// ConflictError 创建或者修改时间时与其他会议冲突
+type ConflictError struct {
+	Conflicts []*MeetingConflict
+}
+
+func (mine *ConflictError) Error() string {
+	list := make([]string, 0, len(mine.Conflicts))
+	for _, item := range mine.Conflicts {
+		list = append(list, fmt.Sprintf("%s(%s)", item.Meeting.UID, strings.Join(item.Reasons, ",")))
+	}
+	return "the meeting time is conflict with " + strings.Join(list, ";")
+}
+
+// ConflictsOf 错误为时间冲突时返回冲突的会议
+func ConflictsOf(err error) []*MeetingConflict {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return conflict.Conflicts
+	}
+	return nil
+}
+
+// MeetingPlan 用于检查冲突的会议安排，UID为已有的会议(检查时排除)，Stop为空时按照默认时长计算；
+// 同一个小组和会议室只检查同一个场景(Owner)的会议，为空时使用小组的场景；参会人检查所有场景的会议
+type MeetingPlan struct {
+	UID      string
+	Owner    string
+	Group    string
+	Type     LocationType
+	Location string
+	Users    []string
+	Start    time.Time
+	Stop     time.Time
+}
+
+// TimeSlot 空闲的时间段
+type TimeSlot struct {
+	Start time.Time
+	Stop  time.Time
+}
+
+// 没有结束时间的会议按照配置的时长计算
+func meetingLength() time.Duration {
+	if config.Schema.Meeting.Length > 0 {
+		return time.Duration(config.Schema.Meeting.Length) * time.Second
+	}
+	return time.Hour
+}
+
+func stopOf(start, stop time.Time) time.Time {
+	if stop.After(start) {
+		return stop
+	}
+	return start.Add(meetingLength())
+}
+
+func sameRoom(a, b string) bool {
+	a = strings.TrimSpace(a)
+	return len(a) > 0 && strings.EqualFold(a, strings.TrimSpace(b))
+}
+
+// 会议的参会人，为小组的成员以及被邀请的成员；teams缓存查询过的小组
+func (mine *cacheContext) attendeesOf(group string, notifies []string, teams map[string][]string) []string {
+	members, ok := teams[group]
+	if !ok && len(group) > 0 {
+		if team, er := mine.GetTeam(group); er == nil && team != nil {
+			members = team.Members
+		}
+		teams[group] = members
+	}
+	list := make([]string, 0, len(members)+len(notifies))
+	list = append(list, members...)
+	for _, user := range notifies {
+		if !tool.HasItem(list, user) {
+			list = append(list, user)
+		}
+	}
+	return list
+}
+
+// 会议所属的场景，owner为空时使用小组的场景，都没有时返回错误
+func (mine *cacheContext) ownerOf(owner, group string) (string, error) {
+	if len(owner) > 0 {
+		return owner, nil
+	}
+	if len(group) > 0 {
+		team, er := mine.GetTeam(group)
+		if er == nil && team != nil && len(team.Owner) > 0 {
+			return team.Owner, nil
+		}
+	}
+	return "", errors.New("the owner of the meeting is empty and can not be found from the group")
+}
+
+// 所有场景中与[start, stop)重叠的会议，关闭的不算；没有结束时间的按照默认时长计算
+func (mine *cacheContext) overlapMeetings(start, stop time.Time) ([]*MeetingInfo, error) {
+	// 结束时间在start之后的，以及没有结束时间但是开始时间在start之前一个默认时长内的
+	first, _, err := mine.meetings.QueryMeetings(proxy.NewQuery(true, proxy.Less("startAt", stop), proxy.Greater("stopAt", start)))
+	if err != nil {
+		return nil, err
+	}
+	second, _, err := mine.meetings.QueryMeetings(proxy.NewQuery(true, proxy.Less("startAt", stop),
+		proxy.Greater("startAt", start.Add(-meetingLength()))))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*MeetingInfo, 0, len(first)+len(second))
+	added := make(map[string]bool, len(first)+len(second))
+	for _, db := range append(first, second...) {
+		uid := db.UID.Hex()
+		if added[uid] || db.Status == uint8(Close) || db.StartTime.IsZero() {
+			continue
+		}
+		added[uid] = true
+		if !db.StartTime.Before(stop) || !stopOf(db.StartTime, db.StopTime).After(start) {
+			continue
+		}
+		info := new(MeetingInfo)
+		info.initInfo(db)
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartTime.Before(list[j].StartTime)
+	})
+	return list, nil
+}
+
+// FindConflicts 与安排的时间重叠的会议：同一个场景中同一个小组或者同一个会议室(室内的地点相同)，以及任何场景中有相同参会人的
+func (mine *cacheContext) FindConflicts(plan *MeetingPlan) ([]*MeetingConflict, error) {
+	if plan.Start.IsZero() {
+		return make([]*MeetingConflict, 0, 1), nil
+	}
+	owner, err := mine.ownerOf(plan.Owner, plan.Group)
+	if err != nil {
+		return nil, err
+	}
+	array, err := mine.overlapMeetings(plan.Start, stopOf(plan.Start, plan.Stop))
+	if err != nil {
+		return nil, err
+	}
+	teams := make(map[string][]string, 5)
+	users := plan.Users
+	if users == nil {
+		users = mine.attendeesOf(plan.Group, nil, teams)
+	}
+	list := make([]*MeetingConflict, 0, len(array))
+	for _, info := range array {
+		if info.UID == plan.UID {
+			continue
+		}
+		item := &MeetingConflict{Meeting: info, Reasons: make([]string, 0, 3), Users: make([]string, 0, 2)}
+		if info.Owner == owner {
+			if len(plan.Group) > 0 && info.Group == plan.Group {
+				item.Reasons = append(item.Reasons, ConflictGroup)
+			}
+			if plan.Type == InRoom && info.Type == InRoom && sameRoom(plan.Location, info.Location) {
+				item.Reasons = append(item.Reasons, ConflictRoom)
+			}
+		}
+		for _, user := range mine.attendeesOf(info.Group, info.Notifies, teams) {
+			if tool.HasItem(users, user) {
+				item.Users = append(item.Users, user)
+			}
+		}
+		if len(item.Users) > 0 {
+			item.Reasons = append(item.Reasons, ConflictAttendee)
+		}
+		if len(item.Reasons) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// 配置中不允许冲突时检查，有冲突时返回ConflictError
+func (mine *cacheContext) checkConflicts(plan *MeetingPlan) error {
+	if !config.Schema.Meeting.Conflict {
+		return nil
+	}
+	list, err := mine.FindConflicts(plan)
+	if err != nil {
+		return err
+	}
+	if len(list) > 0 {
+		return &ConflictError{Conflicts: list}
+	}
+	return nil
+}
+
+// Conflicts 会议在[start, stop)时的冲突，时间为空时使用会议当前的时间
+func (mine *MeetingInfo) Conflicts(start, stop time.Time) ([]*MeetingConflict, error) {
+	if start.IsZero() {
+		start = mine.StartTime
+		stop = mine.StopTime
+	}
+	return cacheCtx.FindConflicts(mine.plan(start, stop))
+}
+
+func (mine *MeetingInfo) plan(start, stop time.Time) *MeetingPlan {
+	return &MeetingPlan{UID: mine.UID, Owner: mine.Owner, Group: mine.Group, Type: mine.Type, Location: mine.Location,
+		Users: cacheCtx.attendeesOf(mine.Group, mine.Notifies, make(map[string][]string, 1)), Start: start, Stop: stop}
+}
+
+// WorkHours 工作时间，Start和End为一天中的秒数，Days为工作日(0为周日)，为空时每天都是
+type WorkHours struct {
+	Start int
+	End   int
+	Days  []time.Weekday
+}
+
+// ParseWorkHours 解析 09:00-18:00 的格式，为空时为全天
+func ParseWorkHours(str string, days []int) (*WorkHours, error) {
+	hours := &WorkHours{Start: 0, End: 24 * 3600, Days: make([]time.Weekday, 0, len(days))}
+	for _, day := range days {
+		if day < 0 || day > 6 {
+			return nil, errors.New(fmt.Sprintf("the work day(%d) should be 0-6", day))
+		}
+		hours.Days = append(hours.Days, time.Weekday(day))
+	}
+	if len(str) < 1 {
+		return hours, nil
+	}
+	arr := strings.Split(str, "-")
+	if len(arr) != 2 {
+		return nil, errors.New("the work hours should be like 09:00-18:00")
+	}
+	clock := func(s string) (int, error) {
+		var h, m int
+		_, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &h, &m)
+		if err != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m > 0) {
+			return 0, errors.New("the work hours should be like 09:00-18:00")
+		}
+		return h*3600 + m*60, nil
+	}
+	var err error
+	if hours.Start, err = clock(arr[0]); err != nil {
+		return nil, err
+	}
+	if hours.End, err = clock(arr[1]); err != nil {
+		return nil, err
+	}
+	if hours.End <= hours.Start {
+		return nil, errors.New("the end of work hours should be after the start")
+	}
+	return hours, nil
+}
+
+func (mine *WorkHours) isWorkDay(day time.Weekday) bool {
+	if len(mine.Days) < 1 {
+		return true
+	}
+	for _, item := range mine.Days {
+		if item == day {
+			return true
+		}
+	}
+	return false
+}
+
+func alignSlot(t time.Time) time.Time {
+	aligned := t.Truncate(slotAlign)
+	if aligned.Before(t) {
+		aligned = aligned.Add(slotAlign)
+	}
+	return aligned
+}
+
+// FindFreeSlots 小组在[from, to)的工作时间内最近的count个空闲时间段，长度为length；owner为空时使用小组的场景，
+// 场景中小组的会议以及成员在任何场景中参加的其他会议都算忙碌，每段空闲时间中按顺序连续安排
+func (mine *cacheContext) FindFreeSlots(owner, group string, length time.Duration, count int, from, to time.Time, hours *WorkHours) ([]TimeSlot, error) {
+	if length <= 0 || count < 1 || !to.After(from) {
+		return nil, errors.New("the length, count or time range is error")
+	}
+	if count > maxSlotCount {
+		count = maxSlotCount
+	}
+	owner, err := mine.ownerOf(owner, group)
+	if err != nil {
+		return nil, err
+	}
+	array, err := mine.overlapMeetings(from, to)
+	if err != nil {
+		return nil, err
+	}
+	teams := make(map[string][]string, 5)
+	users := mine.attendeesOf(group, nil, teams)
+	busy := make([]TimeSlot, 0, len(array))
+	for _, info := range array {
+		relevant := len(group) > 0 && info.Group == group && info.Owner == owner
+		if !relevant {
+			for _, user := range mine.attendeesOf(info.Group, info.Notifies, teams) {
+				if tool.HasItem(users, user) {
+					relevant = true
+					break
+				}
+			}
+		}
+		if relevant {
+			busy = append(busy, TimeSlot{Start: info.StartTime, Stop: stopOf(info.StartTime, info.StopTime)})
+		}
+	}
+	list := make([]TimeSlot, 0, count)
+	local := from.In(time.Local)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+	for ; day.Before(to) && len(list) < count; day = day.AddDate(0, 0, 1) {
+		if !hours.isWorkDay(day.Weekday()) {
+			continue
+		}
+		begin := day.Add(time.Duration(hours.Start) * time.Second)
+		end := day.Add(time.Duration(hours.End) * time.Second)
+		if begin.Before(from) {
+			begin = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		t := alignSlot(begin)
+		for !t.Add(length).After(end) && len(list) < count {
+			stop := t.Add(length)
+			moved := false
+			for _, item := range busy {
+				if item.Start.Before(stop) && item.Stop.After(t) {
+					// 从忙碌结束后重新开始
+					t = alignSlot(item.Stop)
+					moved = true
+					break
+				}
+			}
+			if moved {
+				continue
+			}
+			list = append(list, TimeSlot{Start: t, Stop: stop})
+			t = stop
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"omo.msa.assignment/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWorkHours(t *testing.T) {
+	cases := []struct {
+		text    string
+		days    []int
+		start   int
+		end     int
+		wantErr bool
+	}{
+		{text: "", start: 0, end: 24 * 3600},
+		{text: "09:00-18:00", days: []int{1, 2, 3, 4, 5}, start: 9 * 3600, end: 18 * 3600},
+		{text: " 08:30 - 24:00 ", start: 8*3600 + 1800, end: 24 * 3600},
+		{text: "18:00-09:00", wantErr: true},
+		{text: "09:00", wantErr: true},
+		{text: "09:60-18:00", wantErr: true},
+		{text: "09:00-24:30", wantErr: true},
+		{text: "09:00-18:00", days: []int{7}, wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.text, func(t *testing.T) {
+			hours, err := ParseWorkHours(item.text, item.days)
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if err == nil && (hours.Start != item.start || hours.End != item.end || len(hours.Days) != len(item.days)) {
+				t.Errorf("the work hours = %+v", hours)
+			}
+		})
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	ctx := newTestContext(t)
+	start := time.Date(2030, time.January, 2, 9, 0, 0, 0, time.Local)
+	first := newTestTeam(t, ctx, "scene", 0, "a", "b")
+	second := newTestTeam(t, ctx, "scene", 0, "b", "c")
+	empty := newTestTeam(t, ctx, "scene", 0)
+	other := newTestTeam(t, ctx, "other", 0, "a")
+	meeting := newTestMeeting(t, ctx, "scene", first.UID, "Room 1", start)
+	cases := []struct {
+		name    string
+		plan    MeetingPlan
+		reasons []string
+		users   []string
+		wantErr bool
+	}{
+		{name: "same group", plan: MeetingPlan{Group: first.UID, Type: InRoom, Location: "Room 2", Start: start},
+			reasons: []string{ConflictGroup, ConflictAttendee}, users: []string{"a", "b"}},
+		{name: "same attendee", plan: MeetingPlan{Group: second.UID, Type: InRoom, Location: "Room 2", Start: start.Add(30 * time.Minute)},
+			reasons: []string{ConflictAttendee}, users: []string{"b"}},
+		{name: "same room", plan: MeetingPlan{Group: empty.UID, Type: InRoom, Location: " room 1", Start: start},
+			reasons: []string{ConflictRoom}},
+		// 其他场景的会议室不冲突，参会人仍然冲突
+		{name: "other scene", plan: MeetingPlan{Group: other.UID, Type: InRoom, Location: "Room 1", Start: start},
+			reasons: []string{ConflictAttendee}, users: []string{"a"}},
+		{name: "owner of plan", plan: MeetingPlan{Owner: "other", Group: second.UID, Type: InRoom, Location: "Room 1", Start: start},
+			reasons: []string{ConflictAttendee}, users: []string{"b"}},
+		{name: "other scene without attendees", plan: MeetingPlan{Owner: "other", Group: empty.UID, Type: InRoom, Location: "Room 1",
+			Start: start}},
+		{name: "no owner", plan: MeetingPlan{Type: InRoom, Location: "Room 1", Start: start}, wantErr: true},
+		{name: "after the meeting", plan: MeetingPlan{Group: first.UID, Start: start.Add(time.Hour)}},
+		{name: "before the meeting", plan: MeetingPlan{Group: first.UID, Start: start.Add(-time.Hour), Stop: start}},
+		{name: "exclude itself", plan: MeetingPlan{UID: meeting.UID, Group: first.UID, Start: start}},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			list, err := ctx.FindConflicts(&item.plan)
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if len(item.reasons) < 1 {
+				if len(list) > 0 {
+					t.Errorf("the conflicts = %d, want 0", len(list))
+				}
+				return
+			}
+			if len(list) != 1 || list[0].Meeting.UID != meeting.UID {
+				t.Fatalf("the conflicts = %d, want 1", len(list))
+			}
+			if !reflect.DeepEqual(list[0].Reasons, item.reasons) {
+				t.Errorf("the reasons = %v, want %v", list[0].Reasons, item.reasons)
+			}
+			if len(item.users) > 0 && !reflect.DeepEqual(list[0].Users, item.users) {
+				t.Errorf("the users = %v, want %v", list[0].Users, item.users)
+			}
+		})
+	}
+}
+
+func TestCheckConflicts(t *testing.T) {
+	ctx := newTestContext(t)
+	start := time.Date(2030, time.January, 2, 9, 0, 0, 0, time.Local)
+	team := newTestTeam(t, ctx, "scene", 0, "a")
+	partner := newTestTeam(t, ctx, "other", 0, "a")
+	newTestMeeting(t, ctx, "scene", team.UID, "Room 1", start)
+	defer func(value bool) { config.Schema.Meeting.Conflict = value }(config.Schema.Meeting.Conflict)
+	cases := []struct {
+		name      string
+		conflict  bool
+		owner     string
+		group     string
+		location  string
+		conflicts int
+		wantErr   bool
+	}{
+		{name: "disabled", conflict: false, owner: "scene", location: "Room 1"},
+		// 默认检查冲突，不检查时创建的会议也算冲突
+		{name: "enabled", conflict: true, owner: "scene", location: "Room 1", conflicts: 2},
+		{name: "other scene", conflict: true, owner: "other", location: "Room 1"},
+		// 其他场景中相同的参会人也算冲突
+		{name: "other scene attendee", conflict: true, owner: "other", group: partner.UID, location: "Room 2", conflicts: 1},
+		{name: "owner of group", conflict: true, group: team.UID, location: "Room 2", conflicts: 1},
+		{name: "no owner", conflict: true, location: "Room 1", wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			config.Schema.Meeting.Conflict = item.conflict
+			_, err := ctx.CreateMeeting(&pb.ReqMeetingAdd{Name: "meeting", Owner: item.owner, Group: item.group, Type: uint32(InRoom),
+				Location: item.location, Appointed: start.Format("2006-01-02 15:04")})
+			if (err != nil) != (item.conflicts > 0 || item.wantErr) {
+				t.Fatalf("the error = %v, want conflicts = %d", err, item.conflicts)
+			}
+			if len(ConflictsOf(err)) != item.conflicts {
+				t.Errorf("the conflicts of error = %d, want %d", len(ConflictsOf(err)), item.conflicts)
+			}
+		})
+	}
+}
+
+func TestFindFreeSlots(t *testing.T) {
+	ctx := newTestContext(t)
+	// 2030-01-02为周三
+	day := time.Date(2030, time.January, 2, 0, 0, 0, 0, time.Local)
+	at := func(d, hour, minute int) time.Time {
+		return day.AddDate(0, 0, d).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	team := newTestTeam(t, ctx, "scene", 0, "a", "b")
+	partner := newTestTeam(t, ctx, "scene", 0, "b")
+	newTestMeeting(t, ctx, "scene", team.UID, "", at(0, 9, 0))
+	newTestMeeting(t, ctx, "scene", partner.UID, "", at(0, 11, 0))
+	// 其他场景的会议，小组的成员参加时也算忙碌
+	newTestMeeting(t, ctx, "other", team.UID, "", at(0, 10, 0))
+	hours, err := ParseWorkHours("09:00-13:00", []int{1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		length time.Duration
+		count  int
+		from   time.Time
+		to     time.Time
+		want   []time.Time
+	}{
+		{name: "one hour", length: time.Hour, count: 5, from: day, to: at(2, 0, 0),
+			want: []time.Time{at(0, 12, 0), at(1, 9, 0), at(1, 10, 0), at(1, 11, 0), at(1, 12, 0)}},
+		{name: "two hours", length: 2 * time.Hour, count: 2, from: day, to: at(2, 0, 0),
+			want: []time.Time{at(1, 9, 0), at(1, 11, 0)}},
+		{name: "aligned from", length: 30 * time.Minute, count: 2, from: at(0, 10, 5), to: at(1, 0, 0),
+			want: []time.Time{at(0, 12, 0), at(0, 12, 30)}},
+		{name: "skip weekend", length: 4 * time.Hour, count: 2, from: at(2, 0, 0), to: at(6, 0, 0),
+			want: []time.Time{at(2, 9, 0), at(5, 9, 0)}},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			list, err := ctx.FindFreeSlots("scene", team.UID, item.length, item.count, item.from, item.to, hours)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != len(item.want) {
+				t.Fatalf("the slots = %v, want %v", list, item.want)
+			}
+			for i, slot := range list {
+				if !slot.Start.Equal(item.want[i]) || !slot.Stop.Equal(item.want[i].Add(item.length)) {
+					t.Errorf("the slot[%d] = %v - %v, want start %v", i, slot.Start, slot.Stop, item.want[i])
+				}
+			}
+		})
+	}
+}
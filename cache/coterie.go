@@ -4,7 +4,6 @@ import (
 	"errors"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"math"
 	"omo.msa.assignment/proxy"
 	"omo.msa.assignment/proxy/nosql"
 	"time"
@@ -26,9 +25,13 @@ type CoterieInfo struct {
 }
 
 func (mine *cacheContext) CreateCoterie(info *pb.ReqCoterieAdd) (*CoterieInfo, error) {
+	id, err := mine.nextID(nosql.TableCoterie)
+	if err != nil {
+		return nil, err
+	}
 	db := new(nosql.Coterie)
 	db.UID = primitive.NewObjectID()
-	db.ID = nosql.GetCoterieNextID()
+	db.ID = id
 	db.CreatedTime = time.Now()
 	db.UpdatedTime = time.Now()
 	db.Operator = info.Operator
@@ -39,7 +42,11 @@ func (mine *cacheContext) CreateCoterie(info *pb.ReqCoterieAdd) (*CoterieInfo, e
 	db.Cover = info.Cover
 	db.Centre = info.Centre
 	db.Type = uint8(info.Type)
-	db.Passwords = info.Passwords
+	psw, err := hashPasswords(info.Passwords)
+	if err != nil {
+		return nil, err
+	}
+	db.Passwords = psw
 	db.Master = info.Master
 	db.Assistants = make([]string, 0, 1)
 	db.Tags = make([]string, 0, 1)
@@ -48,7 +55,7 @@ func (mine *cacheContext) CreateCoterie(info *pb.ReqCoterieAdd) (*CoterieInfo, e
 		db.Members = append(db.Members, proxy.MemberInfo{User: member.User, Name: member.Name, Remark: member.Remark})
 	}
 
-	err := nosql.CreateCoterie(db)
+	err = mine.coteries.CreateCoterie(db)
 	if err == nil {
 		tmp := new(CoterieInfo)
 		tmp.initInfo(db)
@@ -58,7 +65,7 @@ func (mine *cacheContext) CreateCoterie(info *pb.ReqCoterieAdd) (*CoterieInfo, e
 }
 
 func (mine *cacheContext) GetCoterie(uid string) (*CoterieInfo, error) {
-	db, err := nosql.GetCoterie(uid)
+	db, err := mine.coteries.GetCoterie(uid)
 	if err != nil {
 		return nil, err
 	}
@@ -67,10 +74,10 @@ func (mine *cacheContext) GetCoterie(uid string) (*CoterieInfo, error) {
 	return info, nil
 }
 
-func (mine *cacheContext) GetCoteriesByMember(uid string) ([]*CoterieInfo, error) {
-	dbs, err := nosql.GetCoteriesByMember(uid)
+func (mine *cacheContext) GetCoteriesByMember(uid string, page *PageInfo) (uint32, uint32, []*CoterieInfo, error) {
+	dbs, num, err := mine.coteries.QueryCoteries(page.query(true, proxy.Equal("members.user", uid)))
 	if err != nil {
-		return make([]*CoterieInfo, 0, 1), err
+		return 0, 0, make([]*CoterieInfo, 0, 1), err
 	}
 	list := make([]*CoterieInfo, 0, len(dbs))
 	for _, db := range dbs {
@@ -78,39 +85,39 @@ func (mine *cacheContext) GetCoteriesByMember(uid string) ([]*CoterieInfo, error
 		info.initInfo(db)
 		list = append(list, info)
 	}
-
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetAllCoteries(page, num uint32) (uint32, uint32, []*CoterieInfo, error) {
-	if page < 1 {
-		page = 1
+// GetAllCoteries 按照创建时间倒序分页，默认返回第一页
+func (mine *cacheContext) GetAllCoteries(page *PageInfo) (uint32, uint32, []*CoterieInfo, error) {
+	if page == nil {
+		page = new(PageInfo)
 	}
-	if num < 1 {
-		num = 10
+	if page.Page < 1 && len(page.Cursor) < 1 {
+		page.Page = 1
 	}
-	start := (page - 1) * num
-	dbs, err := nosql.GetAllCoteries(int64(start), int64(num))
-
+	query := page.query(true)
+	query.Sort = "createdAt"
+	query.Desc = true
+	dbs, num, err := mine.coteries.QueryCoteries(query)
 	if err != nil {
 		return 0, 0, make([]*CoterieInfo, 0, 1), err
 	}
-	total := nosql.GetActivitiesCount()
-	pages := math.Ceil(float64(total) / float64(num))
 	list := make([]*CoterieInfo, 0, len(dbs))
 	for _, db := range dbs {
 		info := new(CoterieInfo)
 		info.initInfo(db)
 		list = append(list, info)
 	}
-
-	return uint32(total), uint32(pages), list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetCoteriesByCreator(uid string) ([]*CoterieInfo, error) {
-	dbs, err := nosql.GetCoteriesByCreator(uid)
+func (mine *cacheContext) GetCoteriesByCreator(uid string, page *PageInfo) (uint32, uint32, []*CoterieInfo, error) {
+	dbs, num, err := mine.coteries.QueryCoteries(page.query(true, proxy.Equal("creator", uid)))
 	if err != nil {
-		return make([]*CoterieInfo, 0, 1), err
+		return 0, 0, make([]*CoterieInfo, 0, 1), err
 	}
 	list := make([]*CoterieInfo, 0, len(dbs))
 	for _, db := range dbs {
@@ -118,14 +125,14 @@ func (mine *cacheContext) GetCoteriesByCreator(uid string) ([]*CoterieInfo, erro
 		info.initInfo(db)
 		list = append(list, info)
 	}
-
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetCoteriesByMaster(uid string) ([]*CoterieInfo, error) {
-	dbs, err := nosql.GetCoteriesByMaster(uid)
+func (mine *cacheContext) GetCoteriesByMaster(uid string, page *PageInfo) (uint32, uint32, []*CoterieInfo, error) {
+	dbs, num, err := mine.coteries.QueryCoteries(page.query(true, proxy.Equal("master", uid)))
 	if err != nil {
-		return make([]*CoterieInfo, 0, 1), err
+		return 0, 0, make([]*CoterieInfo, 0, 1), err
 	}
 	list := make([]*CoterieInfo, 0, len(dbs))
 	for _, db := range dbs {
@@ -133,12 +140,12 @@ func (mine *cacheContext) GetCoteriesByMaster(uid string) ([]*CoterieInfo, error
 		info.initInfo(db)
 		list = append(list, info)
 	}
-
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
 func (mine *cacheContext) GetCoterieByCreator(user string) (*CoterieInfo, error) {
-	db, err := nosql.GetCoterieByCreator(user)
+	db, err := mine.coteries.GetCoterieByCreator(user)
 	if err != nil {
 		return nil, err
 	}
@@ -148,7 +155,7 @@ func (mine *cacheContext) GetCoterieByCreator(user string) (*CoterieInfo, error)
 }
 
 func (mine *cacheContext) GetCoterieByCentre(centre string) (*CoterieInfo, error) {
-	db, err := nosql.GetCoterieByCentre(centre)
+	db, err := mine.coteries.GetCoterieByCentre(centre)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +165,7 @@ func (mine *cacheContext) GetCoterieByCentre(centre string) (*CoterieInfo, error
 }
 
 func (mine *cacheContext) RemoveCoterie(uid, operator string) error {
-	return nosql.RemoveCoterie(uid, operator)
+	return mine.coteries.RemoveCoterie(uid, operator)
 }
 
 func (mine *CoterieInfo) initInfo(db *nosql.Coterie) {
@@ -189,8 +196,17 @@ func (mine *CoterieInfo) UpdateBase(name, remark, psw, operator string) error {
 	if len(remark) < 1 {
 		remark = mine.Remark
 	}
-	err := nosql.UpdateCoterieBase(mine.UID, name, remark, psw, operator)
+	// 密码为空时不修改
+	if len(psw) < 1 {
+		psw = mine.Passwords
+	}
+	psw, err := hashPasswords(psw)
+	if err != nil {
+		return err
+	}
+	err = cacheCtx.coteries.UpdateCoterieBase(mine.UID, name, remark, psw, operator)
 	if err == nil {
+		mine.Passwords = psw
 		mine.Name = name
 		mine.Remark = remark
 		mine.Operator = operator
@@ -199,7 +215,7 @@ func (mine *CoterieInfo) UpdateBase(name, remark, psw, operator string) error {
 }
 
 func (mine *CoterieInfo) UpdateMaster(master, operator string) error {
-	err := nosql.UpdateCoterieMaster(mine.UID, master, operator)
+	err := cacheCtx.coteries.UpdateCoterieMaster(mine.UID, master, operator)
 	if err == nil {
 		mine.Master = master
 		mine.Operator = operator
@@ -208,7 +224,7 @@ func (mine *CoterieInfo) UpdateMaster(master, operator string) error {
 }
 
 func (mine *CoterieInfo) UpdateStatus(operator string, st uint8) error {
-	err := nosql.UpdateCoterieStatus(mine.UID, operator, st)
+	err := cacheCtx.coteries.UpdateCoterieStatus(mine.UID, operator, st)
 	if err == nil {
 		mine.Status = st
 		mine.Operator = operator
@@ -217,7 +233,11 @@ func (mine *CoterieInfo) UpdateStatus(operator string, st uint8) error {
 }
 
 func (mine *CoterieInfo) UpdatePasswords(psw, operator string) error {
-	err := nosql.UpdateCoteriePasswords(mine.UID, operator, psw)
+	psw, err := hashPasswords(psw)
+	if err != nil {
+		return err
+	}
+	err = cacheCtx.coteries.UpdateCoteriePasswords(mine.UID, operator, psw)
 	if err == nil {
 		mine.Passwords = psw
 		mine.Operator = operator
@@ -225,8 +245,22 @@ func (mine *CoterieInfo) UpdatePasswords(psw, operator string) error {
 	return err
 }
 
+// VerifyPasswords 检查用户输入的密码，连续输错多次后该用户暂时不能再尝试
+func (mine *CoterieInfo) VerifyPasswords(user, psw string) error {
+	return verifyPasswords(mine.Passwords, mine.UID, user, psw)
+}
+
+// JoinWithPasswords 密码正确时加入圈子，已经是成员时不修改
+func (mine *CoterieInfo) JoinWithPasswords(user, name, remark, psw string) error {
+	err := mine.VerifyPasswords(user, psw)
+	if err != nil {
+		return err
+	}
+	return mine.AppendMember(user, name, remark)
+}
+
 func (mine *CoterieInfo) UpdateTags(operator string, tags []string) error {
-	err := nosql.UpdateCoterieTags(mine.UID, operator, tags)
+	err := cacheCtx.coteries.UpdateCoterieTags(mine.UID, operator, tags)
 	if err == nil {
 		mine.Tags = tags
 		mine.Operator = operator
@@ -246,7 +280,7 @@ func (mine *CoterieInfo) UpdateMemberIdentify(user, name, remark string) error {
 }
 
 func (mine *CoterieInfo) UpdateAssistants(operator string, list []string) error {
-	err := nosql.UpdateCoterieAssistants(mine.UID, operator, list)
+	err := cacheCtx.coteries.UpdateCoterieAssistants(mine.UID, operator, list)
 	if err == nil {
 		mine.Assistants = list
 		mine.Operator = operator
@@ -268,7 +302,7 @@ func (mine *CoterieInfo) AppendMember(user, name, remark string) error {
 		return nil
 	}
 	t := proxy.MemberInfo{User: user, Name: name, Remark: remark}
-	err := nosql.AppendCoterieMember(mine.UID, t)
+	err := cacheCtx.coteries.AppendCoterieMember(mine.UID, t)
 	if err == nil {
 		mine.Members = append(mine.Members, t)
 	}
@@ -279,7 +313,7 @@ func (mine *CoterieInfo) SubtractMember(member string) error {
 	if !mine.HadMember(member) {
 		return nil
 	}
-	err := nosql.SubtractCoterieMember(mine.UID, member)
+	err := cacheCtx.coteries.SubtractCoterieMember(mine.UID, member)
 	if err == nil {
 		for i := 0; i < len(mine.Members); i += 1 {
 			if mine.Members[i].User == member {
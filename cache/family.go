@@ -32,9 +32,13 @@ type FamilyInfo struct {
 }
 
 func (mine *cacheContext) CreateFamily(info *pb.ReqFamilyAdd) (*FamilyInfo, error) {
+	id, err := mine.nextID(nosql.TableFamily)
+	if err != nil {
+		return nil, err
+	}
 	db := new(nosql.Family)
 	db.UID = primitive.NewObjectID()
-	db.ID = nosql.GetFamilyNextID()
+	db.ID = id
 	db.CreatedTime = time.Now()
 	db.UpdatedTime = time.Now()
 	db.Operator = info.Operator
@@ -45,7 +49,11 @@ func (mine *cacheContext) CreateFamily(info *pb.ReqFamilyAdd) (*FamilyInfo, erro
 	db.Status = 0
 	db.Location = info.Location
 	db.Address = info.Address
-	db.Passwords = info.Passwords
+	psw, err := hashPasswords(info.Passwords)
+	if err != nil {
+		return nil, err
+	}
+	db.Passwords = psw
 	db.Master = info.Master
 	db.Assistants = make([]string, 0, 1)
 	db.Children = make([]string, 0, 1)
@@ -56,7 +64,7 @@ func (mine *cacheContext) CreateFamily(info *pb.ReqFamilyAdd) (*FamilyInfo, erro
 		db.Members = append(db.Members, proxy.MemberInfo{User: member.User, Name: member.Name, Remark: member.Remark})
 	}
 
-	err := nosql.CreateFamily(db)
+	err = mine.families.CreateFamily(db)
 	if err == nil {
 		tmp := new(FamilyInfo)
 		tmp.initInfo(db)
@@ -66,7 +74,7 @@ func (mine *cacheContext) CreateFamily(info *pb.ReqFamilyAdd) (*FamilyInfo, erro
 }
 
 func (mine *cacheContext) GetFamily(uid string) (*FamilyInfo, error) {
-	db, err := nosql.GetFamily(uid)
+	db, err := mine.families.GetFamily(uid)
 	if err != nil {
 		return nil, err
 	}
@@ -75,10 +83,10 @@ func (mine *cacheContext) GetFamily(uid string) (*FamilyInfo, error) {
 	return info, nil
 }
 
-func (mine *cacheContext) GetFamiliesByMember(uid string) ([]*FamilyInfo, error) {
-	dbs, err := nosql.GetFamiliesByMember(uid)
+func (mine *cacheContext) GetFamiliesByMember(uid string, page *PageInfo) (uint32, uint32, []*FamilyInfo, error) {
+	dbs, num, err := mine.families.QueryFamilies(page.query(true, proxy.Equal("members.user", uid)))
 	if err != nil {
-		return make([]*FamilyInfo, 0, 1), err
+		return 0, 0, make([]*FamilyInfo, 0, 1), err
 	}
 	list := make([]*FamilyInfo, 0, len(dbs))
 	for _, db := range dbs {
@@ -86,14 +94,14 @@ func (mine *cacheContext) GetFamiliesByMember(uid string) ([]*FamilyInfo, error)
 		info.initInfo(db)
 		list = append(list, info)
 	}
-
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetFamiliesByAgent(uid string) ([]*FamilyInfo, error) {
-	dbs, err := nosql.GetFamiliesByAgent(uid)
+func (mine *cacheContext) GetFamiliesByAgent(uid string, page *PageInfo) (uint32, uint32, []*FamilyInfo, error) {
+	dbs, num, err := mine.families.QueryFamilies(page.query(true, proxy.Equal("agents", uid)))
 	if err != nil {
-		return make([]*FamilyInfo, 0, 1), err
+		return 0, 0, make([]*FamilyInfo, 0, 1), err
 	}
 	list := make([]*FamilyInfo, 0, len(dbs))
 	for _, db := range dbs {
@@ -101,14 +109,14 @@ func (mine *cacheContext) GetFamiliesByAgent(uid string) ([]*FamilyInfo, error)
 		info.initInfo(db)
 		list = append(list, info)
 	}
-
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetFamiliesByRegion(region string) ([]*FamilyInfo, error) {
-	dbs, err := nosql.GetFamiliesByRegion(region)
+func (mine *cacheContext) GetFamiliesByRegion(region string, page *PageInfo) (uint32, uint32, []*FamilyInfo, error) {
+	dbs, num, err := mine.families.QueryFamilies(page.query(true, proxy.Equal("region", region)))
 	if err != nil {
-		return make([]*FamilyInfo, 0, 1), err
+		return 0, 0, make([]*FamilyInfo, 0, 1), err
 	}
 	list := make([]*FamilyInfo, 0, len(dbs))
 	for _, db := range dbs {
@@ -116,24 +124,27 @@ func (mine *cacheContext) GetFamiliesByRegion(region string) ([]*FamilyInfo, err
 		info.initInfo(db)
 		list = append(list, info)
 	}
-
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetFamiliesByRegions(regions []string) ([]*FamilyInfo, error) {
-	list := make([]*FamilyInfo, 0, 100)
-	for _, region := range regions {
-		array,_ := mine.GetFamiliesByRegion(region)
-		for _, info := range array {
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetFamiliesByRegions(regions []string, page *PageInfo) (uint32, uint32, []*FamilyInfo, error) {
+	dbs, num, err := mine.families.QueryFamilies(page.query(true, proxy.In("region", regions)))
+	if err != nil {
+		return 0, 0, make([]*FamilyInfo, 0, 1), err
 	}
-
-	return list, nil
+	list := make([]*FamilyInfo, 0, len(dbs))
+	for _, db := range dbs {
+		info := new(FamilyInfo)
+		info.initInfo(db)
+		list = append(list, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
 func (mine *cacheContext) GetFamilyByCreator(user string) (*FamilyInfo, error) {
-	db, err := nosql.GetFamilyByCreator(user)
+	db, err := mine.families.GetFamilyByCreator(user)
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +154,7 @@ func (mine *cacheContext) GetFamilyByCreator(user string) (*FamilyInfo, error) {
 }
 
 func (mine *cacheContext) GetFamilyByMaster(user string) (*FamilyInfo, error) {
-	db, err := nosql.GetFamilyByMaster(user)
+	db, err := mine.families.GetFamilyByMaster(user)
 	if err != nil {
 		return nil, err
 	}
@@ -153,7 +164,7 @@ func (mine *cacheContext) GetFamilyByMaster(user string) (*FamilyInfo, error) {
 }
 
 func (mine *cacheContext) GetFamilyByChild(child string) (*FamilyInfo, error) {
-	db, err := nosql.GetFamilyByChild(child)
+	db, err := mine.families.GetFamilyByChild(child)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +174,7 @@ func (mine *cacheContext) GetFamilyByChild(child string) (*FamilyInfo, error) {
 }
 
 func (mine *cacheContext) RemoveFamily(uid, operator string) error {
-	return nosql.RemoveFamily(uid, operator)
+	return mine.families.RemoveFamily(uid, operator)
 }
 
 func (mine *FamilyInfo) initInfo(db *nosql.Family) {
@@ -196,8 +207,17 @@ func (mine *FamilyInfo) UpdateBase(name, remark, psw, operator string) error {
 	if len(remark) < 1 {
 		remark = mine.Remark
 	}
-	err := nosql.UpdateFamilyBase(mine.UID, name, remark, psw, operator)
+	// 密码为空时不修改
+	if len(psw) < 1 {
+		psw = mine.Passwords
+	}
+	psw, err := hashPasswords(psw)
+	if err != nil {
+		return err
+	}
+	err = cacheCtx.families.UpdateFamilyBase(mine.UID, name, remark, psw, operator)
 	if err == nil {
+		mine.Passwords = psw
 		mine.Name = name
 		mine.Remark = remark
 		mine.Operator = operator
@@ -206,7 +226,7 @@ func (mine *FamilyInfo) UpdateBase(name, remark, psw, operator string) error {
 }
 
 func (mine *FamilyInfo) UpdateMaster(master, operator string) error {
-	err := nosql.UpdateFamilyMaster(mine.UID, master, operator)
+	err := cacheCtx.families.UpdateFamilyMaster(mine.UID, master, operator)
 	if err == nil {
 		mine.Master = master
 		mine.Operator = operator
@@ -215,7 +235,7 @@ func (mine *FamilyInfo) UpdateMaster(master, operator string) error {
 }
 
 func (mine *FamilyInfo) UpdateStatus(operator string, st uint8) error {
-	err := nosql.UpdateFamilyStatus(mine.UID, operator, st)
+	err := cacheCtx.families.UpdateFamilyStatus(mine.UID, operator, st)
 	if err == nil {
 		mine.Status = st
 		mine.Operator = operator
@@ -223,8 +243,12 @@ func (mine *FamilyInfo) UpdateStatus(operator string, st uint8) error {
 	return err
 }
 
-func (mine *FamilyInfo) UpdatePasswords(psw, operator  string) error {
-	err := nosql.UpdateFamilyPasswords(mine.UID, operator, psw)
+func (mine *FamilyInfo) UpdatePasswords(psw, operator string) error {
+	psw, err := hashPasswords(psw)
+	if err != nil {
+		return err
+	}
+	err = cacheCtx.families.UpdateFamilyPasswords(mine.UID, operator, psw)
 	if err == nil {
 		mine.Passwords = psw
 		mine.Operator = operator
@@ -232,8 +256,22 @@ func (mine *FamilyInfo) UpdatePasswords(psw, operator  string) error {
 	return err
 }
 
+// VerifyPasswords 检查用户输入的密码，连续输错多次后该用户暂时不能再尝试
+func (mine *FamilyInfo) VerifyPasswords(user, psw string) error {
+	return verifyPasswords(mine.Passwords, mine.UID, user, psw)
+}
+
+// JoinWithPasswords 密码正确时加入家庭，已经是成员时不修改
+func (mine *FamilyInfo) JoinWithPasswords(user, name, remark, psw string) error {
+	err := mine.VerifyPasswords(user, psw)
+	if err != nil {
+		return err
+	}
+	return mine.AppendMember(user, name, remark)
+}
+
 func (mine *FamilyInfo) UpdateTags(operator string, tags []string) error {
-	err := nosql.UpdateFamilyTags(mine.UID, operator, tags)
+	err := cacheCtx.families.UpdateFamilyTags(mine.UID, operator, tags)
 	if err == nil {
 		mine.Tags = tags
 		mine.Operator = operator
@@ -242,7 +280,7 @@ func (mine *FamilyInfo) UpdateTags(operator string, tags []string) error {
 }
 
 func (mine *FamilyInfo) UpdateAgents(operator string, list []string) error {
-	err := nosql.UpdateFamilyAgents(mine.UID, operator, list)
+	err := cacheCtx.families.UpdateFamilyAgents(mine.UID, operator, list)
 	if err == nil {
 		mine.Agents = list
 		mine.Operator = operator
@@ -251,7 +289,7 @@ func (mine *FamilyInfo) UpdateAgents(operator string, list []string) error {
 }
 
 func (mine *FamilyInfo) UpdateChildren(operator string, list []string) error {
-	err := nosql.UpdateFamilyChildren(mine.UID, operator, list)
+	err := cacheCtx.families.UpdateFamilyChildren(mine.UID, operator, list)
 	if err == nil {
 		mine.Children = list
 		mine.Operator = operator
@@ -271,7 +309,7 @@ func (mine *FamilyInfo) UpdateMemberIdentify(user, name, remark string) error {
 }
 
 func (mine *FamilyInfo) UpdateAssistants(operator string, list []string) error {
-	err := nosql.UpdateFamilyAssistants(mine.UID, operator, list)
+	err := cacheCtx.families.UpdateFamilyAssistants(mine.UID, operator, list)
 	if err == nil {
 		mine.Assistants = list
 		mine.Operator = operator
@@ -293,7 +331,7 @@ func (mine *FamilyInfo) AppendMember(user, name, remark string) error {
 		return nil
 	}
 	t := proxy.MemberInfo{User: user, Name: name, Remark: remark}
-	err := nosql.AppendFamilyMember(mine.UID, t)
+	err := cacheCtx.families.AppendFamilyMember(mine.UID, t)
 	if err == nil {
 		mine.Members = append(mine.Members, t)
 	}
@@ -304,7 +342,7 @@ func (mine *FamilyInfo) SubtractMember(member string) error {
 	if !mine.HadMember(member) {
 		return nil
 	}
-	err := nosql.SubtractFamilyMember(mine.UID, member)
+	err := cacheCtx.families.SubtractFamilyMember(mine.UID, member)
 	if err == nil {
 		for i := 0; i < len(mine.Members); i += 1 {
 			if mine.Members[i].User == member {
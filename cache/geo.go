@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 地球的平均半径，单位为米
+const earthRadius = 6371008.8
+
+var ErrLocationFormat = errors.New("the location should be latitude,longitude")
+
+// GeoPoint 经纬度坐标(WGS84)
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// ParseGeoPoint 支持 纬度,经度 的格式，以及以前的 x|经度|纬度 的格式
+func ParseGeoPoint(str string) (GeoPoint, error) {
+	var lat, lng string
+	if strings.Contains(str, "|") {
+		arr := strings.Split(str, "|")
+		if len(arr) < 3 {
+			return GeoPoint{}, ErrLocationFormat
+		}
+		lat, lng = arr[2], arr[1]
+	} else {
+		arr := strings.Split(str, ",")
+		if len(arr) != 2 {
+			return GeoPoint{}, ErrLocationFormat
+		}
+		lat, lng = arr[0], arr[1]
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	if err != nil {
+		return GeoPoint{}, ErrLocationFormat
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+	if err != nil {
+		return GeoPoint{}, ErrLocationFormat
+	}
+	if x < -90 || x > 90 || y < -180 || y > 180 {
+		return GeoPoint{}, ErrLocationFormat
+	}
+	return GeoPoint{Latitude: x, Longitude: y}, nil
+}
+
+func (mine GeoPoint) String() string {
+	return fmt.Sprintf("%s,%s", strconv.FormatFloat(mine.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(mine.Longitude, 'f', -1, 64))
+}
+
+// Distance 按照haversine公式计算两点之间的球面距离，单位为米
+func (mine GeoPoint) Distance(to GeoPoint) float64 {
+	rad := math.Pi / 180
+	lat1 := mine.Latitude * rad
+	lat2 := to.Latitude * rad
+	dLat := lat2 - lat1
+	dLng := (to.Longitude - mine.Longitude) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
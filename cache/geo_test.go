@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"omo.msa.assignment/config"
+	"testing"
+	"time"
+)
+
+func TestParseGeoPoint(t *testing.T) {
+	cases := []struct {
+		name    string
+		str     string
+		want    GeoPoint
+		wantErr bool
+	}{
+		{name: "lat,lng", str: "39.9042,116.4074", want: GeoPoint{Latitude: 39.9042, Longitude: 116.4074}},
+		{name: "spaces", str: " -33.8688 , 151.2093 ", want: GeoPoint{Latitude: -33.8688, Longitude: 151.2093}},
+		{name: "legacy", str: "x|116.4074|39.9042", want: GeoPoint{Latitude: 39.9042, Longitude: 116.4074}},
+		{name: "legacy short", str: "x|116.4074", wantErr: true},
+		{name: "one value", str: "39.9042", wantErr: true},
+		{name: "not number", str: "north,east", wantErr: true},
+		{name: "latitude out of range", str: "91,0", wantErr: true},
+		{name: "longitude out of range", str: "0,-181", wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			point, err := ParseGeoPoint(item.str)
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if point != item.want {
+				t.Errorf("the point = %v, want %v", point, item.want)
+			}
+		})
+	}
+}
+
+func TestGeoDistance(t *testing.T) {
+	cases := []struct {
+		name string
+		from GeoPoint
+		to   GeoPoint
+		want float64
+		// 允许的误差，单位为米
+		delta float64
+	}{
+		{name: "same", from: GeoPoint{Latitude: 39.9042, Longitude: 116.4074}, to: GeoPoint{Latitude: 39.9042, Longitude: 116.4074}},
+		{name: "one degree of latitude", from: GeoPoint{}, to: GeoPoint{Latitude: 1}, want: 111195.08, delta: 0.1},
+		{name: "one degree of longitude on equator", from: GeoPoint{}, to: GeoPoint{Longitude: 1}, want: 111195.08, delta: 0.1},
+		{name: "cross the date line", from: GeoPoint{Longitude: 179.5}, to: GeoPoint{Longitude: -179.5}, want: 111195.08, delta: 0.1},
+		{name: "beijing to shanghai", from: GeoPoint{Latitude: 39.9042, Longitude: 116.4074},
+			to: GeoPoint{Latitude: 31.2304, Longitude: 121.4737}, want: 1067000, delta: 1000},
+		{name: "antipodes", from: GeoPoint{Latitude: 90}, to: GeoPoint{Latitude: -90}, want: math.Pi * earthRadius, delta: 0.1},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			distance := item.from.Distance(item.to)
+			if math.Abs(distance-item.want) > item.delta {
+				t.Errorf("the distance = %f, want %f", distance, item.want)
+			}
+			if back := item.to.Distance(item.from); math.Abs(back-distance) > 1e-6 {
+				t.Errorf("the distance back = %f, want %f", back, distance)
+			}
+		})
+	}
+}
+
+func TestCheckSign(t *testing.T) {
+	defer func(conf config.MeetingConfig) {
+		config.Schema.Meeting = conf
+	}(config.Schema.Meeting)
+	config.Schema.Meeting.SignBefore = 600
+	config.Schema.Meeting.SignLate = 300
+	config.Schema.Meeting.Radius = 100
+	start := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC)
+	center := "39.9,116.4"
+	cases := []struct {
+		name     string
+		meet     MeetingInfo
+		location string
+		now      time.Duration
+		state    uint8
+		distance float64
+		err      error
+	}{
+		{name: "not open", meet: MeetingInfo{StartTime: start}, now: -20 * time.Minute, distance: -1, err: ErrSignNotOpen},
+		{name: "open early", meet: MeetingInfo{StartTime: start}, now: -5 * time.Minute, distance: -1},
+		{name: "always open", meet: MeetingInfo{StartTime: start, SignBefore: -1}, now: -24 * time.Hour, distance: -1},
+		{name: "late", meet: MeetingInfo{StartTime: start}, now: 10 * time.Minute, state: SignStateLate, distance: -1},
+		{name: "longer late", meet: MeetingInfo{StartTime: start, SignLate: 1200}, now: 10 * time.Minute, distance: -1},
+		{name: "never late", meet: MeetingInfo{StartTime: start, SignLate: -1}, now: time.Hour, distance: -1},
+		{name: "stopped", meet: MeetingInfo{StartTime: start, StopTime: start.Add(time.Hour)}, now: time.Hour, distance: -1,
+			err: ErrSignClosed},
+		{name: "closed", meet: MeetingInfo{StartTime: start, Status: Close}, distance: -1, err: ErrSignClosed},
+		{name: "inside", meet: MeetingInfo{StartTime: start, Type: Outside, Location: center}, location: "39.9005,116.4",
+			distance: 55.6},
+		{name: "outside", meet: MeetingInfo{StartTime: start, Type: Outside, Location: center}, location: "39.902,116.4",
+			state: SignStateOutside, distance: 222.4},
+		{name: "larger radius", meet: MeetingInfo{StartTime: start, Type: Outside, Location: center, Radius: 500},
+			location: "39.902,116.4", distance: 222.4},
+		{name: "any where", meet: MeetingInfo{StartTime: start, Type: Outside, Location: center, Radius: -1},
+			location: "40.9,116.4", distance: 111195.1},
+		{name: "no location", meet: MeetingInfo{StartTime: start, Type: Outside, Location: center}, distance: -1,
+			err: ErrLocationFormat},
+		{name: "bad location", meet: MeetingInfo{StartTime: start, Type: Outside, Location: center}, location: "here",
+			distance: -1, err: ErrLocationFormat},
+		{name: "in room", meet: MeetingInfo{StartTime: start, Type: InRoom, Location: "room"}, location: "39.902,116.4",
+			distance: -1},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			state, distance, err := item.meet.checkSign(item.location, start.Add(item.now))
+			if !errors.Is(err, item.err) {
+				t.Fatalf("the error = %v, want %v", err, item.err)
+			}
+			if state != item.state || distance != item.distance {
+				t.Errorf("the state = %d, the distance = %v, want %d and %v", state, distance, item.state, item.distance)
+			}
+		})
+	}
+}
+
+func TestSignMeeting(t *testing.T) {
+	defer func(conf config.MeetingConfig) {
+		config.Schema.Meeting = conf
+	}(config.Schema.Meeting)
+	config.Schema.Meeting.Radius = 100
+	config.Schema.Meeting.SignLate = 600
+	ctx := newTestContext(t)
+	info := newTestMeeting(t, ctx, "scene", "", "", time.Now())
+	if err := info.UpdateLocation("39.9,116.4", "admin", Outside); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name     string
+		location string
+		err      error
+		state    uint8
+		signed   bool
+		records  int
+	}{
+		{name: "outside", location: "39.902,116.4", err: ErrSignOutside, state: SignStateOutside, records: 1},
+		{name: "inside", location: "39.9005,116.4", signed: true, records: 2},
+		// 已经签到的返回之前的记录
+		{name: "again", location: "39.902,116.4", signed: true, records: 2},
+	}
+	for _, step := range steps {
+		record, err := info.Sign("user", "user", step.location, "phone")
+		if !errors.Is(err, step.err) {
+			t.Fatalf("%s: the error = %v, want %v", step.name, err, step.err)
+		}
+		if record.State != step.state {
+			t.Errorf("%s: the state = %d, want %d", step.name, record.State, step.state)
+		}
+		db, _ := ctx.GetMeeting(info.UID)
+		if db.HadSigned("user") != step.signed || len(db.Records) != step.records {
+			t.Errorf("%s: the signed = %v, the records = %d, want %v and %d", step.name, db.HadSigned("user"),
+				len(db.Records), step.signed, step.records)
+		}
+	}
+}
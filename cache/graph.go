@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"errors"
+	"sort"
+)
+
+// TaskGraph 任务的依赖关系图(DAG)，Nodes按照拓扑顺序排列
+type TaskGraph struct {
+	Owner string
+	Nodes []*TaskInfo
+	// 关键路径，即耗时最长的一条依赖链
+	Critical []*TaskInfo
+	// 关键路径的总耗时，单位为天
+	Length uint32
+}
+
+type TaskReadyHandler func(info *TaskInfo)
+
+var taskReadyHandlers = make([]TaskReadyHandler, 0, 1)
+
+// WatchTaskReady 注册回调，当任务的前置任务全部完成时通知
+func WatchTaskReady(handler TaskReadyHandler) {
+	if handler != nil {
+		taskReadyHandlers = append(taskReadyHandlers, handler)
+	}
+}
+
+// 检查前置任务：必须存在、未删除、属于同一个owner，并且不能形成环
+func (mine *cacheContext) checkPreTasks(owner, uid string, list []string) error {
+	for i, item := range list {
+		if len(item) < 1 {
+			return errors.New("the pre task uid is empty")
+		}
+		if item == uid {
+			return errors.New("the task can not depend on itself")
+		}
+		for j := 0; j < i; j += 1 {
+			if list[j] == item {
+				return errors.New("the pre task is repeated: " + item)
+			}
+		}
+		db, err := mine.tasks.GetTask(item)
+		if err != nil {
+			return errors.New("the pre task not existed: " + item)
+		}
+		if !db.DeleteTime.IsZero() {
+			return errors.New("the pre task had removed: " + item)
+		}
+		if db.Owner != owner {
+			return errors.New("the pre task not match the owner: " + item)
+		}
+	}
+	if len(uid) < 1 {
+		return nil
+	}
+	visited := make(map[string]bool, 10)
+	for _, item := range list {
+		if mine.reachTask(item, uid, visited) {
+			return errors.New("the pre tasks will make a cycle: " + item)
+		}
+	}
+	return nil
+}
+
+// 沿着前置任务查找，判断from是否依赖于target
+func (mine *cacheContext) reachTask(from, target string, visited map[string]bool) bool {
+	if from == target {
+		return true
+	}
+	if visited[from] {
+		return false
+	}
+	visited[from] = true
+	db, err := mine.tasks.GetTask(from)
+	if err != nil {
+		return false
+	}
+	for _, item := range db.PreTasks {
+		if mine.reachTask(item, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// 通知已经解除阻塞的后续任务
+func (mine *cacheContext) notifyDependents(uid string) {
+	dbs, err := mine.tasks.GetTasksByPreTask(uid)
+	if err != nil {
+		return
+	}
+	for _, db := range dbs {
+		info := new(TaskInfo)
+		info.initInfo(db)
+		if info.Status != TaskStatusIdle || info.CheckPreTasks() != nil {
+			continue
+		}
+		for _, handler := range taskReadyHandlers {
+			handler(info)
+		}
+	}
+}
+
+// GetTaskGraph 获取owner下所有任务的依赖关系图，包括拓扑顺序以及关键路径
+func (mine *cacheContext) GetTaskGraph(owner string) (*TaskGraph, error) {
+	if len(owner) < 1 {
+		return nil, errors.New("the owner is empty")
+	}
+	dbs, err := mine.tasks.GetTasksByOwner2(owner)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]*TaskInfo, len(dbs))
+	for _, db := range dbs {
+		info := new(TaskInfo)
+		info.initInfo(db)
+		all[info.UID] = info
+	}
+	degrees := make(map[string]int, len(all))
+	dependents := make(map[string][]*TaskInfo, len(all))
+	for _, info := range all {
+		degrees[info.UID] = 0
+		for _, pre := range info.PreTasks {
+			if _, ok := all[pre]; ok {
+				degrees[info.UID] += 1
+				dependents[pre] = append(dependents[pre], info)
+			}
+		}
+	}
+	queue := make([]*TaskInfo, 0, len(all))
+	for _, info := range all {
+		if degrees[info.UID] == 0 {
+			queue = append(queue, info)
+		}
+	}
+	graph := &TaskGraph{Owner: owner, Nodes: make([]*TaskInfo, 0, len(all))}
+	// 同一层级按照ID排序，保证结果稳定
+	for len(queue) > 0 {
+		sort.Slice(queue, func(i, j int) bool { return queue[i].ID < queue[j].ID })
+		info := queue[0]
+		queue = queue[1:]
+		graph.Nodes = append(graph.Nodes, info)
+		for _, next := range dependents[info.UID] {
+			degrees[next.UID] -= 1
+			if degrees[next.UID] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	if len(graph.Nodes) != len(all) {
+		return nil, errors.New("the task graph has a cycle")
+	}
+
+	lengths := make(map[string]uint32, len(all))
+	previous := make(map[string]string, len(all))
+	var last *TaskInfo
+	for _, info := range graph.Nodes {
+		var most uint32 = 0
+		for _, pre := range info.PreTasks {
+			if _, ok := all[pre]; ok && lengths[pre] > most {
+				most = lengths[pre]
+				previous[info.UID] = pre
+			}
+		}
+		lengths[info.UID] = most + mine.taskDays(info)
+		if last == nil || lengths[info.UID] > lengths[last.UID] {
+			last = info
+		}
+	}
+	graph.Critical = make([]*TaskInfo, 0, 5)
+	if last != nil {
+		graph.Length = lengths[last.UID]
+		for uid := last.UID; len(uid) > 0; uid = previous[uid] {
+			graph.Critical = append([]*TaskInfo{all[uid]}, graph.Critical...)
+		}
+	}
+	return graph, nil
+}
+
+// 任务的耗时(天)，时间无法解析时按一天计算
+func (mine *cacheContext) taskDays(info *TaskInfo) uint32 {
+	begin, err := mine.formatDate(info.Duration.Begin)
+	if err != nil {
+		return 1
+	}
+	end, err := mine.formatDate(info.Duration.End)
+	if err != nil || end.Before(begin) {
+		return 1
+	}
+	return uint32(end.Sub(begin).Hours()/24) + 1
+}
+
+// CheckPreTasks 检查前置任务是否全部完成，已删除的前置任务不再阻塞
+func (mine *TaskInfo) CheckPreTasks() error {
+	for _, item := range mine.PreTasks {
+		db, err := cacheCtx.tasks.GetTask(item)
+		if err != nil || !db.DeleteTime.IsZero() {
+			continue
+		}
+		if TaskStatus(db.Status) != TaskStatusEnd {
+			return errors.New("the pre task is not finished: " + item)
+		}
+	}
+	return nil
+}
+
+func (mine *TaskInfo) UpdatePreTasks(operator string, list []string) error {
+	if list == nil {
+		list = make([]string, 0, 1)
+	}
+	err := cacheCtx.checkPreTasks(mine.Owner, mine.UID, list)
+	if err != nil {
+		return err
+	}
+	err = cacheCtx.tasks.UpdateTaskPreTasks(mine.UID, operator, list)
+	if err == nil {
+		mine.PreTasks = list
+		mine.Operator = operator
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"sync"
+)
+
+// 小组的最大层级，数据错误出现环时也不会死循环
+const maxTeamDepth = 32
+
+var (
+	ErrTeamHasChildren = errors.New("the team has sub teams")
+	ErrTeamCycle       = errors.New("the parent can not be the team itself or its descendant")
+	ErrTeamOwner       = errors.New("the parent team belongs to another owner")
+)
+
+// 检查上级的路径和修改上级之间没有加锁，同时移动时可能出现环，所以移动只在当前进程中依次执行；
+// 部署多个实例时不能保证
+var teamMoveLock sync.Mutex
+
+// 未删除的小组
+func (mine *cacheContext) aliveTeam(uid string) (*TeamInfo, error) {
+	if len(uid) < 1 {
+		return nil, errors.New("the team uid is empty")
+	}
+	db, err := mine.teams.GetTeam(uid)
+	if err != nil {
+		return nil, err
+	}
+	if db == nil || !db.DeleteTime.IsZero() {
+		return nil, errors.New("not found the team of " + uid)
+	}
+	info := new(TeamInfo)
+	info.initInfo(db)
+	return info, nil
+}
+
+// GetTeamChildren 直接的下级小组
+func (mine *cacheContext) GetTeamChildren(uid string, page *PageInfo) (uint32, uint32, []*TeamInfo) {
+	dbs, num, err := mine.teams.QueryTeams(page.query(true, proxy.Equal("parent", uid)))
+	if err != nil {
+		return 0, 0, make([]*TeamInfo, 0, 1)
+	}
+	list := make([]*TeamInfo, 0, len(dbs))
+	for _, db := range dbs {
+		info := new(TeamInfo)
+		info.initInfo(db)
+		list = append(list, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, list
+}
+
+// GetTeamDescendants 所有的下级小组，按层级从上到下
+func (mine *cacheContext) GetTeamDescendants(uid string) ([]*TeamInfo, error) {
+	list := make([]*TeamInfo, 0, 10)
+	visited := map[string]bool{uid: true}
+	parents := []string{uid}
+	for depth := 0; depth < maxTeamDepth && len(parents) > 0; depth += 1 {
+		dbs, _, err := mine.teams.QueryTeams(proxy.NewQuery(true, proxy.In("parent", parents)))
+		if err != nil {
+			return nil, err
+		}
+		parents = make([]string, 0, len(dbs))
+		for _, db := range dbs {
+			if visited[db.UID.Hex()] {
+				continue
+			}
+			visited[db.UID.Hex()] = true
+			info := new(TeamInfo)
+			info.initInfo(db)
+			list = append(list, info)
+			parents = append(parents, info.UID)
+		}
+	}
+	return list, nil
+}
+
+// Ancestors 上级小组的路径，从最上级开始，不包括自己
+func (mine *TeamInfo) Ancestors() ([]*TeamInfo, error) {
+	list := make([]*TeamInfo, 0, 5)
+	visited := map[string]bool{mine.UID: true}
+	parent := mine.Parent
+	for depth := 0; depth < maxTeamDepth && len(parent) > 0; depth += 1 {
+		if visited[parent] {
+			return nil, ErrTeamCycle
+		}
+		visited[parent] = true
+		info, err := cacheCtx.aliveTeam(parent)
+		if err != nil {
+			// 上级已经删除时到此为止
+			break
+		}
+		list = append([]*TeamInfo{info}, list...)
+		parent = info.Parent
+	}
+	return list, nil
+}
+
+// MoveTo 移动到其他小组下(包括所有的下级)，parent为空时为最上级；不能移动到自己或者自己的下级，
+// 也不能移动到其他场景(Owner)的小组下
+func (mine *TeamInfo) MoveTo(parent, operator string) error {
+	if parent == mine.Parent {
+		return nil
+	}
+	teamMoveLock.Lock()
+	defer teamMoveLock.Unlock()
+	if len(parent) > 0 {
+		if parent == mine.UID {
+			return ErrTeamCycle
+		}
+		target, err := cacheCtx.aliveTeam(parent)
+		if err != nil {
+			return err
+		}
+		if target.Owner != mine.Owner {
+			return ErrTeamOwner
+		}
+		ancestors, err := target.Ancestors()
+		if err != nil {
+			return err
+		}
+		for _, item := range ancestors {
+			if item.UID == mine.UID {
+				return ErrTeamCycle
+			}
+		}
+		if len(ancestors)+2+mine.height() > maxTeamDepth {
+			return errors.New("the team hierarchy is too deep")
+		}
+	}
+	err := cacheCtx.teams.UpdateTeamParent(mine.UID, parent, operator)
+	if err == nil {
+		mine.Parent = parent
+		mine.Operator = operator
+	}
+	return err
+}
+
+// 下级的层数
+func (mine *TeamInfo) height() int {
+	parents := []string{mine.UID}
+	num := 0
+	for ; num < maxTeamDepth && len(parents) > 0; num += 1 {
+		dbs, _, err := cacheCtx.teams.QueryTeams(proxy.NewQuery(true, proxy.In("parent", parents)))
+		if err != nil || len(dbs) < 1 {
+			break
+		}
+		parents = make([]string, 0, len(dbs))
+		for _, db := range dbs {
+			parents = append(parents, db.UID.Hex())
+		}
+	}
+	return num
+}
+
+// AllMembers 自己以及所有下级小组的成员，重复的只算一次
+func (mine *TeamInfo) AllMembers() ([]string, error) {
+	children, err := cacheCtx.GetTeamDescendants(mine.UID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, len(mine.Members)*(len(children)+1))
+	added := make(map[string]bool, cap(list))
+	for _, team := range append([]*TeamInfo{mine}, children...) {
+		for _, member := range team.Members {
+			if len(member) > 0 && !added[member] {
+				added[member] = true
+				list = append(list, member)
+			}
+		}
+	}
+	return list, nil
+}
+
+// RemoveTeamTree 删除小组，有下级时cascade为false则不删除，为true时先删除所有的下级；返回删除的小组
+func (mine *cacheContext) RemoveTeamTree(uid, operator string, cascade bool) ([]string, error) {
+	if len(uid) < 1 {
+		return nil, errors.New("the team uid is empty")
+	}
+	children, err := mine.GetTeamDescendants(uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) > 0 && !cascade {
+		return nil, ErrTeamHasChildren
+	}
+	list := make([]string, 0, len(children)+1)
+	// 从最下级开始删除，中途失败时不会留下没有上级的小组
+	for i := len(children) - 1; i >= 0; i -= 1 {
+		err = mine.teams.RemoveTeam(children[i].UID, operator)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, children[i].UID)
+	}
+	err = mine.teams.RemoveTeam(uid, operator)
+	if err != nil {
+		return list, err
+	}
+	return append(list, uid), nil
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"errors"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newTestChild(t *testing.T, ctx *cacheContext, owner, parent string) *TeamInfo {
+	t.Helper()
+	info, err := ctx.CreateTeam(&pb.ReqTeamAdd{Name: "team", Owner: owner, Operator: "admin", Parent: parent})
+	if err != nil {
+		t.Fatalf("create the team failed: %v", err)
+	}
+	return info
+}
+
+func uidsOf(list []*TeamInfo) []string {
+	uids := make([]string, 0, len(list))
+	for _, item := range list {
+		uids = append(uids, item.UID)
+	}
+	return uids
+}
+
+func TestMoveTeam(t *testing.T) {
+	// root -> middle -> leaf，another为同一个场景的小组，foreign为其他场景的小组
+	type tree struct {
+		root, middle, leaf, another, foreign, removed *TeamInfo
+	}
+	cases := []struct {
+		name    string
+		team    func(tr *tree) *TeamInfo
+		parent  func(tr *tree) string
+		err     error
+		wantErr bool
+		// 移动后另一个小组的所有下级
+		under       func(tr *tree) *TeamInfo
+		descendants func(tr *tree) []string
+	}{
+		{name: "to another", team: func(tr *tree) *TeamInfo { return tr.middle }, parent: func(tr *tree) string { return tr.another.UID },
+			under:       func(tr *tree) *TeamInfo { return tr.another },
+			descendants: func(tr *tree) []string { return []string{tr.middle.UID, tr.leaf.UID} }},
+		{name: "to top", team: func(tr *tree) *TeamInfo { return tr.middle }, parent: func(tr *tree) string { return "" },
+			under:       func(tr *tree) *TeamInfo { return tr.root },
+			descendants: func(tr *tree) []string { return []string{} }},
+		{name: "same parent", team: func(tr *tree) *TeamInfo { return tr.leaf }, parent: func(tr *tree) string { return tr.middle.UID },
+			under:       func(tr *tree) *TeamInfo { return tr.root },
+			descendants: func(tr *tree) []string { return []string{tr.middle.UID, tr.leaf.UID} }},
+		{name: "to itself", team: func(tr *tree) *TeamInfo { return tr.middle }, parent: func(tr *tree) string { return tr.middle.UID },
+			err: ErrTeamCycle},
+		{name: "to descendant", team: func(tr *tree) *TeamInfo { return tr.root }, parent: func(tr *tree) string { return tr.leaf.UID },
+			err: ErrTeamCycle},
+		{name: "other owner", team: func(tr *tree) *TeamInfo { return tr.middle }, parent: func(tr *tree) string { return tr.foreign.UID },
+			err: ErrTeamOwner},
+		{name: "removed parent", team: func(tr *tree) *TeamInfo { return tr.middle }, parent: func(tr *tree) string { return tr.removed.UID },
+			wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			tr := &tree{root: newTestChild(t, ctx, "scene", "")}
+			tr.middle = newTestChild(t, ctx, "scene", tr.root.UID)
+			tr.leaf = newTestChild(t, ctx, "scene", tr.middle.UID)
+			tr.another = newTestChild(t, ctx, "scene", "")
+			tr.foreign = newTestChild(t, ctx, "other", "")
+			tr.removed = newTestChild(t, ctx, "scene", "")
+			if _, err := ctx.RemoveTeamTree(tr.removed.UID, "admin", false); err != nil {
+				t.Fatal(err)
+			}
+			team := item.team(tr)
+			before := team.Parent
+			parent := item.parent(tr)
+			err := team.MoveTo(parent, "admin")
+			if item.err != nil || item.wantErr {
+				if err == nil || (item.err != nil && !errors.Is(err, item.err)) {
+					t.Fatalf("the error = %v, want %v", err, item.err)
+				}
+				db, _ := ctx.GetTeam(team.UID)
+				if db.Parent != before || team.Parent != before {
+					t.Errorf("the parent = %s, want %s", db.Parent, before)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			db, _ := ctx.GetTeam(team.UID)
+			if db.Parent != parent {
+				t.Errorf("the parent = %s, want %s", db.Parent, parent)
+			}
+			list, err := ctx.GetTeamDescendants(item.under(tr).UID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uids, want := uidsOf(list), item.descendants(tr); !reflect.DeepEqual(uids, want) {
+				t.Errorf("the descendants = %v, want %v", uids, want)
+			}
+		})
+	}
+}
+
+func TestMoveTeamConcurrently(t *testing.T) {
+	ctx := newTestContext(t)
+	for i := 0; i < 20; i += 1 {
+		first := newTestChild(t, ctx, "scene", "")
+		second := newTestChild(t, ctx, "scene", "")
+		var wait sync.WaitGroup
+		errs := make([]error, 2)
+		for j, pair := range [][2]*TeamInfo{{first, second}, {second, first}} {
+			wait.Add(1)
+			go func(j int, team, parent *TeamInfo) {
+				defer wait.Done()
+				errs[j] = team.MoveTo(parent.UID, "admin")
+			}(j, pair[0], pair[1])
+		}
+		wait.Wait()
+		// 同时互相移动时只有一个成功，不会出现环
+		if (errs[0] == nil) == (errs[1] == nil) {
+			t.Fatalf("the errors = %v, want only one success", errs)
+		}
+		db, _ := ctx.GetTeam(first.UID)
+		if _, err := db.Ancestors(); err != nil {
+			t.Fatalf("the ancestors error = %v", err)
+		}
+	}
+}
+
+func TestCreateTeamParent(t *testing.T) {
+	ctx := newTestContext(t)
+	root := newTestChild(t, ctx, "scene", "")
+	cases := []struct {
+		name   string
+		owner  string
+		parent string
+		err    error
+	}{
+		{name: "same owner", owner: "scene", parent: root.UID},
+		{name: "other owner", owner: "other", parent: root.UID, err: ErrTeamOwner},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			_, err := ctx.CreateTeam(&pb.ReqTeamAdd{Name: item.name, Owner: item.owner, Operator: "admin", Parent: item.parent})
+			if !errors.Is(err, item.err) {
+				t.Errorf("the error = %v, want %v", err, item.err)
+			}
+		})
+	}
+}
+
+func TestRemoveTeamTree(t *testing.T) {
+	cases := []struct {
+		name    string
+		cascade bool
+		leaf    bool
+		err     error
+		removed int
+	}{
+		{name: "has children", err: ErrTeamHasChildren},
+		{name: "cascade", cascade: true, removed: 3},
+		{name: "leaf", leaf: true, removed: 1},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			root := newTestChild(t, ctx, "scene", "")
+			middle := newTestChild(t, ctx, "scene", root.UID)
+			leaf := newTestChild(t, ctx, "scene", middle.UID)
+			uid := root.UID
+			if item.leaf {
+				uid = leaf.UID
+			}
+			list, err := ctx.RemoveTeamTree(uid, "admin", item.cascade)
+			if !errors.Is(err, item.err) {
+				t.Fatalf("the error = %v, want %v", err, item.err)
+			}
+			if len(list) != item.removed {
+				t.Fatalf("the removed = %v, want %d", list, item.removed)
+			}
+			// 从最下级开始删除
+			if item.cascade && !reflect.DeepEqual(list, []string{leaf.UID, middle.UID, root.UID}) {
+				t.Errorf("the removed = %v", list)
+			}
+			for _, uid := range list {
+				if _, err := ctx.aliveTeam(uid); err == nil {
+					t.Errorf("the team %s is not removed", uid)
+				}
+			}
+		})
+	}
+}
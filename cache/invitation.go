@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+const (
+	// 去掉了容易混淆的0、O、1、I
+	invitationLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	invitationLength  = 8
+)
+
+const (
+	InvitationStateValid   = "valid"
+	InvitationStateUsedUp  = "used"
+	InvitationStateExpired = "expired"
+	InvitationStateRevoked = "revoked"
+)
+
+type InvitationInfo struct {
+	baseInfo
+	Code string
+	// 与申请的类型一致，ApplyTypeCoterie或者ApplyTypeFamily
+	Type  uint8
+	Scene string
+	Group string
+	// 预设的成员名称和备注
+	Remark  string
+	Limit   uint32
+	Used    uint32
+	Expired time.Time
+	Revoked time.Time
+}
+
+// 可以邀请加入的圈子或者家庭，加入时检查和修改在一次更新中完成
+type memberGroup interface {
+	MemberRoster
+	HadMember(member string) bool
+}
+
+func (mine *cacheContext) getMemberGroup(tp uint8, uid string) (memberGroup, error) {
+	switch tp {
+	case ApplyTypeCoterie:
+		return mine.GetCoterie(uid)
+	case ApplyTypeFamily:
+		return mine.GetFamily(uid)
+	}
+	return nil, errors.New(fmt.Sprintf("the invitation type(%d) is not supported", tp))
+}
+
+func newInvitationCode() (string, error) {
+	buf := make([]byte, invitationLength)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = invitationLetters[int(b)%len(invitationLetters)]
+	}
+	return string(buf), nil
+}
+
+// CreateInvitation 生成邀请码，limit为可以使用的次数，expire为有效时长(0表示不过期)
+func (mine *cacheContext) CreateInvitation(tp uint8, scene, group, name, remark, operator string, limit uint32, expire time.Duration) (*InvitationInfo, error) {
+	if limit < 1 {
+		return nil, errors.New("the invitation limit must be more than 0")
+	}
+	_, err := mine.getMemberGroup(tp, group)
+	if err != nil {
+		return nil, err
+	}
+	var code string
+	for i := 0; i < 5; i += 1 {
+		code, err = newInvitationCode()
+		if err != nil {
+			return nil, err
+		}
+		had, _ := mine.invitations.GetInvitationByCode(code)
+		if had == nil {
+			break
+		}
+		code = ""
+	}
+	if len(code) < 1 {
+		return nil, errors.New("generate the invitation code failed")
+	}
+	id, err := mine.nextID(nosql.TableInvitation)
+	if err != nil {
+		return nil, err
+	}
+	db := new(nosql.Invitation)
+	db.UID = primitive.NewObjectID()
+	db.CreatedTime = time.Now()
+	db.UpdatedTime = time.Now()
+	db.ID = id
+	db.Creator = operator
+	db.Name = name
+	db.Code = code
+	db.Type = tp
+	db.Scene = scene
+	db.Group = group
+	db.Remark = remark
+	db.Limit = limit
+	if expire > 0 {
+		db.Expired = db.CreatedTime.Add(expire)
+	}
+	err = mine.invitations.CreateInvitation(db)
+	if err != nil {
+		return nil, err
+	}
+	info := new(InvitationInfo)
+	info.initInfo(db)
+	return info, nil
+}
+
+func (mine *cacheContext) GetInvitationByCode(code string) (*InvitationInfo, error) {
+	db, err := mine.invitations.GetInvitationByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	info := new(InvitationInfo)
+	info.initInfo(db)
+	return info, nil
+}
+
+// GetInvitationsByGroup 圈子或者家庭的邀请码，all为false时只返回还可以使用的
+func (mine *cacheContext) GetInvitationsByGroup(group string, all bool) ([]*InvitationInfo, error) {
+	array, _, err := mine.invitations.QueryInvitations(proxy.NewQuery(!all, proxy.Equal("group", group)))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := make([]*InvitationInfo, 0, len(array))
+	for _, item := range array {
+		info := new(InvitationInfo)
+		info.initInfo(item)
+		if !all && info.State(now) != InvitationStateValid {
+			continue
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
+
+func (mine *InvitationInfo) initInfo(db *nosql.Invitation) {
+	mine.UID = db.UID.Hex()
+	mine.ID = db.ID
+	mine.Name = db.Name
+	mine.CreateTime = db.CreatedTime
+	mine.UpdateTime = db.UpdatedTime
+	mine.Creator = db.Creator
+	mine.Operator = db.Operator
+	mine.Code = db.Code
+	mine.Type = db.Type
+	mine.Scene = db.Scene
+	mine.Group = db.Group
+	mine.Remark = db.Remark
+	mine.Limit = db.Limit
+	mine.Used = db.Used
+	mine.Expired = db.Expired
+	mine.Revoked = db.DeleteTime
+}
+
+func (mine *InvitationInfo) State(now time.Time) string {
+	if !mine.Revoked.IsZero() {
+		return InvitationStateRevoked
+	}
+	if !mine.Expired.IsZero() && !mine.Expired.After(now) {
+		return InvitationStateExpired
+	}
+	if mine.Used >= mine.Limit {
+		return InvitationStateUsedUp
+	}
+	return InvitationStateValid
+}
+
+// Revoke 撤销邀请码，已经加入的成员不受影响
+func (mine *InvitationInfo) Revoke(operator string) error {
+	if !mine.Revoked.IsZero() {
+		return nil
+	}
+	err := cacheCtx.invitations.RemoveInvitation(mine.UID, operator)
+	if err == nil {
+		mine.Operator = operator
+		mine.Revoked = time.Now()
+	}
+	return err
+}
+
+// 加入失败时返还使用次数
+func (mine *InvitationInfo) release() {
+	err := cacheCtx.invitations.ReleaseInvitation(mine.UID)
+	if err == nil && mine.Used > 0 {
+		mine.Used -= 1
+	}
+}
+
+// Redeem 使用邀请码加入圈子或者家庭，并记录一条已通过的申请，邀请人为邀请码的创建者；
+// name为空时使用邀请码预设的成员名称
+func (mine *InvitationInfo) Redeem(user, name string) (*ApplyInfo, error) {
+	now := time.Now()
+	if st := mine.State(now); st != InvitationStateValid {
+		return nil, errors.New(fmt.Sprintf("the invitation is %s", st))
+	}
+	group, err := cacheCtx.getMemberGroup(mine.Type, mine.Group)
+	if err != nil {
+		return nil, err
+	}
+	if group.HadMember(user) {
+		return nil, errors.New("the user had been a member of the group")
+	}
+	ok, err := cacheCtx.invitations.UseInvitation(mine.UID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("the invitation had been used up or expired")
+	}
+	mine.Used += 1
+	if len(name) < 1 {
+		name = mine.Name
+	}
+	// 同一个用户同时使用时只有一次能够加入，其他的返还使用次数
+	results, err := group.AppendMembers([]proxy.MemberInfo{{User: user, Name: name, Remark: mine.Remark}})
+	if err == nil && results[0].State != RosterAdded {
+		err = errors.New("the user had been a member of the group")
+	}
+	if err != nil {
+		mine.release()
+		return nil, err
+	}
+	db, err := cacheCtx.newApply(user, mine.Scene, mine.Group, user, mine.Creator, mine.Remark, mine.Type)
+	if err == nil {
+		db.Status = ApplyStatusPass
+		db.Reason = mine.Code
+		db.Operator = mine.Creator
+		db.Decider = mine.Creator
+		db.DecidedTime = now
+		err = cacheCtx.applies.CreateApply(db)
+	}
+	if err != nil {
+		_, _ = group.SubtractMembers([]string{user})
+		mine.release()
+		return nil, err
+	}
+	info := new(ApplyInfo)
+	info.initInfo(db)
+	return info, nil
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"errors"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"omo.msa.assignment/proxy/nosql"
+	"testing"
+	"time"
+)
+
+// 创建申请时返回错误的存储
+type stubApplies struct {
+	nosql.ApplyStore
+}
+
+func (mine *stubApplies) CreateApply(info *nosql.Apply) error {
+	return errors.New("the apply store is broken")
+}
+
+func newTestFamily(t *testing.T, ctx *cacheContext, users ...string) *FamilyInfo {
+	t.Helper()
+	members := make([]*pb.IdentifyInfo, 0, len(users))
+	for _, user := range users {
+		members = append(members, &pb.IdentifyInfo{User: user})
+	}
+	info, err := ctx.CreateFamily(&pb.ReqFamilyAdd{Name: "family", Operator: "admin", Members: members})
+	if err != nil {
+		t.Fatalf("create the family failed: %v", err)
+	}
+	return info
+}
+
+func TestUseInvitation(t *testing.T) {
+	now := time.Now()
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			if err := InitDataWith(backend.stores(t)); err != nil {
+				t.Fatal(err)
+			}
+			ctx := cacheCtx
+			family := newTestFamily(t, ctx, "a")
+			info, err := ctx.CreateInvitation(ApplyTypeFamily, "scene", family.UID, "", "", "admin", 2, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store := ctx.invitations
+			steps := []struct {
+				name string
+				run  func() (bool, error)
+				want bool
+				used uint32
+			}{
+				{name: "first", run: func() (bool, error) { return store.UseInvitation(info.UID, now) }, want: true, used: 1},
+				{name: "second", run: func() (bool, error) { return store.UseInvitation(info.UID, now) }, want: true, used: 2},
+				{name: "used up", run: func() (bool, error) { return store.UseInvitation(info.UID, now) }, used: 2},
+				{name: "release", run: func() (bool, error) { return true, store.ReleaseInvitation(info.UID) }, want: true, used: 1},
+				{name: "expired", run: func() (bool, error) { return store.UseInvitation(info.UID, now.Add(2*time.Hour)) }, used: 1},
+				{name: "after release", run: func() (bool, error) { return store.UseInvitation(info.UID, now) }, want: true, used: 2},
+				{name: "release all", run: func() (bool, error) {
+					_ = store.ReleaseInvitation(info.UID)
+					_ = store.ReleaseInvitation(info.UID)
+					return true, store.ReleaseInvitation(info.UID)
+				}, want: true, used: 0},
+				{name: "revoked", run: func() (bool, error) {
+					if err := info.Revoke("admin"); err != nil {
+						return false, err
+					}
+					return store.UseInvitation(info.UID, now)
+				}, used: 0},
+			}
+			for _, step := range steps {
+				ok, err := step.run()
+				if err != nil || ok != step.want {
+					t.Fatalf("%s: the result = %v, the error = %v, want %v", step.name, ok, err, step.want)
+				}
+				db, err := store.GetInvitation(info.UID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if db.Used != step.used {
+					t.Errorf("%s: the used = %d, want %d", step.name, db.Used, step.used)
+				}
+			}
+		})
+	}
+}
+
+func TestRedeemInvitation(t *testing.T) {
+	cases := []struct {
+		name    string
+		user    string
+		limit   uint32
+		expire  time.Duration
+		revoke  bool
+		broken  bool
+		wantErr bool
+		used    uint32
+		members []string
+	}{
+		{name: "joined", user: "b", limit: 1, used: 1, members: []string{"a", "b"}},
+		{name: "member", user: "a", limit: 1, wantErr: true, members: []string{"a"}},
+		{name: "expired", user: "b", limit: 1, expire: time.Nanosecond, wantErr: true, members: []string{"a"}},
+		{name: "revoked", user: "b", limit: 1, revoke: true, wantErr: true, members: []string{"a"}},
+		// 记录申请失败时移除成员并返还使用次数
+		{name: "apply failed", user: "b", limit: 1, broken: true, wantErr: true, members: []string{"a"}},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			family := newTestFamily(t, ctx, "a")
+			info, err := ctx.CreateInvitation(ApplyTypeFamily, "scene", family.UID, "", "", "admin", item.limit, item.expire)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.revoke {
+				_ = info.Revoke("admin")
+			}
+			if item.broken {
+				ctx.applies = &stubApplies{ApplyStore: ctx.applies}
+			}
+			time.Sleep(time.Millisecond)
+			apply, err := info.Redeem(item.user, "")
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if err == nil && (apply.Status != ApplyStatusPass || apply.Inviter != "admin" || apply.Reason != info.Code) {
+				t.Errorf("the apply = %+v", apply)
+			}
+			db, _ := ctx.invitations.GetInvitation(info.UID)
+			if db.Used != item.used || info.Used != item.used {
+				t.Errorf("the used = %d and %d, want %d", db.Used, info.Used, item.used)
+			}
+			family, _ = ctx.GetFamily(family.UID)
+			if users := rosterUsers(family.Members); !sameUsers(users, item.members) {
+				t.Errorf("the members = %v, want %v", users, item.members)
+			}
+		})
+	}
+}
+
+func TestRedeemInvitationConcurrently(t *testing.T) {
+	ctx := newTestContext(t)
+	family := newTestFamily(t, ctx)
+	info, err := ctx.CreateInvitation(ApplyTypeFamily, "scene", family.UID, "", "", "admin", 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := []string{"a", "b", "c", "d", "e", "a"}
+	errs := make(chan error, len(users))
+	for _, user := range users {
+		go func(user string) {
+			code, _ := ctx.GetInvitationByCode(info.Code)
+			_, err := code.Redeem(user, "")
+			errs <- err
+		}(user)
+	}
+	joined := 0
+	for range users {
+		if err := <-errs; err == nil {
+			joined += 1
+		}
+	}
+	db, _ := ctx.GetInvitationByCode(info.Code)
+	family, _ = ctx.GetFamily(family.UID)
+	if joined != 3 || db.Used != 3 || len(family.Members) != 3 {
+		t.Errorf("the joined = %d, the used = %d, the members = %d, want 3", joined, db.Used, len(family.Members))
+	}
+}
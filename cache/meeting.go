@@ -1,8 +1,12 @@
 package cache
 
 import (
+	"errors"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"omo.msa.assignment/config"
+	"omo.msa.assignment/proxy"
 	"omo.msa.assignment/proxy/nosql"
 	"omo.msa.assignment/tool"
 	"time"
@@ -32,6 +36,21 @@ const (
 	Outside LocationType = 1
 )
 
+const (
+	// 按时签到
+	SignStateNormal uint8 = 0
+	// 超过迟到的时间后签到
+	SignStateLate uint8 = 1
+	// 不在签到范围内，不算签到
+	SignStateOutside uint8 = 2
+)
+
+var (
+	ErrSignNotOpen = errors.New("the meeting sign is not open yet")
+	ErrSignClosed  = errors.New("the meeting had stopped")
+	ErrSignOutside = errors.New("the location is out of the meeting range")
+)
+
 type MeetingStatus uint8
 
 type LocationType uint8
@@ -55,12 +74,44 @@ type MeetingInfo struct {
 	Signs     []string
 	Submits   []string
 	Notifies  []string
+	// 签到的范围和时间，0表示使用默认值，小于0表示不限制
+	Radius     int32
+	SignBefore int32
+	SignLate   int32
+	Records    []proxy.SignInfo
+	// 每个成员的签到、提交、邀请和回复
+	Attends []proxy.AttendInfo
+	// 重复的规则，只保存在系列的第一次会议上；Series为第一次会议的uid，Occurrence为按照规则计算的开始时间
+	Rule       string
+	Series     string
+	Occurrence time.Time
+	// 每次会议持续的秒数
+	Span       int64
+	Revision   uint32
+	Exceptions []proxy.ExceptionInfo
 }
 
+// CreateMeeting 配置中不允许冲突时与其他会议冲突返回ConflictError，没有结束时间时按照默认时长检查
 func (mine *cacheContext) CreateMeeting(in *pb.ReqMeetingAdd) (*MeetingInfo, error) {
+	return mine.createMeeting(in, true)
+}
+
+func (mine *cacheContext) createMeeting(in *pb.ReqMeetingAdd, check bool) (*MeetingInfo, error) {
+	location := in.Location
+	if LocationType(in.Type) == Outside {
+		point, err := ParseGeoPoint(location)
+		if err != nil {
+			return nil, err
+		}
+		location = point.String()
+	}
+	id, err := mine.nextID(nosql.TableMeeting)
+	if err != nil {
+		return nil, err
+	}
 	db := new(nosql.Meeting)
 	db.UID = primitive.NewObjectID()
-	db.ID = nosql.GetMeetingNextID()
+	db.ID = id
 	db.CreatedTime = time.Now()
 	db.Creator = in.Operator
 	db.Name = in.Name
@@ -71,17 +122,27 @@ func (mine *cacheContext) CreateMeeting(in *pb.ReqMeetingAdd) (*MeetingInfo, err
 	db.Signs = make([]string, 0, 1)
 	db.Submits = make([]string, 0, 1)
 	db.Notifies = make([]string, 0, 1)
+	db.Records = make([]proxy.SignInfo, 0, 1)
+	db.Attends = make([]proxy.AttendInfo, 0, 1)
 	db.Appointed = in.Appointed
-	db.Location = in.Location
-	db.StartTime, _ = Context().formatTime(in.Appointed)
+	db.Location = location
+	start, er := Context().formatTime(in.Appointed)
+	db.StartTime = start
 	db.Type = uint8(in.Type)
+	if er == nil && check {
+		err := mine.checkConflicts(&MeetingPlan{Owner: in.Owner, Group: in.Group, Type: LocationType(in.Type), Location: location, Start: start})
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	err := nosql.CreateMeeting(db)
+	err = mine.meetings.CreateMeeting(db)
 	if err != nil {
 		return nil, err
 	}
 	info := new(MeetingInfo)
 	info.initInfo(db)
+	wakeMeetingScheduler()
 	return info, nil
 }
 
@@ -89,7 +150,7 @@ func (mine *cacheContext) GetMeeting(uid string) (*MeetingInfo, error) {
 	if uid == "" {
 		return nil, nil
 	}
-	db, err := nosql.GetMeeting(uid)
+	db, err := mine.meetings.GetMeeting(uid)
 	if err != nil {
 		return nil, err
 	}
@@ -102,61 +163,78 @@ func (mine *cacheContext) RemoveMeeting(uid, operator string) error {
 	if uid == "" {
 		return nil
 	}
-	return nosql.RemoveMeeting(uid, operator)
+	return mine.meetings.RemoveMeeting(uid, operator)
 }
 
-func (mine *cacheContext) GetMeetingsByGroup(uid string) []*MeetingInfo {
-	list := make([]*MeetingInfo, 0, 5)
-	array, err := nosql.GetMeetingsByGroup(uid)
-	if err == nil {
-		for _, item := range array {
-			info := new(MeetingInfo)
-			info.initInfo(item)
-
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetMeetingsByGroup(uid string, page *PageInfo) (uint32, uint32, []*MeetingInfo) {
+	array, num, err := mine.meetings.QueryMeetings(page.query(true, proxy.Equal("group", uid)))
+	if err != nil {
+		return 0, 0, make([]*MeetingInfo, 0, 1)
+	}
+	list := make([]*MeetingInfo, 0, len(array))
+	for _, item := range array {
+		info := new(MeetingInfo)
+		info.initInfo(item)
+		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
-func (mine *cacheContext) GetMeetingsByTime(group, from, to string) ([]*MeetingInfo, error) {
-	list := make([]*MeetingInfo, 0, 5)
+func (mine *cacheContext) GetMeetingsByTime(group, from, to string, page *PageInfo) (uint32, uint32, []*MeetingInfo, error) {
 	begin, err := mine.formatDate(from)
 	if err != nil {
-		return list, err
+		return 0, 0, make([]*MeetingInfo, 0, 1), err
 	}
 	end, err := mine.formatDate(to)
 	if err != nil {
-		return list, err
+		return 0, 0, make([]*MeetingInfo, 0, 1), err
 	}
-	utcB := begin.Unix()
-	utcE := end.Unix()
-
-	array, err := nosql.GetMeetingsByGroup(group)
-	if err == nil {
-		for _, item := range array {
-			if item.StartTime.Unix() > utcB && item.StopTime.Unix() < utcE {
-				info := new(MeetingInfo)
-				info.initInfo(item)
-				list = append(list, info)
-			}
-		}
+	// 按照开始时间查询，没有结束时间的会议也包括在内
+	array, num, err := mine.meetings.QueryMeetings(page.query(true, proxy.Equal("group", group),
+		proxy.Greater("startAt", begin), proxy.Less("startAt", end)))
+	if err != nil {
+		return 0, 0, make([]*MeetingInfo, 0, 1), err
+	}
+	list := make([]*MeetingInfo, 0, len(array))
+	for _, item := range array {
+		info := new(MeetingInfo)
+		info.initInfo(item)
+		list = append(list, info)
 	}
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetMeetingsByOwner(uid string) []*MeetingInfo {
-	list := make([]*MeetingInfo, 0, 5)
-	array, err := nosql.GetMeetingsByScene(uid)
-	if err == nil {
-		for _, item := range array {
-			info := new(MeetingInfo)
-			info.initInfo(item)
+// GetMeetingsByStatus 场景下某个状态的会议，状态由调度保存
+func (mine *cacheContext) GetMeetingsByStatus(owner string, status MeetingStatus, page *PageInfo) (uint32, uint32, []*MeetingInfo, error) {
+	array, num, err := mine.meetings.QueryMeetings(page.query(true, proxy.Equal("owner", owner), proxy.Equal("status", uint8(status))))
+	if err != nil {
+		return 0, 0, make([]*MeetingInfo, 0, 1), err
+	}
+	list := make([]*MeetingInfo, 0, len(array))
+	for _, item := range array {
+		info := new(MeetingInfo)
+		info.initInfo(item)
+		list = append(list, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
+}
 
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetMeetingsByOwner(uid string, page *PageInfo) (uint32, uint32, []*MeetingInfo) {
+	array, num, err := mine.meetings.QueryMeetings(page.query(true, proxy.Equal("owner", uid)))
+	if err != nil {
+		return 0, 0, make([]*MeetingInfo, 0, 1)
+	}
+	list := make([]*MeetingInfo, 0, len(array))
+	for _, item := range array {
+		info := new(MeetingInfo)
+		info.initInfo(item)
+		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
 func (mine *MeetingInfo) initInfo(db *nosql.Meeting) bool {
@@ -190,24 +268,33 @@ func (mine *MeetingInfo) initInfo(db *nosql.Meeting) bool {
 	if mine.Notifies == nil {
 		mine.Notifies = make([]string, 0, 1)
 	}
+	mine.Radius = db.Radius
+	mine.SignBefore = db.SignBefore
+	mine.SignLate = db.SignLate
+	mine.Records = db.Records
+	if mine.Records == nil {
+		mine.Records = make([]proxy.SignInfo, 0, 1)
+	}
+	mine.initAttends(db.Attends)
+	mine.Rule = db.Rule
+	mine.Series = db.Series
+	mine.Occurrence = db.Occurrence
+	mine.Span = db.Span
+	mine.Revision = db.Revision
+	mine.Exceptions = db.Exceptions
+	if mine.Exceptions == nil {
+		mine.Exceptions = make([]proxy.ExceptionInfo, 0, 1)
+	}
 	return true
 }
 
+// CheckStatus 当前时间对应的状态，与调度保存的规则一致，调度还没有执行时也能返回正确的状态
 func (mine *MeetingInfo) CheckStatus() MeetingStatus {
-	if mine.Status == AutoStop || mine.Status == Close {
-		return mine.Status
-	}
-	now := time.Now().Unix()
-	if now > mine.StopTime.Unix() {
-		mine.Status = AutoStop
-	} else if now > mine.StartTime.Unix() {
-		mine.Status = Idle
-	}
-	return mine.Status
+	return mine.expectStatus(time.Now())
 }
 
 func (mine *MeetingInfo) UpdateBase(name, remark, operator string) error {
-	err := nosql.UpdateMeetingBase(mine.UID, name, remark, operator)
+	err := cacheCtx.meetings.UpdateMeetingBase(mine.UID, name, remark, operator)
 	if err == nil {
 		mine.Name = name
 		mine.Remark = remark
@@ -216,8 +303,16 @@ func (mine *MeetingInfo) UpdateBase(name, remark, operator string) error {
 	return err
 }
 
+// UpdateLocation 户外的会议的地点为 纬度,经度
 func (mine *MeetingInfo) UpdateLocation(location, operator string, kind LocationType) error {
-	err := nosql.UpdateMeetingLocation(mine.UID, location, operator, uint8(kind))
+	if kind == Outside {
+		point, err := ParseGeoPoint(location)
+		if err != nil {
+			return err
+		}
+		location = point.String()
+	}
+	err := cacheCtx.meetings.UpdateMeetingLocation(mine.UID, location, operator, uint8(kind))
 	if err == nil {
 		mine.Type = kind
 		mine.Location = location
@@ -226,20 +321,35 @@ func (mine *MeetingInfo) UpdateLocation(location, operator string, kind Location
 	return err
 }
 
+// UpdateStartEnd 修改开始和结束的时间，重复会议中的某一次会记录为例外，以后不会按照规则重新生成；
+// 配置中不允许冲突时与其他会议冲突返回ConflictError
 func (mine *MeetingInfo) UpdateStartEnd(begin, end int64, operator string) error {
+	err := cacheCtx.checkConflicts(mine.plan(time.Unix(begin, 0), time.Unix(end, 0)))
+	if err != nil {
+		return err
+	}
+	err = mine.updateDate(begin, end, operator)
+	if err != nil {
+		return err
+	}
+	return mine.rescheduled(operator)
+}
+
+func (mine *MeetingInfo) updateDate(begin, end int64, operator string) error {
 	from := time.Unix(begin, 0).UTC()
 	to := time.Unix(end, 0).UTC()
-	err := nosql.UpdateMeetingDate(mine.UID, operator, from, to)
+	err := cacheCtx.meetings.UpdateMeetingDate(mine.UID, operator, from, to)
 	if err == nil {
 		mine.StartTime = from
 		mine.Operator = operator
 		mine.StopTime = to
+		mine.reschedule()
 	}
 	return err
 }
 
 func (mine *MeetingInfo) UpdateGroup(group, operator string) error {
-	err := nosql.UpdateMeetingGroup(mine.UID, operator, group)
+	err := cacheCtx.meetings.UpdateMeetingGroup(mine.UID, operator, group)
 	if err == nil {
 		mine.Group = group
 		mine.Operator = operator
@@ -252,11 +362,13 @@ func (mine *MeetingInfo) UpdateStop(date, operator string) error {
 	if err != nil {
 		return err
 	}
-	err = nosql.UpdateMeetingStop(mine.UID, operator, t)
+	err = cacheCtx.meetings.UpdateMeetingStop(mine.UID, operator, t)
 	if err == nil {
 		mine.StopTime = t
 		mine.UpdateTime = time.Now()
 		mine.Operator = operator
+		mine.reschedule()
+		err = mine.rescheduled(operator)
 	}
 	return err
 }
@@ -277,38 +389,120 @@ func (mine *MeetingInfo) HadSigned(user string) bool {
 	return false
 }
 
-func (mine *MeetingInfo) Sign(member, operator, location string) error {
-	if mine.HadSigned(member) {
-		return nil
-	}
-	//if len(mine.Signs) == 0 {
-	//	mine.StartTime = time.Now()
-	//}
-	//if mine.Type == Outside && !Context().checkDistance(mine.Location, location) {
-	//	return errors.New("the user location incorrect")
-	//}
-	err := nosql.AppendMeetingSign(mine.UID, member, operator)
+// UpdateSignRule 修改签到的范围(米)，开始前可以签到的秒数以及开始后不算迟到的秒数
+func (mine *MeetingInfo) UpdateSignRule(radius, before, late int32, operator string) error {
+	err := cacheCtx.meetings.UpdateMeetingSignRule(mine.UID, operator, radius, before, late)
 	if err == nil {
-		mine.Signs = append(mine.Signs, member)
+		mine.Radius = radius
+		mine.SignBefore = before
+		mine.SignLate = late
 		mine.Operator = operator
 	}
 	return err
 }
 
+// 会议的设置为0时使用配置中的默认值，小于0时不限制
+func signRule(value, def int32) int32 {
+	if value == 0 {
+		return def
+	}
+	return value
+}
+
+// 签到的状态以及距离，户外的会议需要在范围内
+func (mine *MeetingInfo) checkSign(location string, now time.Time) (uint8, float64, error) {
+	conf := config.Schema.Meeting
+	if mine.Status == Close || (!mine.StopTime.IsZero() && !now.Before(mine.StopTime)) {
+		return 0, -1, ErrSignClosed
+	}
+	state := SignStateNormal
+	if !mine.StartTime.IsZero() {
+		before := signRule(mine.SignBefore, conf.SignBefore)
+		if before >= 0 && now.Before(mine.StartTime.Add(-time.Duration(before)*time.Second)) {
+			return 0, -1, ErrSignNotOpen
+		}
+		late := signRule(mine.SignLate, conf.SignLate)
+		if late >= 0 && now.After(mine.StartTime.Add(time.Duration(late)*time.Second)) {
+			state = SignStateLate
+		}
+	}
+	distance := -1.0
+	if len(location) > 0 {
+		point, err := ParseGeoPoint(location)
+		if err != nil {
+			return 0, -1, err
+		}
+		center, er := ParseGeoPoint(mine.Location)
+		if er == nil {
+			distance = math.Round(point.Distance(center)*10) / 10
+		}
+	}
+	radius := signRule(mine.Radius, conf.Radius)
+	if mine.Type != Outside || radius < 0 {
+		return state, distance, nil
+	}
+	if len(location) < 1 {
+		return 0, -1, ErrLocationFormat
+	}
+	if distance < 0 {
+		return 0, -1, errors.New("the meeting location is not a coordinate")
+	}
+	if distance > float64(radius) {
+		return SignStateOutside, distance, nil
+	}
+	return state, distance, nil
+}
+
+// Sign 签到并记录时间、位置和状态，超出范围时也记录但不算签到；已经签到的返回之前的记录
+func (mine *MeetingInfo) Sign(member, operator, location, device string) (*proxy.SignInfo, error) {
+	if mine.HadSigned(member) {
+		for i := len(mine.Records) - 1; i >= 0; i -= 1 {
+			if mine.Records[i].User == member && mine.Records[i].State != SignStateOutside {
+				return &mine.Records[i], nil
+			}
+		}
+		return &proxy.SignInfo{User: member, Distance: -1}, nil
+	}
+	now := time.Now()
+	state, distance, err := mine.checkSign(location, now)
+	if err != nil {
+		return nil, err
+	}
+	record := proxy.SignInfo{User: member, CreatedTime: now, Operator: operator, Location: location,
+		Distance: distance, State: state}
+	if state == SignStateOutside {
+		err = cacheCtx.meetings.SaveMeetingAttend(mine.UID, &nosql.AttendChange{Record: &record, Operator: operator})
+		if err != nil {
+			return nil, err
+		}
+		mine.Records = append(mine.Records, record)
+		mine.Operator = operator
+		return &record, ErrSignOutside
+	}
+	attend := mine.GetAttend(member)
+	attend.SignedTime = now
+	attend.Location = location
+	attend.Distance = distance
+	attend.Device = device
+	attend.Late = state == SignStateLate
+	err = mine.saveAttend(attend, "signs", &record, operator)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
 func (mine *MeetingInfo) Submit(member, operator string) error {
 	if tool.HasItem(mine.Submits, member) {
 		return nil
 	}
-	err := nosql.AppendMeetingSubmit(mine.UID, member, operator)
-	if err == nil {
-		mine.Submits = append(mine.Submits, member)
-		mine.Operator = operator
-	}
-	return err
+	attend := mine.GetAttend(member)
+	attend.SubmittedTime = time.Now()
+	return mine.saveAttend(attend, "submits", nil, operator)
 }
 
 func (mine *MeetingInfo) Close(operator string) error {
-	err := nosql.StopMeeting(mine.UID, operator)
+	err := cacheCtx.meetings.StopMeeting(mine.UID, operator)
 	if err == nil {
 		mine.Status = Close
 		mine.StopTime = time.Now()
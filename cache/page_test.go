@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTeamsPage(t *testing.T) {
+	ctx := newTestContext(t)
+	uids := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		uids = append(uids, newTestTeam(t, ctx, "scene", 0).UID)
+	}
+	newTestTeam(t, ctx, "other", 0)
+	if err := ctx.RemoveTeam(uids[2], "admin"); err != nil {
+		t.Fatal(err)
+	}
+	alive := []string{uids[0], uids[1], uids[3], uids[4]}
+	cases := []struct {
+		name  string
+		page  *PageInfo
+		want  []string
+		total uint32
+		pages uint32
+	}{
+		{name: "all", want: alive, total: 4, pages: 1},
+		{name: "first page", page: &PageInfo{Page: 1, Number: 3}, want: alive[:3], total: 4, pages: 2},
+		{name: "last page", page: &PageInfo{Page: 2, Number: 3}, want: alive[3:], total: 4, pages: 2},
+		{name: "after the last page", page: &PageInfo{Page: 3, Number: 3}, want: []string{}, total: 4, pages: 2},
+		{name: "default number", page: &PageInfo{Page: 1}, want: alive, total: 4, pages: 1},
+		{name: "cursor", page: &PageInfo{Cursor: uids[0], Number: 2}, want: alive[1:3], total: 4, pages: 2},
+		{name: "cursor of removed", page: &PageInfo{Cursor: uids[2], Number: 2}, want: alive[2:], total: 4, pages: 2},
+		{name: "cursor at the end", page: &PageInfo{Cursor: uids[4], Number: 2}, want: []string{}, total: 4, pages: 2},
+		{name: "bad cursor", page: &PageInfo{Cursor: "bad", Number: 2}, want: []string{}},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			total, pages, list := ctx.GetTeamsByOwner("scene", item.page)
+			got := make([]string, 0, len(list))
+			for _, info := range list {
+				got = append(got, info.UID)
+			}
+			if !reflect.DeepEqual(got, item.want) {
+				t.Errorf("the teams = %v, want %v", got, item.want)
+			}
+			if total != item.total || pages != item.pages {
+				t.Errorf("the total = %d, the pages = %d, want %d and %d", total, pages, item.total, item.pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"omo.msa.assignment/proxy"
+	"sort"
+	"time"
+)
+
+// PaperRule 组卷规则，从分类及其所有子分类中抽取题目
+type PaperRule struct {
+	Category string
+	// 抽取的数量，为0时按照权重分配试卷的总数
+	Count uint32
+	// 权重，小于1时按1计算
+	Weight uint32
+}
+
+// PaperOption 组卷参数
+type PaperOption struct {
+	Rules []PaperRule
+	// 按照权重分配到Count为0的规则的题目总数
+	Total uint32
+	// 随机种子，相同的种子和题库会得到相同的试卷，为0时随机生成
+	Seed int64
+	// 是否打乱选项的顺序
+	Shuffle bool
+	// 排除该用户在Recent时间内答过的题目
+	User   string
+	Recent time.Duration
+}
+
+// GeneratePaper 随机组卷，打乱选项时只改变选项的顺序，选项的id和答案不变
+func (mine *cacheContext) GeneratePaper(option *PaperOption) (int64, []*QuestionInfo, error) {
+	if option == nil || len(option.Rules) < 1 {
+		return 0, nil, errors.New("the paper rules is empty")
+	}
+	counts, err := option.counts()
+	if err != nil {
+		return 0, nil, err
+	}
+	excludes, err := mine.recentQuestions(option.User, option.Recent)
+	if err != nil {
+		return 0, nil, err
+	}
+	seed := option.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(seed))
+	list := make([]*QuestionInfo, 0, 10)
+	for i, rule := range option.Rules {
+		if counts[i] < 1 {
+			continue
+		}
+		pool, err := mine.getPaperPool(rule.Category, excludes)
+		if err != nil {
+			return 0, nil, err
+		}
+		if uint32(len(pool)) < counts[i] {
+			return 0, nil, errors.New(fmt.Sprintf("the questions of category %s is not enough, need %d but %d", rule.Category, counts[i], len(pool)))
+		}
+		random.Shuffle(len(pool), func(a, b int) {
+			pool[a], pool[b] = pool[b], pool[a]
+		})
+		for _, item := range pool[:counts[i]] {
+			// 不同规则的分类有重叠时，同一道题只出现一次
+			excludes[item.UID] = true
+			if option.Shuffle {
+				item.shuffleOptions(random)
+			}
+			list = append(list, item)
+		}
+	}
+	return seed, list, nil
+}
+
+// 每条规则需要抽取的数量，按照权重用最大余数法分配总数
+func (mine *PaperOption) counts() ([]uint32, error) {
+	counts := make([]uint32, len(mine.Rules))
+	var fixed uint32 = 0
+	var weights uint32 = 0
+	for i, rule := range mine.Rules {
+		if len(rule.Category) < 1 {
+			return nil, errors.New("the category of paper rule is empty")
+		}
+		if rule.Count > 0 {
+			counts[i] = rule.Count
+			fixed += rule.Count
+		} else {
+			weights += rule.weight()
+		}
+	}
+	if weights < 1 {
+		return counts, nil
+	}
+	if mine.Total <= fixed {
+		return nil, errors.New(fmt.Sprintf("the total %d is not more than the fixed count %d", mine.Total, fixed))
+	}
+	left := mine.Total - fixed
+	type remainder struct {
+		index int
+		value uint32
+	}
+	remainders := make([]remainder, 0, len(mine.Rules))
+	var used uint32 = 0
+	for i, rule := range mine.Rules {
+		if rule.Count > 0 {
+			continue
+		}
+		counts[i] = left * rule.weight() / weights
+		used += counts[i]
+		remainders = append(remainders, remainder{index: i, value: left * rule.weight() % weights})
+	}
+	sort.SliceStable(remainders, func(a, b int) bool {
+		return remainders[a].value > remainders[b].value
+	})
+	for i := 0; used < left; i++ {
+		counts[remainders[i].index] += 1
+		used += 1
+	}
+	return counts, nil
+}
+
+func (mine PaperRule) weight() uint32 {
+	if mine.Weight < 1 {
+		return 1
+	}
+	return mine.Weight
+}
+
+// 用户最近答过的题目
+func (mine *cacheContext) recentQuestions(user string, recent time.Duration) (map[string]bool, error) {
+	excludes := make(map[string]bool)
+	if len(user) < 1 || recent <= 0 {
+		return excludes, nil
+	}
+	dbs, _, err := mine.quizzes.QueryQuizRecords(proxy.NewQuery(true, proxy.Equal("user", user),
+		proxy.Greater("createdAt", time.Now().Add(-recent))))
+	if err != nil {
+		return nil, err
+	}
+	for _, db := range dbs {
+		excludes[db.Question] = true
+	}
+	return excludes, nil
+}
+
+// 分类及其所有子分类下可以抽取的题目，按照id排序保证相同的种子得到相同的结果
+func (mine *cacheContext) getPaperPool(category string, excludes map[string]bool) ([]*QuestionInfo, error) {
+	categories, err := mine.getCategoryTree(category)
+	if err != nil {
+		return nil, err
+	}
+	pool := make([]*QuestionInfo, 0, 20)
+	for _, uid := range categories {
+		_, _, list, err := mine.GetQuestionsByCategory(uid, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list {
+			if !excludes[item.UID] {
+				pool = append(pool, item)
+			}
+		}
+	}
+	sort.Slice(pool, func(a, b int) bool {
+		if pool[a].ID != pool[b].ID {
+			return pool[a].ID < pool[b].ID
+		}
+		return pool[a].UID < pool[b].UID
+	})
+	return pool, nil
+}
+
+// 分类以及所有parent链上包含它的子孙分类
+func (mine *cacheContext) getCategoryTree(root string) ([]string, error) {
+	list := []string{root}
+	visited := map[string]bool{root: true}
+	for i := 0; i < len(list); i++ {
+		_, _, children, err := mine.GetCategoriesByParent(list[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if !visited[child.UID] {
+				visited[child.UID] = true
+				list = append(list, child.UID)
+			}
+		}
+	}
+	return list, nil
+}
+
+// 只打乱选项的顺序，选项的id保持不变，提交的答案仍然按照原来的id判分
+func (mine *QuestionInfo) shuffleOptions(random *rand.Rand) {
+	options := make([]proxy.PairInfo, len(mine.Options))
+	copy(options, mine.Options)
+	random.Shuffle(len(options), func(a, b int) {
+		options[a], options[b] = options[b], options[a]
+	})
+	mine.Options = options
+}
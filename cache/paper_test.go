@@ -0,0 +1,87 @@
+package cache
+
+import (
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPaperCounts(t *testing.T) {
+	cases := []struct {
+		name    string
+		option  PaperOption
+		want    []uint32
+		wantErr bool
+	}{
+		{name: "fixed", option: PaperOption{Rules: []PaperRule{{Category: "a", Count: 3}, {Category: "b", Count: 2}}},
+			want: []uint32{3, 2}},
+		{name: "weights", option: PaperOption{Total: 10, Rules: []PaperRule{{Category: "a", Weight: 2}, {Category: "b", Weight: 1}}},
+			want: []uint32{7, 3}},
+		{name: "fixed and weights", option: PaperOption{Total: 10,
+			Rules: []PaperRule{{Category: "a", Count: 4}, {Category: "b"}, {Category: "c"}, {Category: "d"}, {Category: "e"}}},
+			want: []uint32{4, 2, 2, 1, 1}},
+		{name: "total too small", option: PaperOption{Total: 4, Rules: []PaperRule{{Category: "a", Count: 4}, {Category: "b"}}},
+			wantErr: true},
+		{name: "empty category", option: PaperOption{Rules: []PaperRule{{Count: 1}}}, wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			counts, err := item.option.counts()
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(counts, item.want) {
+				t.Errorf("the counts = %v, want %v", counts, item.want)
+			}
+		})
+	}
+}
+
+func TestGeneratePaperShuffle(t *testing.T) {
+	ctx := newTestContext(t)
+	category, err := ctx.NewCategory("category", "", "scene", "admin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := []*pb.QuestionOption{{Id: 1, Desc: "A"}, {Id: 2, Desc: "B"}, {Id: 3, Desc: "C"}, {Id: 4, Desc: "D"}}
+	for i := 0; i < 5; i++ {
+		_, err = ctx.NewQuestion("title", "", category.UID, "", "admin", 0, []uint32{2, 4}, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	option := &PaperOption{Rules: []PaperRule{{Category: category.UID, Count: 3}}, Seed: 7, Shuffle: true}
+	seed, paper, err := ctx.GeneratePaper(option)
+	if err != nil || seed != 7 || len(paper) != 3 {
+		t.Fatalf("the seed = %d, the paper = %d, the error = %v", seed, len(paper), err)
+	}
+	_, again, _ := ctx.GeneratePaper(option)
+	for i, question := range paper {
+		if question.UID != again[i].UID || !reflect.DeepEqual(question.Options, again[i].Options) {
+			t.Errorf("the paper of the same seed is different at %d", i)
+		}
+		descs := make(map[string]string, len(question.Options))
+		for _, item := range question.Options {
+			descs[item.Key] = item.Value
+		}
+		if !reflect.DeepEqual(descs, map[string]string{"1": "A", "2": "B", "3": "C", "4": "D"}) {
+			t.Errorf("the options = %v, the ids should be kept", question.Options)
+		}
+		if !reflect.DeepEqual(question.Answers, []uint32{2, 4}) {
+			t.Errorf("the answers = %v, want [2 4]", question.Answers)
+		}
+		// 按照打乱后的选项描述作答，提交的仍然是原来的id
+		chosen := make([]uint32, 0, 2)
+		for _, item := range question.Options {
+			if item.Value == "B" || item.Value == "D" {
+				id, _ := strconv.Atoi(item.Key)
+				chosen = append(chosen, uint32(id))
+			}
+		}
+		record, err := ctx.SubmitAnswers("scene", "user", question.UID, chosen)
+		if err != nil || !record.Correct {
+			t.Errorf("the answers %v should be correct, the error = %v", chosen, err)
+		}
+	}
+}
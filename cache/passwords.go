@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/micro/go-micro/v2/logger"
+	"golang.org/x/crypto/bcrypt"
+	"omo.msa.assignment/proxy"
+	"sync"
+	"time"
+)
+
+const (
+	// 连续输错的次数达到上限后锁定一段时间
+	passwordsMaxFailures = 5
+	passwordsLockTime    = 15 * time.Minute
+)
+
+var ErrPasswordsNotMatch = errors.New("the passwords is not matched")
+
+type passwordsAttempt struct {
+	failures uint32
+	locked   time.Time
+}
+
+// 按圈子或者家庭以及用户记录输错的次数，只保存在当前进程中，
+// 部署多个实例时每个实例分别计数
+type passwordsThrottle struct {
+	lock  sync.Mutex
+	items map[string]*passwordsAttempt
+}
+
+var throttle = &passwordsThrottle{items: make(map[string]*passwordsAttempt)}
+
+func throttleKey(group, user string) string {
+	return group + "/" + user
+}
+
+func (mine *passwordsThrottle) check(key string, now time.Time) error {
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	item, ok := mine.items[key]
+	if ok && item.locked.After(now) {
+		return errors.New(fmt.Sprintf("too many wrong passwords, try again after %s", item.locked.Format("15:04:05")))
+	}
+	return nil
+}
+
+func (mine *passwordsThrottle) fail(key string, now time.Time) {
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	item, ok := mine.items[key]
+	if !ok || (!item.locked.IsZero() && !item.locked.After(now)) {
+		item = new(passwordsAttempt)
+		mine.items[key] = item
+	}
+	item.failures += 1
+	if item.failures >= passwordsMaxFailures {
+		item.locked = now.Add(passwordsLockTime)
+	}
+}
+
+func (mine *passwordsThrottle) pass(key string) {
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	delete(mine.items, key)
+}
+
+// 用户输入的密码总是hash后保存，空密码表示没有密码
+func hashPasswords(psw string) (string, error) {
+	if len(psw) < 1 {
+		return psw, nil
+	}
+	data, err := bcrypt.GenerateFromPassword([]byte(psw), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func isHashedPasswords(psw string) bool {
+	_, err := bcrypt.Cost([]byte(psw))
+	return err == nil
+}
+
+// 检查用户输入的密码，在同一个圈子或者家庭中连续输错多次后锁定该用户
+func verifyPasswords(hashed, group, user, psw string) error {
+	now := time.Now()
+	key := throttleKey(group, user)
+	err := throttle.check(key, now)
+	if err != nil {
+		return err
+	}
+	if len(hashed) < 1 {
+		return errors.New("the group has no passwords")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(psw)) != nil {
+		throttle.fail(key, now)
+		return ErrPasswordsNotMatch
+	}
+	throttle.pass(key)
+	return nil
+}
+
+// 启动和恢复备份后把以前保存的明文密码改为hash，已经hash的不修改
+func (mine *cacheContext) migratePasswords() error {
+	var num = 0
+	coteries, _, err := mine.coteries.QueryCoteries(proxy.NewQuery(false))
+	if err != nil {
+		return err
+	}
+	for _, db := range coteries {
+		if len(db.Passwords) < 1 || isHashedPasswords(db.Passwords) {
+			continue
+		}
+		psw, err := hashPasswords(db.Passwords)
+		if err != nil {
+			return err
+		}
+		err = mine.coteries.UpdateCoteriePasswords(db.UID.Hex(), db.Operator, psw)
+		if err != nil {
+			return err
+		}
+		num += 1
+	}
+	families, _, err := mine.families.QueryFamilies(proxy.NewQuery(false))
+	if err != nil {
+		return err
+	}
+	for _, db := range families {
+		if len(db.Passwords) < 1 || isHashedPasswords(db.Passwords) {
+			continue
+		}
+		psw, err := hashPasswords(db.Passwords)
+		if err != nil {
+			return err
+		}
+		err = mine.families.UpdateFamilyPasswords(db.UID.Hex(), db.Operator, psw)
+		if err != nil {
+			return err
+		}
+		num += 1
+	}
+	if num > 0 {
+		logger.Warnf("hashed the plaintext passwords of %d coteries and families", num)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+	"omo.msa.assignment/proxy/memory"
+	"omo.msa.assignment/proxy/nosql"
+	"testing"
+	"time"
+)
+
+func TestHashPasswords(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		psw  string
+	}{
+		{name: "empty"},
+		{name: "plain", psw: "123456"},
+		// 用户输入的密码和bcrypt的格式相同时也要hash
+		{name: "like hashed", psw: string(hashed)},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			psw, err := hashPasswords(item.psw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(item.psw) < 1 {
+				if len(psw) > 0 {
+					t.Errorf("the passwords = %q, want empty", psw)
+				}
+				return
+			}
+			if psw == item.psw || bcrypt.CompareHashAndPassword([]byte(psw), []byte(item.psw)) != nil {
+				t.Errorf("the passwords %q is not hashed from %q", psw, item.psw)
+			}
+		})
+	}
+}
+
+func TestVerifyPasswords(t *testing.T) {
+	throttle = &passwordsThrottle{items: make(map[string]*passwordsAttempt)}
+	hashed, err := hashPasswords("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name   string
+		group  string
+		user   string
+		psw    string
+		times  int
+		want   error
+		locked bool
+	}{
+		{name: "wrong", group: "a", user: "u", psw: "000000", times: passwordsMaxFailures, want: ErrPasswordsNotMatch},
+		{name: "locked", group: "a", user: "u", psw: "123456", times: 1, locked: true},
+		{name: "other group", group: "b", user: "u", psw: "123456", times: 1},
+		{name: "other user", group: "a", user: "v", psw: "123456", times: 1},
+		// 输对之后重新计数
+		{name: "wrong again", group: "b", user: "u", psw: "000000", times: passwordsMaxFailures - 1, want: ErrPasswordsNotMatch},
+		{name: "right", group: "b", user: "u", psw: "123456", times: 1},
+		{name: "not locked", group: "b", user: "u", psw: "000000", times: 1, want: ErrPasswordsNotMatch},
+	}
+	for _, step := range steps {
+		var err error
+		for i := 0; i < step.times; i += 1 {
+			err = verifyPasswords(hashed, step.group, step.user, step.psw)
+		}
+		if step.locked {
+			if err == nil || errors.Is(err, ErrPasswordsNotMatch) {
+				t.Errorf("%s: the error = %v, want locked", step.name, err)
+			}
+		} else if !errors.Is(err, step.want) {
+			t.Errorf("%s: the error = %v, want %v", step.name, err, step.want)
+		}
+	}
+	if err = verifyPasswords("", "c", "u", ""); err == nil {
+		t.Error("the group without passwords should not be verified")
+	}
+}
+
+func TestMigratePasswords(t *testing.T) {
+	stores := memory.NewStores()
+	hashed, err := hashPasswords("654321")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		psw  string
+	}{
+		{name: "plain", psw: "123456"},
+		{name: "hashed", psw: hashed},
+		{name: "empty"},
+	}
+	uids := make([]primitive.ObjectID, 0, len(cases))
+	for _, item := range cases {
+		db := &nosql.Family{UID: primitive.NewObjectID(), Name: item.name, Passwords: item.psw,
+			CreatedTime: time.Now(), UpdatedTime: time.Now()}
+		if err = stores.Family.CreateFamily(db); err != nil {
+			t.Fatal(err)
+		}
+		uids = append(uids, db.UID)
+	}
+	if err = InitDataWith(stores); err != nil {
+		t.Fatal(err)
+	}
+	for i, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			db, _ := stores.Family.GetFamily(uids[i].Hex())
+			switch item.name {
+			case "plain":
+				if bcrypt.CompareHashAndPassword([]byte(db.Passwords), []byte(item.psw)) != nil {
+					t.Errorf("the passwords %q is not hashed from %q", db.Passwords, item.psw)
+				}
+			default:
+				// 已经hash的不会再次hash
+				if db.Passwords != item.psw {
+					t.Errorf("the passwords = %q, want %q", db.Passwords, item.psw)
+				}
+			}
+		})
+	}
+}
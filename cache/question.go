@@ -22,9 +22,18 @@ type QuestionInfo struct {
 }
 
 func (mine *cacheContext) NewQuestion(title, remark, category, entity, operator string, cd int, answers []uint32, options []*pb.QuestionOption) (*QuestionInfo, error) {
+	id, err := mine.nextID(nosql.TableQuestion)
+	if err != nil {
+		return nil, err
+	}
+	return mine.createQuestion(id, title, remark, category, entity, operator, cd, answers, options)
+}
+
+// 批量导入时id是预留好的
+func (mine *cacheContext) createQuestion(id uint64, title, remark, category, entity, operator string, cd int, answers []uint32, options []*pb.QuestionOption) (*QuestionInfo, error) {
 	db := new(nosql.Question)
 	db.UID = primitive.NewObjectID()
-	db.ID = nosql.GetQuestionNextID()
+	db.ID = id
 	db.CreatedTime = time.Now()
 	db.Creator = operator
 	db.Title = title
@@ -41,7 +50,7 @@ func (mine *cacheContext) NewQuestion(title, remark, category, entity, operator
 			Value: v.Desc,
 		})
 	}
-	err := nosql.CreateQuestion(db)
+	err := mine.questions.CreateQuestion(db)
 	if err != nil {
 		return nil, err
 	}
@@ -50,25 +59,26 @@ func (mine *cacheContext) NewQuestion(title, remark, category, entity, operator
 	return info, nil
 }
 
-func (mine *cacheContext) GetQuestionsByNameAndKind(title, category string) ([]*QuestionInfo, error) {
+func (mine *cacheContext) GetQuestionsByNameAndKind(title, category string, page *PageInfo) (uint32, uint32, []*QuestionInfo, error) {
 	if title == "" {
-		return nil, errors.New("the parent is null")
+		return 0, 0, nil, errors.New("the parent is null")
 	}
-	array, err := nosql.GetQuestionsByTitle(title, category)
+	array, num, err := mine.questions.QueryQuestions(page.query(true, proxy.Equal("title", title), proxy.Equal("category", category)))
 	if err != nil {
-		return nil, err
+		return 0, 0, nil, err
 	}
-	questions := make([]*QuestionInfo, 0, len(array))
-	for _, v := range array {
-		question := new(QuestionInfo)
-		question.initInfo(v)
-		questions = append(questions, question)
+	list := make([]*QuestionInfo, 0, len(array))
+	for _, question := range array {
+		info := new(QuestionInfo)
+		info.initInfo(question)
+		list = append(list, info)
 	}
-	return questions, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
 func (mine *cacheContext) GetQuestion(uid string) (*QuestionInfo, error) {
-	db, err := nosql.GetQuestion(uid)
+	db, err := mine.questions.GetQuestion(uid)
 	if err == nil {
 		info := new(QuestionInfo)
 		info.initInfo(db)
@@ -77,46 +87,49 @@ func (mine *cacheContext) GetQuestion(uid string) (*QuestionInfo, error) {
 	return nil, err
 }
 
-func (mine *cacheContext) GetQuestionsByName(title string) ([]*QuestionInfo, error) {
-	dbs, err := nosql.GetQuestionsByName(title)
-	list := make([]*QuestionInfo, 0, 100)
+func (mine *cacheContext) GetQuestionsByName(title string, page *PageInfo) (uint32, uint32, []*QuestionInfo, error) {
+	array, num, err := mine.questions.QueryQuestions(page.query(true, proxy.Equal("title", title)))
 	if err != nil {
-		return nil, err
+		return 0, 0, nil, err
 	}
-	for _, question := range dbs {
+	list := make([]*QuestionInfo, 0, len(array))
+	for _, question := range array {
 		info := new(QuestionInfo)
 		info.initInfo(question)
 		list = append(list, info)
 	}
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetQuestionsByCategory(kind string) ([]*QuestionInfo, error) {
-	list := make([]*QuestionInfo, 0, 100)
-	array, err := nosql.GetQuestionsByCategory(kind)
+func (mine *cacheContext) GetQuestionsByCategory(kind string, page *PageInfo) (uint32, uint32, []*QuestionInfo, error) {
+	array, num, err := mine.questions.QueryQuestions(page.query(true, proxy.Equal("category", kind)))
 	if err != nil {
-		return nil, err
+		return 0, 0, nil, err
 	}
+	list := make([]*QuestionInfo, 0, len(array))
 	for _, question := range array {
 		info := new(QuestionInfo)
 		info.initInfo(question)
 		list = append(list, info)
 	}
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetQuestionsByEntity(entity string) ([]*QuestionInfo, error) {
-	list := make([]*QuestionInfo, 0, 100)
-	array, err := nosql.GetQuestionsByQuote(entity)
+func (mine *cacheContext) GetQuestionsByEntity(entity string, page *PageInfo) (uint32, uint32, []*QuestionInfo, error) {
+	array, num, err := mine.questions.QueryQuestions(page.query(true, proxy.Equal("quote", entity)))
 	if err != nil {
-		return nil, err
+		return 0, 0, nil, err
 	}
+	list := make([]*QuestionInfo, 0, len(array))
 	for _, question := range array {
 		info := new(QuestionInfo)
 		info.initInfo(question)
 		list = append(list, info)
 	}
-	return list, nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
 func (mine *QuestionInfo) initInfo(db *nosql.Question) {
@@ -135,7 +148,7 @@ func (mine *QuestionInfo) initInfo(db *nosql.Question) {
 }
 
 func (mine *QuestionInfo) UpdateAnswers(operator string, answers []uint32) error {
-	err := nosql.UpdateQuestionAnswers(mine.UID, operator, answers)
+	err := cacheCtx.questions.UpdateQuestionAnswers(mine.UID, operator, answers)
 	if err == nil {
 		mine.Answers = answers
 		mine.Operator = operator
@@ -147,7 +160,7 @@ func (mine *QuestionInfo) UpdateAssets(operator string, arr []string) error {
 	if arr == nil {
 		arr = make([]string, 0, 1)
 	}
-	err := nosql.UpdateQuestionAssets(mine.UID, operator, arr)
+	err := cacheCtx.questions.UpdateQuestionAssets(mine.UID, operator, arr)
 	if err == nil {
 		mine.Assets = arr
 		mine.Operator = operator
@@ -156,7 +169,7 @@ func (mine *QuestionInfo) UpdateAssets(operator string, arr []string) error {
 }
 
 func (mine *QuestionInfo) UpdateOptions(operator string, lis []proxy.PairInfo) error {
-	err := nosql.UpdateQuestionOptions(mine.UID, operator, lis)
+	err := cacheCtx.questions.UpdateQuestionOptions(mine.UID, operator, lis)
 	if err == nil {
 		mine.Options = lis
 		mine.Operator = operator
@@ -164,7 +177,7 @@ func (mine *QuestionInfo) UpdateOptions(operator string, lis []proxy.PairInfo) e
 	return err
 }
 func (mine *QuestionInfo) Delete(uid string) error {
-	err := nosql.RemoveQuestion(mine.UID, uid)
+	err := cacheCtx.questions.RemoveQuestion(mine.UID, uid)
 	if err == nil {
 		return err
 	}
@@ -178,7 +191,7 @@ func (mine *QuestionInfo) UpdateBase(title, remark, operator, category string, c
 			Value: v.Desc,
 		})
 	}
-	err := nosql.UpdateQuestionBase(mine.UID, title, remark, operator, category, cd, answers, arr)
+	err := cacheCtx.questions.UpdateQuestionBase(mine.UID, title, remark, operator, category, cd, answers, arr)
 	if err == nil {
 		mine.Name = title
 		mine.Remark = remark
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+// QuizRecordInfo 用户的一次答题记录
+type QuizRecordInfo struct {
+	baseInfo
+	User     string
+	Owner    string
+	Question string
+	Category string
+	Answers  []uint32
+	Correct  bool
+}
+
+func (mine *QuizRecordInfo) initInfo(db *nosql.QuizRecord) {
+	mine.UID = db.UID.Hex()
+	mine.ID = db.ID
+	mine.CreateTime = db.CreatedTime
+	mine.UpdateTime = db.UpdatedTime
+	mine.Creator = db.Creator
+	mine.Operator = db.Operator
+	mine.User = db.User
+	mine.Owner = db.Owner
+	mine.Question = db.Question
+	mine.Category = db.Category
+	mine.Answers = db.Answers
+	mine.Correct = db.Correct
+}
+
+// ErrQuizCoolingDown 同一个用户对同一道题两次答题的间隔小于题目的cd
+var ErrQuizCoolingDown = errors.New("the question is cooling down")
+
+// 记录本次提交，两次提交的间隔不能小于题目的cd，单位为秒；判断和记录在存储中一次完成，并发的提交只有一个成功
+func (mine *cacheContext) checkQuizCooldown(user string, question *QuestionInfo) error {
+	if question.Cd < 1 {
+		return nil
+	}
+	now := time.Now()
+	cd := time.Duration(question.Cd) * time.Second
+	ok, last, err := mine.quizzes.TrySubmitQuiz(user, question.UID, now, cd)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		wait := cd - now.Sub(last)
+		return fmt.Errorf("%w, please wait %d seconds", ErrQuizCoolingDown, int(wait.Seconds())+1)
+	}
+	return nil
+}
+
+// SubmitAnswers 提交答案并判分，选项与题目的答案完全一致(不区分顺序)才算答对
+func (mine *cacheContext) SubmitAnswers(owner, user, uid string, answers []uint32) (*QuizRecordInfo, error) {
+	if len(user) < 1 {
+		return nil, errors.New("the user is empty")
+	}
+	if len(answers) < 1 {
+		return nil, errors.New("the answers is empty")
+	}
+	db, err := mine.questions.GetQuestion(uid)
+	if err != nil {
+		return nil, errors.New("not found the question of " + uid)
+	}
+	if !db.DeleteTime.IsZero() {
+		return nil, errors.New("the question had removed: " + uid)
+	}
+	question := new(QuestionInfo)
+	question.initInfo(db)
+	err = mine.checkQuizCooldown(user, question)
+	if err != nil {
+		return nil, err
+	}
+	id, err := mine.nextID(nosql.TableRecord)
+	if err != nil {
+		return nil, err
+	}
+	record := new(nosql.QuizRecord)
+	record.UID = primitive.NewObjectID()
+	record.ID = id
+	record.CreatedTime = time.Now()
+	record.Creator = user
+	record.User = user
+	record.Owner = owner
+	record.Question = question.UID
+	record.Category = question.Category
+	record.Answers = answers
+	record.Correct = question.IsCorrect(answers)
+	err = mine.quizzes.CreateQuizRecord(record)
+	if err != nil {
+		return nil, err
+	}
+	info := new(QuizRecordInfo)
+	info.initInfo(record)
+	return info, nil
+}
+
+// IsCorrect 判断答案是否正确，重复的选项只算一次
+func (mine *QuestionInfo) IsCorrect(answers []uint32) bool {
+	if len(mine.Answers) < 1 {
+		return false
+	}
+	right := make(map[uint32]bool, len(mine.Answers))
+	for _, item := range mine.Answers {
+		right[item] = true
+	}
+	chosen := make(map[uint32]bool, len(answers))
+	for _, item := range answers {
+		if !right[item] {
+			return false
+		}
+		chosen[item] = true
+	}
+	return len(chosen) == len(right)
+}
+
+func (mine *cacheContext) GetQuizRecord(uid string) (*QuizRecordInfo, error) {
+	db, err := mine.quizzes.GetQuizRecord(uid)
+	if err != nil {
+		return nil, err
+	}
+	info := new(QuizRecordInfo)
+	info.initInfo(db)
+	return info, nil
+}
+
+// GetQuizRecordsByUser 用户的答题历史，最新的在前，owner为空时不限制场景
+func (mine *cacheContext) GetQuizRecordsByUser(owner, user string, page *PageInfo) (uint32, uint32, []*QuizRecordInfo, error) {
+	conditions := []proxy.Condition{proxy.Equal("user", user)}
+	if len(owner) > 0 {
+		conditions = append(conditions, proxy.Equal("owner", owner))
+	}
+	return mine.queryQuizRecords(page, conditions...)
+}
+
+func (mine *cacheContext) GetQuizRecordsByQuestion(question string, page *PageInfo) (uint32, uint32, []*QuizRecordInfo, error) {
+	return mine.queryQuizRecords(page, proxy.Equal("question", question))
+}
+
+func (mine *cacheContext) queryQuizRecords(page *PageInfo, list ...proxy.Condition) (uint32, uint32, []*QuizRecordInfo, error) {
+	query := page.query(true, list...)
+	query.Desc = true
+	dbs, num, err := mine.quizzes.QueryQuizRecords(query)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	all := make([]*QuizRecordInfo, 0, len(dbs))
+	for _, db := range dbs {
+		info := new(QuizRecordInfo)
+		info.initInfo(db)
+		all = append(all, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, all, nil
+}
+
+// GetQuestionAccuracy 题目的答题次数以及答对的次数
+func (mine *cacheContext) GetQuestionAccuracy(question string) (uint32, uint32, error) {
+	page := &PageInfo{Page: 1, Number: 1}
+	total, _, _, err := mine.queryQuizRecords(page, proxy.Equal("question", question))
+	if err != nil {
+		return 0, 0, err
+	}
+	right, _, _, err := mine.queryQuizRecords(page, proxy.Equal("question", question), proxy.Equal("correct", true))
+	if err != nil {
+		return 0, 0, err
+	}
+	return total, right, nil
+}
+
+// GetQuizRanking 分类的排行榜，按照答对的次数排序，limit为0时返回全部
+func (mine *cacheContext) GetQuizRanking(category string, limit uint32) ([]*proxy.QuizScore, error) {
+	if len(category) < 1 {
+		return nil, errors.New("the category is empty")
+	}
+	return mine.quizzes.GetQuizRanking(category, int64(limit))
+}
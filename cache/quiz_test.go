@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTrySubmitQuiz(t *testing.T) {
+	now := time.Date(2030, time.January, 2, 9, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name     string
+		user     string
+		question string
+		at       time.Time
+		want     bool
+		last     time.Time
+	}{
+		{name: "first", user: "a", question: "q1", at: now, want: true},
+		{name: "cooling", user: "a", question: "q1", at: now.Add(30 * time.Second), last: now},
+		{name: "other user", user: "b", question: "q1", at: now.Add(30 * time.Second), want: true},
+		{name: "other question", user: "a", question: "q2", at: now.Add(30 * time.Second), want: true},
+		{name: "cooled", user: "a", question: "q1", at: now.Add(time.Minute), want: true},
+		{name: "cooling again", user: "a", question: "q1", at: now.Add(time.Minute + time.Second), last: now.Add(time.Minute)},
+	}
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			store := backend.stores(t).Quiz
+			for _, step := range steps {
+				ok, last, err := store.TrySubmitQuiz(step.user, step.question, step.at, time.Minute)
+				if err != nil {
+					t.Fatalf("%s: %v", step.name, err)
+				}
+				if ok != step.want || !last.Equal(step.last) {
+					t.Errorf("%s: the result = %v and %v, want %v and %v", step.name, ok, last, step.want, step.last)
+				}
+			}
+		})
+	}
+}
+
+func TestSubmitAnswersCooldown(t *testing.T) {
+	cases := []struct {
+		name    string
+		cd      int
+		success int
+	}{
+		{name: "no cd", cd: 0, success: 5},
+		{name: "cd", cd: 60, success: 1},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			question, err := ctx.NewQuestion("title", "", "category", "", "admin", item.cd, []uint32{1}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var wait sync.WaitGroup
+			errs := make(chan error, 5)
+			for i := 0; i < 5; i++ {
+				wait.Add(1)
+				go func() {
+					defer wait.Done()
+					_, err := ctx.SubmitAnswers("scene", "user", question.UID, []uint32{1})
+					errs <- err
+				}()
+			}
+			wait.Wait()
+			close(errs)
+			success := 0
+			for err := range errs {
+				if err == nil {
+					success += 1
+				} else if !errors.Is(err, ErrQuizCoolingDown) {
+					t.Errorf("the error = %v, want %v", err, ErrQuizCoolingDown)
+				}
+			}
+			total, _, _, _ := ctx.GetQuizRecordsByUser("scene", "user", nil)
+			if success != item.success || total != uint32(item.success) {
+				t.Errorf("the success = %d, the records = %d, want %d", success, total, item.success)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"errors"
+	"github.com/micro/go-micro/v2/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"time"
+)
+
+func newRecordUID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// 旧的记录没有uid，无法单独修改或者删除，启动时补齐
+func (mine *cacheContext) checkRecordUIDs() error {
+	dbs, _, err := mine.tasks.QueryTasks(proxy.NewQuery(false, proxy.Equal("records.uid", "")))
+	if err != nil {
+		return err
+	}
+	for _, db := range dbs {
+		for i := range db.Records {
+			if len(db.Records[i].UID) < 1 {
+				db.Records[i].UID = newRecordUID()
+			}
+		}
+		err = mine.tasks.UpdateTaskRecords(db.UID.Hex(), db.Records)
+		if err != nil {
+			return err
+		}
+	}
+	if len(dbs) > 0 {
+		logger.Warnf("filled the record uids of %d tasks", len(dbs))
+	}
+	return nil
+}
+
+func (mine *TaskInfo) GetRecord(uid string) (*proxy.RecordInfo, error) {
+	if len(uid) < 1 {
+		return nil, errors.New("the record uid is empty")
+	}
+	for i := 0; i < len(mine.Records); i += 1 {
+		if mine.Records[i].UID == uid {
+			return &mine.Records[i], nil
+		}
+	}
+	return nil, errors.New("not found the record of " + uid)
+}
+
+// GetRecords 按照创建的顺序分页返回满足条件的记录，游标为上一页最后一条记录的uid
+func (mine *TaskInfo) GetRecords(filter *proxy.RecordFilter, page *PageInfo) (uint32, uint32, []proxy.RecordInfo, error) {
+	if filter == nil {
+		filter = new(proxy.RecordFilter)
+	}
+	var begin, end time.Time
+	var err error
+	if len(filter.Begin) > 0 {
+		begin, err = cacheCtx.formatDate(filter.Begin)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+	}
+	if len(filter.End) > 0 {
+		end, err = cacheCtx.formatDate(filter.End)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	all := make([]proxy.RecordInfo, 0, len(mine.Records))
+	for _, item := range mine.Records {
+		if len(filter.Executor) > 0 && item.Executor != filter.Executor {
+			continue
+		}
+		if len(filter.States) > 0 && !hasStatus(filter.States, item.Status) {
+			continue
+		}
+		if !begin.IsZero() && item.CreatedTime.Before(begin) {
+			continue
+		}
+		if !end.IsZero() && !item.CreatedTime.Before(end) {
+			continue
+		}
+		all = append(all, item)
+	}
+	query := page.query(false)
+	start := query.Skip
+	if len(query.Cursor) > 0 {
+		start = -1
+		for i, item := range all {
+			if item.UID == query.Cursor {
+				start = int64(i + 1)
+				break
+			}
+		}
+		if start < 0 {
+			return 0, 0, nil, errors.New("the cursor is invalid")
+		}
+	}
+	list := make([]proxy.RecordInfo, 0, 10)
+	for i := start; i < int64(len(all)); i += 1 {
+		if query.Limit > 0 && int64(len(list)) >= query.Limit {
+			break
+		}
+		list = append(list, all[i])
+	}
+	total, maxPage := page.result(int64(len(all)))
+	return total, maxPage, list, nil
+}
+
+func hasStatus(list []uint8, st uint8) bool {
+	for _, item := range list {
+		if item == st {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateRecord 修改记录的内容，记录的状态以及创建信息保持不变
+func (mine *TaskInfo) UpdateRecord(data proxy.RecordInfo) error {
+	old, err := mine.GetRecord(data.UID)
+	if err != nil {
+		return err
+	}
+	data.Creator = old.Creator
+	data.CreatedTime = old.CreatedTime
+	data.Status = old.Status
+	err = cacheCtx.tasks.UpdateTaskRecord(mine.UID, data)
+	if err == nil {
+		*old = data
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"testing"
+	"time"
+)
+
+func TestCheckRecordUIDs(t *testing.T) {
+	for _, item := range testBackends {
+		t.Run(item.name, func(t *testing.T) {
+			stores := item.stores(t)
+			old := &nosql.Task{UID: primitive.NewObjectID(), ID: 1, Name: "old", Owner: "scene",
+				CreatedTime: time.Now(), UpdatedTime: time.Now(),
+				Records: []proxy.RecordInfo{{Name: "first"}, {UID: "kept", Name: "second"}, {Name: "third"}}}
+			fresh := &nosql.Task{UID: primitive.NewObjectID(), ID: 2, Name: "fresh", Owner: "scene",
+				CreatedTime: time.Now(), UpdatedTime: time.Now(), Records: []proxy.RecordInfo{{UID: "a", Name: "first"}}}
+			for _, task := range []*nosql.Task{old, fresh} {
+				if err := stores.Task.CreateTask(task); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := InitDataWith(stores); err != nil {
+				t.Fatal(err)
+			}
+			info, err := cacheCtx.GetTask(old.UID.Hex())
+			if err != nil {
+				t.Fatal(err)
+			}
+			uids := make(map[string]bool, len(info.Records))
+			for _, record := range info.Records {
+				if len(record.UID) < 1 || uids[record.UID] {
+					t.Errorf("the uid of record(%s) = %q, want a new unique one", record.Name, record.UID)
+				}
+				uids[record.UID] = true
+			}
+			if len(info.Records) != 3 || info.Records[1].UID != "kept" || info.Records[0].Name != "first" {
+				t.Errorf("the records = %+v", info.Records)
+			}
+			other, _ := cacheCtx.GetTask(fresh.UID.Hex())
+			if len(other.Records) != 1 || other.Records[0].UID != "a" {
+				t.Errorf("the records of the fresh task = %+v", other.Records)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/config"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"strconv"
+	"time"
+)
+
+// IsRecurring 是否为重复会议的第一次(保存规则)
+func (mine *MeetingInfo) IsRecurring() bool {
+	return len(mine.Rule) > 0 && mine.Series == mine.UID
+}
+
+// InSeries 是否属于某个重复会议
+func (mine *MeetingInfo) InSeries() bool {
+	return len(mine.Series) > 0
+}
+
+// 同一个系列中某一次的uid是固定的，前4个字节为时间，多个服务同时生成时不会重复；
+// 重新设置规则后revision改变，以前删除的不影响重新生成
+func occurrenceID(series string, revision uint32, occurrence time.Time) primitive.ObjectID {
+	var id primitive.ObjectID
+	sum := sha1.Sum([]byte(series + "|" + strconv.FormatUint(uint64(revision), 10) + "|" + strconv.FormatInt(occurrence.Unix(), 10)))
+	binary.BigEndian.PutUint32(id[0:4], uint32(occurrence.Unix()))
+	copy(id[4:], sum[:8])
+	return id
+}
+
+func horizonOf(conf config.MeetingConfig) time.Duration {
+	return time.Duration(conf.Horizon) * time.Second
+}
+
+func (mine *MeetingInfo) exceptionOf(occurrence time.Time) *proxy.ExceptionInfo {
+	for i := 0; i < len(mine.Exceptions); i += 1 {
+		if mine.Exceptions[i].Occurrence.Equal(occurrence) {
+			return &mine.Exceptions[i]
+		}
+	}
+	return nil
+}
+
+// 在系列的第一次会议上记录某一次的例外
+func (mine *MeetingInfo) saveException(item proxy.ExceptionInfo) error {
+	list := make([]proxy.ExceptionInfo, 0, len(mine.Exceptions)+1)
+	for _, exception := range mine.Exceptions {
+		if !exception.Occurrence.Equal(item.Occurrence) {
+			list = append(list, exception)
+		}
+	}
+	list = append(list, item)
+	err := cacheCtx.meetings.UpdateMeetingExceptions(mine.UID, item.Operator, list)
+	if err == nil {
+		mine.Exceptions = list
+	}
+	return err
+}
+
+func (mine *MeetingInfo) getSeries() (*MeetingInfo, error) {
+	if !mine.InSeries() {
+		return nil, errors.New("the meeting is not recurring")
+	}
+	if mine.Series == mine.UID {
+		return mine, nil
+	}
+	return cacheCtx.GetMeeting(mine.Series)
+}
+
+// MaterializeMeetings 生成所有重复会议在[now, now+horizon)之间还没有生成的会议，返回生成的数量
+func (mine *cacheContext) MaterializeMeetings(horizon time.Duration, now time.Time) (int, error) {
+	if horizon < 1 {
+		return 0, nil
+	}
+	array, _, err := mine.meetings.QueryMeetings(proxy.NewQuery(true, proxy.Greater("rule", "")))
+	if err != nil {
+		return 0, err
+	}
+	num := 0
+	for _, db := range array {
+		info := new(MeetingInfo)
+		info.initInfo(db)
+		count, err := info.materialize(now, now.Add(horizon))
+		num += count
+		if err != nil {
+			return num, err
+		}
+	}
+	return num, nil
+}
+
+// 按照规则生成[from, to)之间的会议，取消的和已经生成的(包括删除的)不再生成
+func (mine *MeetingInfo) materialize(from, to time.Time) (int, error) {
+	if !mine.IsRecurring() {
+		return 0, nil
+	}
+	rule, err := ParseRecurrence(mine.Rule)
+	if err != nil {
+		return 0, err
+	}
+	num := 0
+	for _, t := range rule.Occurrences(mine.Occurrence, from, to) {
+		if t.Equal(mine.Occurrence) {
+			continue
+		}
+		start := t
+		stop := time.Time{}
+		if mine.Span > 0 {
+			stop = t.Add(time.Duration(mine.Span) * time.Second)
+		}
+		if exception := mine.exceptionOf(t); exception != nil {
+			if exception.Cancelled {
+				continue
+			}
+			start = exception.StartTime
+			stop = exception.StopTime
+		}
+		id := occurrenceID(mine.UID, mine.Revision, t)
+		if had, _ := cacheCtx.meetings.GetMeeting(id.Hex()); had != nil {
+			continue
+		}
+		db, err := mine.occurrenceOf(id, t, start.UTC(), stop.UTC())
+		if err != nil {
+			return num, err
+		}
+		err = cacheCtx.meetings.CreateMeeting(db)
+		if err != nil {
+			// 其他服务已经生成了
+			if had, _ := cacheCtx.meetings.GetMeeting(id.Hex()); had != nil {
+				continue
+			}
+			return num, err
+		}
+		num += 1
+	}
+	return num, nil
+}
+
+func (mine *MeetingInfo) occurrenceOf(id primitive.ObjectID, occurrence, start, stop time.Time) (*nosql.Meeting, error) {
+	num, err := cacheCtx.nextID(nosql.TableMeeting)
+	if err != nil {
+		return nil, err
+	}
+	db := new(nosql.Meeting)
+	db.UID = id
+	db.ID = num
+	db.CreatedTime = time.Now()
+	db.UpdatedTime = db.CreatedTime
+	db.Creator = mine.Creator
+	db.Operator = mine.Creator
+	db.Name = mine.Name
+	db.Remark = mine.Remark
+	db.Group = mine.Group
+	db.Owner = mine.Owner
+	db.Type = uint8(mine.Type)
+	db.Location = mine.Location
+	db.Status = uint8(Pending)
+	db.StartTime = start
+	db.StopTime = stop
+	db.Appointed = start.In(time.Local).Format("2006-01-02 15:04")
+	db.Signs = make([]string, 0, 1)
+	db.Submits = make([]string, 0, 1)
+	db.Notifies = make([]string, 0, 1)
+	db.Records = make([]proxy.SignInfo, 0, 1)
+	db.Attends = make([]proxy.AttendInfo, 0, 1)
+	db.Radius = mine.Radius
+	db.SignBefore = mine.SignBefore
+	db.SignLate = mine.SignLate
+	db.Series = mine.UID
+	db.Occurrence = occurrence
+	return db, nil
+}
+
+// 删除系列中after之后还没有开始的会议，已经开始或者有人签到的保留
+func (mine *cacheContext) removeFollowing(series string, after time.Time, operator string) error {
+	array, _, err := mine.meetings.QueryMeetings(proxy.NewQuery(true, proxy.Equal("series", series),
+		proxy.Greater("occurrence", after)))
+	if err != nil {
+		return err
+	}
+	for _, db := range array {
+		if db.UID.Hex() == series || db.Status != uint8(Pending) || len(db.Signs) > 0 {
+			continue
+		}
+		err = mine.meetings.RemoveMeeting(db.UID.Hex(), operator)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateRule 设置重复的规则，以当前的开始时间为第一次，已经生成的还没有开始的会议重新生成；规则为空时不再重复
+func (mine *MeetingInfo) UpdateRule(text, operator string) error {
+	if mine.InSeries() && mine.Series != mine.UID {
+		return errors.New("the meeting is an occurrence of the series, please update the following")
+	}
+	if len(text) < 1 {
+		if !mine.IsRecurring() {
+			return nil
+		}
+		err := cacheCtx.removeFollowing(mine.UID, mine.Occurrence, operator)
+		if err != nil {
+			return err
+		}
+		err = cacheCtx.meetings.UpdateMeetingRule(mine.UID, operator, "", mine.Series, mine.Occurrence, mine.Span, mine.Revision)
+		if err == nil {
+			mine.Rule = ""
+			mine.Operator = operator
+		}
+		return err
+	}
+	rule, err := ParseRecurrence(text)
+	if err != nil {
+		return err
+	}
+	if mine.StartTime.IsZero() {
+		return errors.New("the start time of the meeting is empty")
+	}
+	var span int64 = 0
+	if !mine.StopTime.IsZero() && mine.StopTime.After(mine.StartTime) {
+		span = int64(mine.StopTime.Sub(mine.StartTime).Seconds())
+	}
+	if mine.IsRecurring() {
+		err = cacheCtx.removeFollowing(mine.UID, mine.Occurrence, operator)
+		if err != nil {
+			return err
+		}
+	}
+	err = cacheCtx.meetings.UpdateMeetingRule(mine.UID, operator, rule.String(), mine.UID, mine.StartTime, span, mine.Revision+1)
+	if err != nil {
+		return err
+	}
+	mine.Revision += 1
+	mine.Rule = rule.String()
+	mine.Series = mine.UID
+	mine.Occurrence = mine.StartTime
+	mine.Span = span
+	mine.Operator = operator
+	if len(mine.Exceptions) > 0 {
+		// 以前的例外按照以前的规则计算
+		err = mine.saveExceptions(nil, operator)
+		if err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	_, err = mine.materialize(now, now.Add(horizonOf(config.Schema.Meeting)))
+	wakeMeetingScheduler()
+	return err
+}
+
+func (mine *MeetingInfo) saveExceptions(list []proxy.ExceptionInfo, operator string) error {
+	if list == nil {
+		list = make([]proxy.ExceptionInfo, 0, 1)
+	}
+	err := cacheCtx.meetings.UpdateMeetingExceptions(mine.UID, operator, list)
+	if err == nil {
+		mine.Exceptions = list
+	}
+	return err
+}
+
+// UpdateFollowing 修改这一次以及之后的会议：原来的系列在这一次之前结束，这一次作为新系列的第一次；
+// rule为空时使用原来的规则(COUNT为剩余的次数)
+func (mine *MeetingInfo) UpdateFollowing(begin, end int64, text, operator string) error {
+	master, err := mine.getSeries()
+	if err != nil {
+		return err
+	}
+	if len(master.Rule) < 1 {
+		return errors.New("the series is not recurring any more")
+	}
+	old, err := ParseRecurrence(master.Rule)
+	if err != nil {
+		return err
+	}
+	if len(text) < 1 {
+		rule := *old
+		if rule.Count > 0 {
+			index := old.IndexOf(master.Occurrence, mine.Occurrence)
+			if index < 0 {
+				return errors.New("the meeting is not an occurrence of the series")
+			}
+			rule.Count -= index
+		}
+		text = rule.String()
+	}
+	if _, err = ParseRecurrence(text); err != nil {
+		return err
+	}
+	if master.UID != mine.UID {
+		// 原来的系列在这一次之前结束
+		old.Count = 0
+		old.Until = mine.Occurrence.Add(-time.Second)
+		err = cacheCtx.meetings.UpdateMeetingRule(master.UID, operator, old.String(), master.Series, master.Occurrence, master.Span, master.Revision)
+		if err != nil {
+			return err
+		}
+		list := make([]proxy.ExceptionInfo, 0, len(master.Exceptions))
+		for _, item := range master.Exceptions {
+			if item.Occurrence.Before(mine.Occurrence) {
+				list = append(list, item)
+			}
+		}
+		err = master.saveExceptions(list, operator)
+		if err != nil {
+			return err
+		}
+		err = cacheCtx.removeFollowing(master.UID, mine.Occurrence, operator)
+		if err != nil {
+			return err
+		}
+		mine.Rule = ""
+		mine.Series = ""
+	}
+	err = mine.updateDate(begin, end, operator)
+	if err != nil {
+		return err
+	}
+	return mine.UpdateRule(text, operator)
+}
+
+// Cancel 取消重复会议中的这一次，以后不会再生成；第一次会议保存规则，只修改为关闭
+func (mine *MeetingInfo) Cancel(operator string) error {
+	master, err := mine.getSeries()
+	if err != nil {
+		return err
+	}
+	err = master.saveException(proxy.ExceptionInfo{Occurrence: mine.Occurrence, Cancelled: true, Operator: operator, CreatedTime: time.Now()})
+	if err != nil {
+		return err
+	}
+	if master.UID == mine.UID {
+		if mine.Status == Close {
+			return nil
+		}
+		_, err = cacheCtx.meetings.TransitMeeting(mine.UID, uint8(mine.Status), uint8(Close))
+		if err == nil {
+			mine.Status = Close
+		}
+		return err
+	}
+	return cacheCtx.RemoveMeeting(mine.UID, operator)
+}
+
+// CancelOccurrence 取消系列中某一次(按照规则计算的开始时间)，可以是还没有生成的
+func (mine *MeetingInfo) CancelOccurrence(occurrence time.Time, operator string) error {
+	if !mine.IsRecurring() {
+		return errors.New("the meeting is not recurring")
+	}
+	rule, err := ParseRecurrence(mine.Rule)
+	if err != nil {
+		return err
+	}
+	if rule.IndexOf(mine.Occurrence, occurrence) < 0 {
+		return errors.New("the time is not an occurrence of the series")
+	}
+	if occurrence.Equal(mine.Occurrence) {
+		return mine.Cancel(operator)
+	}
+	db, _ := cacheCtx.meetings.GetMeeting(occurrenceID(mine.UID, mine.Revision, occurrence).Hex())
+	if db != nil && db.DeleteTime.IsZero() {
+		info := new(MeetingInfo)
+		info.initInfo(db)
+		return info.Cancel(operator)
+	}
+	return mine.saveException(proxy.ExceptionInfo{Occurrence: occurrence, Cancelled: true, Operator: operator, CreatedTime: time.Now()})
+}
+
+// 修改了系列中某一次的时间时记录例外
+func (mine *MeetingInfo) rescheduled(operator string) error {
+	if !mine.InSeries() || mine.Occurrence.IsZero() {
+		return nil
+	}
+	master, err := mine.getSeries()
+	if err != nil {
+		return err
+	}
+	return master.saveException(proxy.ExceptionInfo{Occurrence: mine.Occurrence, StartTime: mine.StartTime,
+		StopTime: mine.StopTime, Operator: operator, CreatedTime: time.Now()})
+}
+
+func (mine *cacheContext) GetMeetingsBySeries(uid string, page *PageInfo) (uint32, uint32, []*MeetingInfo) {
+	query := page.query(true, proxy.Equal("series", uid))
+	query.Sort = "occurrence"
+	array, num, err := mine.meetings.QueryMeetings(query)
+	if err != nil {
+		return 0, 0, make([]*MeetingInfo, 0, 1)
+	}
+	list := make([]*MeetingInfo, 0, len(array))
+	for _, item := range array {
+		info := new(MeetingInfo)
+		info.initInfo(item)
+		list = append(list, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, list
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"testing"
+	"time"
+)
+
+func newTestMeeting(t *testing.T, ctx *cacheContext, owner, group, location string, start time.Time) *MeetingInfo {
+	t.Helper()
+	info, err := ctx.CreateMeeting(&pb.ReqMeetingAdd{Name: "meeting", Owner: owner, Group: group, Operator: "admin",
+		Type: uint32(InRoom), Location: location, Appointed: start.Format("2006-01-02 15:04")})
+	if err != nil {
+		t.Fatalf("create the meeting failed: %v", err)
+	}
+	return info
+}
+
+func TestMaterialize(t *testing.T) {
+	start := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.Local)
+	cases := []struct {
+		name string
+		rule string
+		want int
+	}{
+		{name: "daily count", rule: "FREQ=DAILY;COUNT=3", want: 2},
+		{name: "every three days", rule: "FREQ=DAILY;INTERVAL=3", want: 3},
+		{name: "weekly", rule: "FREQ=WEEKLY", want: 1},
+		{name: "weekdays", rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", want: 7},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			info := newTestMeeting(t, ctx, "scene", "", "", start)
+			if err := info.UpdateRule(item.rule, "admin"); err != nil {
+				t.Fatal(err)
+			}
+			to := start.AddDate(0, 0, 10)
+			num, err := info.materialize(start, to)
+			if err != nil || num != item.want {
+				t.Fatalf("the materialized = %d, want %d, the error = %v", num, item.want, err)
+			}
+			num, err = info.materialize(start, to)
+			if err != nil || num != 0 {
+				t.Errorf("materialize again = %d, the error = %v", num, err)
+			}
+			_, _, list := ctx.GetMeetingsBySeries(info.UID, nil)
+			if len(list) != item.want+1 {
+				t.Errorf("the meetings of series = %d, want %d", len(list), item.want+1)
+			}
+		})
+	}
+}
+
+func TestMaterializeCancelled(t *testing.T) {
+	ctx := newTestContext(t)
+	start := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.Local)
+	info := newTestMeeting(t, ctx, "scene", "", "", start)
+	if err := info.UpdateRule("FREQ=DAILY;COUNT=4", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := info.CancelOccurrence(start.AddDate(0, 0, 2), "admin"); err != nil {
+		t.Fatal(err)
+	}
+	num, err := info.materialize(start, start.AddDate(0, 0, 10))
+	if err != nil || num != 2 {
+		t.Fatalf("the materialized = %d, want 2, the error = %v", num, err)
+	}
+}
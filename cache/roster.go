@@ -0,0 +1,438 @@
+package cache
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/tool"
+)
+
+const (
+	// 加入了成员
+	RosterAdded = "added"
+	// 已经是成员，替换时为保留的成员
+	RosterMember = "member"
+	// 移除了成员
+	RosterRemoved = "removed"
+	// 不是成员
+	RosterNone = "none"
+	// 没有修改，Reason为原因
+	RosterRejected = "rejected"
+	// 小组空出名额后从候补中加入
+	RosterPromoted = "promoted"
+)
+
+const (
+	GroupTeam    = "team"
+	GroupFamily  = "family"
+	GroupCoterie = "coterie"
+)
+
+// 读取之后成员被其他人修改时重新计算的次数
+const rosterRetry = 3
+
+var ErrRosterChanged = errors.New("the members are changed by others at the same time, please retry")
+
+// RosterResult 批量修改时每个成员的结果
+type RosterResult struct {
+	User   string
+	State  string
+	Reason string
+}
+
+// MemberRoster 可以批量修改成员的小组、家庭或者圈子，每次修改在数据库中为一次更新，不会只修改一部分
+type MemberRoster interface {
+	AppendMembers(list []proxy.MemberInfo) ([]*RosterResult, error)
+	SubtractMembers(list []string) ([]*RosterResult, error)
+	ReplaceMembers(operator string, list []proxy.MemberInfo) ([]*RosterResult, error)
+}
+
+// GetRoster kind为team、family或者coterie
+func (mine *cacheContext) GetRoster(kind, uid string) (MemberRoster, error) {
+	switch kind {
+	case GroupTeam:
+		info, err := mine.GetTeam(uid)
+		if err != nil {
+			return nil, err
+		}
+		return info, nil
+	case GroupFamily:
+		info, err := mine.GetFamily(uid)
+		if err != nil {
+			return nil, err
+		}
+		return info, nil
+	case GroupCoterie:
+		info, err := mine.GetCoterie(uid)
+		if err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+	return nil, errors.New("the group type is not supported: " + kind)
+}
+
+// ToMembers 只有用户的成员列表
+func ToMembers(users []string) []proxy.MemberInfo {
+	list := make([]proxy.MemberInfo, 0, len(users))
+	for _, user := range users {
+		list = append(list, proxy.MemberInfo{User: user})
+	}
+	return list
+}
+
+// 按照列表的顺序生成结果，空的和重复的拒绝，had为true的是已有的成员；返回结果以及需要修改的成员
+func checkRoster(list []proxy.MemberInfo, had func(string) bool) ([]*RosterResult, []*RosterResult, []proxy.MemberInfo) {
+	results := make([]*RosterResult, 0, len(list))
+	fresh := make([]*RosterResult, 0, len(list))
+	members := make([]proxy.MemberInfo, 0, len(list))
+	checked := make(map[string]bool, len(list))
+	for _, item := range list {
+		result := &RosterResult{User: item.User}
+		results = append(results, result)
+		if len(item.User) < 1 {
+			result.State = RosterRejected
+			result.Reason = "the user is empty"
+		} else if checked[item.User] {
+			result.State = RosterRejected
+			result.Reason = "the user is repeated in the list"
+		} else if had(item.User) {
+			result.State = RosterMember
+		} else {
+			result.State = RosterAdded
+			fresh = append(fresh, result)
+			members = append(members, item)
+		}
+		checked[item.User] = true
+	}
+	return results, fresh, members
+}
+
+func rosterUsers(list []proxy.MemberInfo) []string {
+	users := make([]string, 0, len(list))
+	for _, item := range list {
+		users = append(users, item.User)
+	}
+	return users
+}
+
+// 超出名额的成员拒绝，返回可以加入的
+func rejectFull(fresh []*RosterResult, members []proxy.MemberInfo, seats int) ([]*RosterResult, []proxy.MemberInfo) {
+	if seats < 0 || len(fresh) <= seats {
+		return fresh, members
+	}
+	for _, result := range fresh[seats:] {
+		result.State = RosterRejected
+		result.Reason = ErrTeamFull.Error()
+	}
+	return fresh[:seats], members[:seats]
+}
+
+// AppendMembers 一次加入多个成员，超出名额的拒绝(不加入候补)
+func (mine *TeamInfo) AppendMembers(list []proxy.MemberInfo) ([]*RosterResult, error) {
+	for i := 0; i < rosterRetry; i += 1 {
+		results, fresh, members := checkRoster(list, mine.HadMember)
+		_, members = rejectFull(fresh, members, mine.Seats())
+		users := rosterUsers(members)
+		ok, err := cacheCtx.teams.AppendTeamMembers(mine.UID, users, mine.MaxNum)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mine.Members = append(mine.Members, users...)
+			for _, user := range users {
+				mine.Waits = pullWait(mine.Waits, user)
+			}
+			return results, nil
+		}
+		err = mine.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrRosterChanged
+}
+
+// SubtractMembers 一次移除多个成员，空出的名额由最早的候补补上，补上的成员在结果的最后
+func (mine *TeamInfo) SubtractMembers(list []string) ([]*RosterResult, error) {
+	results, users := checkRemove(list, mine.HadMember)
+	if len(users) > 0 {
+		err := cacheCtx.teams.SubtractTeamMembers(mine.UID, users)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			mine.Members = pullString(mine.Members, user)
+		}
+	}
+	promoted, err := mine.promoteWaits()
+	for _, user := range promoted {
+		results = append(results, &RosterResult{User: user, State: RosterPromoted})
+	}
+	return results, err
+}
+
+// ReplaceMembers 成员替换为列表中的，不在列表中的移除；已有的成员优先保留，新的成员按照顺序使用剩余的名额，超出的拒绝；
+// 新的成员从候补中移除，空出的名额由最早的候补补上
+func (mine *TeamInfo) ReplaceMembers(operator string, list []proxy.MemberInfo) ([]*RosterResult, error) {
+	for i := 0; i < rosterRetry; i += 1 {
+		results, users := mine.replaceRoster(list)
+		ok, err := cacheCtx.teams.ReplaceTeamMembers(mine.UID, operator, mine.Members, users)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mine.Members = users
+			for _, user := range users {
+				mine.Waits = pullWait(mine.Waits, user)
+			}
+			mine.Operator = operator
+			promoted, err := mine.promoteWaits()
+			for _, user := range promoted {
+				results = append(results, &RosterResult{User: user, State: RosterPromoted})
+			}
+			return results, err
+		}
+		err = mine.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrRosterChanged
+}
+
+// 按照当前的成员计算替换的结果以及替换后的成员
+func (mine *TeamInfo) replaceRoster(list []proxy.MemberInfo) ([]*RosterResult, []string) {
+	results, fresh, members := checkRoster(list, mine.HadMember)
+	keeps := make([]string, 0, len(list))
+	for _, result := range results {
+		if result.State == RosterMember {
+			keeps = append(keeps, result.User)
+		}
+	}
+	seats := -1
+	if mine.MaxNum > 0 {
+		seats = int(mine.MaxNum) - len(keeps)
+		if seats < 0 {
+			seats = 0
+		}
+	}
+	_, members = rejectFull(fresh, members, seats)
+	users := append(keeps, rosterUsers(members)...)
+	for _, member := range mine.Members {
+		if !tool.HasItem(users, member) {
+			results = append(results, &RosterResult{User: member, State: RosterRemoved})
+		}
+	}
+	return results, users
+}
+
+// 移除的结果，空的和重复的拒绝，不是成员的为none；返回结果以及需要移除的成员
+func checkRemove(list []string, had func(string) bool) ([]*RosterResult, []string) {
+	results := make([]*RosterResult, 0, len(list))
+	users := make([]string, 0, len(list))
+	checked := make(map[string]bool, len(list))
+	for _, user := range list {
+		result := &RosterResult{User: user}
+		results = append(results, result)
+		if len(user) < 1 {
+			result.State = RosterRejected
+			result.Reason = "the user is empty"
+		} else if checked[user] {
+			result.State = RosterRejected
+			result.Reason = "the user is repeated in the list"
+		} else if had(user) {
+			result.State = RosterRemoved
+			users = append(users, user)
+		} else {
+			result.State = RosterNone
+		}
+		checked[user] = true
+	}
+	return results, users
+}
+
+// 替换时的成员列表，已有的成员没有名字和备注时保留原来的
+func replaceRoster(list []proxy.MemberInfo, old []proxy.MemberInfo) ([]*RosterResult, []proxy.MemberInfo) {
+	had := func(user string) bool {
+		return findMember(old, user) != nil
+	}
+	results, _, _ := checkRoster(list, had)
+	members := make([]proxy.MemberInfo, 0, len(list))
+	for i, result := range results {
+		if result.State == RosterRejected {
+			continue
+		}
+		item := list[i]
+		if tmp := findMember(old, item.User); tmp != nil && len(item.Name) < 1 && len(item.Remark) < 1 {
+			item = *tmp
+		}
+		members = append(members, item)
+	}
+	for _, item := range old {
+		if findMember(members, item.User) == nil {
+			results = append(results, &RosterResult{User: item.User, State: RosterRemoved})
+		}
+	}
+	return results, members
+}
+
+func findMember(list []proxy.MemberInfo, user string) *proxy.MemberInfo {
+	for i := range list {
+		if list[i].User == user {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+func pullMember(list []proxy.MemberInfo, user string) []proxy.MemberInfo {
+	arr := make([]proxy.MemberInfo, 0, len(list))
+	for _, item := range list {
+		if item.User != user {
+			arr = append(arr, item)
+		}
+	}
+	return arr
+}
+
+func pullString(list []string, value string) []string {
+	arr := make([]string, 0, len(list))
+	for _, item := range list {
+		if item != value {
+			arr = append(arr, item)
+		}
+	}
+	return arr
+}
+
+func (mine *FamilyInfo) reload() error {
+	db, err := cacheCtx.families.GetFamily(mine.UID)
+	if err != nil {
+		return err
+	}
+	mine.initInfo(db)
+	return nil
+}
+
+// AppendMembers 一次加入多个成员
+func (mine *FamilyInfo) AppendMembers(list []proxy.MemberInfo) ([]*RosterResult, error) {
+	for i := 0; i < rosterRetry; i += 1 {
+		results, _, members := checkRoster(list, mine.HadMember)
+		ok, err := cacheCtx.families.AppendFamilyMembers(mine.UID, members)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mine.Members = append(mine.Members, members...)
+			return results, nil
+		}
+		err = mine.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrRosterChanged
+}
+
+// SubtractMembers 一次移除多个成员
+func (mine *FamilyInfo) SubtractMembers(list []string) ([]*RosterResult, error) {
+	results, users := checkRemove(list, mine.HadMember)
+	if len(users) < 1 {
+		return results, nil
+	}
+	err := cacheCtx.families.SubtractFamilyMembers(mine.UID, users)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		mine.Members = pullMember(mine.Members, user)
+	}
+	return results, nil
+}
+
+// ReplaceMembers 成员替换为列表中的，不在列表中的移除
+func (mine *FamilyInfo) ReplaceMembers(operator string, list []proxy.MemberInfo) ([]*RosterResult, error) {
+	for i := 0; i < rosterRetry; i += 1 {
+		results, members := replaceRoster(list, mine.Members)
+		ok, err := cacheCtx.families.ReplaceFamilyMembers(mine.UID, operator, rosterUsers(mine.Members), members)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mine.Members = members
+			mine.Operator = operator
+			return results, nil
+		}
+		err = mine.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrRosterChanged
+}
+
+func (mine *CoterieInfo) reload() error {
+	db, err := cacheCtx.coteries.GetCoterie(mine.UID)
+	if err != nil {
+		return err
+	}
+	mine.initInfo(db)
+	return nil
+}
+
+// AppendMembers 一次加入多个成员
+func (mine *CoterieInfo) AppendMembers(list []proxy.MemberInfo) ([]*RosterResult, error) {
+	for i := 0; i < rosterRetry; i += 1 {
+		results, _, members := checkRoster(list, mine.HadMember)
+		ok, err := cacheCtx.coteries.AppendCoterieMembers(mine.UID, members)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mine.Members = append(mine.Members, members...)
+			return results, nil
+		}
+		err = mine.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrRosterChanged
+}
+
+// SubtractMembers 一次移除多个成员
+func (mine *CoterieInfo) SubtractMembers(list []string) ([]*RosterResult, error) {
+	results, users := checkRemove(list, mine.HadMember)
+	if len(users) < 1 {
+		return results, nil
+	}
+	err := cacheCtx.coteries.SubtractCoterieMembers(mine.UID, users)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		mine.Members = pullMember(mine.Members, user)
+	}
+	return results, nil
+}
+
+// ReplaceMembers 成员替换为列表中的，不在列表中的移除
+func (mine *CoterieInfo) ReplaceMembers(operator string, list []proxy.MemberInfo) ([]*RosterResult, error) {
+	for i := 0; i < rosterRetry; i += 1 {
+		results, members := replaceRoster(list, mine.Members)
+		ok, err := cacheCtx.coteries.ReplaceCoterieMembers(mine.UID, operator, rosterUsers(mine.Members), members)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mine.Members = members
+			mine.Operator = operator
+			return results, nil
+		}
+		err = mine.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrRosterChanged
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"testing"
+)
+
+type rosterCase struct {
+	name    string
+	append  []string
+	remove  []string
+	replace []string
+	// 每个结果为 user:state
+	results []string
+	members []string
+}
+
+func resultsOf(list []*RosterResult) []string {
+	arr := make([]string, 0, len(list))
+	for _, item := range list {
+		arr = append(arr, item.User+":"+item.State)
+	}
+	return arr
+}
+
+func applyRoster(roster MemberRoster, item rosterCase) ([]*RosterResult, error) {
+	if item.append != nil {
+		return roster.AppendMembers(ToMembers(item.append))
+	}
+	if item.remove != nil {
+		return roster.SubtractMembers(item.remove)
+	}
+	return roster.ReplaceMembers("admin", ToMembers(item.replace))
+}
+
+func TestTeamRoster(t *testing.T) {
+	ctx := newTestContext(t)
+	info := newTestTeam(t, ctx, "scene", 3, "a")
+	steps := []rosterCase{
+		{name: "append", append: []string{"b", "", "b", "a", "c", "d"},
+			results: []string{"b:added", ":rejected", "b:rejected", "a:member", "c:added", "d:rejected"},
+			members: []string{"a", "b", "c"}},
+		{name: "subtract", remove: []string{"a", "x", "a"},
+			results: []string{"a:removed", "x:none", "a:rejected"}, members: []string{"b", "c"}},
+		{name: "replace", replace: []string{"c", "d", "e", "f"},
+			results: []string{"c:member", "d:added", "e:added", "f:rejected", "b:removed"}, members: []string{"c", "d", "e"}},
+		{name: "replace nothing", replace: []string{},
+			results: []string{"c:removed", "d:removed", "e:removed"}, members: []string{}},
+	}
+	for _, step := range steps {
+		results, err := applyRoster(info, step)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := resultsOf(results); !sameUsers(got, step.results) {
+			t.Errorf("%s: the results = %v, want %v", step.name, got, step.results)
+		}
+		db, _ := ctx.GetTeam(info.UID)
+		if !sameUsers(info.Members, step.members) || !sameUsers(db.Members, step.members) {
+			t.Fatalf("%s: the members = %v and %v, want %v", step.name, info.Members, db.Members, step.members)
+		}
+	}
+}
+
+func TestTeamRosterWaits(t *testing.T) {
+	cases := []struct {
+		rosterCase
+		waits []string
+	}{
+		{rosterCase: rosterCase{name: "subtract promotes", remove: []string{"a"},
+			results: []string{"a:removed", "c:promoted"}, members: []string{"b", "c"}}, waits: []string{"d"}},
+		{rosterCase: rosterCase{name: "append pulls the wait", append: []string{"d"},
+			results: []string{"d:rejected"}, members: []string{"a", "b"}}, waits: []string{"c", "d"}},
+		{rosterCase: rosterCase{name: "replace pulls the wait", replace: []string{"b", "d"},
+			results: []string{"b:member", "d:added", "a:removed"}, members: []string{"b", "d"}}, waits: []string{"c"}},
+		{rosterCase: rosterCase{name: "replace promotes", replace: []string{"b"},
+			results: []string{"b:member", "a:removed", "c:promoted"}, members: []string{"b", "c"}}, waits: []string{"d"}},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			info := newTestTeam(t, ctx, "scene", 2, "a", "b")
+			for _, user := range []string{"c", "d"} {
+				if state, err := info.Join(user, "admin"); err != nil || state != SeatWaiting {
+					t.Fatalf("the state = %s, the error = %v", state, err)
+				}
+			}
+			results, err := applyRoster(info, item.rosterCase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultsOf(results); !sameUsers(got, item.results) {
+				t.Errorf("the results = %v, want %v", got, item.results)
+			}
+			db, _ := ctx.GetTeam(info.UID)
+			for _, team := range []*TeamInfo{info, db} {
+				if !sameUsers(team.Members, item.members) || !sameUsers(team.WaitUsers(), item.waits) {
+					t.Errorf("the members = %v, the waits = %v, want %v and %v", team.Members, team.WaitUsers(), item.members, item.waits)
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceMembersChanged(t *testing.T) {
+	ctx := newTestContext(t)
+	family, err := ctx.CreateFamily(&pb.ReqFamilyAdd{Name: "family", Operator: "admin",
+		Members: []*pb.IdentifyInfo{{User: "a", Name: "A"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	team := newTestTeam(t, ctx, "scene", 0, "a")
+	cases := []struct {
+		name string
+		kind string
+		uid  string
+	}{
+		{name: "family", kind: GroupFamily, uid: family.UID},
+		{name: "team", kind: GroupTeam, uid: team.UID},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			stale, err := ctx.GetRoster(item.kind, item.uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fresh, _ := ctx.GetRoster(item.kind, item.uid)
+			if _, err = fresh.AppendMembers(ToMembers([]string{"b"})); err != nil {
+				t.Fatal(err)
+			}
+			// 旧的成员列表不匹配时重新读取，其他请求加入的成员也会被替换掉
+			results, err := stale.ReplaceMembers("admin", ToMembers([]string{"a", "c"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"a:member", "c:added", "b:removed"}
+			if got := resultsOf(results); !sameUsers(got, want) {
+				t.Errorf("the results = %v, want %v", got, want)
+			}
+			db, _ := ctx.GetRoster(item.kind, item.uid)
+			if users := rosterUsersOf(db); !sameUsers(users, []string{"a", "c"}) {
+				t.Errorf("the members = %v", users)
+			}
+		})
+	}
+	db, _ := ctx.GetFamily(family.UID)
+	if member := findMember(db.Members, "a"); member == nil || member.Name != "A" {
+		t.Errorf("the name of kept member is lost: %v", member)
+	}
+}
+
+func rosterUsersOf(roster MemberRoster) []string {
+	switch info := roster.(type) {
+	case *TeamInfo:
+		return info.Members
+	case *FamilyInfo:
+		return rosterUsers(info.Members)
+	case *CoterieInfo:
+		return rosterUsers(info.Members)
+	}
+	return nil
+}
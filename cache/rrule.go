@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+// 生成重复时间时最多检查的周期数，避免错误的规则导致死循环
+const maxRulePeriods = 100000
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// 星期几，Ordinal为每月的第几个(负数为倒数第几个)，0表示每一个
+type weekdayNum struct {
+	Ordinal int
+	Day     time.Weekday
+}
+
+// RecurrenceRule RFC 5545中RRULE的子集：FREQ(DAILY/WEEKLY/MONTHLY)、INTERVAL、BYDAY、UNTIL和COUNT
+type RecurrenceRule struct {
+	Freq     string
+	Interval int
+	ByDay    []weekdayNum
+	Until    time.Time
+	Count    int
+}
+
+func parseWeekday(str string, monthly bool) (weekdayNum, error) {
+	item := weekdayNum{}
+	if len(str) < 2 {
+		return item, errors.New("the BYDAY is error: " + str)
+	}
+	name := str[len(str)-2:]
+	found := false
+	for i, day := range weekdayNames {
+		if day == name {
+			item.Day = time.Weekday(i)
+			found = true
+		}
+	}
+	if !found {
+		return item, errors.New("the BYDAY is error: " + str)
+	}
+	if len(str) > 2 {
+		if !monthly {
+			return item, errors.New("the ordinal of BYDAY only supported by MONTHLY")
+		}
+		num, err := strconv.Atoi(str[:len(str)-2])
+		if err != nil || num == 0 || num > 5 || num < -5 {
+			return item, errors.New("the BYDAY is error: " + str)
+		}
+		item.Ordinal = num
+	}
+	return item, nil
+}
+
+// 只有日期时为当天的最后一秒(本地时间)，没有Z时为本地时间
+func parseUntil(str string) (time.Time, error) {
+	if t, err := time.ParseInLocation("20060102T150405Z", str, time.UTC); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", str, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("20060102", str, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("the UNTIL is error: " + str)
+	}
+	return t.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// ParseRecurrence 解析 FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,WE;UNTIL=20261231 的格式，可以带RRULE:前缀
+func ParseRecurrence(text string) (*RecurrenceRule, error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "RRULE:")
+	if len(text) < 1 {
+		return nil, errors.New("the recurrence rule is empty")
+	}
+	rule := &RecurrenceRule{Interval: 1}
+	byDay := ""
+	for _, part := range strings.Split(text, ";") {
+		if len(part) < 1 {
+			continue
+		}
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) != 2 {
+			return nil, errors.New("the recurrence rule is error: " + part)
+		}
+		key := strings.ToUpper(pair[0])
+		value := strings.ToUpper(pair[1])
+		switch key {
+		case "FREQ":
+			if value != FreqDaily && value != FreqWeekly && value != FreqMonthly {
+				return nil, errors.New(fmt.Sprintf("the FREQ(%s) is not supported", value))
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			num, err := strconv.Atoi(value)
+			if err != nil || num < 1 {
+				return nil, errors.New("the INTERVAL should be a positive number")
+			}
+			rule.Interval = num
+		case "COUNT":
+			num, err := strconv.Atoi(value)
+			if err != nil || num < 1 {
+				return nil, errors.New("the COUNT should be a positive number")
+			}
+			rule.Count = num
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = t
+		case "BYDAY":
+			byDay = value
+		case "WKST":
+			if value != "MO" {
+				return nil, errors.New("only WKST=MO is supported")
+			}
+		default:
+			return nil, errors.New(fmt.Sprintf("the %s of recurrence rule is not supported", key))
+		}
+	}
+	if len(rule.Freq) < 1 {
+		return nil, errors.New("the FREQ of recurrence rule is empty")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("the UNTIL and COUNT can not be used together")
+	}
+	if len(byDay) > 0 {
+		for _, item := range strings.Split(byDay, ",") {
+			day, err := parseWeekday(strings.TrimSpace(item), rule.Freq == FreqMonthly)
+			if err != nil {
+				return nil, err
+			}
+			rule.ByDay = append(rule.ByDay, day)
+		}
+	}
+	return rule, nil
+}
+
+func (mine *RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + mine.Freq}
+	if mine.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(mine.Interval))
+	}
+	if len(mine.ByDay) > 0 {
+		days := make([]string, 0, len(mine.ByDay))
+		for _, item := range mine.ByDay {
+			str := weekdayNames[item.Day]
+			if item.Ordinal != 0 {
+				str = strconv.Itoa(item.Ordinal) + str
+			}
+			days = append(days, str)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if !mine.Until.IsZero() {
+		parts = append(parts, "UNTIL="+mine.Until.UTC().Format("20060102T150405Z"))
+	}
+	if mine.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(mine.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (mine *RecurrenceRule) hasDay(day time.Weekday) bool {
+	for _, item := range mine.ByDay {
+		if item.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+// 某个月中符合BYDAY的日期
+func (mine *RecurrenceRule) monthDays(year int, month time.Month, clock time.Time) []time.Time {
+	list := make([]time.Time, 0, 5)
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local).Day()
+	for _, item := range mine.ByDay {
+		days := make([]int, 0, 5)
+		for d := 1; d <= last; d += 1 {
+			if time.Date(year, month, d, 0, 0, 0, 0, time.Local).Weekday() == item.Day {
+				days = append(days, d)
+			}
+		}
+		if item.Ordinal > 0 && item.Ordinal <= len(days) {
+			days = days[item.Ordinal-1 : item.Ordinal]
+		} else if item.Ordinal < 0 && -item.Ordinal <= len(days) {
+			days = days[len(days)+item.Ordinal : len(days)+item.Ordinal+1]
+		} else if item.Ordinal != 0 {
+			days = days[:0]
+		}
+		for _, d := range days {
+			list = append(list, time.Date(year, month, d, clock.Hour(), clock.Minute(), clock.Second(), 0, time.Local))
+		}
+	}
+	return list
+}
+
+// 第period个周期内的时间，按时间排序
+func (mine *RecurrenceRule) candidates(start time.Time, period int) []time.Time {
+	step := period * mine.Interval
+	h, m, s := start.Clock()
+	list := make([]time.Time, 0, 7)
+	switch mine.Freq {
+	case FreqDaily:
+		day := time.Date(start.Year(), start.Month(), start.Day()+step, h, m, s, 0, time.Local)
+		if len(mine.ByDay) < 1 || mine.hasDay(day.Weekday()) {
+			list = append(list, day)
+		}
+	case FreqWeekly:
+		// 每周从周一开始
+		monday := start.Day() - (int(start.Weekday())+6)%7 + 7*step
+		days := mine.ByDay
+		if len(days) < 1 {
+			days = []weekdayNum{{Day: start.Weekday()}}
+		}
+		for _, item := range days {
+			offset := (int(item.Day) + 6) % 7
+			list = append(list, time.Date(start.Year(), start.Month(), monday+offset, h, m, s, 0, time.Local))
+		}
+	case FreqMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(step), 1, h, m, s, 0, time.Local)
+		if len(mine.ByDay) < 1 {
+			// 没有这一天的月份跳过
+			day := time.Date(first.Year(), first.Month(), start.Day(), h, m, s, 0, time.Local)
+			if day.Month() == first.Month() {
+				list = append(list, day)
+			}
+		} else {
+			list = append(list, mine.monthDays(first.Year(), first.Month(), start)...)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Before(list[j])
+	})
+	return list
+}
+
+// Occurrences 从start开始按照规则计算的时间中，在[from, to)之间的；COUNT从start开始计算
+func (mine *RecurrenceRule) Occurrences(start, from, to time.Time) []time.Time {
+	start = start.In(time.Local)
+	list := make([]time.Time, 0, 10)
+	num := 0
+	var last time.Time
+	for period := 0; period < maxRulePeriods; period += 1 {
+		for _, t := range mine.candidates(start, period) {
+			if t.Before(start) || !t.After(last) {
+				continue
+			}
+			if !mine.Until.IsZero() && t.After(mine.Until) {
+				return list
+			}
+			if !t.Before(to) {
+				return list
+			}
+			num += 1
+			last = t
+			if !t.Before(from) {
+				list = append(list, t)
+			}
+			if mine.Count > 0 && num >= mine.Count {
+				return list
+			}
+		}
+	}
+	return list
+}
+
+// IndexOf 时间是第几次(从0开始)，不是规则中的时间时返回-1
+func (mine *RecurrenceRule) IndexOf(start, t time.Time) int {
+	list := mine.Occurrences(start, start, t.Add(time.Second))
+	if len(list) < 1 || !list[len(list)-1].Equal(t) {
+		return -1
+	}
+	return len(list) - 1
+}
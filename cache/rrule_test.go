@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	cases := []struct {
+		text     string
+		freq     string
+		interval int
+		count    int
+		days     int
+		wantErr  bool
+	}{
+		{text: "FREQ=DAILY", freq: FreqDaily, interval: 1},
+		{text: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", freq: FreqWeekly, interval: 2, days: 2},
+		{text: "freq=monthly;byday=-1fr;count=3", freq: FreqMonthly, interval: 1, count: 3, days: 1},
+		{text: "FREQ=DAILY;UNTIL=20300101T000000Z;WKST=MO", freq: FreqDaily, interval: 1},
+		{text: "", wantErr: true},
+		{text: "INTERVAL=2", wantErr: true},
+		{text: "FREQ=YEARLY", wantErr: true},
+		{text: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{text: "FREQ=DAILY;COUNT=2;UNTIL=20300101", wantErr: true},
+		{text: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{text: "FREQ=MONTHLY;BYDAY=6MO", wantErr: true},
+		{text: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.text, func(t *testing.T) {
+			rule, err := ParseRecurrence(item.text)
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if rule.Freq != item.freq || rule.Interval != item.interval || rule.Count != item.count || len(rule.ByDay) != item.days {
+				t.Errorf("the rule = %+v", rule)
+			}
+			again, err := ParseRecurrence(rule.String())
+			if err != nil || again.String() != rule.String() {
+				t.Errorf("the rule %s can not be parsed again: %v", rule.String(), err)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2030, month, d, 9, 0, 0, 0, time.Local)
+	}
+	// 2030-01-01为周二
+	start := day(time.January, 1)
+	cases := []struct {
+		name string
+		text string
+		from time.Time
+		to   time.Time
+		want []time.Time
+	}{
+		{name: "daily count", text: "FREQ=DAILY;COUNT=3", from: start, to: day(time.February, 1),
+			want: []time.Time{day(time.January, 1), day(time.January, 2), day(time.January, 3)}},
+		{name: "count from start", text: "FREQ=DAILY;COUNT=5", from: day(time.January, 4), to: day(time.February, 1),
+			want: []time.Time{day(time.January, 4), day(time.January, 5)}},
+		{name: "interval until", text: "FREQ=DAILY;INTERVAL=2;UNTIL=20300107T235959Z", from: start, to: day(time.February, 1),
+			want: []time.Time{day(time.January, 1), day(time.January, 3), day(time.January, 5), day(time.January, 7)}},
+		{name: "weekly by day", text: "FREQ=WEEKLY;BYDAY=MO,WE", from: start, to: day(time.January, 10),
+			want: []time.Time{day(time.January, 2), day(time.January, 7), day(time.January, 9)}},
+		{name: "last friday", text: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=2", from: start, to: day(time.December, 1),
+			want: []time.Time{day(time.January, 25), day(time.February, 22)}},
+		{name: "window", text: "FREQ=DAILY", from: day(time.January, 5), to: day(time.January, 7),
+			want: []time.Time{day(time.January, 5), day(time.January, 6)}},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			rule, err := ParseRecurrence(item.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			list := rule.Occurrences(start, item.from, item.to)
+			if len(list) != len(item.want) {
+				t.Fatalf("the occurrences = %v, want %v", list, item.want)
+			}
+			for i := range list {
+				if !list[i].Equal(item.want[i]) {
+					t.Errorf("the occurrence[%d] = %v, want %v", i, list[i], item.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	start := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.Local)
+	rule, _ := ParseRecurrence("FREQ=DAILY;INTERVAL=2")
+	cases := []struct {
+		t    time.Time
+		want int
+	}{
+		{t: start, want: 0},
+		{t: start.AddDate(0, 0, 4), want: 2},
+		{t: start.AddDate(0, 0, 3), want: -1},
+		{t: start.Add(time.Hour), want: -1},
+	}
+	for _, item := range cases {
+		if num := rule.IndexOf(start, item.t); num != item.want {
+			t.Errorf("the index of %v = %d, want %d", item.t, num, item.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"github.com/micro/go-micro/v2/logger"
+	"omo.msa.assignment/config"
+	"omo.msa.assignment/proxy"
+	"time"
+)
+
+type MeetingHandler func(info *MeetingInfo)
+
+var meetingStartHandlers = make([]MeetingHandler, 0, 1)
+var meetingStopHandlers = make([]MeetingHandler, 0, 1)
+
+// 会议的时间修改后唤醒调度，重新计算下一次检查的时间
+var meetingWake = make(chan struct{}, 1)
+
+// 错过的时间超过grace时不再通知，启动调度时设置
+var meetingGrace time.Duration = 0
+
+// WatchMeetingStart 注册回调，会议到开始时间并保存状态后通知
+func WatchMeetingStart(handler MeetingHandler) {
+	if handler != nil {
+		meetingStartHandlers = append(meetingStartHandlers, handler)
+	}
+}
+
+// WatchMeetingStop 注册回调，会议到结束时间并保存状态后通知，手动关闭的会议不通知
+func WatchMeetingStop(handler MeetingHandler) {
+	if handler != nil {
+		meetingStopHandlers = append(meetingStopHandlers, handler)
+	}
+}
+
+func wakeMeetingScheduler() {
+	select {
+	case meetingWake <- struct{}{}:
+	default:
+	}
+}
+
+// 按照开始和结束时间计算会议的状态，零值的时间表示没有设置
+func (mine *MeetingInfo) expectStatus(now time.Time) MeetingStatus {
+	if mine.Status == Close {
+		return Close
+	}
+	if !mine.StopTime.IsZero() && !now.Before(mine.StopTime) {
+		return AutoStop
+	}
+	if !mine.StartTime.IsZero() && !now.Before(mine.StartTime) {
+		return Idle
+	}
+	return Pending
+}
+
+// 下一次需要修改状态的时间，没有时为零值
+func (mine *MeetingInfo) nextInstant(now time.Time) time.Time {
+	var next time.Time
+	for _, t := range []time.Time{mine.StartTime, mine.StopTime} {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// 保存按时间计算的状态，只有状态没有被其他操作修改时才成功，成功后通知开始或者结束
+func (mine *MeetingInfo) transit(now time.Time, grace time.Duration) (bool, error) {
+	from := mine.Status
+	to := mine.expectStatus(now)
+	if from == to {
+		return false, nil
+	}
+	ok, err := cacheCtx.meetings.TransitMeeting(mine.UID, uint8(from), uint8(to))
+	if err != nil || !ok {
+		return false, err
+	}
+	mine.Status = to
+	mine.UpdateTime = now
+	fresh := func(t time.Time) bool {
+		return grace < 1 || now.Sub(t) <= grace
+	}
+	if to == Idle || (to == AutoStop && from == Pending && !mine.StartTime.IsZero()) {
+		if fresh(mine.StartTime) {
+			for _, handler := range meetingStartHandlers {
+				handler(mine)
+			}
+		}
+	}
+	if to == AutoStop && fresh(mine.StopTime) {
+		for _, handler := range meetingStopHandlers {
+			handler(mine)
+		}
+	}
+	return true, nil
+}
+
+// 会议的时间修改后立即更新状态(结束后延长时间会重新开始)，并唤醒调度
+func (mine *MeetingInfo) reschedule() {
+	_, err := mine.transit(time.Now(), meetingGrace)
+	if err != nil {
+		logger.Warnf("transit the meeting(%s) failed: %s", mine.UID, err.Error())
+	}
+	wakeMeetingScheduler()
+}
+
+// ReconcileMeetings 检查所有未开始和进行中的会议，保存到时间的状态；返回修改的数量以及下一次需要检查的时间
+func (mine *cacheContext) ReconcileMeetings(conf config.MeetingConfig, now time.Time) (int, time.Time, error) {
+	var next time.Time
+	array, _, err := mine.meetings.QueryMeetings(proxy.NewQuery(true, proxy.In("status", []uint8{uint8(Pending), uint8(Idle)})))
+	if err != nil {
+		return 0, next, err
+	}
+	grace := time.Duration(conf.Grace) * time.Second
+	num := 0
+	for _, db := range array {
+		info := new(MeetingInfo)
+		info.initInfo(db)
+		done, err := info.transit(now, grace)
+		if err != nil {
+			return num, next, err
+		}
+		if done {
+			num += 1
+		}
+		if info.Status == AutoStop {
+			continue
+		}
+		t := info.nextInstant(now)
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return num, next, nil
+}
+
+// StartMeetingScheduler 启动时检查一次所有的会议，之后在最近的开始或者结束时间检查，最长间隔为interval；
+// interval为0时不启动，返回停止的函数
+func StartMeetingScheduler(conf config.MeetingConfig) func() {
+	if conf.Interval < 1 {
+		return func() {}
+	}
+	meetingGrace = time.Duration(conf.Grace) * time.Second
+	interval := time.Duration(conf.Interval) * time.Second
+	stop := make(chan struct{})
+	reconcile := func() time.Duration {
+		now := time.Now()
+		count, err := cacheCtx.MaterializeMeetings(horizonOf(conf), now)
+		if err != nil {
+			logger.Warnf("materialize the recurring meetings failed: %s", err.Error())
+		}
+		if count > 0 {
+			logger.Infof("materialize the recurring meetings: created = %d", count)
+		}
+		num, next, err := cacheCtx.ReconcileMeetings(conf, now)
+		if err != nil {
+			logger.Warnf("reconcile the meetings failed: %s", err.Error())
+		}
+		if num > 0 {
+			logger.Infof("reconcile the meetings: transited = %d", num)
+		}
+		if !next.IsZero() && next.Sub(now) < interval {
+			return next.Sub(now)
+		}
+		return interval
+	}
+	go func() {
+		timer := time.NewTimer(reconcile())
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+			case <-meetingWake:
+				if !timer.Stop() {
+					<-timer.C
+				}
+			case <-stop:
+				return
+			}
+			timer.Reset(reconcile())
+		}
+	}()
+	return func() {
+		close(stop)
+	}
+}
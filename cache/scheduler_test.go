@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"omo.msa.assignment/config"
+	"testing"
+	"time"
+)
+
+func TestReconcileMeetings(t *testing.T) {
+	now := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		start   time.Duration
+		stop    time.Duration
+		close   bool
+		grace   int64
+		status  MeetingStatus
+		started int
+		stopped int
+		next    time.Duration
+	}{
+		{name: "pending", start: time.Hour, stop: 2 * time.Hour, status: Pending, next: time.Hour},
+		{name: "started", start: -time.Hour, stop: time.Hour, status: Idle, started: 1, next: time.Hour},
+		// 错过了开始时间时同时通知开始和结束
+		{name: "stopped", start: -2 * time.Hour, stop: -time.Hour, status: AutoStop, started: 1, stopped: 1},
+		{name: "missed", start: -2 * time.Hour, stop: -time.Hour, grace: 600, status: AutoStop},
+		{name: "missed start", start: -2 * time.Hour, stop: time.Hour, grace: 600, status: Idle, next: time.Hour},
+		{name: "closed", start: -time.Hour, stop: time.Hour, close: true, status: Close},
+	}
+	defer func(start, stop []MeetingHandler) {
+		meetingStartHandlers = start
+		meetingStopHandlers = stop
+	}(meetingStartHandlers, meetingStopHandlers)
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			started, stopped := 0, 0
+			meetingStartHandlers = []MeetingHandler{func(info *MeetingInfo) { started += 1 }}
+			meetingStopHandlers = []MeetingHandler{func(info *MeetingInfo) { stopped += 1 }}
+			info := newTestMeeting(t, ctx, "scene", "", "", now)
+			err := info.updateDate(now.Add(item.start).Unix(), now.Add(item.stop).Unix(), "admin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.close {
+				if err = info.Close("admin"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			conf := config.MeetingConfig{Grace: item.grace}
+			num, next, err := ctx.ReconcileMeetings(conf, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 0
+			if item.status != Pending && item.status != Close {
+				want = 1
+			}
+			if num != want {
+				t.Errorf("the transited = %d, want %d", num, want)
+			}
+			if (item.next == 0 && !next.IsZero()) || (item.next != 0 && !next.Equal(now.Add(item.next))) {
+				t.Errorf("the next = %v, want %v later", next, item.next)
+			}
+			if started != item.started || stopped != item.stopped {
+				t.Errorf("the started = %d, the stopped = %d, want %d and %d", started, stopped, item.started, item.stopped)
+			}
+			// 再次检查时不会重复修改和通知
+			num, _, err = ctx.ReconcileMeetings(conf, now)
+			if err != nil || num != 0 || started != item.started || stopped != item.stopped {
+				t.Errorf("reconcile again: the transited = %d, the error = %v", num, err)
+			}
+			db, _ := ctx.GetMeeting(info.UID)
+			if db.Status != item.status {
+				t.Errorf("the status = %d, want %d", db.Status, item.status)
+			}
+		})
+	}
+}
+
+func TestTransitMeeting(t *testing.T) {
+	now := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.Local)
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			if err := InitDataWith(backend.stores(t)); err != nil {
+				t.Fatal(err)
+			}
+			info := newTestMeeting(t, cacheCtx, "scene", "", "", now)
+			stale, _ := cacheCtx.GetMeeting(info.UID)
+			steps := []struct {
+				name   string
+				meet   *MeetingInfo
+				now    time.Time
+				want   bool
+				status MeetingStatus
+			}{
+				{name: "not started", meet: info, now: now.Add(-time.Minute), status: Pending},
+				{name: "started", meet: info, now: now, want: true, status: Idle},
+				// 状态已经被修改时以数据库中的为准
+				{name: "stale", meet: stale, now: now, status: Idle},
+			}
+			for _, step := range steps {
+				ok, err := step.meet.transit(step.now, 0)
+				if err != nil || ok != step.want {
+					t.Errorf("%s: the result = %v, the error = %v, want %v", step.name, ok, err, step.want)
+				}
+				db, _ := cacheCtx.GetMeeting(info.UID)
+				if db.Status != step.status {
+					t.Errorf("%s: the status = %d, want %d", step.name, db.Status, step.status)
+				}
+			}
+			if err := info.Close("admin"); err != nil {
+				t.Fatal(err)
+			}
+			num, _, err := cacheCtx.ReconcileMeetings(config.MeetingConfig{}, now.Add(time.Hour))
+			if err != nil || num != 0 {
+				t.Errorf("the closed meeting is transited = %d, the error = %v", num, err)
+			}
+		})
+	}
+}
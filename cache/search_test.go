@@ -0,0 +1,80 @@
+package cache
+
+import (
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"omo.msa.assignment/proxy"
+	"reflect"
+	"testing"
+)
+
+func TestSearchTasks(t *testing.T) {
+	ctx := newTestContext(t)
+	tasks := []*pb.ReqTaskAdd{
+		{Name: "Clean", Remark: "clean the room", Owner: "scene", Type: 1, Tags: []string{"a", "b"}, Regions: []string{"r1"},
+			Duration: &pb.DateInfo{Begin: "2030-01-01", End: "2030-01-10"}},
+		{Name: "Paint", Remark: "paint the ROOM wall", Owner: "scene", Type: 2, Tags: []string{"a"},
+			Duration: &pb.DateInfo{Begin: "2030-02-01"}},
+		{Name: "Review", Owner: "other", Type: 1, Tags: []string{"a", "b"}, Duration: &pb.DateInfo{}},
+		{Name: "Busy", Owner: "scene", Type: 1, Duration: &pb.DateInfo{Begin: "2029-12-01", End: "2029-12-31"}},
+	}
+	for _, item := range tasks {
+		item.Operator = "admin"
+		info, err := ctx.CreateTask(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name == "Busy" {
+			if err = info.UpdateStatus(TaskStatusBusy, "admin", ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	cases := []struct {
+		name    string
+		filter  proxy.TaskFilter
+		page    uint32
+		number  uint32
+		want    []string
+		total   uint32
+		pages   uint32
+		wantErr bool
+	}{
+		{name: "owner", filter: proxy.TaskFilter{Owner: "scene"}, want: []string{"Busy", "Clean", "Paint"}, total: 3},
+		{name: "keyword", filter: proxy.TaskFilter{Owner: "scene", Keyword: "Room"}, want: []string{"Clean", "Paint"}, total: 2},
+		{name: "all tags", filter: proxy.TaskFilter{Tags: []string{"a", "b"}}, want: []string{"Clean", "Review"}, total: 2},
+		{name: "region", filter: proxy.TaskFilter{Region: "r1"}, want: []string{"Clean"}, total: 1},
+		{name: "types", filter: proxy.TaskFilter{Types: []uint8{2}}, want: []string{"Paint"}, total: 1},
+		{name: "states", filter: proxy.TaskFilter{States: []uint8{uint8(TaskStatusBusy)}}, want: []string{"Busy"}, total: 1},
+		{name: "date range", filter: proxy.TaskFilter{Owner: "scene", Begin: "2030-01-05", End: "2030-01-20"},
+			want: []string{"Clean"}, total: 1},
+		{name: "no end", filter: proxy.TaskFilter{Owner: "scene", Begin: "2030-03-01"}, want: []string{"Paint"}, total: 1},
+		{name: "page", filter: proxy.TaskFilter{Owner: "scene"}, page: 2, number: 2, want: []string{"Paint"}, total: 3, pages: 2},
+		{name: "desc", filter: proxy.TaskFilter{Owner: "scene", Desc: true}, page: 1, number: 2,
+			want: []string{"Paint", "Clean"}, total: 3, pages: 2},
+		{name: "bad sort", filter: proxy.TaskFilter{Sort: "unknown"}, wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			if len(item.filter.Sort) < 1 {
+				item.filter.Sort = "name"
+			}
+			total, pages, list, err := ctx.SearchTasks(&item.filter, item.page, item.number)
+			if (err != nil) != item.wantErr {
+				t.Fatalf("the error = %v, want error = %v", err, item.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			names := make([]string, 0, len(list))
+			for _, info := range list {
+				names = append(names, info.Name)
+			}
+			if !reflect.DeepEqual(names, item.want) {
+				t.Errorf("the tasks = %v, want %v", names, item.want)
+			}
+			if total != item.total || (item.page > 0 && pages != item.pages) {
+				t.Errorf("the total = %d, the pages = %d, want %d and %d", total, pages, item.total, item.pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"time"
+)
+
+const (
+	// 加入了小组
+	SeatJoined = "joined"
+	// 已经是成员
+	SeatMember = "member"
+	// 满员，加入了候补
+	SeatWaiting = "waiting"
+	// 离开了小组或者候补
+	SeatLeft = "left"
+	// 不是成员也不在候补中
+	SeatNone = "none"
+)
+
+var ErrTeamFull = errors.New("the team is full")
+
+// Seats 剩余的名额，没有限制(MaxNum为0)时为-1，超员时为0
+func (mine *TeamInfo) Seats() int {
+	if mine.MaxNum < 1 {
+		return -1
+	}
+	num := int(mine.MaxNum) - len(mine.Members)
+	if num < 0 {
+		return 0
+	}
+	return num
+}
+
+// Overflow 减少名额后超出的成员数量，超员时已有的成员不受影响，只是不能再加入
+func (mine *TeamInfo) Overflow() int {
+	if mine.MaxNum < 1 || len(mine.Members) <= int(mine.MaxNum) {
+		return 0
+	}
+	return len(mine.Members) - int(mine.MaxNum)
+}
+
+func (mine *TeamInfo) HadWait(member string) bool {
+	for _, item := range mine.Waits {
+		if item.User == member {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitUsers 候补的成员，最早的在前
+func (mine *TeamInfo) WaitUsers() []string {
+	list := make([]string, 0, len(mine.Waits))
+	for _, item := range mine.Waits {
+		list = append(list, item.User)
+	}
+	return list
+}
+
+func (mine *TeamInfo) reload() error {
+	db, err := cacheCtx.teams.GetTeam(mine.UID)
+	if err != nil {
+		return err
+	}
+	if db == nil {
+		return errors.New("not found the team of " + mine.UID)
+	}
+	mine.initInfo(db)
+	return nil
+}
+
+// Join 有名额时加入小组，满员时加入候补，返回SeatJoined、SeatMember或者SeatWaiting
+func (mine *TeamInfo) Join(member, operator string) (string, error) {
+	if len(member) < 1 {
+		return "", errors.New("the member uid is empty")
+	}
+	if mine.HadMember(member) {
+		return SeatMember, nil
+	}
+	err := mine.AppendMember(member)
+	if err == nil {
+		return SeatJoined, nil
+	}
+	if err != ErrTeamFull {
+		return "", err
+	}
+	if mine.HadWait(member) {
+		return SeatWaiting, nil
+	}
+	wait := proxy.WaitInfo{User: member, CreatedTime: time.Now(), Operator: operator}
+	err = cacheCtx.teams.AppendTeamWait(mine.UID, wait)
+	if err != nil {
+		return "", err
+	}
+	mine.Waits = append(mine.Waits, wait)
+	return SeatWaiting, nil
+}
+
+// Leave 离开小组或者候补，返回因此从候补中加入小组的成员
+func (mine *TeamInfo) Leave(member string) ([]string, error) {
+	if mine.HadMember(member) {
+		err := mine.subtractMember(member)
+		if err != nil {
+			return nil, err
+		}
+		return mine.promoteWaits()
+	}
+	if mine.HadWait(member) {
+		err := cacheCtx.teams.SubtractTeamWait(mine.UID, member)
+		if err != nil {
+			return nil, err
+		}
+		mine.Waits = pullWait(mine.Waits, member)
+	}
+	return make([]string, 0, 1), nil
+}
+
+// UpdateLimit 修改名额，增加时候补的成员按照先后加入；减少到少于已有的成员时不移除成员，空出名额之前不能再加入
+func (mine *TeamInfo) UpdateLimit(operator string, limit uint16) ([]string, error) {
+	err := cacheCtx.teams.UpdateTeamLimit(mine.UID, operator, limit)
+	if err != nil {
+		return nil, err
+	}
+	mine.MaxNum = limit
+	mine.Operator = operator
+	return mine.promoteWaits()
+}
+
+// 有空出的名额时按照先后把候补的成员加入小组
+func (mine *TeamInfo) promoteWaits() ([]string, error) {
+	list := make([]string, 0, 2)
+	// 每次失败都会重新读取，候补的数量有限，不会一直循环
+	for tries := len(mine.Waits) + 1; tries > 0 && len(mine.Waits) > 0 && mine.Seats() != 0; tries -= 1 {
+		user := mine.Waits[0].User
+		if mine.HadMember(user) {
+			err := cacheCtx.teams.SubtractTeamWait(mine.UID, user)
+			if err != nil {
+				return list, err
+			}
+			mine.Waits = pullWait(mine.Waits, user)
+			continue
+		}
+		ok, err := cacheCtx.teams.JoinTeam(mine.UID, user, mine.MaxNum)
+		if err != nil {
+			return list, err
+		}
+		if ok {
+			mine.Members = append(mine.Members, user)
+			mine.Waits = pullWait(mine.Waits, user)
+			list = append(list, user)
+			continue
+		}
+		err = mine.reload()
+		if err != nil {
+			return list, err
+		}
+	}
+	return list, nil
+}
+
+func pullWait(array []proxy.WaitInfo, user string) []proxy.WaitInfo {
+	list := make([]proxy.WaitInfo, 0, len(array))
+	for _, item := range array {
+		if item.User != user {
+			list = append(list, item)
+		}
+	}
+	return list
+}
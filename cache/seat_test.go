@@ -0,0 +1,118 @@
+package cache
+
+import (
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"reflect"
+	"testing"
+)
+
+func newTestTeam(t *testing.T, ctx *cacheContext, owner string, limit uint32, members ...string) *TeamInfo {
+	t.Helper()
+	info, err := ctx.CreateTeam(&pb.ReqTeamAdd{Name: "team", Owner: owner, Operator: "admin", Limit: limit})
+	if err != nil {
+		t.Fatalf("create the team failed: %v", err)
+	}
+	if len(members) > 0 {
+		_, err = info.AppendMembers(ToMembers(members))
+		if err != nil {
+			t.Fatalf("append the members failed: %v", err)
+		}
+	}
+	return info
+}
+
+func TestTeamSeats(t *testing.T) {
+	ctx := newTestContext(t)
+	info := newTestTeam(t, ctx, "scene", 2)
+	steps := []struct {
+		name     string
+		join     string
+		leave    string
+		limit    uint16
+		state    string
+		promoted []string
+		members  []string
+		waits    []string
+	}{
+		{name: "join a", join: "a", state: SeatJoined, members: []string{"a"}},
+		{name: "join b", join: "b", state: SeatJoined, members: []string{"a", "b"}},
+		{name: "join c", join: "c", state: SeatWaiting, members: []string{"a", "b"}, waits: []string{"c"}},
+		{name: "join d", join: "d", state: SeatWaiting, members: []string{"a", "b"}, waits: []string{"c", "d"}},
+		{name: "join c again", join: "c", state: SeatWaiting, members: []string{"a", "b"}, waits: []string{"c", "d"}},
+		{name: "join a again", join: "a", state: SeatMember, members: []string{"a", "b"}, waits: []string{"c", "d"}},
+		{name: "leave a", leave: "a", promoted: []string{"c"}, members: []string{"b", "c"}, waits: []string{"d"}},
+		{name: "leave d", leave: "d", members: []string{"b", "c"}},
+		{name: "join e", join: "e", state: SeatWaiting, members: []string{"b", "c"}, waits: []string{"e"}},
+		{name: "limit 1", limit: 1, members: []string{"b", "c"}, waits: []string{"e"}},
+		{name: "leave b", leave: "b", members: []string{"c"}, waits: []string{"e"}},
+		{name: "limit 3", limit: 3, promoted: []string{"e"}, members: []string{"c", "e"}},
+		{name: "leave none", leave: "x", members: []string{"c", "e"}},
+	}
+	for _, step := range steps {
+		var promoted []string
+		var err error
+		if len(step.join) > 0 {
+			var state string
+			state, err = info.Join(step.join, "admin")
+			if err == nil && state != step.state {
+				t.Fatalf("%s: the state = %s, want %s", step.name, state, step.state)
+			}
+		} else if len(step.leave) > 0 {
+			promoted, err = info.Leave(step.leave)
+		} else {
+			promoted, err = info.UpdateLimit("admin", step.limit)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if len(promoted) > 0 || len(step.promoted) > 0 {
+			if !reflect.DeepEqual(promoted, step.promoted) {
+				t.Errorf("%s: the promoted = %v, want %v", step.name, promoted, step.promoted)
+			}
+		}
+		db, err := ctx.GetTeam(info.UID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, team := range []*TeamInfo{info, db} {
+			if !sameUsers(team.Members, step.members) || !sameUsers(team.WaitUsers(), step.waits) {
+				t.Fatalf("%s: the members = %v, the waits = %v, want %v and %v", step.name,
+					team.Members, team.WaitUsers(), step.members, step.waits)
+			}
+		}
+	}
+}
+
+func TestTeamJoinChanged(t *testing.T) {
+	ctx := newTestContext(t)
+	info := newTestTeam(t, ctx, "scene", 1)
+	stale, err := ctx.GetTeam(info.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, err := info.Join("a", "admin"); err != nil || state != SeatJoined {
+		t.Fatalf("the state = %s, the error = %v", state, err)
+	}
+	// 没有看到其他请求加入的成员，数据库中的名额检查让它进入候补
+	state, err := stale.Join("b", "admin")
+	if err != nil || state != SeatWaiting {
+		t.Fatalf("the state = %s, the error = %v", state, err)
+	}
+	db, _ := ctx.GetTeam(info.UID)
+	if !sameUsers(db.Members, []string{"a"}) || !sameUsers(db.WaitUsers(), []string{"b"}) {
+		t.Errorf("the members = %v, the waits = %v", db.Members, db.WaitUsers())
+	}
+}
+
+// 顺序相同的两个列表，nil和空的相同
+func sameUsers(list, want []string) bool {
+	if len(list) != len(want) {
+		return false
+	}
+	for i := range list {
+		if list[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"errors"
+	"github.com/micro/go-micro/v2/logger"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+)
+
+// 分类和题目共用题目的序号
+func sequenceOf(table string) string {
+	if table == nosql.TableCategory {
+		return nosql.TableQuestion
+	}
+	return table
+}
+
+// 预留连续的count个id，返回第一个，用于批量创建
+func (mine *cacheContext) reserveIDs(table string, count uint64) (uint64, error) {
+	return mine.sequences.ReserveSequence(sequenceOf(table), count)
+}
+
+// 集合中最大的id，包括已删除的数据
+func (mine *cacheContext) maxID(table string) (uint64, error) {
+	query := proxy.NewQuery(false)
+	query.Sort = "id"
+	query.Desc = true
+	query.Limit = 1
+	var id uint64
+	var err error
+	switch table {
+	case nosql.TableTask:
+		id, err = firstID(mine.tasks.QueryTasks(query))
+	case nosql.TableAgent:
+		id, err = firstID(mine.agents.QueryAgents(query))
+	case nosql.TableTeam:
+		id, err = firstID(mine.teams.QueryTeams(query))
+	case nosql.TableFamily:
+		id, err = firstID(mine.families.QueryFamilies(query))
+	case nosql.TableCoterie:
+		id, err = firstID(mine.coteries.QueryCoteries(query))
+	case nosql.TableApply:
+		id, err = firstID(mine.applies.QueryApplies(query))
+	case nosql.TableInvitation:
+		id, err = firstID(mine.invitations.QueryInvitations(query))
+	case nosql.TableMeeting:
+		id, err = firstID(mine.meetings.QueryMeetings(query))
+	case nosql.TableQuestion:
+		id, err = firstID(mine.questions.QueryQuestions(query))
+	case nosql.TableCategory:
+		id, err = firstID(mine.categories.QueryCategories(query))
+	case nosql.TableRecord:
+		id, err = firstID(mine.quizzes.QueryQuizRecords(query))
+	default:
+		err = errors.New("the table has no sequence: " + table)
+	}
+	return id, err
+}
+
+func firstID[T any](list []*T, _ int64, err error) (uint64, error) {
+	if err != nil || len(list) < 1 {
+		return 0, err
+	}
+	return idOf(list[0]), nil
+}
+
+func idOf(doc interface{}) uint64 {
+	switch item := doc.(type) {
+	case *nosql.Task:
+		return item.ID
+	case *nosql.Agent:
+		return item.ID
+	case *nosql.Team:
+		return item.ID
+	case *nosql.Family:
+		return item.ID
+	case *nosql.Coterie:
+		return item.ID
+	case *nosql.Apply:
+		return item.ID
+	case *nosql.Invitation:
+		return item.ID
+	case *nosql.Meeting:
+		return item.ID
+	case *nosql.Question:
+		return item.ID
+	case *nosql.Category:
+		return item.ID
+	case *nosql.QuizRecord:
+		return item.ID
+	}
+	return 0
+}
+
+// 启动和恢复备份后检查序号，小于集合中最大的id时修改为最大的id，避免分配重复的id
+func (mine *cacheContext) checkSequences() error {
+	maxes := make(map[string]uint64, len(nosql.BackupTables))
+	for _, table := range nosql.BackupTables {
+		if table == nosql.TableSequence {
+			continue
+		}
+		id, err := mine.maxID(table)
+		if err != nil {
+			return err
+		}
+		name := sequenceOf(table)
+		if id > maxes[name] {
+			maxes[name] = id
+		}
+	}
+	for _, table := range nosql.BackupTables {
+		max, ok := maxes[table]
+		if !ok || max < 1 {
+			continue
+		}
+		repaired, err := mine.sequences.RepairSequence(table, max)
+		if err != nil {
+			return err
+		}
+		if repaired {
+			logger.Warnf("the sequence of %s is repaired to %d", table, max)
+		}
+	}
+	return nil
+}
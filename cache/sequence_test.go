@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy/memory"
+	"omo.msa.assignment/proxy/nosql"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSequenceStore(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			store := backend.stores(t).Sequence
+			var wait sync.WaitGroup
+			var lock sync.Mutex
+			ids := make(map[uint64]bool, 20)
+			for i := 0; i < 20; i++ {
+				wait.Add(1)
+				go func() {
+					defer wait.Done()
+					id, err := store.GetSequenceNext("items")
+					lock.Lock()
+					defer lock.Unlock()
+					if err != nil || ids[id] || id < 1 || id > 20 {
+						t.Errorf("the id = %d, the error = %v", id, err)
+					}
+					ids[id] = true
+				}()
+			}
+			wait.Wait()
+			steps := []struct {
+				name    string
+				run     func() (uint64, error)
+				want    uint64
+				wantErr bool
+			}{
+				{name: "reserve", run: func() (uint64, error) { return store.ReserveSequence("items", 5) }, want: 21},
+				{name: "reserve nothing", run: func() (uint64, error) { return store.ReserveSequence("items", 0) }, wantErr: true},
+				{name: "next after reserve", run: func() (uint64, error) { return store.GetSequenceNext("items") }, want: 26},
+				{name: "repair smaller", run: func() (uint64, error) { return repairOf(store.RepairSequence("items", 10)) }, want: 0},
+				{name: "repair larger", run: func() (uint64, error) { return repairOf(store.RepairSequence("items", 40)) }, want: 1},
+				{name: "next after repair", run: func() (uint64, error) { return store.GetSequenceNext("items") }, want: 41},
+				{name: "repair new", run: func() (uint64, error) { return repairOf(store.RepairSequence("others", 3)) }, want: 1},
+				{name: "next of new", run: func() (uint64, error) { return store.GetSequenceNext("others") }, want: 4},
+			}
+			for _, step := range steps {
+				num, err := step.run()
+				if (err != nil) != step.wantErr {
+					t.Fatalf("%s: the error = %v, want error = %v", step.name, err, step.wantErr)
+				}
+				if num != step.want {
+					t.Errorf("%s: the result = %d, want %d", step.name, num, step.want)
+				}
+			}
+		})
+	}
+}
+
+func repairOf(repaired bool, err error) (uint64, error) {
+	if repaired {
+		return 1, err
+	}
+	return 0, err
+}
+
+func TestCheckSequences(t *testing.T) {
+	stores := memory.NewStores()
+	now := time.Now()
+	docs := []func() error{
+		func() error {
+			return stores.Team.CreateTeam(&nosql.Team{UID: primitive.NewObjectID(), ID: 5, CreatedTime: now})
+		},
+		func() error {
+			return stores.Question.CreateQuestion(&nosql.Question{UID: primitive.NewObjectID(), ID: 3, CreatedTime: now})
+		},
+		// 分类和题目共用题目的序号
+		func() error {
+			return stores.Category.CreateCategory(&nosql.Category{UID: primitive.NewObjectID(), ID: 7, CreatedTime: now})
+		},
+	}
+	for _, create := range docs {
+		if err := create(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := stores.Sequence.ReserveSequence(nosql.TableTask, 9); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitDataWith(stores); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		table string
+		want  uint64
+	}{
+		{table: nosql.TableTeam, want: 6},
+		{table: nosql.TableQuestion, want: 8},
+		{table: nosql.TableTask, want: 10},
+		{table: nosql.TableMeeting, want: 1},
+	}
+	for _, item := range cases {
+		t.Run(item.table, func(t *testing.T) {
+			id, err := cacheCtx.nextID(item.table)
+			if err != nil || id != item.want {
+				t.Errorf("the next id = %d, the error = %v, want %d", id, err, item.want)
+			}
+		})
+	}
+}
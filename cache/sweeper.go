@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"github.com/micro/go-micro/v2/logger"
+	"omo.msa.assignment/config"
+	"omo.msa.assignment/proxy"
+	"time"
+)
+
+// 定时检查时记录的审核人
+const applySweeper = "sweeper"
+
+// 每次从数据库读取的待审核申请的数量
+var applySweepPage int64 = 200
+
+type ApplyRemindHandler func(info *ApplyInfo)
+
+var applyRemindHandlers = make([]ApplyRemindHandler, 0, 1)
+
+// WatchApplyRemind 注册回调，申请待审核的时间超过提醒时长时通知一次
+func WatchApplyRemind(handler ApplyRemindHandler) {
+	if handler != nil {
+		applyRemindHandlers = append(applyRemindHandlers, handler)
+	}
+}
+
+type applyRule struct {
+	expire time.Duration
+	remind time.Duration
+}
+
+// 场景的配置为0时使用默认值，小于0时表示该场景不处理
+func applyRules(conf config.ApplyConfig) (applyRule, map[string]applyRule) {
+	base := applyRule{expire: time.Duration(conf.Expire) * time.Second, remind: time.Duration(conf.Remind) * time.Second}
+	scenes := make(map[string]applyRule, len(conf.Scenes))
+	for _, item := range conf.Scenes {
+		rule := base
+		if item.Expire != 0 {
+			rule.expire = time.Duration(item.Expire) * time.Second
+		}
+		if item.Remind != 0 {
+			rule.remind = time.Duration(item.Remind) * time.Second
+		}
+		scenes[item.Scene] = rule
+	}
+	return base, scenes
+}
+
+// 最短的时长，待审核时间比它短的申请不需要检查
+func minApplyDuration(base applyRule, scenes map[string]applyRule) time.Duration {
+	var min time.Duration = 0
+	check := func(t time.Duration) {
+		if t > 0 && (min == 0 || t < min) {
+			min = t
+		}
+	}
+	check(base.expire)
+	check(base.remind)
+	for _, rule := range scenes {
+		check(rule.expire)
+		check(rule.remind)
+	}
+	return min
+}
+
+func expiredStatus(conf config.ApplyConfig) uint8 {
+	if conf.Status == ApplyStatusStay {
+		return ApplyStatusStay
+	}
+	return ApplyStatusExpired
+}
+
+// SweepApplies 检查一次待审核的申请，超过过期时长的修改状态，超过提醒时长的通知一次；返回过期和提醒的数量
+func (mine *cacheContext) SweepApplies(conf config.ApplyConfig, now time.Time) (int, int, error) {
+	base, scenes := applyRules(conf)
+	min := minApplyDuration(base, scenes)
+	if min < 1 {
+		return 0, 0, nil
+	}
+	// 按uid的顺序分页读取，过期的申请修改状态后不会影响后面的页
+	query := proxy.NewQuery(true, proxy.Equal("status", uint8(ApplyStatusPending)), proxy.Less("submit", now.Add(-min)))
+	query.Limit = applySweepPage
+	status := expiredStatus(conf)
+	expired := 0
+	reminded := 0
+	for {
+		array, _, err := mine.applies.QueryApplies(query)
+		if err != nil {
+			return expired, reminded, err
+		}
+		for _, db := range array {
+			rule, ok := scenes[db.Scene]
+			if !ok {
+				rule = base
+			}
+			submit := db.SubmitTime
+			if submit.IsZero() {
+				submit = db.CreatedTime
+			}
+			age := now.Sub(submit)
+			if rule.expire > 0 && age >= rule.expire {
+				done, err := mine.applies.DecideApply(db.UID.Hex(), applySweeper, "expired", []uint8{ApplyStatusPending}, status)
+				if err != nil {
+					return expired, reminded, err
+				}
+				if done {
+					expired += 1
+				}
+				continue
+			}
+			if rule.remind > 0 && age >= rule.remind && db.RemindTime.IsZero() {
+				done, err := mine.applies.RemindApply(db.UID.Hex(), now)
+				if err != nil {
+					return expired, reminded, err
+				}
+				if !done {
+					continue
+				}
+				reminded += 1
+				db.RemindTime = now
+				info := new(ApplyInfo)
+				info.initInfo(db)
+				for _, handler := range applyRemindHandlers {
+					handler(info)
+				}
+			}
+		}
+		if int64(len(array)) < query.Limit {
+			break
+		}
+		query.Cursor = array[len(array)-1].UID.Hex()
+	}
+	return expired, reminded, nil
+}
+
+// StartApplySweeper 按照配置的间隔在后台检查申请，interval为0时不启动；返回停止的函数
+func StartApplySweeper(conf config.ApplyConfig) func() {
+	if conf.Interval < 1 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	sweep := func() {
+		expired, reminded, err := cacheCtx.SweepApplies(conf, time.Now())
+		if err != nil {
+			logger.Warnf("sweep the applies failed: %s", err.Error())
+		}
+		if expired > 0 || reminded > 0 {
+			logger.Infof("sweep the applies: expired = %d, reminded = %d", expired, reminded)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(conf.Interval) * time.Second)
+		defer ticker.Stop()
+		sweep()
+		for {
+			select {
+			case <-ticker.C:
+				sweep()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+	}
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"omo.msa.assignment/config"
+	"testing"
+	"time"
+)
+
+func TestSweepApplies(t *testing.T) {
+	conf := config.ApplyConfig{Expire: 3600, Remind: 600, Scenes: []config.ApplySceneConfig{
+		{Scene: "quick", Expire: 60},
+		{Scene: "never", Expire: -1, Remind: -1},
+	}}
+	type sweep struct {
+		after    time.Duration
+		expired  int
+		reminded int
+	}
+	cases := []struct {
+		name   string
+		scene  string
+		stay   bool
+		sweeps []sweep
+		status uint8
+	}{
+		{name: "pending", scene: "scene", sweeps: []sweep{{after: time.Minute}}, status: ApplyStatusPending},
+		// 提醒只通知一次
+		{name: "remind", scene: "scene", sweeps: []sweep{{after: 11 * time.Minute, reminded: 1}, {after: 20 * time.Minute}},
+			status: ApplyStatusPending},
+		{name: "expire", scene: "scene", sweeps: []sweep{{after: 11 * time.Minute, reminded: 1}, {after: 2 * time.Hour, expired: 1},
+			{after: 3 * time.Hour}}, status: ApplyStatusExpired},
+		{name: "stay", scene: "scene", stay: true, sweeps: []sweep{{after: 2 * time.Hour, expired: 1}}, status: ApplyStatusStay},
+		{name: "scene expire", scene: "quick", sweeps: []sweep{{after: 2 * time.Minute, expired: 1}}, status: ApplyStatusExpired},
+		{name: "scene never", scene: "never", sweeps: []sweep{{after: 2 * time.Hour}}, status: ApplyStatusPending},
+	}
+	defer func(handlers []ApplyRemindHandler) {
+		applyRemindHandlers = handlers
+	}(applyRemindHandlers)
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			ctx := newTestContext(t)
+			notified := 0
+			applyRemindHandlers = []ApplyRemindHandler{func(info *ApplyInfo) { notified += 1 }}
+			info, err := ctx.CreateApply("admin", item.scene, "group", "user", "", "", ApplyTypeOther)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule := conf
+			if item.stay {
+				rule.Status = ApplyStatusStay
+			}
+			reminded := 0
+			for _, step := range item.sweeps {
+				expired, num, err := ctx.SweepApplies(rule, info.SubmitTime.Add(step.after))
+				if err != nil || expired != step.expired || num != step.reminded {
+					t.Errorf("after %v: the expired = %d, the reminded = %d, the error = %v, want %d and %d",
+						step.after, expired, num, err, step.expired, step.reminded)
+				}
+				reminded += step.reminded
+			}
+			if notified != reminded {
+				t.Errorf("the notified = %d, want %d", notified, reminded)
+			}
+			db, _ := ctx.GetApply(info.UID)
+			if db.Status != item.status {
+				t.Errorf("the status = %d, want %d", db.Status, item.status)
+			}
+			if db.Status != ApplyStatusPending && db.Decider != applySweeper {
+				t.Errorf("the decider = %s, want %s", db.Decider, applySweeper)
+			}
+		})
+	}
+}
+
+func TestSweepAppliesPages(t *testing.T) {
+	defer func(page int64) {
+		applySweepPage = page
+	}(applySweepPage)
+	applySweepPage = 2
+	conf := config.ApplyConfig{Expire: 60}
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			if err := InitDataWith(backend.stores(t)); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i += 1 {
+				if _, err := cacheCtx.CreateApply("admin", "scene", "group", "user", "", "", ApplyTypeOther); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// 每一页的申请过期后后面的页仍然可以读取
+			expired, _, err := cacheCtx.SweepApplies(conf, time.Now().Add(time.Hour))
+			if err != nil || expired != 5 {
+				t.Errorf("the expired = %d, the error = %v, want 5", expired, err)
+			}
+		})
+	}
+}
@@ -33,13 +33,22 @@ type TaskInfo struct {
 	Tags      []string
 	Assets    []string
 	Records   []proxy.RecordInfo
+	Histories []proxy.StatusHistory
 }
 
 func (mine *cacheContext) CreateTask(info *pb.ReqTaskAdd) (*TaskInfo, error) {
+	err := mine.checkPreTasks(info.Owner, "", info.Pretasks)
+	if err != nil {
+		return nil, err
+	}
+	id, err := mine.nextID(nosql.TableTask)
+	if err != nil {
+		return nil, err
+	}
 	db := new(nosql.Task)
 	db.UID = primitive.NewObjectID()
 	db.Type = uint8(info.Type)
-	db.ID = nosql.GetTaskNextID()
+	db.ID = id
 	db.CreatedTime = time.Now()
 	db.UpdatedTime = time.Now()
 	db.Operator = info.Operator
@@ -55,6 +64,8 @@ func (mine *cacheContext) CreateTask(info *pb.ReqTaskAdd) (*TaskInfo, error) {
 	db.Tags = info.Tags
 	db.Assets = info.Assets
 	db.Records = make([]proxy.RecordInfo, 0, 1)
+	db.Histories = make([]proxy.StatusHistory, 0, 1)
+	db.Executors = make([]string, 0, 1)
 	if db.Regions == nil {
 		db.Regions = make([]string, 0, 1)
 	}
@@ -67,7 +78,7 @@ func (mine *cacheContext) CreateTask(info *pb.ReqTaskAdd) (*TaskInfo, error) {
 	if db.Assets == nil {
 		db.Assets = make([]string, 0, 1)
 	}
-	err := nosql.CreateTask(db)
+	err = mine.tasks.CreateTask(db)
 	if err == nil {
 		tmp := new(TaskInfo)
 		tmp.initInfo(db)
@@ -80,7 +91,7 @@ func (mine *cacheContext) GetTask(uid string) (*TaskInfo,error) {
 	if len(uid) < 2 {
 		return nil,errors.New("the task uid is empty")
 	}
-	db, err := nosql.GetTask(uid)
+	db, err := mine.tasks.GetTask(uid)
 	if err == nil {
 		info := new(TaskInfo)
 		info.initInfo(db)
@@ -89,32 +100,46 @@ func (mine *cacheContext) GetTask(uid string) (*TaskInfo,error) {
 	return nil,err
 }
 
-func (mine *cacheContext) GetTasksByOwner(parent string, st int, page, number uint32) (uint32, uint32, []*TaskInfo) {
+func (mine *cacheContext) GetTasksByOwner(parent string, st int, page *PageInfo) (uint32, uint32, []*TaskInfo) {
+	conditions := []proxy.Condition{proxy.Equal("owner", parent)}
+	if st >= 0 {
+		conditions = append(conditions, proxy.Equal("status", uint8(st)))
+	}
+	dbs, num, err := mine.tasks.QueryTasks(page.query(true, conditions...))
+	if err != nil {
+		return 0, 0, make([]*TaskInfo, 0, 1)
+	}
+	list := make([]*TaskInfo, 0, len(dbs))
+	for _, item := range dbs {
+		info := new(TaskInfo)
+		info.initInfo(item)
+		list = append(list, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, list
+}
+
+// SearchTasks 在数据库中分页查询，page为0时返回全部
+func (mine *cacheContext) SearchTasks(filter *proxy.TaskFilter, page, number uint32) (uint32, uint32, []*TaskInfo, error) {
 	if number < 1 {
 		number = 10
 	}
-	var dbs []*nosql.Task
-	var err error
-	if st < 0 {
-		dbs, err = nosql.GetTasksByOwner2(parent)
-	}else{
-		dbs, err = nosql.GetTasksByOwner(parent, uint8(st))
-	}
+	filter.Skip, filter.Limit = pageRange(page, number)
+	dbs, total, err := mine.tasks.SearchTasks(filter)
 	if err != nil {
-		return 0, 0, make([]*TaskInfo, 0, 1)
+		return 0, 0, nil, err
 	}
-	all := make([]*TaskInfo, 0, len(dbs))
+	list := make([]*TaskInfo, 0, len(dbs))
 	for _, item := range dbs {
 		info := new(TaskInfo)
 		info.initInfo(item)
-		all = append(all, info)
+		list = append(list, info)
 	}
-	total, maxPage, list := checkPage(page, number, all)
-	return total, maxPage, list.([]*TaskInfo)
+	return uint32(total), maxPageOf(uint32(total), number), list, nil
 }
 
 func (mine *cacheContext) GetTasksByType(owner string, tp uint8) []*TaskInfo {
-	dbs, err := nosql.GetTasksByType(owner, tp)
+	dbs, err := mine.tasks.GetTasksByType(owner, tp)
 	if err != nil {
 		return make([]*TaskInfo, 0, 1)
 	}
@@ -127,15 +152,23 @@ func (mine *cacheContext) GetTasksByType(owner string, tp uint8) []*TaskInfo {
 	return all
 }
 
-func (mine *cacheContext) GetTasksByRegions(regions []string, st int) []*TaskInfo {
-	all := make([]*TaskInfo, 0, len(regions)*100)
-	for _, region := range regions {
-		list, err := mine.GetTasksByRegion(region, st)
-		if err == nil {
-			all = append(all, list...)
-		}
+func (mine *cacheContext) GetTasksByRegions(regions []string, st int, page *PageInfo) (uint32, uint32, []*TaskInfo) {
+	conditions := []proxy.Condition{proxy.In("regions", regions)}
+	if st >= int(TaskStatusIdle) {
+		conditions = append(conditions, proxy.Equal("status", uint8(st)))
 	}
-	return all
+	dbs, num, err := mine.tasks.QueryTasks(page.query(true, conditions...))
+	if err != nil {
+		return 0, 0, make([]*TaskInfo, 0, 1)
+	}
+	list := make([]*TaskInfo, 0, len(dbs))
+	for _, item := range dbs {
+		info := new(TaskInfo)
+		info.initInfo(item)
+		list = append(list, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
 func (mine *cacheContext) GetTasksByRegion(region string, st int) ([]*TaskInfo,error) {
@@ -145,9 +178,9 @@ func (mine *cacheContext) GetTasksByRegion(region string, st int) ([]*TaskInfo,e
 	var dbs []*nosql.Task
 	var err error
 	if st < int(TaskStatusIdle) {
-		dbs, err = nosql.GetTasksByRegion2(region)
+		dbs, err = mine.tasks.GetTasksByRegion2(region)
 	}else{
-		dbs, err = nosql.GetTasksByRegion(region, uint8(st))
+		dbs, err = mine.tasks.GetTasksByRegion(region, uint8(st))
 	}
 
 	if err != nil {
@@ -162,59 +195,55 @@ func (mine *cacheContext) GetTasksByRegion(region string, st int) ([]*TaskInfo,e
 	return all,nil
 }
 
-func (mine *cacheContext) GetTasksByAgent(agent string, st int) ([]*TaskInfo,error) {
+func (mine *cacheContext) GetTasksByAgent(agent string, st int, page *PageInfo) (uint32, uint32, []*TaskInfo, error) {
 	if agent == "" {
-		return nil, errors.New("the agent is empty")
+		return 0, 0, nil, errors.New("the agent is empty")
 	}
-	var dbs []*nosql.Task
-	var err error
-	if st < int(TaskStatusIdle) {
-		dbs, err = nosql.GetTasksByAgent2(agent)
-	}else{
-		dbs, err = nosql.GetTasksByAgent(agent, uint8(st))
+	conditions := []proxy.Condition{proxy.Equal("executors", agent)}
+	if st >= int(TaskStatusIdle) {
+		conditions = append(conditions, proxy.Equal("status", uint8(st)))
 	}
-
+	dbs, num, err := mine.tasks.QueryTasks(page.query(true, conditions...))
 	if err != nil {
-		return nil,err
+		return 0, 0, nil, err
 	}
-	all := make([]*TaskInfo, 0, len(dbs))
+	list := make([]*TaskInfo, 0, len(dbs))
 	for _, item := range dbs {
 		info := new(TaskInfo)
 		info.initInfo(item)
-		all = append(all, info)
+		list = append(list, info)
 	}
-	return all,nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
-func (mine *cacheContext) GetTasksByTarget(target string, st int) ([]*TaskInfo,error) {
+func (mine *cacheContext) GetTasksByTarget(target string, st int, page *PageInfo) (uint32, uint32, []*TaskInfo, error) {
 	if target == "" {
-		return nil, errors.New("the target is empty")
+		return 0, 0, nil, errors.New("the target is empty")
 	}
-	var dbs []*nosql.Task
-	var err error
-	if st < int(TaskStatusIdle) {
-		dbs, err = nosql.GetTasksByTarget2(target)
-	}else{
-		dbs, err = nosql.GetTasksByTarget(target, uint8(st))
+	conditions := []proxy.Condition{proxy.Equal("target", target)}
+	if st >= int(TaskStatusIdle) {
+		conditions = append(conditions, proxy.Equal("status", uint8(st)))
 	}
-
+	dbs, num, err := mine.tasks.QueryTasks(page.query(true, conditions...))
 	if err != nil {
-		return nil,err
+		return 0, 0, nil, err
 	}
-	all := make([]*TaskInfo, 0, len(dbs))
+	list := make([]*TaskInfo, 0, len(dbs))
 	for _, item := range dbs {
 		info := new(TaskInfo)
 		info.initInfo(item)
-		all = append(all, info)
+		list = append(list, info)
 	}
-	return all,nil
+	total, maxPage := page.result(num)
+	return total, maxPage, list, nil
 }
 
 func RemoveTask(uid, operator string) error {
 	if len(uid) < 1 {
 		return errors.New("the team uid is empty")
 	}
-	err := nosql.RemoveTask(uid, operator)
+	err := cacheCtx.tasks.RemoveTask(uid, operator)
 	return err
 }
 
@@ -240,6 +269,7 @@ func (mine *TaskInfo) initInfo(db *nosql.Task) {
 	mine.Tags = db.Tags
 	mine.Assets = db.Assets
 	mine.Records = db.Records
+	mine.Histories = db.Histories
 }
 
 func (mine *TaskInfo) UpdateBase(name, remark, operator string, assets []string) error {
@@ -249,7 +279,7 @@ func (mine *TaskInfo) UpdateBase(name, remark, operator string, assets []string)
 	if len(remark) < 1 {
 		remark = mine.Remark
 	}
-	err := nosql.UpdateTaskBase(mine.UID, name, remark, operator, assets)
+	err := cacheCtx.tasks.UpdateTaskBase(mine.UID, name, remark, operator, assets)
 	if err == nil {
 		mine.Name = name
 		mine.Remark = remark
@@ -263,7 +293,7 @@ func (mine *TaskInfo) UpdateType(operator string, tp uint8) error {
 	if uint8(mine.Type) == tp {
 		return nil
 	}
-	err := nosql.UpdateTaskType(mine.UID, operator, tp)
+	err := cacheCtx.tasks.UpdateTaskType(mine.UID, operator, tp)
 	if err == nil {
 		mine.Type = tp
 		mine.Operator = operator
@@ -272,7 +302,7 @@ func (mine *TaskInfo) UpdateType(operator string, tp uint8) error {
 }
 
 func (mine *TaskInfo) UpdateExecutors(operator string, agents []string) error {
-	err := nosql.UpdateTaskExecutors(mine.UID, operator, agents)
+	err := cacheCtx.tasks.UpdateTaskExecutors(mine.UID, operator, agents)
 	if err == nil {
 		mine.Executors = agents
 		mine.Operator = operator
@@ -281,7 +311,7 @@ func (mine *TaskInfo) UpdateExecutors(operator string, agents []string) error {
 }
 
 func (mine *TaskInfo) UpdateTags(operator string, list []string) error {
-	err := nosql.UpdateTaskTags(mine.UID, operator, list)
+	err := cacheCtx.tasks.UpdateTaskTags(mine.UID, operator, list)
 	if err == nil {
 		mine.Tags = list
 		mine.Operator = operator
@@ -289,13 +319,42 @@ func (mine *TaskInfo) UpdateTags(operator string, list []string) error {
 	return err
 }
 
-func (mine *TaskInfo) UpdateStatus(st TaskStatus, operator string) error {
-	err := nosql.UpdateTaskStatus(mine.UID, uint8(st), operator)
-	if err == nil {
-		mine.Status = st
-		mine.Operator = operator
+// UpdateStatus 按照流转表变更状态并记录历史，前置任务全部完成之后才能开始或者结束任务
+func (mine *TaskInfo) UpdateStatus(st TaskStatus, operator, reason string) error {
+	if st == mine.Status {
+		return nil
 	}
-	return err
+	err := mine.CheckTransition(st)
+	if err != nil {
+		return err
+	}
+	if st == TaskStatusBusy || st == TaskStatusEnd {
+		err = mine.CheckPreTasks()
+		if err != nil {
+			return err
+		}
+	}
+	history := proxy.StatusHistory{
+		Creator:     operator,
+		CreatedTime: time.Now(),
+		From:        uint8(mine.Status),
+		To:          uint8(st),
+		Reason:      reason,
+	}
+	ok, err := cacheCtx.tasks.TransitTask(mine.UID, operator, history)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTaskStatusChanged
+	}
+	mine.Status = st
+	mine.Operator = operator
+	mine.Histories = append(mine.Histories, history)
+	if st == TaskStatusEnd {
+		cacheCtx.notifyDependents(mine.UID)
+	}
+	return nil
 }
 
 func (mine *TaskInfo) HadExecutor(member string) bool {
@@ -320,7 +379,7 @@ func (mine *TaskInfo) AppendExecutor(member string) error {
 	if mine.HadExecutor(member){
 		return nil
 	}
-	err := nosql.AppendTaskExecutor(mine.UID, member)
+	err := cacheCtx.tasks.AppendTaskExecutor(mine.UID, member)
 	if err == nil {
 		mine.Executors = append(mine.Executors, member)
 	}
@@ -331,7 +390,7 @@ func (mine *TaskInfo) SubtractExecutor(member string) error {
 	if !mine.HadExecutor(member){
 		return nil
 	}
-	err := nosql.SubtractTaskExecutor(mine.UID, member)
+	err := cacheCtx.tasks.SubtractTaskExecutor(mine.UID, member)
 	if err == nil {
 		for i := 0;i < len(mine.Executors);i += 1 {
 			if mine.Executors[i] == member {
@@ -352,12 +411,13 @@ func (mine *TaskInfo)AddRecord(tmp *pb.ReqTaskRecord) error {
 	st := TaskStatus(tmp.Status)
 	var err error
 	if mine.Status != st {
-		err = mine.UpdateStatus(st, tmp.Creator)
+		err = mine.UpdateStatus(st, tmp.Creator, tmp.Name)
 		if err != nil {
 			return err
 		}
 	}
 	info := proxy.RecordInfo{
+		UID: newRecordUID(),
 		Creator: tmp.Creator,
 		CreatedTime: time.Now(),
 		Name: tmp.Name,
@@ -367,7 +427,7 @@ func (mine *TaskInfo)AddRecord(tmp *pb.ReqTaskRecord) error {
 		Tags: tmp.Tags,
 		Assets: tmp.Assets,
 	}
-	err = nosql.AppendTaskRecord(mine.UID, info)
+	err = cacheCtx.tasks.AppendTaskRecord(mine.UID, info)
 	if err == nil {
 		mine.Records = append(mine.Records, info)
 		arr := make([]string, 0, 1)
@@ -381,7 +441,7 @@ func (mine *TaskInfo)RemoveRecord(uid string) error {
 	if !mine.HadRecord(uid){
 		return nil
 	}
-	err := nosql.SubtractTaskRecord(mine.UID, uid)
+	err := cacheCtx.tasks.SubtractTaskRecord(mine.UID, uid)
 	if err == nil {
 		for i := 0;i < len(mine.Records);i += 1 {
 			if mine.Records[i].UID == uid {
@@ -1,9 +1,9 @@
 package cache
 
 import (
-	"errors"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"omo.msa.assignment/proxy/nosql"
 	"time"
 )
@@ -20,12 +20,26 @@ type TeamInfo struct {
 	Tags       []string
 	Assistants []string
 	Members    []string
+	Waits      []proxy.WaitInfo
 }
 
 func (mine *cacheContext) CreateTeam(info *pb.ReqTeamAdd) (*TeamInfo, error) {
+	if len(info.Parent) > 0 {
+		parent, err := mine.aliveTeam(info.Parent)
+		if err != nil {
+			return nil, err
+		}
+		if parent.Owner != info.Owner {
+			return nil, ErrTeamOwner
+		}
+	}
+	id, err := mine.nextID(nosql.TableTeam)
+	if err != nil {
+		return nil, err
+	}
 	db := new(nosql.Team)
 	db.UID = primitive.NewObjectID()
-	db.ID = nosql.GetTeamNextID()
+	db.ID = id
 	db.CreatedTime = time.Now()
 	db.UpdatedTime = time.Now()
 	db.Operator = info.Operator
@@ -40,8 +54,9 @@ func (mine *cacheContext) CreateTeam(info *pb.ReqTeamAdd) (*TeamInfo, error) {
 	db.Region = info.Region
 	db.Tags = make([]string, 0, 1)
 	db.Members = make([]string, 0, 1)
+	db.Waits = make([]proxy.WaitInfo, 0, 1)
 	db.Assistants = make([]string, 0, 1)
-	err := nosql.CreateTeam(db)
+	err = mine.teams.CreateTeam(db)
 	if err == nil {
 		tmp := new(TeamInfo)
 		tmp.initInfo(db)
@@ -51,7 +66,7 @@ func (mine *cacheContext) CreateTeam(info *pb.ReqTeamAdd) (*TeamInfo, error) {
 }
 
 func (mine *cacheContext) GetTeam(uid string) (*TeamInfo, error) {
-	db, err := nosql.GetTeam(uid)
+	db, err := mine.teams.GetTeam(uid)
 	if err == nil {
 		info := new(TeamInfo)
 		info.initInfo(db)
@@ -60,45 +75,47 @@ func (mine *cacheContext) GetTeam(uid string) (*TeamInfo, error) {
 	return nil, err
 }
 
-func (mine *cacheContext) GetTeamsByOwner(scene string) []*TeamInfo {
-	list := make([]*TeamInfo, 0, 10)
-	dbs, err := nosql.GetTeamsByOwner(scene)
-	if err == nil {
-		for _, db := range dbs {
-			info := new(TeamInfo)
-			info.initInfo(db)
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetTeamsByOwner(scene string, page *PageInfo) (uint32, uint32, []*TeamInfo) {
+	dbs, num, err := mine.teams.QueryTeams(page.query(true, proxy.Equal("owner", scene)))
+	if err != nil {
+		return 0, 0, make([]*TeamInfo, 0, 1)
+	}
+	list := make([]*TeamInfo, 0, len(dbs))
+	for _, db := range dbs {
+		info := new(TeamInfo)
+		info.initInfo(db)
+		list = append(list, info)
 	}
-	return list
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
-func (mine *cacheContext) GetTeamsByUser(user string) []*TeamInfo {
-	list := make([]*TeamInfo, 0, 10)
-	dbs, err := nosql.GetTeamsByMember(user)
-	if err == nil {
-		for _, db := range dbs {
-			info := new(TeamInfo)
-			info.initInfo(db)
-			list = append(list, info)
-		}
+func (mine *cacheContext) GetTeamsByUser(user string, page *PageInfo) (uint32, uint32, []*TeamInfo) {
+	dbs, num, err := mine.teams.QueryTeams(page.query(false, proxy.Equal("members", user)))
+	if err != nil {
+		return 0, 0, make([]*TeamInfo, 0, 1)
 	}
-	return list
+	list := make([]*TeamInfo, 0, len(dbs))
+	for _, db := range dbs {
+		info := new(TeamInfo)
+		info.initInfo(db)
+		list = append(list, info)
+	}
+	total, maxPage := page.result(num)
+	return total, maxPage, list
 }
 
 func (mine *cacheContext) HadTeamByName(scene, name string) bool {
-	db, _ := nosql.GetTeamByName(scene, name)
+	db, _ := mine.teams.GetTeamByName(scene, name)
 	if db == nil {
 		return false
 	}
 	return true
 }
 
+// RemoveTeam 有下级小组时不删除，返回ErrTeamHasChildren
 func (mine *cacheContext) RemoveTeam(uid, operator string) error {
-	if len(uid) < 1 {
-		return errors.New("the team uid is empty")
-	}
-	err := nosql.RemoveTeam(uid, operator)
+	_, err := mine.RemoveTeamTree(uid, operator, false)
 	return err
 }
 
@@ -120,6 +137,10 @@ func (mine *TeamInfo) initInfo(db *nosql.Team) {
 	mine.Tags = db.Tags
 	mine.Assistants = db.Assistants
 	mine.Members = db.Members
+	mine.Waits = db.Waits
+	if mine.Waits == nil {
+		mine.Waits = make([]proxy.WaitInfo, 0, 1)
+	}
 }
 
 func (mine *TeamInfo) UpdateBase(name, remark, operator string) error {
@@ -129,7 +150,7 @@ func (mine *TeamInfo) UpdateBase(name, remark, operator string) error {
 	if len(remark) < 1 {
 		remark = mine.Remark
 	}
-	err := nosql.UpdateTeamBase(mine.UID, name, remark, operator)
+	err := cacheCtx.teams.UpdateTeamBase(mine.UID, name, remark, operator)
 	if err == nil {
 		mine.Name = name
 		mine.Remark = remark
@@ -139,7 +160,7 @@ func (mine *TeamInfo) UpdateBase(name, remark, operator string) error {
 }
 
 func (mine *TeamInfo) UpdateMaster(master, operator string) error {
-	err := nosql.UpdateTeamMaster(mine.UID, master, operator)
+	err := cacheCtx.teams.UpdateTeamMaster(mine.UID, master, operator)
 	if err == nil {
 		mine.Master = master
 		mine.Operator = operator
@@ -148,7 +169,7 @@ func (mine *TeamInfo) UpdateMaster(master, operator string) error {
 }
 
 func (mine *TeamInfo) UpdateStatus(operator string, st uint8) error {
-	err := nosql.UpdateTeamStatus(mine.UID, operator, st)
+	err := cacheCtx.teams.UpdateTeamStatus(mine.UID, operator, st)
 	if err == nil {
 		mine.Status = st
 		mine.Operator = operator
@@ -157,7 +178,7 @@ func (mine *TeamInfo) UpdateStatus(operator string, st uint8) error {
 }
 
 func (mine *TeamInfo) UpdateRegion(region, operator string) error {
-	err := nosql.UpdateTeamRegion(mine.UID, region, operator)
+	err := cacheCtx.teams.UpdateTeamRegion(mine.UID, region, operator)
 	if err == nil {
 		mine.Region = region
 		mine.Operator = operator
@@ -166,7 +187,7 @@ func (mine *TeamInfo) UpdateRegion(region, operator string) error {
 }
 
 func (mine *TeamInfo) UpdateTags(operator string, tags []string) error {
-	err := nosql.UpdateTeamTags(mine.UID, operator, tags)
+	err := cacheCtx.teams.UpdateTeamTags(mine.UID, operator, tags)
 	if err == nil {
 		mine.Tags = tags
 		mine.Operator = operator
@@ -175,7 +196,7 @@ func (mine *TeamInfo) UpdateTags(operator string, tags []string) error {
 }
 
 func (mine *TeamInfo) UpdateAssistants(operator string, list []string) error {
-	err := nosql.UpdateTeamAssistants(mine.UID, operator, list)
+	err := cacheCtx.teams.UpdateTeamAssistants(mine.UID, operator, list)
 	if err == nil {
 		mine.Assistants = list
 		mine.Operator = operator
@@ -184,7 +205,7 @@ func (mine *TeamInfo) UpdateAssistants(operator string, list []string) error {
 }
 
 func (mine *TeamInfo) UpdateMembers(operator string, list []string) error {
-	err := nosql.UpdateTeamMembers(mine.UID, operator, list)
+	err := cacheCtx.teams.UpdateTeamMembers(mine.UID, operator, list)
 	if err == nil {
 		mine.Members = list
 		mine.Operator = operator
@@ -201,42 +222,42 @@ func (mine *TeamInfo) HadMember(member string) bool {
 	return false
 }
 
-func (mine *TeamInfo) AppendMembers(list []string) error {
-	for _, s := range list {
-		err := mine.AppendMember(s)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
+// AppendMember 满员时返回ErrTeamFull，需要候补时使用Join
 func (mine *TeamInfo) AppendMember(member string) error {
 	if mine.HadMember(member) {
 		return nil
 	}
-	err := nosql.AppendTeamMember(mine.UID, member)
-	if err == nil {
+	ok, err := cacheCtx.teams.JoinTeam(mine.UID, member, mine.MaxNum)
+	if err != nil {
+		return err
+	}
+	if ok {
 		mine.Members = append(mine.Members, member)
+		mine.Waits = pullWait(mine.Waits, member)
+		return nil
 	}
-	return err
-}
-
-func (mine *TeamInfo) SubtractMembers(members []string) error {
-	for _, member := range members {
-		err := mine.SubtractMember(member)
-		if err != nil {
-			return err
-		}
+	// 其他地方已经加入或者已经满员
+	err = mine.reload()
+	if err != nil {
+		return err
 	}
-	return nil
+	if mine.HadMember(member) {
+		return nil
+	}
+	return ErrTeamFull
 }
 
+// SubtractMember 成员离开后空出的名额由最早的候补补上；不是成员但是在候补中时只移除候补
 func (mine *TeamInfo) SubtractMember(member string) error {
+	_, err := mine.Leave(member)
+	return err
+}
+
+func (mine *TeamInfo) subtractMember(member string) error {
 	if !mine.HadMember(member) {
 		return nil
 	}
-	err := nosql.SubtractTeamMember(mine.UID, member)
+	err := cacheCtx.teams.SubtractTeamMember(mine.UID, member)
 	if err == nil {
 		for i := 0; i < len(mine.Members); i += 1 {
 			if mine.Members[i] == member {
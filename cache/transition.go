@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"omo.msa.assignment/config"
+)
+
+const DefaultTaskType = -1
+
+// ErrTaskStatusChanged 变更状态时任务的状态已经被其他请求修改
+var ErrTaskStatusChanged = errors.New("the task status had been changed by others, please reload it")
+
+// 默认的状态流转表：结束的任务不能再变更，冻结的任务只能恢复为空闲或者进行中
+var defaultTransitions = map[TaskStatus][]TaskStatus{
+	TaskStatusIdle:  {TaskStatusBusy, TaskStatusEnd, TaskStatusFroze},
+	TaskStatusBusy:  {TaskStatusIdle, TaskStatusEnd, TaskStatusFroze},
+	TaskStatusEnd:   {},
+	TaskStatusFroze: {TaskStatusIdle, TaskStatusBusy},
+}
+
+// 按照任务类型区分的流转表，没有单独配置的类型使用DefaultTaskType
+var taskTransitions map[int]map[TaskStatus][]TaskStatus
+
+// 配置中的规则会覆盖默认规则中相同的起始状态
+func initTransitions(list []config.TransitionConfig) {
+	base := make(map[TaskStatus][]TaskStatus, len(defaultTransitions))
+	for key, value := range defaultTransitions {
+		base[key] = value
+	}
+	for _, item := range list {
+		if item.Type == DefaultTaskType {
+			base[TaskStatus(item.From)] = switchStatusArray(item.To)
+		}
+	}
+	taskTransitions = map[int]map[TaskStatus][]TaskStatus{DefaultTaskType: base}
+	for _, item := range list {
+		if item.Type == DefaultTaskType {
+			continue
+		}
+		table, ok := taskTransitions[item.Type]
+		if !ok {
+			table = make(map[TaskStatus][]TaskStatus, len(base))
+			for key, value := range base {
+				table[key] = value
+			}
+			taskTransitions[item.Type] = table
+		}
+		table[TaskStatus(item.From)] = switchStatusArray(item.To)
+	}
+}
+
+func switchStatusArray(list []int) []TaskStatus {
+	arr := make([]TaskStatus, 0, len(list))
+	for _, item := range list {
+		arr = append(arr, TaskStatus(item))
+	}
+	return arr
+}
+
+func getTransitions(tp uint8) map[TaskStatus][]TaskStatus {
+	if taskTransitions == nil {
+		initTransitions(nil)
+	}
+	table, ok := taskTransitions[int(tp)]
+	if ok {
+		return table
+	}
+	return taskTransitions[DefaultTaskType]
+}
+
+// CheckTransition 检查任务当前的状态是否可以变更为目标状态
+func (mine *TaskInfo) CheckTransition(st TaskStatus) error {
+	table := getTransitions(mine.Type)
+	if _, ok := table[st]; !ok {
+		return errors.New(fmt.Sprintf("the task status(%d) is not defined", st))
+	}
+	for _, item := range table[mine.Status] {
+		if item == st {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("the task status can not change from %d to %d", mine.Status, st))
+}
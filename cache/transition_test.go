@@ -0,0 +1,124 @@
+package cache
+
+import (
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	"omo.msa.assignment/config"
+	"testing"
+)
+
+func newTestTask(t *testing.T, ctx *cacheContext, pretasks ...string) *TaskInfo {
+	t.Helper()
+	info, err := ctx.CreateTask(&pb.ReqTaskAdd{Name: "task", Owner: "scene", Operator: "admin",
+		Duration: &pb.DateInfo{}, Pretasks: pretasks})
+	if err != nil {
+		t.Fatalf("create the task failed: %v", err)
+	}
+	return info
+}
+
+func TestCheckTransition(t *testing.T) {
+	defer initTransitions(nil)
+	initTransitions([]config.TransitionConfig{{Type: 3, From: int(TaskStatusEnd), To: []int{int(TaskStatusIdle)}}})
+	cases := []struct {
+		name    string
+		tp      uint8
+		from    TaskStatus
+		to      TaskStatus
+		wantErr bool
+	}{
+		{name: "idle to busy", from: TaskStatusIdle, to: TaskStatusBusy},
+		{name: "busy to end", from: TaskStatusBusy, to: TaskStatusEnd},
+		{name: "froze to idle", from: TaskStatusFroze, to: TaskStatusIdle},
+		{name: "froze to end", from: TaskStatusFroze, to: TaskStatusEnd, wantErr: true},
+		{name: "end to idle", from: TaskStatusEnd, to: TaskStatusIdle, wantErr: true},
+		{name: "undefined status", from: TaskStatusIdle, to: TaskStatus(5), wantErr: true},
+		{name: "reopen by type", tp: 3, from: TaskStatusEnd, to: TaskStatusIdle},
+		{name: "type keeps default", tp: 3, from: TaskStatusEnd, to: TaskStatusBusy, wantErr: true},
+	}
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			info := &TaskInfo{Type: item.tp, Status: item.from}
+			err := info.CheckTransition(item.to)
+			if (err != nil) != item.wantErr {
+				t.Errorf("the error = %v, want error = %v", err, item.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	ctx := newTestContext(t)
+	info := newTestTask(t, ctx)
+	steps := []struct {
+		to      TaskStatus
+		wantErr bool
+	}{
+		{to: TaskStatusBusy},
+		{to: TaskStatusFroze},
+		{to: TaskStatusEnd, wantErr: true},
+		{to: TaskStatusIdle},
+		{to: TaskStatusEnd},
+		{to: TaskStatusBusy, wantErr: true},
+	}
+	histories := 0
+	for _, step := range steps {
+		from := info.Status
+		err := info.UpdateStatus(step.to, "admin", "test")
+		if (err != nil) != step.wantErr {
+			t.Fatalf("change from %d to %d: the error = %v, want error = %v", from, step.to, err, step.wantErr)
+		}
+		if err == nil {
+			histories += 1
+		}
+	}
+	db, err := ctx.GetTask(info.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Status != TaskStatusEnd || len(db.Histories) != histories {
+		t.Errorf("the status = %d, the histories = %d, want %d and %d", db.Status, len(db.Histories), TaskStatusEnd, histories)
+	}
+	last := db.Histories[len(db.Histories)-1]
+	if last.From != uint8(TaskStatusIdle) || last.To != uint8(TaskStatusEnd) {
+		t.Errorf("the last history is from %d to %d", last.From, last.To)
+	}
+}
+
+func TestUpdateStatusChanged(t *testing.T) {
+	ctx := newTestContext(t)
+	info := newTestTask(t, ctx)
+	stale, err := ctx.GetTask(info.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = info.UpdateStatus(TaskStatusEnd, "admin", "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stale.UpdateStatus(TaskStatusBusy, "other", "start")
+	if err != ErrTaskStatusChanged {
+		t.Fatalf("the error = %v, want %v", err, ErrTaskStatusChanged)
+	}
+	db, _ := ctx.GetTask(info.UID)
+	if db.Status != TaskStatusEnd || len(db.Histories) != 1 {
+		t.Errorf("the status = %d, the histories = %d", db.Status, len(db.Histories))
+	}
+}
+
+func TestUpdateStatusPreTasks(t *testing.T) {
+	ctx := newTestContext(t)
+	pre := newTestTask(t, ctx)
+	info := newTestTask(t, ctx, pre.UID)
+	if err := info.UpdateStatus(TaskStatusBusy, "admin", ""); err == nil {
+		t.Fatal("the task should not start before the pre task is finished")
+	}
+	if err := info.UpdateStatus(TaskStatusFroze, "admin", ""); err != nil {
+		t.Fatalf("froze the task failed: %v", err)
+	}
+	if err := pre.UpdateStatus(TaskStatusEnd, "admin", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := info.UpdateStatus(TaskStatusBusy, "admin", ""); err != nil {
+		t.Fatalf("start the task failed: %v", err)
+	}
+}
@@ -18,6 +18,24 @@ const defaultJson string = `{
 		"user": "root",
 		"password": "pass2019",
 		"type": "mongodb"
+	},
+	"apply": {
+		"interval": 600,
+		"expire": 2592000,
+		"remind": 259200,
+		"status": 7
+	},
+	"meeting": {
+		"interval": 60,
+		"grace": 3600,
+		"radius": 200,
+		"signBefore": 1800,
+		"signLate": 300,
+		"horizon": 2592000,
+		"conflict": true,
+		"length": 3600,
+		"workHours": "09:00-18:00",
+		"workDays": [1, 2, 3, 4, 5]
 	}
 }
 `
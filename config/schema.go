@@ -21,8 +21,59 @@ type DBConfig struct {
 	Name     string `json:"name"`
 }
 
+// TransitionConfig 任务状态的流转规则，type为-1时作为所有类型的默认规则
+type TransitionConfig struct {
+	Type int   `json:"type"`
+	From int   `json:"from"`
+	To   []int `json:"to"`
+}
+
+type TaskConfig struct {
+	Transitions []TransitionConfig `json:"transitions"`
+}
+
+// ApplySceneConfig 按场景覆盖申请的过期和提醒时长
+type ApplySceneConfig struct {
+	Scene  string `json:"scene"`
+	Expire int64  `json:"expire"`
+	Remind int64  `json:"remind"`
+}
+
+// ApplyConfig 申请的定时检查，时长都为秒：
+// 待审核超过expire后修改为status(7为过期，6为搁置)，超过remind后提醒一次，为0时不处理；interval为0时不启动
+type ApplyConfig struct {
+	Interval int64              `json:"interval"`
+	Expire   int64              `json:"expire"`
+	Remind   int64              `json:"remind"`
+	Status   int                `json:"status"`
+	Scenes   []ApplySceneConfig `json:"scenes"`
+}
+
+// MeetingConfig 会议状态的定时调度，时长都为秒：
+// 到开始和结束时间时修改状态，interval为两次检查的最长间隔，为0时不启动；
+// 错过的时间超过grace时只修改状态不再通知，为0时都通知；
+// radius、signBefore和signLate为会议没有设置时的签到范围(米)、开始前可以签到的时长以及开始后不算迟到的时长；
+// horizon为重复会议提前生成的时长，为0时不生成；
+// conflict为true时创建和修改时间时不允许与同一场景中同一小组、同一会议室或者任何场景中相同参会人的会议重叠，没有结束时间的会议按照length计算；
+// workHours(如09:00-18:00)和workDays(0为周日)为查找空闲时间时默认的工作时间
+type MeetingConfig struct {
+	Interval   int64  `json:"interval"`
+	Grace      int64  `json:"grace"`
+	Radius     int32  `json:"radius"`
+	SignBefore int32  `json:"signBefore"`
+	SignLate   int32  `json:"signLate"`
+	Horizon    int64  `json:"horizon"`
+	Conflict   bool   `json:"conflict"`
+	Length     int64  `json:"length"`
+	WorkHours  string `json:"workHours"`
+	WorkDays   []int  `json:"workDays"`
+}
+
 type SchemaConfig struct {
 	Service  ServiceConfig `json:"service"`
 	Logger   LoggerConfig  `json:"logger"`
 	Database DBConfig      `json:"database"`
+	Task     TaskConfig    `json:"task"`
+	Apply    ApplyConfig   `json:"apply"`
+	Meeting  MeetingConfig `json:"meeting"`
 }
@@ -91,8 +91,7 @@ func (mine *AgentService) GetStatistic(ctx context.Context, in *pb.RequestFilter
 		return nil
 	}
 	if in.Key == "region" {
-		list := cache.Context().GetAgentsByRegion(in.Value)
-		out.Count = uint32(len(list))
+		out.Count, _, _ = cache.Context().GetAgentsByRegion(in.Value, countPage())
 	}
 
 	out.Status = outLog(path, out)
@@ -119,16 +118,19 @@ func (mine *AgentService) RemoveOne(ctx context.Context, in *pb.RequestInfo, out
 func (mine *AgentService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyAgentList) error {
 	path := "agent.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var total uint32 = 0
+	var max uint32 = 0
 	var list []*cache.AgentInfo
 	var err error
-	if in.Key == "" {
-		list = cache.Context().GetAgentsByOwner(in.Owner)
-	} else if in.Key == "way" {
-		list = cache.Context().GetAgentsByWay(in.Owner, in.Value)
-	} else if in.Key == "array" {
-		list = cache.Context().GetAgentsByArray(in.Values)
-	} else if in.Key == "region" {
-		list = cache.Context().GetAgentsByRegion(in.Value)
+	if key == "" {
+		total, max, list = cache.Context().GetAgentsByOwner(in.Owner, page)
+	} else if key == "way" {
+		total, max, list = cache.Context().GetAgentsByWay(in.Owner, in.Value, page)
+	} else if key == "array" {
+		total, max, list = cache.Context().GetAgentsByArray(in.Values, page)
+	} else if key == "region" {
+		total, max, list = cache.Context().GetAgentsByRegion(in.Value, page)
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -141,6 +143,9 @@ func (mine *AgentService) GetListByFilter(ctx context.Context, in *pb.RequestFil
 	for _, value := range list {
 		out.List = append(out.List, switchAgent(value))
 	}
+	out.PageNow = in.Page
+	out.Total = total
+	out.PageMax = max
 	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
 	return nil
 }
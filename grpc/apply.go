@@ -31,6 +31,21 @@ func switchApply(info *cache.ApplyInfo) *pb.ApplyInfo {
 	return tmp
 }
 
+// 已经审核过的申请返回NotMatch，拒绝时没有原因返回Empty
+func checkApplyStatus(info *cache.ApplyInfo, st uint8, reason string) (pbstatus.ResultStatus, error) {
+	if info.Status == st {
+		return pbstatus.ResultStatus_Success, nil
+	}
+	err := info.CheckDecision(st)
+	if err != nil {
+		return pbstatus.ResultStatus_NotMatch, err
+	}
+	if st == cache.ApplyStatusRefused && len(reason) < 1 {
+		return pbstatus.ResultStatus_Empty, errors.New("the reason of refusal is empty")
+	}
+	return pbstatus.ResultStatus_Success, nil
+}
+
 func (mine *ApplyService) AddOne(ctx context.Context, in *pb.ReqApplyAdd, out *pb.ReplyApplyOne) error {
 	path := "apply.add"
 	inLog(path, in)
@@ -98,19 +113,22 @@ func (mine *ApplyService) RemoveOne(ctx context.Context, in *pb.RequestInfo, out
 func (mine *ApplyService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyApplyList) error {
 	path := "apply.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var total uint32 = 0
+	var max uint32 = 0
 	var list []*cache.ApplyInfo
 	var err error
-	if in.Key == "" {
-		list = cache.Context().GetAppliesByOwner(in.Owner, -1)
-	} else if in.Key == "creator" {
-		list = cache.Context().GetAppliesByCreator(in.Value)
-	} else if in.Key == "application" {
-		list = cache.Context().GetAppliesByUser(in.Value)
-	} else if in.Key == "group" {
-		list = cache.Context().GetAppliesByGroup(in.Value)
-	} else if in.Key == "scene" {
+	if key == "" {
+		total, max, list = cache.Context().GetAppliesByOwner(in.Owner, -1, page)
+	} else if key == "creator" {
+		total, max, list = cache.Context().GetAppliesByCreator(in.Value, page)
+	} else if key == "application" {
+		total, max, list = cache.Context().GetAppliesByUser(in.Value, page)
+	} else if key == "group" {
+		total, max, list = cache.Context().GetAppliesByGroup(in.Value, page)
+	} else if key == "scene" {
 		tp := parseStringToInt(in.Value)
-		list = cache.Context().GetAppliesByOwner(in.Owner, int32(tp))
+		total, max, list = cache.Context().GetAppliesByOwner(in.Owner, int32(tp), page)
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -122,7 +140,9 @@ func (mine *ApplyService) GetListByFilter(ctx context.Context, in *pb.RequestFil
 	for _, value := range list {
 		out.List = append(out.List, switchApply(value))
 	}
-
+	out.PageNow = in.Page
+	out.Total = total
+	out.PageMax = max
 	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
 	return nil
 }
@@ -166,6 +186,11 @@ func (mine *ApplyService) UpdateStatus(ctx context.Context, in *pb.RequestIntFla
 		out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
 		return nil
 	}
+	code, er := checkApplyStatus(info, uint8(in.Flag), in.Remark)
+	if er != nil {
+		out.Status = outError(path, er.Error(), code)
+		return nil
+	}
 	err := info.SetStatus(uint8(in.Flag), in.Remark, in.Operator)
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
@@ -0,0 +1,212 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"net/url"
+	"omo.msa.assignment/cache"
+	"strconv"
+	"time"
+)
+
+// AttendanceService 会议的签到、邀请以及出勤统计，proto中没有单独的定义，复用已有的消息类型
+type AttendanceService struct{}
+
+func unixOf(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func boolOf(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// 出勤情况以 user=x&expected=x&signed=x&late=x&left=x&early=x&submitted=x&notified=x&acked=x&answer=x&device=x&location=x&distance=x 的格式返回，时间为unix秒(0表示没有)
+func switchAttendance(item *cache.AttendanceItem) string {
+	params := url.Values{}
+	params.Set("user", item.User)
+	params.Set("expected", boolOf(item.Expected))
+	if item.Signed && item.SignedTime.IsZero() {
+		// 以前的签到没有时间
+		params.Set("signed", "1")
+	} else {
+		params.Set("signed", unixOf(item.SignedTime))
+	}
+	params.Set("late", boolOf(item.Late))
+	params.Set("left", unixOf(item.LeftTime))
+	params.Set("early", boolOf(item.LeftEarly))
+	params.Set("submitted", unixOf(item.SubmittedTime))
+	params.Set("notified", unixOf(item.NotifiedTime))
+	params.Set("acked", unixOf(item.AckedTime))
+	params.Set("answer", strconv.Itoa(int(item.Answer)))
+	params.Set("device", item.Device)
+	params.Set("location", item.Location)
+	params.Set("distance", strconv.FormatFloat(item.Distance, 'f', -1, 64))
+	return params.Encode()
+}
+
+func answerOf(flag string) uint8 {
+	switch flag {
+	case "accept":
+		return cache.AnswerAccept
+	case "decline":
+		return cache.AnswerDecline
+	case "tentative":
+		return cache.AnswerTentative
+	}
+	return cache.AnswerNone
+}
+
+// Sign uid为会议，key为签到的成员(为空时为operator)，value为位置(纬度,经度)，values[0]为设备；返回的uid为签到的记录
+func (mine *AttendanceService) Sign(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyInfo) error {
+	path := "attendance.sign"
+	inLog(path, in)
+	member := in.Key
+	if len(member) < 1 {
+		member = in.Operator
+	}
+	if len(in.Uid) < 1 || len(member) < 1 {
+		out.Status = outError(path, "the uid or member is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetMeeting(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the meeting not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	device := ""
+	if len(in.Values) > 0 {
+		device = in.Values[0]
+	}
+	record, err := info.Sign(member, in.Operator, in.Value, device)
+	if record != nil {
+		out.Uid = switchSign(record)
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), signStatus(err))
+		return nil
+	}
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// Notify uid为会议，values为邀请的成员；返回的list为新邀请的成员，已经邀请过的不再通知
+func (mine *AttendanceService) Notify(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyList) error {
+	path := "attendance.notify"
+	inLog(path, in)
+	if len(in.Uid) < 1 || len(in.Values) < 1 {
+		out.Status = outError(path, "the uid or users is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetMeeting(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the meeting not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	list, err := info.Notify(in.Values, in.Operator)
+	out.Uid = in.Uid
+	out.List = list
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// Acknowledge uid为会议，user为被邀请的成员(为空时为operator)，flag为accept、decline或者tentative
+func (mine *AttendanceService) Acknowledge(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyInfo) error {
+	path := "attendance.acknowledge"
+	inLog(path, in)
+	user := in.User
+	if len(user) < 1 {
+		user = in.Operator
+	}
+	if len(in.Uid) < 1 || len(user) < 1 {
+		out.Status = outError(path, "the uid or user is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	answer := answerOf(in.Flag)
+	if answer == cache.AnswerNone {
+		out.Status = outError(path, "the flag should be accept, decline or tentative", pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	info, er := cache.Context().GetMeeting(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the meeting not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	if !info.HadNotified(user) {
+		out.Status = outError(path, "the user had not been invited", pbstatus.ResultStatus_Prohibition)
+		return nil
+	}
+	err := info.Acknowledge(user, answer)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// Leave uid为会议，user为离开的成员(为空时为operator)，早于结束时间离开的记为早退
+func (mine *AttendanceService) Leave(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyInfo) error {
+	path := "attendance.leave"
+	inLog(path, in)
+	user := in.User
+	if len(user) < 1 {
+		user = in.Operator
+	}
+	if len(in.Uid) < 1 || len(user) < 1 {
+		out.Status = outError(path, "the uid or user is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetMeeting(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the meeting not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	if !info.HadSigned(user) {
+		out.Status = outError(path, "the user had not signed", pbstatus.ResultStatus_Prohibition)
+		return nil
+	}
+	err := info.Leave(user)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// GetReport uid为会议，返回应到(小组成员和被邀请的成员)以及实际签到的每个成员的出勤情况，统计数量使用MeetingService.GetStatistic
+func (mine *AttendanceService) GetReport(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyList) error {
+	path := "attendance.getReport"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetMeeting(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the meeting not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	report := info.GetAttendance()
+	out.Uid = in.Uid
+	out.List = make([]string, 0, len(report.Items))
+	for _, item := range report.Items {
+		out.List = append(out.List, switchAttendance(item))
+	}
+	out.Status = outLog(path, fmt.Sprintf("the expected = %d, signed = %d, absent = %d", report.Expected, report.Signed, report.Absent))
+	return nil
+}
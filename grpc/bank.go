@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"omo.msa.assignment/cache"
+	"omo.msa.assignment/tool"
+)
+
+// BankService 题库的导入导出，proto中没有单独的定义，复用已有的消息类型，文件内容都使用base64编码
+type BankService struct{}
+
+func bankFormat(format string) string {
+	if len(format) < 1 {
+		return cache.BankFormatJson
+	}
+	return format
+}
+
+// Export uid为分类，flag为格式(json、csv、xlsx，默认为json)，name不为空时同时保存到服务端的该目录中；
+// 返回的list[0]为文件内容，uid为保存的文件路径
+func (mine *BankService) Export(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyList) error {
+	path := "bank.export"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the category is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	format := bankFormat(in.Flag)
+	if len(in.Name) > 0 {
+		info, er := cache.Context().GetOneCategory(in.Uid)
+		if er != nil || info == nil {
+			out.Status = outError(path, "not found the category", pbstatus.ResultStatus_NotExisted)
+			return nil
+		}
+		file, er := info.Export(in.Name, format)
+		if er != nil {
+			out.Status = outError(path, er.Error(), pbstatus.ResultStatus_DBException)
+			return nil
+		}
+		out.Uid = file
+	}
+	data, err := cache.Context().ExportQuestionBank(in.Uid, format)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.List = []string{base64.StdEncoding.EncodeToString(data)}
+	out.Status = outLog(path, fmt.Sprintf("the format = %s, the size = %d", format, len(data)))
+	return nil
+}
+
+// Import uid为导入到的父分类(为空时为顶级分类)，key为格式，value为文件内容，values中包含dry时只预演不导入；
+// 返回的list[0]为统计，格式为 total=x&created=x&duplicated=x&invalid=x&failed=x&skipped=x&categories=x，之后为每条题目的问题
+func (mine *BankService) Import(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyList) error {
+	path := "bank.import"
+	inLog(path, fmt.Sprintf("the parent = %s, the format = %s, the size = %d", in.Uid, in.Key, len(in.Value)))
+	body, err := base64.StdEncoding.DecodeString(in.Value)
+	if err != nil || len(body) < 1 {
+		out.Status = outError(path, "the content is empty or not base64", pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	data, err := cache.DecodeBankData(bankFormat(in.Key), body)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	report, err := cache.Context().ImportQuestionBank(in.Uid, in.Operator, data, tool.HasItem(in.Values, "dry"))
+	if report != nil {
+		out.Uid = in.Uid
+		out.List = make([]string, 0, len(report.Messages)+1)
+		out.List = append(out.List, fmt.Sprintf("total=%d&created=%d&duplicated=%d&invalid=%d&failed=%d&skipped=%d&categories=%d",
+			report.Total, report.Created, report.Duplicated, report.Invalid, report.Failed, report.Skipped, report.Categories))
+		out.List = append(out.List, report.Messages...)
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	if report.Invalid > 0 {
+		out.Status = outError(path, fmt.Sprintf("the invalid count = %d, nothing imported", report.Invalid), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	out.Status = outLog(path, out.List[0])
+	return nil
+}
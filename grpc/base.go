@@ -5,6 +5,7 @@ import (
 	"github.com/micro/go-micro/v2/logger"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"omo.msa.assignment/cache"
 	"strconv"
 	"strings"
 )
@@ -66,3 +67,93 @@ func parseStringToInt(src string) int64 {
 	}
 	return st
 }
+
+func splitValues(src string) []string {
+	list := make([]string, 0, 5)
+	for _, item := range strings.Split(src, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseUint8Array(src string) ([]uint8, error) {
+	arr := splitValues(src)
+	list := make([]uint8, 0, len(arr))
+	for _, item := range arr {
+		num, err := strconv.ParseUint(item, 10, 8)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, uint8(num))
+	}
+	return list, nil
+}
+
+// 解析列表的分页参数，key可以使用 key@cursor 的格式传入游标，即上一页最后一条数据的uid
+func parseFilterPage(in *pb.RequestFilter) (string, *cache.PageInfo) {
+	key := in.Key
+	page := &cache.PageInfo{Page: in.Page, Number: in.Number}
+	if index := strings.LastIndex(key, "@"); index > 0 {
+		page.Cursor = key[index+1:]
+		key = key[:index]
+	}
+	return key, page
+}
+
+// 只需要统计总数时的分页参数
+func countPage() *cache.PageInfo {
+	return &cache.PageInfo{Page: 1, Number: 1}
+}
+
+// 加入时values依次为成员的名称和备注
+func memberIdentify(values []string) (string, string) {
+	var name, remark string
+	if len(values) > 0 {
+		name = values[0]
+	}
+	if len(values) > 1 {
+		remark = values[1]
+	}
+	return name, remark
+}
+
+// 密码错误返回NotMatch，输错次数过多或者没有设置密码返回Prohibition
+func passwordsStatus(err error) pbstatus.ResultStatus {
+	if err == cache.ErrPasswordsNotMatch {
+		return pbstatus.ResultStatus_NotMatch
+	}
+	return pbstatus.ResultStatus_Prohibition
+}
+
+// 日志中不输出密码
+func hidePasswords(in interface{}) interface{} {
+	const mask = "******"
+	switch req := in.(type) {
+	case *pb.RequestUpdate:
+		if req.Key == "passwords" || req.Key == "verifyPasswords" || req.Key == "joinWithPasswords" {
+			tmp := *req
+			tmp.Value = mask
+			return &tmp
+		}
+	case *pb.ReqCoterieAdd:
+		tmp := *req
+		tmp.Passwords = mask
+		return &tmp
+	case *pb.ReqCoterieUpdate:
+		tmp := *req
+		tmp.Passwords = mask
+		return &tmp
+	case *pb.ReqFamilyAdd:
+		tmp := *req
+		tmp.Passwords = mask
+		return &tmp
+	case *pb.ReqFamilyUpdate:
+		tmp := *req
+		tmp.Passwords = mask
+		return &tmp
+	}
+	return in
+}
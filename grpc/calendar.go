@@ -0,0 +1,224 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"net/url"
+	"omo.msa.assignment/cache"
+	"omo.msa.assignment/config"
+	"omo.msa.assignment/tool"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalendarService 会议的iCalendar(.ics)导入导出，proto中没有单独的定义，复用已有的消息类型，文件内容使用base64编码
+type CalendarService struct{}
+
+// Export key为空时导出owner(场景)的会议，为group时导出小组(value)的会议，为series时导出重复会议(value)的每一次；
+// page和number为分页(为0时导出全部)，返回的list[0]为文件内容
+func (mine *CalendarService) Export(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyList) error {
+	path := "calendar.export"
+	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var list []*cache.MeetingInfo
+	var err error
+	name := ""
+	if key == "" {
+		if len(in.Owner) < 1 {
+			out.Status = outError(path, "the owner is empty ", pbstatus.ResultStatus_Empty)
+			return nil
+		}
+		_, _, list = cache.Context().GetMeetingsByOwner(in.Owner, page)
+	} else if key == "group" {
+		if team, er := cache.Context().GetTeam(in.Value); er == nil {
+			name = team.Name
+		}
+		_, _, list = cache.Context().GetMeetingsByGroup(in.Value, page)
+	} else if key == "series" {
+		_, _, list = cache.Context().GetMeetingsBySeries(in.Value, page)
+		if len(list) > 0 {
+			name = list[0].Name
+		}
+	} else {
+		err = errors.New("the key not defined")
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	data := cache.ExportCalendar(name, list)
+	out.Uid = in.Value
+	out.List = []string{base64.StdEncoding.EncodeToString(data)}
+	out.Status = outLog(path, fmt.Sprintf("the meetings = %d, the size = %d", len(list), len(data)))
+	return nil
+}
+
+// Import uid为导入到的小组，owner为场景，value为.ics文件内容，values中包含dry时只预演不导入；
+// 返回的list[0]为统计，格式为 total=x&created=x&duplicated=x&cancelled=x&invalid=x&failed=x&skipped=x，之后为每个事件的问题
+func (mine *CalendarService) Import(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyList) error {
+	path := "calendar.import"
+	inLog(path, fmt.Sprintf("the group = %s, the owner = %s, the size = %d", in.Uid, in.Owner, len(in.Value)))
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the group is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	body, err := base64.StdEncoding.DecodeString(in.Value)
+	if err != nil || len(body) < 1 {
+		out.Status = outError(path, "the content is empty or not base64", pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	events, err := cache.ParseCalendar(body)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	report, err := cache.Context().ImportCalendar(in.Uid, in.Owner, in.Operator, events, tool.HasItem(in.Values, "dry"))
+	if report != nil {
+		out.Uid = in.Uid
+		out.List = make([]string, 0, len(report.Messages)+1)
+		out.List = append(out.List, fmt.Sprintf("total=%d&created=%d&duplicated=%d&cancelled=%d&invalid=%d&failed=%d&skipped=%d",
+			report.Total, report.Created, report.Duplicated, report.Cancelled, report.Invalid, report.Failed, report.Skipped))
+		out.List = append(out.List, report.Messages...)
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	if report.Invalid > 0 {
+		out.Status = outError(path, fmt.Sprintf("the invalid count = %d, nothing imported", report.Invalid), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	out.Status = outLog(path, out.List[0])
+	return nil
+}
+
+// 冲突以 uid=x&name=x&start=x&stop=x&reasons=group,room,attendee&users=a,b 的格式返回，时间为unix秒
+func switchConflict(item *cache.MeetingConflict) string {
+	params := url.Values{}
+	params.Set("uid", item.Meeting.UID)
+	params.Set("name", item.Meeting.Name)
+	params.Set("start", unixOf(item.Meeting.StartTime))
+	params.Set("stop", unixOf(item.Meeting.StopTime))
+	params.Set("reasons", strings.Join(item.Reasons, ","))
+	params.Set("users", strings.Join(item.Users, ","))
+	return params.Encode()
+}
+
+func timeOfUnix(str string) (time.Time, error) {
+	num, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("the time should be unix seconds: " + str)
+	}
+	return time.Unix(num, 0), nil
+}
+
+// GetConflicts key为meeting时检查已有的会议(value)，values为新的开始和结束时间(为空时使用当前的时间)；
+// key为group时检查小组(value)新的安排，values为开始时间、结束时间(0为默认时长)以及室内的地点(可选)；返回的list为冲突的会议
+func (mine *CalendarService) GetConflicts(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyList) error {
+	path := "calendar.getConflicts"
+	inLog(path, in)
+	if len(in.Value) < 1 {
+		out.Status = outError(path, "the value is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	var start, stop time.Time
+	var err error
+	if len(in.Values) > 1 {
+		start, err = timeOfUnix(in.Values[0])
+		if err == nil && in.Values[1] != "0" {
+			stop, err = timeOfUnix(in.Values[1])
+		}
+		if err != nil {
+			out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+			return nil
+		}
+	}
+	var list []*cache.MeetingConflict
+	if in.Key == "meeting" {
+		info, er := cache.Context().GetMeeting(in.Value)
+		if er != nil || info == nil {
+			out.Status = outError(path, "the meeting not found ", pbstatus.ResultStatus_NotExisted)
+			return nil
+		}
+		list, err = info.Conflicts(start, stop)
+	} else if in.Key == "group" {
+		if start.IsZero() {
+			out.Status = outError(path, "the start time is empty ", pbstatus.ResultStatus_Empty)
+			return nil
+		}
+		plan := &cache.MeetingPlan{Owner: in.Owner, Group: in.Value, Type: cache.InRoom, Start: start, Stop: stop}
+		if len(in.Values) > 2 {
+			plan.Location = in.Values[2]
+		}
+		list, err = cache.Context().FindConflicts(plan)
+	} else {
+		err = errors.New("the key not defined")
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Value
+	out.List = make([]string, 0, len(list))
+	for _, item := range list {
+		out.List = append(out.List, switchConflict(item))
+	}
+	out.Status = outLog(path, fmt.Sprintf("the conflicts = %d", len(list)))
+	return nil
+}
+
+// FindSlots value为小组，values依次为时长(秒)、数量、开始时间(unix秒，默认为现在)、结束时间(默认为14天后)以及工作时间(如09:00-18:00，默认使用配置)；
+// 返回的list为空闲的时间段，格式为 start=x&stop=x
+func (mine *CalendarService) FindSlots(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyList) error {
+	path := "calendar.findSlots"
+	inLog(path, in)
+	if len(in.Value) < 1 || len(in.Values) < 2 {
+		out.Status = outError(path, "the group, length or count is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	length := parseStringToInt(in.Values[0])
+	count := parseStringToInt(in.Values[1])
+	if length < 1 || count < 1 {
+		out.Status = outError(path, "the length and count should be positive", pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	from := time.Now()
+	to := from.AddDate(0, 0, 14)
+	var err error
+	if len(in.Values) > 2 && len(in.Values[2]) > 0 {
+		from, err = timeOfUnix(in.Values[2])
+	}
+	if err == nil && len(in.Values) > 3 && len(in.Values[3]) > 0 {
+		to, err = timeOfUnix(in.Values[3])
+	}
+	conf := config.Schema.Meeting
+	work := conf.WorkHours
+	if len(in.Values) > 4 {
+		work = in.Values[4]
+	}
+	var hours *cache.WorkHours
+	if err == nil {
+		hours, err = cache.ParseWorkHours(work, conf.WorkDays)
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	list, err := cache.Context().FindFreeSlots(in.Owner, in.Value, time.Duration(length)*time.Second, int(count), from, to, hours)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Value
+	out.List = make([]string, 0, len(list))
+	for _, item := range list {
+		out.List = append(out.List, fmt.Sprintf("start=%d&stop=%d", item.Start.Unix(), item.Stop.Unix()))
+	}
+	out.Status = outLog(path, fmt.Sprintf("the slots = %d", len(list)))
+	return nil
+}
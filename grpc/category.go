@@ -24,7 +24,7 @@ func switchCategory(info *cache.CategoryInfo) *pb.CategoryInfo {
 	tmp.Parent = info.Parent
 	tmp.Source = info.Quote
 	tmp.Weight = info.Weight
-	children, _ := cache.Context().GetCategoriesByParent(info.UID)
+	_, _, children, _ := cache.Context().GetCategoriesByParent(info.UID, nil)
 	tmp.Children = make([]*pb.CategoryInfo, 0, len(children))
 	for _, child := range children {
 		v := switchCategory(child)
@@ -105,6 +105,9 @@ func (mine *CategoryService) RemoveOne(ctx context.Context, in *pb.RequestInfo,
 func (mine *CategoryService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyCategoryList) error {
 	path := "category.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var total uint32 = 0
+	var max uint32 = 0
 	var list []*cache.CategoryInfo
 	var err error
 
@@ -112,10 +115,10 @@ func (mine *CategoryService) GetListByFilter(ctx context.Context, in *pb.Request
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
 		return nil
 	}
-	if in.Key == "scene" {
-		list, err = cache.Context().GetTopCategoriesByScene(in.Value)
-	} else if in.Key == "parent" {
-		list, err = cache.Context().GetCategoriesByParent(in.Value)
+	if key == "scene" {
+		total, max, list, err = cache.Context().GetTopCategoriesByScene(in.Value, page)
+	} else if key == "parent" {
+		total, max, list, err = cache.Context().GetCategoriesByParent(in.Value, page)
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -128,6 +131,9 @@ func (mine *CategoryService) GetListByFilter(ctx context.Context, in *pb.Request
 	for _, value := range list {
 		out.List = append(out.List, switchCategory(value))
 	}
+	out.PageNow = in.Page
+	out.Total = total
+	out.PageMax = max
 	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
 	return nil
 }
@@ -27,7 +27,6 @@ func switchCoterie(info *cache.CoterieInfo) *pb.CoterieInfo {
 	tmp.Meta = info.Meta
 	tmp.Type = uint32(info.Type)
 	tmp.Status = uint32(info.Status)
-	tmp.Passwords = info.Passwords
 	tmp.Assistants = info.Assistants
 	tmp.Tags = info.Tags
 	tmp.Members = make([]*pb.IdentifyInfo, 0, len(info.Members))
@@ -39,7 +38,7 @@ func switchCoterie(info *cache.CoterieInfo) *pb.CoterieInfo {
 
 func (mine *CoterieService) AddOne(ctx context.Context, in *pb.ReqCoterieAdd, out *pb.ReplyCoterieInfo) error {
 	path := "coterie.addOne"
-	inLog(path, in)
+	inLog(path, hidePasswords(in))
 	if len(in.Name) < 1 {
 		out.Status = outError(path, "the name is empty ", pbstatus.ResultStatus_Empty)
 		return nil
@@ -123,20 +122,21 @@ func (mine *CoterieService) RemoveOne(ctx context.Context, in *pb.RequestInfo, o
 func (mine *CoterieService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyCoterieList) error {
 	path := "coterie.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
 	var list []*cache.CoterieInfo
 	var err error
 	var total uint32 = 0
 	var pages uint32 = 0
-	if in.Key == "user" {
-		list, err = cache.Context().GetCoteriesByMember(in.Value)
-	} else if in.Key == "member" {
-		list, err = cache.Context().GetCoteriesByMember(in.Value)
-	} else if in.Key == "creator" {
-		list, err = cache.Context().GetCoteriesByCreator(in.Value)
-	} else if in.Key == "master" {
-		list, err = cache.Context().GetCoteriesByMaster(in.Value)
-	} else if in.Key == "all" {
-		total, pages, list, err = cache.Context().GetAllCoteries(in.Page, in.Number)
+	if key == "user" {
+		total, pages, list, err = cache.Context().GetCoteriesByMember(in.Value, page)
+	} else if key == "member" {
+		total, pages, list, err = cache.Context().GetCoteriesByMember(in.Value, page)
+	} else if key == "creator" {
+		total, pages, list, err = cache.Context().GetCoteriesByCreator(in.Value, page)
+	} else if key == "master" {
+		total, pages, list, err = cache.Context().GetCoteriesByMaster(in.Value, page)
+	} else if key == "all" {
+		total, pages, list, err = cache.Context().GetAllCoteries(page)
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -156,7 +156,7 @@ func (mine *CoterieService) GetListByFilter(ctx context.Context, in *pb.RequestF
 
 func (mine *CoterieService) UpdateBase(ctx context.Context, in *pb.ReqCoterieUpdate, out *pb.ReplyInfo) error {
 	path := "coterie.updateBase"
-	inLog(path, in)
+	inLog(path, hidePasswords(in))
 	if len(in.Uid) < 1 {
 		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
 		return nil
@@ -178,7 +178,7 @@ func (mine *CoterieService) UpdateBase(ctx context.Context, in *pb.ReqCoterieUpd
 
 func (mine *CoterieService) UpdateByFilter(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyInfo) error {
 	path := "coterie.updateByFilter"
-	inLog(path, in)
+	inLog(path, hidePasswords(in))
 	if len(in.Uid) < 1 {
 		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
 		return nil
@@ -188,6 +188,22 @@ func (mine *CoterieService) UpdateByFilter(ctx context.Context, in *pb.RequestUp
 		out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
 		return nil
 	}
+	if in.Key == "verifyPasswords" || in.Key == "joinWithPasswords" {
+		var err error
+		if in.Key == "verifyPasswords" {
+			err = info.VerifyPasswords(in.Operator, in.Value)
+		} else {
+			name, remark := memberIdentify(in.Values)
+			err = info.JoinWithPasswords(in.Operator, name, remark, in.Value)
+		}
+		if err != nil {
+			out.Status = outError(path, err.Error(), passwordsStatus(err))
+			return nil
+		}
+		out.Uid = in.Uid
+		out.Status = outLog(path, out)
+		return nil
+	}
 	var err error
 	if in.Key == "passwords" {
 		err = info.UpdatePasswords(in.Value, in.Operator)
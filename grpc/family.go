@@ -28,7 +28,6 @@ func switchFamily(info *cache.FamilyInfo) *pb.FamilyInfo {
 	tmp.Region = info.Region
 	tmp.Status = uint32(info.Status)
 	tmp.Location = info.Location
-	tmp.Passwords = info.Passwords
 	tmp.Assistants = info.Assistants
 	tmp.Tags = info.Tags
 	tmp.Agents = info.Agents
@@ -41,7 +40,7 @@ func switchFamily(info *cache.FamilyInfo) *pb.FamilyInfo {
 
 func (mine *FamilyService) AddOne(ctx context.Context, in *pb.ReqFamilyAdd, out *pb.ReplyFamilyInfo) error {
 	path := "family.addOne"
-	inLog(path, in)
+	inLog(path, hidePasswords(in))
 	if len(in.Name) < 1 {
 		out.Status = outError(path, "the name is empty ", pbstatus.ResultStatus_Empty)
 		return nil
@@ -103,8 +102,7 @@ func (mine *FamilyService) GetStatistic(ctx context.Context, in *pb.RequestFilte
 		return nil
 	}
 	if in.Key == "region" {
-		list,_ := cache.Context().GetFamiliesByRegion(in.Value)
-		out.Count = uint32(len(list))
+		out.Count, _, _, _ = cache.Context().GetFamiliesByRegion(in.Value, countPage())
 	}
 
 	out.Status = outLog(path, out)
@@ -131,16 +129,19 @@ func (mine *FamilyService) RemoveOne(ctx context.Context, in *pb.RequestInfo, ou
 func (mine *FamilyService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyFamilyList) error {
 	path := "family.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var total uint32 = 0
+	var pages uint32 = 0
 	var list []*cache.FamilyInfo
 	var err error
-	if in.Key == "region" {
-		list,err = cache.Context().GetFamiliesByRegion(in.Value)
-	} else if in.Key == "regions" {
-		list,err = cache.Context().GetFamiliesByRegions(in.Values)
-	} else if in.Key == "user" {
-		list,err = cache.Context().GetFamiliesByMember(in.Value)
-	} else if in.Key == "agent" {
-		list,err = cache.Context().GetFamiliesByAgent(in.Value)
+	if key == "region" {
+		total, pages, list, err = cache.Context().GetFamiliesByRegion(in.Value, page)
+	} else if key == "regions" {
+		total, pages, list, err = cache.Context().GetFamiliesByRegions(in.Values, page)
+	} else if key == "user" {
+		total, pages, list, err = cache.Context().GetFamiliesByMember(in.Value, page)
+	} else if key == "agent" {
+		total, pages, list, err = cache.Context().GetFamiliesByAgent(in.Value, page)
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -152,13 +153,15 @@ func (mine *FamilyService) GetListByFilter(ctx context.Context, in *pb.RequestFi
 	for _, value := range list {
 		out.List = append(out.List, switchFamily(value))
 	}
+	out.Total = total
+	out.Pages = pages
 	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
 	return nil
 }
 
 func (mine *FamilyService) UpdateBase(ctx context.Context, in *pb.ReqFamilyUpdate, out *pb.ReplyInfo) error {
 	path := "family.updateBase"
-	inLog(path, in)
+	inLog(path, hidePasswords(in))
 	if len(in.Uid) < 1 {
 		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
 		return nil
@@ -180,7 +183,7 @@ func (mine *FamilyService) UpdateBase(ctx context.Context, in *pb.ReqFamilyUpdat
 
 func (mine *FamilyService) UpdateByFilter(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyInfo) error {
 	path := "family.updateByFilter"
-	inLog(path, in)
+	inLog(path, hidePasswords(in))
 	if len(in.Uid) < 1 {
 		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
 		return nil
@@ -190,6 +193,22 @@ func (mine *FamilyService) UpdateByFilter(ctx context.Context, in *pb.RequestUpd
 		out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
 		return nil
 	}
+	if in.Key == "verifyPasswords" || in.Key == "joinWithPasswords" {
+		var err error
+		if in.Key == "verifyPasswords" {
+			err = info.VerifyPasswords(in.Operator, in.Value)
+		} else {
+			name, remark := memberIdentify(in.Values)
+			err = info.JoinWithPasswords(in.Operator, name, remark, in.Value)
+		}
+		if err != nil {
+			out.Status = outError(path, err.Error(), passwordsStatus(err))
+			return nil
+		}
+		out.Uid = in.Uid
+		out.Status = outLog(path, out)
+		return nil
+	}
 	var err error
 	if in.Key == "passwords" {
 		err = info.UpdatePasswords(in.Value, in.Operator)
@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"omo.msa.assignment/cache"
+)
+
+// TeamTreeService 小组的层级，proto中没有单独的定义，复用已有的消息类型；
+// 下级和上级的列表使用TeamService.GetListByFilter(key为children、descendants或者ancestors)
+type TeamTreeService struct{}
+
+// Move uid为移动的小组，value为新的上级小组(为空时为最上级)，下级小组一起移动；不能移动到自己或者自己的下级
+func (mine *TeamTreeService) Move(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyInfo) error {
+	path := "teamTree.move"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetTeam(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the team not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	if len(in.Value) > 0 {
+		if _, er = cache.Context().GetTeam(in.Value); er != nil {
+			out.Status = outError(path, "the parent team not found ", pbstatus.ResultStatus_NotExisted)
+			return nil
+		}
+	}
+	err := info.MoveTo(in.Value, in.Operator)
+	if err == cache.ErrTeamCycle || err == cache.ErrTeamOwner {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_Prohibition)
+		return nil
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// GetMembers uid为小组，返回小组以及所有下级小组的成员，重复的只返回一次
+func (mine *TeamTreeService) GetMembers(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyList) error {
+	path := "teamTree.getMembers"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetTeam(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the team not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	list, err := info.AllMembers()
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.List = list
+	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(list)))
+	return nil
+}
@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"net/url"
+	"omo.msa.assignment/cache"
+	"omo.msa.assignment/tool"
+	"strconv"
+	"time"
+)
+
+// InvitationService 圈子和家庭的邀请码，proto中没有单独的定义，复用已有的消息类型
+type InvitationService struct{}
+
+func invitationType(key string) (uint8, error) {
+	switch key {
+	case "coterie":
+		return cache.ApplyTypeCoterie, nil
+	case "family":
+		return cache.ApplyTypeFamily, nil
+	}
+	return 0, errors.New("the invitation key should be coterie or family")
+}
+
+// 邀请码以 code=x&type=x&group=x&limit=x&used=x&expired=x&state=x&name=x&remark=x&inviter=x 的格式返回，expired为unix秒，0表示不过期
+func switchInvitation(info *cache.InvitationInfo, now time.Time) string {
+	params := url.Values{}
+	params.Set("code", info.Code)
+	params.Set("type", strconv.Itoa(int(info.Type)))
+	params.Set("group", info.Group)
+	params.Set("limit", strconv.Itoa(int(info.Limit)))
+	params.Set("used", strconv.Itoa(int(info.Used)))
+	var expired int64 = 0
+	if !info.Expired.IsZero() {
+		expired = info.Expired.Unix()
+	}
+	params.Set("expired", strconv.FormatInt(expired, 10))
+	params.Set("state", info.State(now))
+	params.Set("name", info.Name)
+	params.Set("remark", info.Remark)
+	params.Set("inviter", info.Creator)
+	return params.Encode()
+}
+
+// Create key为coterie或者family，uid为圈子或者家庭，owner为场景，operator为邀请人；
+// value为 count=1&expire=86400&name=xx&remark=xx，count为可以使用的次数(默认1)，expire为有效秒数(0为不过期)，
+// name和remark为预设的成员名称和备注；返回的uid为邀请码
+func (mine *InvitationService) Create(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyInfo) error {
+	path := "invitation.create"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the group is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	tp, err := invitationType(in.Key)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	params, err := url.ParseQuery(in.Value)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	var count uint64 = 1
+	if len(params.Get("count")) > 0 {
+		count, err = strconv.ParseUint(params.Get("count"), 10, 32)
+		if err != nil || count < 1 {
+			out.Status = outError(path, "the count should be a positive number", pbstatus.ResultStatus_FormatError)
+			return nil
+		}
+	}
+	var expire uint64 = 0
+	if len(params.Get("expire")) > 0 {
+		expire, err = strconv.ParseUint(params.Get("expire"), 10, 32)
+		if err != nil {
+			out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+			return nil
+		}
+	}
+	info, err := cache.Context().CreateInvitation(tp, in.Owner, in.Uid, params.Get("name"), params.Get("remark"),
+		in.Operator, uint32(count), time.Duration(expire)*time.Second)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	out.Uid = info.Code
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// Redeem uid为邀请码，user为加入的用户(为空时为operator)，name为成员名称；返回的uid为记录的申请
+func (mine *InvitationService) Redeem(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyInfo) error {
+	path := "invitation.redeem"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the code is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	user := in.User
+	if len(user) < 1 {
+		user = in.Operator
+	}
+	if len(user) < 1 {
+		out.Status = outError(path, "the user is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetInvitationByCode(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the invitation not found", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	apply, err := info.Redeem(user, in.Name)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_Prohibition)
+		return nil
+	}
+	out.Uid = apply.UID
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// Revoke uid为邀请码
+func (mine *InvitationService) Revoke(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyInfo) error {
+	path := "invitation.revoke"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the code is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetInvitationByCode(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the invitation not found", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	err := info.Revoke(in.Operator)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// GetList value为圈子或者家庭，默认只返回还可以使用的邀请码，values中包含all时返回全部(包括已撤销的)
+func (mine *InvitationService) GetList(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyList) error {
+	path := "invitation.getList"
+	inLog(path, in)
+	if len(in.Value) < 1 {
+		out.Status = outError(path, "the group is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	list, err := cache.Context().GetInvitationsByGroup(in.Value, tool.HasItem(in.Values, "all"))
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	now := time.Now()
+	out.Uid = in.Value
+	out.List = make([]string, 0, len(list))
+	for _, info := range list {
+		out.List = append(out.List, switchInvitation(info, now))
+	}
+	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
+	return nil
+}
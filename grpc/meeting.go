@@ -6,7 +6,11 @@ import (
 	"fmt"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"net/url"
 	"omo.msa.assignment/cache"
+	"omo.msa.assignment/proxy"
+	"strconv"
+	"time"
 )
 
 type MeetingService struct{}
@@ -45,6 +49,14 @@ func (mine *MeetingService) AddOne(ctx context.Context, in *pb.ReqMeetingAdd, ou
 	}
 
 	info, err := cache.Context().CreateMeeting(in)
+	if err == cache.ErrLocationFormat {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	if cache.ConflictsOf(err) != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_Repeated)
+		return nil
+	}
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
 		return nil
@@ -71,14 +83,47 @@ func (mine *MeetingService) GetOne(ctx context.Context, in *pb.RequestInfo, out
 	return nil
 }
 
+// GetStatistic 会议的出勤统计，value为会议，key为expected、signed、late、absent、left、submitted、notified、acked或者extra
 func (mine *MeetingService) GetStatistic(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyStatistic) error {
 	path := "meeting.getStatistic"
 	inLog(path, in)
 	if len(in.Key) < 1 {
-		out.Status = outError(path, "the user is empty ", pbstatus.ResultStatus_Empty)
+		out.Status = outError(path, "the key is empty ", pbstatus.ResultStatus_Empty)
 		return nil
 	}
-
+	info, er := cache.Context().GetMeeting(in.Value)
+	if er != nil || info == nil {
+		out.Status = outError(path, "the meeting not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	report := info.GetAttendance()
+	var count int
+	switch in.Key {
+	case "expected":
+		count = report.Expected
+	case "signed":
+		count = report.Signed
+	case "late":
+		count = report.Late
+	case "absent":
+		count = report.Absent
+	case "left":
+		count = report.LeftEarly
+	case "submitted":
+		count = report.Submitted
+	case "notified":
+		count = report.Notified
+	case "acked":
+		count = report.Acked
+	case "extra":
+		count = report.Extra
+	default:
+		out.Status = outError(path, "the key not defined", pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	out.Key = in.Key
+	out.Owner = in.Value
+	out.Count = uint32(count)
 	out.Status = outLog(path, out)
 	return nil
 }
@@ -103,17 +148,28 @@ func (mine *MeetingService) RemoveOne(ctx context.Context, in *pb.RequestInfo, o
 func (mine *MeetingService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyMeetingList) error {
 	path := "meeting.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var total uint32 = 0
+	var max uint32 = 0
 	var list []*cache.MeetingInfo
 	var err error
-	if in.Key == "" {
-		list = cache.Context().GetMeetingsByOwner(in.Owner)
-	} else if in.Key == "type" {
+	if key == "" {
+		total, max, list = cache.Context().GetMeetingsByOwner(in.Owner, page)
+	} else if key == "type" {
 
-	} else if in.Key == "group" {
-		list = cache.Context().GetMeetingsByGroup(in.Value)
-	} else if in.Key == "time" {
+	} else if key == "group" {
+		total, max, list = cache.Context().GetMeetingsByGroup(in.Value, page)
+	} else if key == "status" {
+		var status int
+		status, err = strconv.Atoi(in.Value)
+		if err == nil {
+			total, max, list, err = cache.Context().GetMeetingsByStatus(in.Owner, cache.MeetingStatus(status), page)
+		}
+	} else if key == "series" {
+		total, max, list = cache.Context().GetMeetingsBySeries(in.Value, page)
+	} else if key == "time" {
 		if len(in.Values) > 1 {
-			list, err = cache.Context().GetMeetingsByTime(in.Owner, in.Values[0], in.Values[1])
+			total, max, list, err = cache.Context().GetMeetingsByTime(in.Owner, in.Values[0], in.Values[1], page)
 		} else {
 			err = errors.New("the params is error")
 		}
@@ -128,7 +184,9 @@ func (mine *MeetingService) GetListByFilter(ctx context.Context, in *pb.RequestF
 	for _, value := range list {
 		out.List = append(out.List, switchMeeting(value))
 	}
-
+	out.PageNow = in.Page
+	out.Total = total
+	out.PageMax = max
 	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
 	return nil
 }
@@ -150,6 +208,12 @@ func (mine *MeetingService) UpdateByFilter(ctx context.Context, in *pb.RequestUp
 		err = info.Submit(in.Value, in.Operator)
 	} else if in.Key == "location" {
 		err = info.UpdateLocation(in.Value, in.Operator, info.Type)
+	} else if in.Key == "sign" {
+		var radius, before, late int32
+		radius, before, late, err = parseSignRule(in.Value, info)
+		if err == nil {
+			err = info.UpdateSignRule(radius, before, late, in.Operator)
+		}
 	} else if in.Key == "stop" {
 		err = info.UpdateStop(in.Value, in.Operator)
 	} else if in.Key == "group" {
@@ -158,9 +222,31 @@ func (mine *MeetingService) UpdateByFilter(ctx context.Context, in *pb.RequestUp
 		begin := parseStringToInt(in.Values[0])
 		end := parseStringToInt(in.Values[1])
 		err = info.UpdateStartEnd(begin, end, in.Operator)
+	} else if in.Key == "rule" {
+		err = info.UpdateRule(in.Value, in.Operator)
+	} else if in.Key == "following" {
+		// values为新的开始和结束时间，为空时不修改；value为新的规则，为空时使用原来的规则
+		begin := info.StartTime.Unix()
+		end := info.StopTime.Unix()
+		if len(in.Values) > 1 {
+			begin = parseStringToInt(in.Values[0])
+			end = parseStringToInt(in.Values[1])
+		}
+		err = info.UpdateFollowing(begin, end, in.Value, in.Operator)
+	} else if in.Key == "cancel" {
+		// values[0]为按照规则计算的开始时间，可以取消还没有生成的会议
+		if len(in.Values) > 0 {
+			err = info.CancelOccurrence(time.Unix(parseStringToInt(in.Values[0]), 0), in.Operator)
+		} else {
+			err = info.Cancel(in.Operator)
+		}
 	} else {
 		err = errors.New("the key not defined")
 	}
+	if cache.ConflictsOf(err) != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_Repeated)
+		return nil
+	}
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
 		return nil
@@ -230,11 +316,60 @@ func (mine *MeetingService) Sign(ctx context.Context, in *pb.ReqMeetingSign, out
 		out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
 		return nil
 	}
-	err := info.Sign(in.Member, in.Operator, in.Location)
+	record, err := info.Sign(in.Member, in.Operator, in.Location, "")
+	if record != nil {
+		out.Uid = switchSign(record)
+	}
 	if err != nil {
-		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		out.Status = outError(path, err.Error(), signStatus(err))
 		return nil
 	}
 	out.Status = outLog(path, out)
 	return nil
 }
+
+// 签到的记录以 user=x&state=x&distance=x&signed=x 的格式返回，state为0(按时)、1(迟到)或者2(超出范围)，distance为米(-1表示没有位置)，signed为unix秒
+func switchSign(record *proxy.SignInfo) string {
+	params := url.Values{}
+	params.Set("user", record.User)
+	params.Set("state", strconv.Itoa(int(record.State)))
+	params.Set("distance", strconv.FormatFloat(record.Distance, 'f', -1, 64))
+	var signed int64 = 0
+	if !record.CreatedTime.IsZero() {
+		signed = record.CreatedTime.Unix()
+	}
+	params.Set("signed", strconv.FormatInt(signed, 10))
+	return params.Encode()
+}
+
+func signStatus(err error) pbstatus.ResultStatus {
+	switch err {
+	case cache.ErrSignOutside:
+		return pbstatus.ResultStatus_NotMatch
+	case cache.ErrSignNotOpen, cache.ErrSignClosed:
+		return pbstatus.ResultStatus_Prohibition
+	case cache.ErrLocationFormat:
+		return pbstatus.ResultStatus_FormatError
+	}
+	return pbstatus.ResultStatus_DBException
+}
+
+// 签到的规则为 radius=200&before=1800&late=300，radius为米，before和late为开始前后的秒数，0为默认值，小于0为不限制
+func parseSignRule(value string, info *cache.MeetingInfo) (int32, int32, int32, error) {
+	params, err := url.ParseQuery(value)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	result := []int32{info.Radius, info.SignBefore, info.SignLate}
+	for i, key := range []string{"radius", "before", "late"} {
+		if len(params.Get(key)) < 1 {
+			continue
+		}
+		num, err := strconv.ParseInt(params.Get(key), 10, 32)
+		if err != nil {
+			return 0, 0, 0, errors.New(fmt.Sprintf("the %s of sign rule should be a number", key))
+		}
+		result[i] = int32(num)
+	}
+	return result[0], result[1], result[2], nil
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"net/url"
 	"omo.msa.assignment/cache"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type QuestionService struct{}
@@ -107,18 +110,24 @@ func (mine *QuestionService) RemoveOne(ctx context.Context, in *pb.RequestInfo,
 }
 
 func (mine *QuestionService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyQuestionList) error {
+	if in.Key == "paper" {
+		return mine.generatePaper(in, out)
+	}
 	path := "question.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var total uint32 = 0
+	var max uint32 = 0
 	var list []*cache.QuestionInfo
 	var err error
-	if in.Key == "entity" {
-		list, err = cache.Context().GetQuestionsByEntity(in.Value)
-	} else if in.Key == "name" {
-		list, err = cache.Context().GetQuestionsByName(in.Value)
-	} else if in.Key == "category" {
-		list, err = cache.Context().GetQuestionsByCategory(in.Value)
-	} else if in.Key == "name_kind" {
-		list, err = cache.Context().GetQuestionsByNameAndKind(in.Value, in.Owner)
+	if key == "entity" {
+		total, max, list, err = cache.Context().GetQuestionsByEntity(in.Value, page)
+	} else if key == "name" {
+		total, max, list, err = cache.Context().GetQuestionsByName(in.Value, page)
+	} else if key == "category" {
+		total, max, list, err = cache.Context().GetQuestionsByCategory(in.Value, page)
+	} else if key == "name_kind" {
+		total, max, list, err = cache.Context().GetQuestionsByNameAndKind(in.Value, in.Owner, page)
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -131,10 +140,96 @@ func (mine *QuestionService) GetListByFilter(ctx context.Context, in *pb.Request
 	for _, value := range list {
 		out.List = append(out.List, switchQuestion(value))
 	}
+	out.PageNow = in.Page
+	out.Total = total
+	out.PageMax = max
 	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
 	return nil
 }
 
+// 随机组卷，value为组卷参数，例如：
+// categories=xx:5,yy:0:2,zz:0:1&total=20&seed=123&shuffle=1&user=xx&recent=86400
+// categories中每一项为 分类:数量:权重，数量为0时按照权重分配total中剩余的题目，recent为排除用户最近答过题目的秒数
+func parsePaperOption(query string) (*cache.PaperOption, error) {
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	option := new(cache.PaperOption)
+	for _, item := range splitValues(params.Get("categories")) {
+		arr := strings.Split(item, ":")
+		if len(arr) > 3 {
+			return nil, errors.New("the paper rule format is error: " + item)
+		}
+		rule := cache.PaperRule{Category: arr[0]}
+		if len(arr) > 1 && len(arr[1]) > 0 {
+			count, err := strconv.ParseUint(arr[1], 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			rule.Count = uint32(count)
+		}
+		if len(arr) > 2 && len(arr[2]) > 0 {
+			weight, err := strconv.ParseUint(arr[2], 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			rule.Weight = uint32(weight)
+		}
+		option.Rules = append(option.Rules, rule)
+	}
+	if len(params.Get("total")) > 0 {
+		total, err := strconv.ParseUint(params.Get("total"), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		option.Total = uint32(total)
+	}
+	if len(params.Get("seed")) > 0 {
+		option.Seed, err = strconv.ParseInt(params.Get("seed"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	option.Shuffle = params.Get("shuffle") == "1" || params.Get("shuffle") == "true"
+	option.User = params.Get("user")
+	if len(params.Get("recent")) > 0 {
+		recent, err := strconv.ParseUint(params.Get("recent"), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		option.Recent = time.Duration(recent) * time.Second
+	}
+	return option, nil
+}
+
+func (mine *QuestionService) generatePaper(in *pb.RequestFilter, out *pb.ReplyQuestionList) error {
+	path := "question.generatePaper"
+	inLog(path, in)
+	option, err := parsePaperOption(in.Value)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	seed, list, err := cache.Context().GeneratePaper(option)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_NotMatch)
+		return nil
+	}
+	out.List = make([]*pb.QuestionInfo, 0, len(list))
+	for _, value := range list {
+		// 试卷发给答题的用户，不能带上答案
+		tmp := switchQuestion(value)
+		tmp.Answers = nil
+		out.List = append(out.List, tmp)
+	}
+	out.Total = uint32(len(out.List))
+	out.PageNow = 1
+	out.PageMax = 1
+	out.Status = outLog(path, fmt.Sprintf("the seed = %d, the length = %d", seed, len(out.List)))
+	return nil
+}
+
 func (mine *QuestionService) UpdateBase(ctx context.Context, in *pb.ReqQuestionUpdate, out *pb.ReplyQuestionOne) error {
 	path := "question.updateBase"
 	inLog(path, in)
@@ -0,0 +1,183 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"omo.msa.assignment/cache"
+	"omo.msa.assignment/proxy"
+	"strconv"
+)
+
+// QuizService 答题服务，proto中没有单独的定义，复用已有的消息类型：
+// 答题记录以RecordInfo返回，name为题目，status为1表示答对，remark为分类，tags为提交的选项
+type QuizService struct{}
+
+func switchQuizRecord(info *cache.QuizRecordInfo) *pb.RecordInfo {
+	tmp := new(pb.RecordInfo)
+	tmp.Uid = info.UID
+	tmp.Created = info.CreateTime.Unix()
+	tmp.Creator = info.Creator
+	tmp.Name = info.Question
+	tmp.Remark = info.Category
+	tmp.Executor = info.User
+	if info.Correct {
+		tmp.Status = 1
+	}
+	tmp.Tags = make([]string, 0, len(info.Answers))
+	for _, item := range info.Answers {
+		tmp.Tags = append(tmp.Tags, strconv.FormatUint(uint64(item), 10))
+	}
+	tmp.Assets = make([]string, 0, 1)
+	return tmp
+}
+
+// 排行榜以RecordInfo返回，uid为用户，status为答对的次数，tags依次为答对的次数和答题次数
+func switchQuizScore(category string, info *proxy.QuizScore) *pb.RecordInfo {
+	tmp := new(pb.RecordInfo)
+	tmp.Uid = info.User
+	tmp.Creator = info.User
+	tmp.Executor = info.User
+	tmp.Name = category
+	tmp.Status = info.Correct
+	tmp.Tags = []string{strconv.FormatUint(uint64(info.Correct), 10), strconv.FormatUint(uint64(info.Total), 10)}
+	tmp.Assets = make([]string, 0, 1)
+	return tmp
+}
+
+func parseAnswers(list []string) ([]uint32, error) {
+	arr := make([]uint32, 0, len(list))
+	for _, item := range list {
+		num, err := strconv.ParseUint(item, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, uint32(num))
+	}
+	return arr, nil
+}
+
+// Submit 提交答案，uid为题目，operator为答题的用户，owner为场景，values为选择的选项
+func (mine *QuizService) Submit(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyTaskRecords) error {
+	path := "quiz.submit"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the question uid is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	if len(in.Operator) < 1 {
+		out.Status = outError(path, "the user is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	answers, er := parseAnswers(in.Values)
+	if er != nil || len(answers) < 1 {
+		out.Status = outError(path, "the answers is empty or format error", pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	_, er = cache.Context().GetQuestion(in.Uid)
+	if er != nil {
+		out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	info, err := cache.Context().SubmitAnswers(in.Owner, in.Operator, in.Uid, answers)
+	if errors.Is(err, cache.ErrQuizCoolingDown) {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_Prohibition)
+		return nil
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Task = in.Uid
+	out.List = []*pb.RecordInfo{switchQuizRecord(info)}
+	out.Status = outLog(path, out)
+	return nil
+}
+
+func (mine *QuizService) GetOne(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyTaskRecords) error {
+	path := "quiz.getOne"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetQuizRecord(in.Uid)
+	if er != nil {
+		out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	out.Task = info.Question
+	out.List = []*pb.RecordInfo{switchQuizRecord(info)}
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// GetListByFilter key为user(用户的答题历史)、question(题目的答题记录)、ranking(分类的排行榜，number为名次数量)
+func (mine *QuizService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyTaskRecords) error {
+	path := "quiz.getListByFilter"
+	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var list []*cache.QuizRecordInfo
+	var err error
+	if key == "user" {
+		_, _, list, err = cache.Context().GetQuizRecordsByUser(in.Owner, in.Value, page)
+	} else if key == "question" {
+		_, _, list, err = cache.Context().GetQuizRecordsByQuestion(in.Value, page)
+	} else if key == "ranking" {
+		var scores []*proxy.QuizScore
+		scores, err = cache.Context().GetQuizRanking(in.Value, in.Number)
+		if err == nil {
+			out.List = make([]*pb.RecordInfo, 0, len(scores))
+			for _, score := range scores {
+				out.List = append(out.List, switchQuizScore(in.Value, score))
+			}
+		}
+	} else {
+		err = errors.New("the key not defined")
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	if key != "ranking" {
+		out.List = make([]*pb.RecordInfo, 0, len(list))
+		for _, value := range list {
+			out.List = append(out.List, switchQuizRecord(value))
+		}
+	}
+	out.Task = in.Value
+	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
+	return nil
+}
+
+// GetStatistic key为user(答题次数)、question(答题次数)、correct(答对次数)、accuracy(正确率的百分比)
+func (mine *QuizService) GetStatistic(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyStatistic) error {
+	path := "quiz.getStatistic"
+	inLog(path, in)
+	var err error
+	if in.Key == "user" {
+		out.Count, _, _, err = cache.Context().GetQuizRecordsByUser(in.Owner, in.Value, countPage())
+	} else if in.Key == "question" || in.Key == "correct" || in.Key == "accuracy" {
+		var total, right uint32
+		total, right, err = cache.Context().GetQuestionAccuracy(in.Value)
+		if in.Key == "question" {
+			out.Count = total
+		} else if in.Key == "correct" {
+			out.Count = right
+		} else if total > 0 {
+			out.Count = right * 100 / total
+		}
+	} else {
+		err = errors.New("the key not defined")
+	}
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Key = in.Key
+	out.Owner = in.Owner
+	out.Status = outLog(path, out)
+	return nil
+}
@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"net/url"
+	"omo.msa.assignment/cache"
+	"omo.msa.assignment/proxy"
+	"strings"
+)
+
+// RosterService 小组、家庭和圈子的批量成员修改，proto中没有单独的定义，复用已有的消息类型；
+// uid为小组、家庭或者圈子，key为team、family或者coterie，values为成员；每次修改在数据库中为一次更新
+type RosterService struct{}
+
+// 成员为用户uid，或者 user=x&name=x&remark=x (名字和备注只用于家庭和圈子)
+func parseRoster(values []string) ([]proxy.MemberInfo, error) {
+	list := make([]proxy.MemberInfo, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "=") {
+			list = append(list, proxy.MemberInfo{User: strings.TrimSpace(value)})
+			continue
+		}
+		query, err := url.ParseQuery(value)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, proxy.MemberInfo{User: strings.TrimSpace(query.Get("user")), Name: query.Get("name"), Remark: query.Get("remark")})
+	}
+	return list, nil
+}
+
+// list[0]为统计 total=x&added=x&member=x&removed=x&none=x&rejected=x&promoted=x，之后为每个成员的结果 user=x&state=x(&reason=x)
+func switchRoster(results []*cache.RosterResult) []string {
+	counts := make(map[string]int, 6)
+	list := make([]string, 0, len(results)+1)
+	list = append(list, "")
+	for _, result := range results {
+		counts[result.State] += 1
+		item := fmt.Sprintf("user=%s&state=%s", url.QueryEscape(result.User), result.State)
+		if len(result.Reason) > 0 {
+			item += "&reason=" + url.QueryEscape(result.Reason)
+		}
+		list = append(list, item)
+	}
+	list[0] = fmt.Sprintf("total=%d&added=%d&member=%d&removed=%d&none=%d&rejected=%d&promoted=%d", len(results),
+		counts[cache.RosterAdded], counts[cache.RosterMember], counts[cache.RosterRemoved], counts[cache.RosterNone],
+		counts[cache.RosterRejected], counts[cache.RosterPromoted])
+	return list
+}
+
+func getRoster(path string, in *pb.RequestUpdate) (cache.MemberRoster, []proxy.MemberInfo, *pb.ReplyStatus) {
+	if len(in.Uid) < 1 {
+		return nil, nil, outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
+	}
+	if len(in.Values) < 1 {
+		return nil, nil, outError(path, "the members is empty ", pbstatus.ResultStatus_Empty)
+	}
+	if in.Key != cache.GroupTeam && in.Key != cache.GroupFamily && in.Key != cache.GroupCoterie {
+		return nil, nil, outError(path, "the key should be team, family or coterie ", pbstatus.ResultStatus_FormatError)
+	}
+	list, err := parseRoster(in.Values)
+	if err != nil {
+		return nil, nil, outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+	}
+	info, err := cache.Context().GetRoster(in.Key, in.Uid)
+	if err != nil {
+		return nil, nil, outError(path, err.Error(), pbstatus.ResultStatus_NotExisted)
+	}
+	return info, list, nil
+}
+
+// Append 加入成员，已经是成员的不修改；小组超出名额的拒绝
+func (mine *RosterService) Append(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyList) error {
+	path := "roster.append"
+	inLog(path, in)
+	info, list, status := getRoster(path, in)
+	if status != nil {
+		out.Status = status
+		return nil
+	}
+	results, err := info.AppendMembers(list)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.List = switchRoster(results)
+	out.Status = outLog(path, out.List[0])
+	return nil
+}
+
+// Subtract 移除成员，小组空出的名额由最早的候补补上
+func (mine *RosterService) Subtract(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyList) error {
+	path := "roster.subtract"
+	inLog(path, in)
+	info, list, status := getRoster(path, in)
+	if status != nil {
+		out.Status = status
+		return nil
+	}
+	users := make([]string, 0, len(list))
+	for _, item := range list {
+		users = append(users, item.User)
+	}
+	results, err := info.SubtractMembers(users)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.List = switchRoster(results)
+	out.Status = outLog(path, out.List[0])
+	return nil
+}
+
+// Replace 成员替换为values中的，不在其中的成员移除
+func (mine *RosterService) Replace(ctx context.Context, in *pb.RequestUpdate, out *pb.ReplyList) error {
+	path := "roster.replace"
+	inLog(path, in)
+	info, list, status := getRoster(path, in)
+	if status != nil {
+		out.Status = status
+		return nil
+	}
+	results, err := info.ReplaceMembers(in.Operator, list)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.List = switchRoster(results)
+	out.Status = outLog(path, out.List[0])
+	return nil
+}
@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
+	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"omo.msa.assignment/cache"
+)
+
+// TeamSeatService 小组的名额和候补，proto中没有单独的定义，复用已有的消息类型；
+// 名额的修改使用TeamService.UpdateByFilter(key为limit)
+type TeamSeatService struct{}
+
+// 名额的统计 limit=x&members=x&seats=x&waits=x，seats为剩余的名额(没有限制时为-1)
+func seatSummary(info *cache.TeamInfo) string {
+	return fmt.Sprintf("limit=%d&members=%d&seats=%d&waits=%d", info.MaxNum, len(info.Members), info.Seats(), len(info.Waits))
+}
+
+// Join uid为小组，list为加入的成员，满员时加入候补；
+// list[0]为名额的统计，之后为每个成员的结果 user=x&state=joined|member|waiting
+func (mine *TeamSeatService) Join(ctx context.Context, in *pb.RequestList, out *pb.ReplyList) error {
+	path := "teamSeat.join"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	if len(in.List) < 1 {
+		out.Status = outError(path, "the list is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetTeam(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the team not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	list := make([]string, 0, len(in.List))
+	for _, user := range in.List {
+		state, err := info.Join(user, in.Operator)
+		if err != nil {
+			out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+			return nil
+		}
+		list = append(list, fmt.Sprintf("user=%s&state=%s", user, state))
+	}
+	out.Uid = in.Uid
+	out.List = append([]string{seatSummary(info)}, list...)
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// Leave uid为小组，list为离开小组或者候补的成员，空出的名额由最早的候补补上；
+// list[0]为名额的统计，之后为每个成员的结果 user=x&state=left|none，以及加入小组的候补 user=x&state=joined
+func (mine *TeamSeatService) Leave(ctx context.Context, in *pb.RequestList, out *pb.ReplyList) error {
+	path := "teamSeat.leave"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetTeam(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the team not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	list := make([]string, 0, len(in.List))
+	promoted := make([]string, 0, 2)
+	for _, user := range in.List {
+		state := cache.SeatNone
+		if info.HadMember(user) || info.HadWait(user) {
+			state = cache.SeatLeft
+		}
+		users, err := info.Leave(user)
+		if err != nil {
+			out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+			return nil
+		}
+		promoted = append(promoted, users...)
+		list = append(list, fmt.Sprintf("user=%s&state=%s", user, state))
+	}
+	for _, user := range promoted {
+		// 后面离开的成员可能是刚加入的候补
+		if info.HadMember(user) {
+			list = append(list, fmt.Sprintf("user=%s&state=%s", user, cache.SeatJoined))
+		}
+	}
+	out.Uid = in.Uid
+	out.List = append([]string{seatSummary(info)}, list...)
+	out.Status = outLog(path, out)
+	return nil
+}
+
+// GetSeats uid为小组，list[0]为名额的统计，之后为候补的成员(最早的在前)
+func (mine *TeamSeatService) GetSeats(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyList) error {
+	path := "teamSeat.getSeats"
+	inLog(path, in)
+	if len(in.Uid) < 1 {
+		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
+		return nil
+	}
+	info, er := cache.Context().GetTeam(in.Uid)
+	if er != nil {
+		out.Status = outError(path, "the team not found ", pbstatus.ResultStatus_NotExisted)
+		return nil
+	}
+	out.Uid = in.Uid
+	out.List = append([]string{seatSummary(info)}, info.WaitUsers()...)
+	out.Status = outLog(path, out)
+	return nil
+}
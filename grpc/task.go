@@ -6,9 +6,11 @@ import (
 	"fmt"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"net/url"
 	"omo.msa.assignment/cache"
 	"omo.msa.assignment/proxy"
 	"strconv"
+	"strings"
 )
 
 type TaskService struct{}
@@ -55,6 +57,147 @@ func switchRecords(array []proxy.RecordInfo) []*pb.RecordInfo {
 	return list
 }
 
+// 非法的状态流转和前置任务未完成都返回Prohibition，通过错误信息区分
+func checkTaskStatus(info *cache.TaskInfo, st cache.TaskStatus) (pbstatus.ResultStatus, error) {
+	if info.Status == st {
+		return pbstatus.ResultStatus_Success, nil
+	}
+	err := info.CheckTransition(st)
+	if err != nil {
+		return pbstatus.ResultStatus_Prohibition, err
+	}
+	if st == cache.TaskStatusBusy || st == cache.TaskStatusEnd {
+		err = info.CheckPreTasks()
+		if err != nil {
+			return pbstatus.ResultStatus_Prohibition, err
+		}
+	}
+	return pbstatus.ResultStatus_Success, nil
+}
+
+// 状态的变更历史以记录的格式返回：status为变更后的状态，tags依次为变更前后的状态，remark为原因
+func switchHistories(array []proxy.StatusHistory) []*pb.RecordInfo {
+	list := make([]*pb.RecordInfo, 0, len(array))
+	for i, info := range array {
+		tmp := new(pb.RecordInfo)
+		tmp.Uid = strconv.Itoa(i)
+		tmp.Creator = info.Creator
+		tmp.Created = info.CreatedTime.Unix()
+		tmp.Name = "status"
+		tmp.Status = uint32(info.To)
+		tmp.Remark = info.Reason
+		tmp.Tags = []string{strconv.Itoa(int(info.From)), strconv.Itoa(int(info.To))}
+		tmp.Assets = make([]string, 0, 1)
+		list = append(list, tmp)
+	}
+	return list
+}
+
+// 查询条件为url的query格式，多个值用逗号分隔，例如：
+// keyword=巡检&tags=a,b&types=1&states=0,1&region=xx&executor=xx&begin=2021-01-01&end=2021-02-01&sort=-created&page=1&number=10
+// sort可选created、updated、id、name、status、begin，前缀-表示倒序，默认为-created
+func parseTaskFilter(owner, keyword, query string) (*proxy.TaskFilter, uint32, uint32, error) {
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	filter := new(proxy.TaskFilter)
+	filter.Owner = owner
+	if len(params.Get("owner")) > 0 {
+		filter.Owner = params.Get("owner")
+	}
+	filter.Keyword = keyword
+	if len(params.Get("keyword")) > 0 {
+		filter.Keyword = params.Get("keyword")
+	}
+	filter.Region = params.Get("region")
+	filter.Executor = params.Get("executor")
+	filter.Begin = params.Get("begin")
+	filter.End = params.Get("end")
+	filter.Tags = splitValues(params.Get("tags"))
+	filter.Types, err = parseUint8Array(params.Get("types"))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	filter.States, err = parseUint8Array(params.Get("states"))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	sort := params.Get("sort")
+	if len(sort) < 1 {
+		sort = "-created"
+	}
+	filter.Desc = strings.HasPrefix(sort, "-")
+	fields := map[string]string{"created": "createdAt", "updated": "updatedAt", "id": "id",
+		"name": "name", "status": "status", "begin": "duration.begin"}
+	field, ok := fields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, 0, 0, errors.New("the sort field not defined: " + sort)
+	}
+	filter.Sort = field
+	var page, number uint64 = 1, 10
+	if len(params.Get("page")) > 0 {
+		page, err = strconv.ParseUint(params.Get("page"), 10, 32)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	if len(params.Get("number")) > 0 {
+		number, err = strconv.ParseUint(params.Get("number"), 10, 32)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	return filter, uint32(page), uint32(number), nil
+}
+
+// 任务记录的查询条件，格式与parseTaskFilter一致，例如：
+// task=xx&executor=xx&states=0,1&begin=2021-01-01&end=2021-02-01
+func parseRecordFilter(query string) (string, *proxy.RecordFilter, error) {
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", nil, err
+	}
+	filter := new(proxy.RecordFilter)
+	filter.Executor = params.Get("executor")
+	filter.Begin = params.Get("begin")
+	filter.End = params.Get("end")
+	filter.States, err = parseUint8Array(params.Get("states"))
+	if err != nil {
+		return "", nil, err
+	}
+	return params.Get("task"), filter, nil
+}
+
+// 修改记录时只修改query中存在的字段，例如：uid=xx&name=xx&remark=xx&executor=xx&tags=a,b&assets=a,b
+func parseRecordUpdate(info *cache.TaskInfo, query string) (proxy.RecordInfo, error) {
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return proxy.RecordInfo{}, err
+	}
+	old, err := info.GetRecord(params.Get("uid"))
+	if err != nil {
+		return proxy.RecordInfo{}, err
+	}
+	record := *old
+	if _, ok := params["name"]; ok {
+		record.Name = params.Get("name")
+	}
+	if _, ok := params["remark"]; ok {
+		record.Remark = params.Get("remark")
+	}
+	if _, ok := params["executor"]; ok {
+		record.Executor = params.Get("executor")
+	}
+	if _, ok := params["tags"]; ok {
+		record.Tags = splitValues(params.Get("tags"))
+	}
+	if _, ok := params["assets"]; ok {
+		record.Assets = splitValues(params.Get("assets"))
+	}
+	return record, nil
+}
+
 func (mine *TaskService) AddOne(ctx context.Context, in *pb.ReqTaskAdd, out *pb.ReplyTaskOne) error {
 	path := "task.add"
 	inLog(path, in)
@@ -86,6 +229,17 @@ func (mine *TaskService) GetOne(ctx context.Context, in *pb.RequestInfo, out *pb
 		return nil
 	}
 	out.Info = switchTask(info)
+	// flag为history时records返回状态的变更历史，为record时只返回name对应的记录
+	if in.Flag == "history" {
+		out.Info.Records = switchHistories(info.Histories)
+	} else if in.Flag == "record" {
+		record, err := info.GetRecord(in.Name)
+		if err != nil {
+			out.Status = outError(path, err.Error(), pbstatus.ResultStatus_NotExisted)
+			return nil
+		}
+		out.Info.Records = switchRecords([]proxy.RecordInfo{*record})
+	}
 	out.Status = outLog(path, out)
 	return nil
 }
@@ -110,9 +264,21 @@ func (mine *TaskService) RemoveOne(ctx context.Context, in *pb.RequestInfo, out
 func (mine *TaskService) Search(ctx context.Context, in *pb.RequestInfo, out *pb.ReplyTaskList) error {
 	path := "task.search"
 	inLog(path, in)
-	var total uint32 = 0
-	var max uint32 = 0
-
+	filter, page, number, err := parseTaskFilter(in.Uid, in.Name, in.Flag)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_FormatError)
+		return nil
+	}
+	total, max, list, err := cache.Context().SearchTasks(filter, page, number)
+	if err != nil {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+		return nil
+	}
+	out.List = make([]*pb.TaskInfo, 0, len(list))
+	for _, value := range list {
+		out.List = append(out.List, switchTask(value))
+	}
+	out.PageNow = page
 	out.Total = total
 	out.PageMax = max
 	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
@@ -122,24 +288,49 @@ func (mine *TaskService) Search(ctx context.Context, in *pb.RequestInfo, out *pb
 func (mine *TaskService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyTaskList) error {
 	path := "task.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
 	var total uint32 = 0
 	var max uint32 = 0
 	var list []*cache.TaskInfo
+	var records []proxy.RecordInfo
 	var err error
-	if in.Key == "status" {
+	if key == "status" {
 		st := parseStringToInt(in.Value)
-		total, max, list = cache.Context().GetTasksByOwner(in.Owner, int(st), in.Page, in.Number)
-	} else if in.Key == "target;status" {
+		total, max, list = cache.Context().GetTasksByOwner(in.Owner, int(st), page)
+	} else if key == "target;status" {
 		uid, st := parseString(in.Value, ";")
-		list, err = cache.Context().GetTasksByTarget(uid, st)
-	} else if in.Key == "agent;status" {
+		total, max, list, err = cache.Context().GetTasksByTarget(uid, st, page)
+	} else if key == "agent;status" {
 		uid, st := parseString(in.Value, ";")
-		list, err = cache.Context().GetTasksByAgent(uid, st)
-	} else if in.Key == "regions" {
-		list = cache.Context().GetTasksByRegions(in.Values, -1)
-	} else if in.Key == "regions;status" {
+		total, max, list, err = cache.Context().GetTasksByAgent(uid, st, page)
+	} else if key == "regions" {
+		total, max, list = cache.Context().GetTasksByRegions(in.Values, -1, page)
+	} else if key == "regions;status" {
 		st := parseStringToInt(in.Value)
-		list = cache.Context().GetTasksByRegions(in.Values, int(st))
+		total, max, list = cache.Context().GetTasksByRegions(in.Values, int(st), page)
+	} else if key == "search" {
+		filter, _, _, er := parseTaskFilter(in.Owner, "", in.Value)
+		if er != nil {
+			out.Status = outError(path, er.Error(), pbstatus.ResultStatus_FormatError)
+			return nil
+		}
+		total, max, list, err = cache.Context().SearchTasks(filter, in.Page, in.Number)
+	} else if key == "records" {
+		var task *cache.TaskInfo
+		task, records, total, max, err = getTaskRecords(in.Value, page)
+		if err == nil {
+			list = []*cache.TaskInfo{task}
+		}
+	} else if key == "graph" || key == "graph.critical" {
+		var graph *cache.TaskGraph
+		graph, err = cache.Context().GetTaskGraph(in.Owner)
+		if err == nil {
+			list = graph.Nodes
+			if key == "graph.critical" {
+				list = graph.Critical
+			}
+			total = uint32(len(list))
+		}
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -152,6 +343,10 @@ func (mine *TaskService) GetListByFilter(ctx context.Context, in *pb.RequestFilt
 	for _, value := range list {
 		out.List = append(out.List, switchTask(value))
 	}
+	// 返回的列表中只有该任务，records为当前页的记录
+	if key == "records" {
+		out.List[0].Records = switchRecords(records)
+	}
 	out.PageNow = in.Page
 	out.Total = total
 	out.PageMax = max
@@ -159,17 +354,48 @@ func (mine *TaskService) GetListByFilter(ctx context.Context, in *pb.RequestFilt
 	return nil
 }
 
+func getTaskRecords(query string, page *cache.PageInfo) (*cache.TaskInfo, []proxy.RecordInfo, uint32, uint32, error) {
+	uid, filter, err := parseRecordFilter(query)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	if len(uid) < 1 {
+		return nil, nil, 0, 0, errors.New("the task uid is empty")
+	}
+	task, err := cache.Context().GetTask(uid)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	total, max, records, err := task.GetRecords(filter, page)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	return task, records, total, max, nil
+}
+
 func (mine *TaskService) GetStatistic(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyStatistic) error {
 	path := "task.getStatistic"
 	inLog(path, in)
-	var list []*cache.TaskInfo
+	var total uint32 = 0
 	var err error
 	if in.Key == "target;status" {
 		uid, st := parseString(in.Value, ";")
-		list, err = cache.Context().GetTasksByTarget(uid, st)
+		total, _, _, err = cache.Context().GetTasksByTarget(uid, st, countPage())
 	} else if in.Key == "agent;status" {
 		uid, st := parseString(in.Value, ";")
-		list, err = cache.Context().GetTasksByAgent(uid, st)
+		total, _, _, err = cache.Context().GetTasksByAgent(uid, st, countPage())
+	} else if in.Key == "records" {
+		_, _, total, _, err = getTaskRecords(in.Value, countPage())
+	} else if in.Key == "graph.length" {
+		var graph *cache.TaskGraph
+		graph, err = cache.Context().GetTaskGraph(in.Owner)
+		if err == nil {
+			out.Key = in.Key
+			out.Owner = in.Owner
+			out.Count = graph.Length
+			out.Status = outLog(path, out)
+			return nil
+		}
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -178,7 +404,7 @@ func (mine *TaskService) GetStatistic(ctx context.Context, in *pb.RequestFilter,
 		return nil
 	}
 	out.Key = in.Key
-	out.Count = uint32(len(list))
+	out.Count = total
 	out.Status = outLog(path, out)
 	return nil
 }
@@ -221,6 +447,15 @@ func (mine *TaskService) UpdateByFilter(ctx context.Context, in *pb.RequestUpdat
 	if in.Key == "" {
 		val, _ := strconv.ParseUint(in.Value, 10, 32)
 		err = info.UpdateType(in.Operator, uint8(val))
+	} else if in.Key == "pretasks" {
+		err = info.UpdatePreTasks(in.Operator, in.Values)
+	} else if in.Key == "record" {
+		record, er := parseRecordUpdate(info, in.Value)
+		if er != nil {
+			out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
+			return nil
+		}
+		err = info.UpdateRecord(record)
 	}
 
 	if err != nil {
@@ -244,8 +479,17 @@ func (mine *TaskService) UpdateStatus(ctx context.Context, in *pb.RequestIntFlag
 		out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
 		return nil
 	}
-
-	err := info.UpdateStatus(cache.TaskStatus(in.Flag), in.Operator)
+	st := cache.TaskStatus(in.Flag)
+	code, er := checkTaskStatus(info, st)
+	if er != nil {
+		out.Status = outError(path, er.Error(), code)
+		return nil
+	}
+	err := info.UpdateStatus(st, in.Operator, in.Remark)
+	if errors.Is(err, cache.ErrTaskStatusChanged) {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_Prohibition)
+		return nil
+	}
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
 		return nil
@@ -320,7 +564,11 @@ func (mine *TaskService) AppendRecord(ctx context.Context, in *pb.ReqTaskRecord,
 		out.Status = outError(path, er.Error(), pbstatus.ResultStatus_NotExisted)
 		return nil
 	}
-
+	code, er := checkTaskStatus(info, cache.TaskStatus(in.Status))
+	if er != nil {
+		out.Status = outError(path, er.Error(), code)
+		return nil
+	}
 	err := info.AddRecord(in)
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
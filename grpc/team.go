@@ -6,7 +6,9 @@ import (
 	"fmt"
 	pb "github.com/xtech-cloud/omo-msp-assignment/proto/assignment"
 	pbstatus "github.com/xtech-cloud/omo-msp-status/proto/status"
+	"math"
 	"omo.msa.assignment/cache"
+	"strings"
 )
 
 type TeamService struct{}
@@ -45,7 +47,17 @@ func (mine *TeamService) AddOne(ctx context.Context, in *pb.ReqTeamAdd, out *pb.
 		out.Status = outError(path, "the name is repeated in scene ", pbstatus.ResultStatus_Repeated)
 		return nil
 	}
+	if len(in.Parent) > 0 {
+		if _, er := cache.Context().GetTeam(in.Parent); er != nil {
+			out.Status = outError(path, "the parent team not found ", pbstatus.ResultStatus_NotExisted)
+			return nil
+		}
+	}
 	info, err := cache.Context().CreateTeam(in)
+	if err == cache.ErrTeamOwner {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_Prohibition)
+		return nil
+	}
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
 		return nil
@@ -79,7 +91,36 @@ func (mine *TeamService) GetStatistic(ctx context.Context, in *pb.RequestFilter,
 		out.Status = outError(path, "the user is empty ", pbstatus.ResultStatus_Empty)
 		return nil
 	}
-
+	// value为小组，members为包括下级小组在内的成员数量(重复的只算一次)
+	if in.Key == "members" || in.Key == "children" || in.Key == "descendants" {
+		info, er := cache.Context().GetTeam(in.Value)
+		if er != nil {
+			out.Status = outError(path, "the team not found ", pbstatus.ResultStatus_NotExisted)
+			return nil
+		}
+		var num int
+		var err error
+		if in.Key == "members" {
+			var list []string
+			list, err = info.AllMembers()
+			num = len(list)
+		} else if in.Key == "children" {
+			var total uint32
+			total, _, _ = cache.Context().GetTeamChildren(info.UID, countPage())
+			num = int(total)
+		} else {
+			var list []*cache.TeamInfo
+			list, err = cache.Context().GetTeamDescendants(info.UID)
+			num = len(list)
+		}
+		if err != nil {
+			out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
+			return nil
+		}
+		out.Count = uint32(num)
+	}
+	out.Key = in.Key
+	out.Owner = in.Owner
 	out.Status = outLog(path, out)
 	return nil
 }
@@ -91,7 +132,12 @@ func (mine *TeamService) RemoveOne(ctx context.Context, in *pb.RequestInfo, out
 		out.Status = outError(path, "the uid is empty ", pbstatus.ResultStatus_Empty)
 		return nil
 	}
-	err := cache.Context().RemoveTeam(in.Uid, in.Operator)
+	// flag为cascade时同时删除所有的下级小组，否则有下级时不删除
+	_, err := cache.Context().RemoveTeamTree(in.Uid, in.Operator, in.Flag == "cascade")
+	if err == cache.ErrTeamHasChildren {
+		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_Prohibition)
+		return nil
+	}
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
 		return nil
@@ -112,15 +158,30 @@ func (mine *TeamService) Search(ctx context.Context, in *pb.RequestInfo, out *pb
 func (mine *TeamService) GetListByFilter(ctx context.Context, in *pb.RequestFilter, out *pb.ReplyTeamList) error {
 	path := "team.getListByFilter"
 	inLog(path, in)
+	key, page := parseFilterPage(in)
+	var total uint32 = 0
+	var pages uint32 = 0
 	var list []*cache.TeamInfo
 	var err error
-	if in.Key == "" {
-		list = cache.Context().GetTeamsByOwner(in.Owner)
-	} else if in.Key == "type" {
+	if key == "" {
+		total, pages, list = cache.Context().GetTeamsByOwner(in.Owner, page)
+	} else if key == "type" {
 
-	} else if in.Key == "user" {
-		list = cache.Context().GetTeamsByUser(in.Value)
-	} else if in.Key == "array" {
+	} else if key == "user" {
+		total, pages, list = cache.Context().GetTeamsByUser(in.Value, page)
+	} else if key == "array" {
+	} else if key == "children" {
+		total, pages, list = cache.Context().GetTeamChildren(in.Value, page)
+	} else if key == "descendants" {
+		list, err = cache.Context().GetTeamDescendants(in.Value)
+		total = uint32(len(list))
+	} else if key == "ancestors" {
+		var info *cache.TeamInfo
+		info, err = cache.Context().GetTeam(in.Value)
+		if err == nil {
+			list, err = info.Ancestors()
+			total = uint32(len(list))
+		}
 	} else {
 		err = errors.New("the key not defined")
 	}
@@ -132,7 +193,8 @@ func (mine *TeamService) GetListByFilter(ctx context.Context, in *pb.RequestFilt
 	for _, value := range list {
 		out.List = append(out.List, switchTeam(value))
 	}
-
+	out.Total = total
+	out.Pages = pages
 	out.Status = outLog(path, fmt.Sprintf("the length = %d", len(out.List)))
 	return nil
 }
@@ -183,6 +245,14 @@ func (mine *TeamService) UpdateByFilter(ctx context.Context, in *pb.RequestUpdat
 		err = info.UpdateMaster(in.Value, in.Operator)
 	} else if in.Key == "assistants" {
 		err = info.UpdateAssistants(in.Operator, in.Values)
+	} else if in.Key == "limit" {
+		// 名额增加时候补的成员按照先后加入
+		num := parseStringToInt(in.Value)
+		if num < 0 || num > math.MaxUint16 {
+			out.Status = outError(path, "the limit is error ", pbstatus.ResultStatus_FormatError)
+			return nil
+		}
+		_, err = info.UpdateLimit(in.Operator, uint16(num))
 	}
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
@@ -226,11 +296,22 @@ func (mine *TeamService) AppendMember(ctx context.Context, in *pb.RequestList, o
 		return nil
 	}
 
-	err := info.AppendMembers(in.List)
+	results, err := info.AppendMembers(cache.ToMembers(in.List))
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
 		return nil
 	}
+	// 有名额的成员已经加入，超出名额的返回MaxLimit
+	full := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.State == cache.RosterRejected && result.Reason == cache.ErrTeamFull.Error() {
+			full = append(full, result.User)
+		}
+	}
+	if len(full) > 0 {
+		out.Status = outError(path, "the team is full, rejected: "+strings.Join(full, ","), pbstatus.ResultStatus_MaxLimit)
+		return nil
+	}
 	out.Uid = in.Uid
 	out.List = info.Members
 	out.Status = outLog(path, out)
@@ -250,7 +331,7 @@ func (mine *TeamService) SubtractMember(ctx context.Context, in *pb.RequestList,
 		return nil
 	}
 
-	err := info.SubtractMembers(in.List)
+	_, err := info.SubtractMembers(in.List)
 	if err != nil {
 		out.Status = outError(path, err.Error(), pbstatus.ResultStatus_DBException)
 		return nil
@@ -14,6 +14,7 @@ import (
 	"omo.msa.assignment/grpc"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -25,10 +26,32 @@ var (
 
 func main() {
 	config.Setup()
+	if len(os.Args) > 1 && runCommand(os.Args[1], os.Args[2:]) {
+		return
+	}
 	err := cache.InitData()
 	if err != nil {
 		panic(err)
 	}
+	cache.WatchTaskReady(func(info *cache.TaskInfo) {
+		logger.Infof("the task(%s) is ready, all pre tasks had finished", info.UID)
+	})
+	cache.WatchApplyRemind(func(info *cache.ApplyInfo) {
+		logger.Infof("the apply(%s) of %s is pending since %s", info.UID, info.Applicant, info.SubmitTime.Format("2006-01-02 15:04"))
+	})
+	stopSweeper := cache.StartApplySweeper(config.Schema.Apply)
+	defer stopSweeper()
+	cache.WatchMeetingStart(func(info *cache.MeetingInfo) {
+		logger.Infof("the meeting(%s) started at %s", info.UID, info.StartTime.Format("2006-01-02 15:04"))
+	})
+	cache.WatchMeetingStop(func(info *cache.MeetingInfo) {
+		logger.Infof("the meeting(%s) stopped at %s", info.UID, info.StopTime.Format("2006-01-02 15:04"))
+	})
+	cache.WatchMeetingNotify(func(info *cache.MeetingInfo, users []string) {
+		logger.Infof("invite %d users to the meeting(%s)", len(users), info.UID)
+	})
+	stopScheduler := cache.StartMeetingScheduler(config.Schema.Meeting)
+	defer stopScheduler()
 	// New Service
 	service := micro.NewService(
 		micro.Name("omo.msa.assignment"),
@@ -49,6 +72,15 @@ func main() {
 	_ = proto.RegisterMeetingServiceHandler(service.Server(), new(grpc.MeetingService))
 	_ = proto.RegisterQuestionServiceHandler(service.Server(), new(grpc.QuestionService))
 	_ = proto.RegisterCategoryServiceHandler(service.Server(), new(grpc.CategoryService))
+	// 答题、题库和邀请码服务在proto中没有定义，按照反射注册，接口为QuizService.Submit、BankService.Import等
+	_ = micro.RegisterHandler(service.Server(), new(grpc.QuizService))
+	_ = micro.RegisterHandler(service.Server(), new(grpc.BankService))
+	_ = micro.RegisterHandler(service.Server(), new(grpc.InvitationService))
+	_ = micro.RegisterHandler(service.Server(), new(grpc.AttendanceService))
+	_ = micro.RegisterHandler(service.Server(), new(grpc.CalendarService))
+	_ = micro.RegisterHandler(service.Server(), new(grpc.TeamTreeService))
+	_ = micro.RegisterHandler(service.Server(), new(grpc.TeamSeatService))
+	_ = micro.RegisterHandler(service.Server(), new(grpc.RosterService))
 
 	app, _ := filepath.Abs(os.Args[0])
 
@@ -67,6 +99,46 @@ func main() {
 	}
 }
 
+// 备份和恢复的命令，执行完后退出：
+// backup [目录] [集合,集合]，默认目录为backup
+// restore <备份的目录> [集合,集合]
+func runCommand(name string, args []string) bool {
+	if name != "backup" && name != "restore" {
+		return false
+	}
+	// 备份和恢复不执行启动时的数据修复，备份的是数据库中原来的数据
+	err := cache.InitStore()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	var tables []string
+	if len(args) > 1 {
+		tables = strings.Split(args[1], ",")
+	}
+	switch name {
+	case "backup":
+		dir := "backup"
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		path, err := cache.Context().Backup(dir, tables)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infof("backup the database to %s", path)
+	case "restore":
+		if len(args) < 1 {
+			logger.Fatal("the backup path is empty")
+		}
+		list, err := cache.Context().Restore(args[0], tables)
+		if err != nil {
+			logger.Fatalf("restored %v, then failed: %s", list, err.Error())
+		}
+		logger.Infof("restore the tables %v from %s", list, args[0])
+	}
+	return true
+}
+
 func md5hex(_file string) string {
 	h := md5.New()
 
@@ -1,9 +1,13 @@
 package proxy
 
 import (
+	"errors"
 	"time"
 )
 
+// ErrNotFound 要查询的数据不存在，所有的存储都返回这个错误
+var ErrNotFound = errors.New("the data is not found")
+
 type DateInfo struct {
 	Begin string `json:"begin" bson:"begin"`
 	End   string `json:"end" bson:"end"`
@@ -27,6 +31,59 @@ type RecordInfo struct {
 	Assets   []string `json:"assets" bson:"assets"`
 }
 
+// StatusHistory 状态的变更记录
+type StatusHistory struct {
+	Creator     string    `json:"creator" bson:"creator"`
+	CreatedTime time.Time `json:"createdAt" bson:"createdAt"`
+	From        uint8     `json:"from" bson:"from"`
+	To          uint8     `json:"to" bson:"to"`
+	Reason      string    `json:"reason" bson:"reason"`
+}
+
+// SignInfo 会议的签到记录，每次签到都记录，距离为米(没有位置时为-1)
+type SignInfo struct {
+	User        string    `json:"user" bson:"user"`
+	CreatedTime time.Time `json:"createdAt" bson:"createdAt"`
+	Operator    string    `json:"operator" bson:"operator"`
+	Location    string    `json:"location" bson:"location"`
+	Distance    float64   `json:"distance" bson:"distance"`
+	State       uint8     `json:"state" bson:"state"`
+}
+
+// AttendInfo 成员参加会议的情况，每个成员一条，时间为零值表示没有
+type AttendInfo struct {
+	User       string    `json:"user" bson:"user"`
+	SignedTime time.Time `json:"signed" bson:"signed"`
+	Location   string    `json:"location" bson:"location"`
+	Distance   float64   `json:"distance" bson:"distance"`
+	Device     string    `json:"device" bson:"device"`
+	Late       bool      `json:"late" bson:"late"`
+	//离开的时间，早于结束时间为早退
+	LeftTime      time.Time `json:"left" bson:"left"`
+	SubmittedTime time.Time `json:"submitted" bson:"submitted"`
+	NotifiedTime  time.Time `json:"notified" bson:"notified"`
+	//被邀请人的回复
+	AckedTime time.Time `json:"acked" bson:"acked"`
+	Answer    uint8     `json:"answer" bson:"answer"`
+}
+
+// ExceptionInfo 重复会议中某一次的例外，Occurrence为按照规则计算的开始时间，取消或者修改了时间
+type ExceptionInfo struct {
+	Occurrence  time.Time `json:"occurrence" bson:"occurrence"`
+	Cancelled   bool      `json:"cancelled" bson:"cancelled"`
+	StartTime   time.Time `json:"startAt" bson:"startAt"`
+	StopTime    time.Time `json:"stopAt" bson:"stopAt"`
+	Operator    string    `json:"operator" bson:"operator"`
+	CreatedTime time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// QuizScore 用户在某个分类下的答题统计
+type QuizScore struct {
+	User    string `json:"user" bson:"user"`
+	Total   uint32 `json:"total" bson:"total"`
+	Correct uint32 `json:"correct" bson:"correct"`
+}
+
 type CustodianInfo struct {
 	User       string         `json:"user" bson:"user"`
 	Identifies []IdentifyInfo `json:"identify" bson:"identify"`
@@ -42,3 +99,10 @@ type MemberInfo struct {
 	Name   string `json:"name" bson:"name"`
 	Remark string `json:"remark" bson:"remark"`
 }
+
+// WaitInfo 小组满员时的候补，按照加入的先后排列
+type WaitInfo struct {
+	User        string    `json:"user" bson:"user"`
+	CreatedTime time.Time `json:"createdAt" bson:"createdAt"`
+	Operator    string    `json:"operator" bson:"operator"`
+}
@@ -0,0 +1,31 @@
+package proxy
+
+// TaskFilter 任务的查询条件，零值表示不限制
+type TaskFilter struct {
+	// 名称或者备注中包含的文字，不区分大小写
+	Keyword  string
+	Owner    string
+	Region   string
+	Executor string
+	// 必须包含全部的标签
+	Tags   []string
+	Types  []uint8
+	States []uint8
+	// 与任务的执行时间段(Duration)有交集，格式与Duration保持一致，结束时间为空表示没有结束
+	Begin string
+	End   string
+	// 排序的bson字段名，为空时按创建时间
+	Sort  string
+	Desc  bool
+	Skip  int64
+	Limit int64
+}
+
+// RecordFilter 任务记录的查询条件，零值表示不限制
+type RecordFilter struct {
+	Executor string
+	States   []uint8
+	// 记录的创建日期，格式为2006-01-02，包含当天
+	Begin string
+	End   string
+}
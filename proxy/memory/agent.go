@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+type agentStore struct {
+	table *collection[nosql.Agent]
+}
+
+func newAgentStore() *agentStore {
+	return &agentStore{table: newCollection(func(t *nosql.Agent) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *agentStore) CreateAgent(info *nosql.Agent) error {
+	return mine.table.insert(info)
+}
+
+func (mine *agentStore) GetAgent(uid string) (*nosql.Agent, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *agentStore) GetAgentByUser(user string) (*nosql.Agent, error) {
+	return mine.table.findOne(func(t *nosql.Agent) bool {
+		return t.User == user
+	})
+}
+
+func (mine *agentStore) GetAgentsByOwner(owner string) ([]*nosql.Agent, error) {
+	return mine.table.findMany(func(t *nosql.Agent) bool {
+		return t.Owner == owner && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *agentStore) GetAgentsByAttach(scene string) ([]*nosql.Agent, error) {
+	return mine.table.findMany(func(t *nosql.Agent) bool {
+		return hasItem(t.Attaches, scene) && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *agentStore) GetAgentsByRegion(region string) ([]*nosql.Agent, error) {
+	return mine.table.findMany(func(t *nosql.Agent) bool {
+		return hasItem(t.Regions, region) && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *agentStore) GetAgentsByWay(owner, way string) ([]*nosql.Agent, error) {
+	return mine.table.findMany(func(t *nosql.Agent) bool {
+		return t.Owner == owner && t.Way == way && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *agentStore) UpdateAgentBase(uid, name, remark, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Name = name
+		t.Remark = remark
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *agentStore) UpdateAgentEntity(uid, entity, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Entity = entity
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *agentStore) UpdateAgentStatus(uid, operator string, st uint8) error {
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Status = st
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *agentStore) UpdateAgentTags(uid, operator string, tags []string) error {
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Tags = copyStrings(tags)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *agentStore) UpdateAgentRegions(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Regions = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *agentStore) UpdateAgentAttaches(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Attaches = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *agentStore) RemoveAgent(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *agentStore) AppendAgentAttach(uid, scene string) error {
+	if len(scene) < 1 {
+		return errors.New("the attach uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Attaches = append(t.Attaches, scene)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *agentStore) SubtractAgentAttach(uid, scene string) error {
+	if len(scene) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Agent) {
+		t.Attaches = pullItem(t.Attaches, scene)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *agentStore) QueryAgents(query *proxy.Query) ([]*nosql.Agent, int64, error) {
+	return mine.table.query(query)
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+type applyStore struct {
+	table *collection[nosql.Apply]
+}
+
+func newApplyStore() *applyStore {
+	return &applyStore{table: newCollection(func(t *nosql.Apply) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *applyStore) CreateApply(info *nosql.Apply) error {
+	return mine.table.insert(info)
+}
+
+func (mine *applyStore) GetApply(uid string) (*nosql.Apply, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *applyStore) GetAppliesByGroup(group string) ([]*nosql.Apply, error) {
+	return mine.table.findMany(func(t *nosql.Apply) bool {
+		return t.Group == group && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *applyStore) GetAppliesByScene(scene string, tp uint8) ([]*nosql.Apply, error) {
+	return mine.table.findMany(func(t *nosql.Apply) bool {
+		return t.Scene == scene && t.Type == tp && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *applyStore) GetAppliesByScene1(scene string) ([]*nosql.Apply, error) {
+	return mine.table.findMany(func(t *nosql.Apply) bool {
+		return t.Scene == scene && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *applyStore) GetAppliesByApplicant(user string) ([]*nosql.Apply, error) {
+	return mine.table.findMany(func(t *nosql.Apply) bool {
+		return t.Applicant == user && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *applyStore) GetAppliesByCreator(user string) ([]*nosql.Apply, error) {
+	return mine.table.findMany(func(t *nosql.Apply) bool {
+		return t.Creator == user && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *applyStore) UpdateApply(uid, reason, operator string, status uint8) error {
+	return mine.table.update(uid, func(t *nosql.Apply) {
+		t.Status = status
+		t.Reason = reason
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *applyStore) DecideApply(uid, operator, reason string, from []uint8, status uint8) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	num := mine.table.updateAll(func(t *nosql.Apply) bool {
+		if t.UID != id || !t.DeleteTime.IsZero() {
+			return false
+		}
+		for _, st := range from {
+			if t.Status == st {
+				return true
+			}
+		}
+		return false
+	}, func(t *nosql.Apply) {
+		now := time.Now()
+		t.Status = status
+		t.Reason = reason
+		t.Operator = operator
+		t.Decider = operator
+		t.DecidedTime = now
+		t.UpdatedTime = now
+	})
+	return num > 0, nil
+}
+
+func (mine *applyStore) RemindApply(uid string, now time.Time) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	num := mine.table.updateAll(func(t *nosql.Apply) bool {
+		return t.UID == id && t.RemindTime.IsZero() && t.DeleteTime.IsZero()
+	}, func(t *nosql.Apply) {
+		t.RemindTime = now
+	})
+	return num > 0, nil
+}
+
+func (mine *applyStore) RemoveApply(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Apply) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *applyStore) QueryApplies(query *proxy.Query) ([]*nosql.Apply, int64, error) {
+	return mine.table.query(query)
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy/nosql"
+	"sort"
+)
+
+type backupStore struct {
+	tasks       *taskStore
+	agents      *agentStore
+	teams       *teamStore
+	families    *familyStore
+	coteries    *coterieStore
+	applies     *applyStore
+	invitations *invitationStore
+	meetings    *meetingStore
+	questions   *questionStore
+	categories  *categoryStore
+	quizzes     *quizStore
+	sequences   *sequenceStore
+}
+
+func dumpCollection[T any](table *collection[T]) ([]interface{}, error) {
+	list, err := table.all()
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		docs = append(docs, item)
+	}
+	return docs, nil
+}
+
+func restoreCollection[T any](table *collection[T], docs []interface{}) error {
+	list := make([]*T, 0, len(docs))
+	for _, item := range docs {
+		doc, ok := item.(*T)
+		if !ok {
+			return errors.New("the document type is not matched")
+		}
+		list = append(list, doc)
+	}
+	return table.reset(list)
+}
+
+func (mine *backupStore) DumpTable(table string) ([]interface{}, error) {
+	switch table {
+	case nosql.TableSequence:
+		return mine.sequences.dump(), nil
+	case nosql.TableTask:
+		return dumpCollection(mine.tasks.table)
+	case nosql.TableAgent:
+		return dumpCollection(mine.agents.table)
+	case nosql.TableTeam:
+		return dumpCollection(mine.teams.table)
+	case nosql.TableFamily:
+		return dumpCollection(mine.families.table)
+	case nosql.TableCoterie:
+		return dumpCollection(mine.coteries.table)
+	case nosql.TableApply:
+		return dumpCollection(mine.applies.table)
+	case nosql.TableInvitation:
+		return dumpCollection(mine.invitations.table)
+	case nosql.TableMeeting:
+		return dumpCollection(mine.meetings.table)
+	case nosql.TableCategory:
+		return dumpCollection(mine.categories.table)
+	case nosql.TableQuestion:
+		return dumpCollection(mine.questions.table)
+	case nosql.TableRecord:
+		return dumpCollection(mine.quizzes.table)
+	default:
+		return nil, errors.New("the table not support backup: " + table)
+	}
+}
+
+func (mine *backupStore) RestoreTable(table string, list []interface{}) error {
+	switch table {
+	case nosql.TableSequence:
+		return mine.sequences.restore(list)
+	case nosql.TableTask:
+		return restoreCollection(mine.tasks.table, list)
+	case nosql.TableAgent:
+		return restoreCollection(mine.agents.table, list)
+	case nosql.TableTeam:
+		return restoreCollection(mine.teams.table, list)
+	case nosql.TableFamily:
+		return restoreCollection(mine.families.table, list)
+	case nosql.TableCoterie:
+		return restoreCollection(mine.coteries.table, list)
+	case nosql.TableApply:
+		return restoreCollection(mine.applies.table, list)
+	case nosql.TableInvitation:
+		return restoreCollection(mine.invitations.table, list)
+	case nosql.TableMeeting:
+		return restoreCollection(mine.meetings.table, list)
+	case nosql.TableCategory:
+		return restoreCollection(mine.categories.table, list)
+	case nosql.TableQuestion:
+		return restoreCollection(mine.questions.table, list)
+	case nosql.TableRecord:
+		return restoreCollection(mine.quizzes.table, list)
+	default:
+		return errors.New("the table not support backup: " + table)
+	}
+}
+
+// 按名称排序，保证备份的内容稳定
+func (mine *sequenceStore) dump() []interface{} {
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	names := make([]string, 0, len(mine.items))
+	for name := range mine.items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		list = append(list, &nosql.Sequence{Name: name, Count: mine.items[name]})
+	}
+	return list
+}
+
+func (mine *sequenceStore) restore(list []interface{}) error {
+	items := make(map[string]uint64, len(list))
+	for _, item := range list {
+		seq, ok := item.(*nosql.Sequence)
+		if !ok {
+			return errors.New("the document type is not matched")
+		}
+		items[seq.Name] = seq.Count
+	}
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	mine.items = items
+	return nil
+}
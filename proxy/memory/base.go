@@ -0,0 +1,305 @@
+package memory
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"sync"
+)
+
+/**
+内存存储实现，数据只保存在进程内，用于本地开发和测试，语义与mongodb实现保持一致：
+1. 读写都是深拷贝(经过bson编解码)，调用者拿到的对象与存储互不影响
+2. 删除只是设置deleteAt，按uid查询仍能查到已删除的数据
+*/
+
+func NewStores() *nosql.Stores {
+	backup := &backupStore{
+		tasks:       newTaskStore(),
+		agents:      newAgentStore(),
+		teams:       newTeamStore(),
+		families:    newFamilyStore(),
+		coteries:    newCoterieStore(),
+		applies:     newApplyStore(),
+		invitations: newInvitationStore(),
+		meetings:    newMeetingStore(),
+		questions:   newQuestionStore(),
+		categories:  newCategoryStore(),
+		quizzes:     newQuizStore(),
+		sequences:   newSequenceStore(),
+	}
+	return &nosql.Stores{
+		Task:       backup.tasks,
+		Agent:      backup.agents,
+		Team:       backup.teams,
+		Family:     backup.families,
+		Coterie:    backup.coteries,
+		Apply:      backup.applies,
+		Invitation: backup.invitations,
+		Meeting:    backup.meetings,
+		Question:   backup.questions,
+		Category:   backup.categories,
+		Quiz:       backup.quizzes,
+		Sequence:   backup.sequences,
+		Backup:     backup,
+	}
+}
+
+type collection[T any] struct {
+	lock  sync.RWMutex
+	items []*T
+	key   func(*T) primitive.ObjectID
+}
+
+func newCollection[T any](key func(*T) primitive.ObjectID) *collection[T] {
+	return &collection[T]{items: make([]*T, 0, 100), key: key}
+}
+
+func clone[T any](src *T) (*T, error) {
+	bytes, err := bson.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+	dst := new(T)
+	err = bson.Unmarshal(bytes, dst)
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+func (mine *collection[T]) insert(info *T) error {
+	if info == nil {
+		return errors.New("the document is nil")
+	}
+	node, err := clone(info)
+	if err != nil {
+		return err
+	}
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	id := mine.key(node)
+	for _, item := range mine.items {
+		if mine.key(item) == id {
+			return errors.New("the document uid is repeated")
+		}
+	}
+	mine.items = append(mine.items, node)
+	return nil
+}
+
+func (mine *collection[T]) indexOf(id primitive.ObjectID) int {
+	for i, item := range mine.items {
+		if mine.key(item) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (mine *collection[T]) get(uid string) (*T, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return nil, err
+	}
+	mine.lock.RLock()
+	defer mine.lock.RUnlock()
+	i := mine.indexOf(id)
+	if i < 0 {
+		return nil, proxy.ErrNotFound
+	}
+	return clone(mine.items[i])
+}
+
+func (mine *collection[T]) findOne(match func(*T) bool) (*T, error) {
+	mine.lock.RLock()
+	defer mine.lock.RUnlock()
+	for _, item := range mine.items {
+		if match(item) {
+			return clone(item)
+		}
+	}
+	return nil, proxy.ErrNotFound
+}
+
+func (mine *collection[T]) findMany(match func(*T) bool) ([]*T, error) {
+	mine.lock.RLock()
+	defer mine.lock.RUnlock()
+	list := make([]*T, 0, 10)
+	for _, item := range mine.items {
+		if match(item) {
+			node, err := clone(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, node)
+		}
+	}
+	return list, nil
+}
+
+func (mine *collection[T]) count(match func(*T) bool) int64 {
+	mine.lock.RLock()
+	defer mine.lock.RUnlock()
+	var num int64 = 0
+	for _, item := range mine.items {
+		if match(item) {
+			num += 1
+		}
+	}
+	return num
+}
+
+// 全部文档，包括已删除的
+func (mine *collection[T]) all() ([]*T, error) {
+	return mine.findMany(func(*T) bool { return true })
+}
+
+// 清空后写入文档，用于恢复备份
+func (mine *collection[T]) reset(list []*T) error {
+	items := make([]*T, 0, len(list))
+	for _, item := range list {
+		node, err := clone(item)
+		if err != nil {
+			return err
+		}
+		items = append(items, node)
+	}
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	mine.items = items
+	return nil
+}
+
+// 按uid修改一个文档，与mongodb的updateOne一样，找不到文档时不报错
+func (mine *collection[T]) update(uid string, fun func(*T)) error {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return err
+	}
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	i := mine.indexOf(id)
+	if i < 0 {
+		return nil
+	}
+	fun(mine.items[i])
+	return nil
+}
+
+func (mine *collection[T]) updateAll(match func(*T) bool, fun func(*T)) int64 {
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	var num int64 = 0
+	for _, item := range mine.items {
+		if match(item) {
+			fun(item)
+			num += 1
+		}
+	}
+	return num
+}
+
+// 对应mongodb的skip和limit，limit为0表示不限制
+func pageOf[T any](list []*T, skip, limit int64) []*T {
+	if skip >= int64(len(list)) {
+		return make([]*T, 0)
+	}
+	if skip > 0 {
+		list = list[skip:]
+	}
+	if limit > 0 && limit < int64(len(list)) {
+		list = list[:limit]
+	}
+	return list
+}
+
+func hasItem(array []string, value string) bool {
+	for _, item := range array {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hasNumber(array []uint8, value uint8) bool {
+	for _, item := range array {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// 对应mongodb的$pull，移除数组里面所有相等的元素
+func pullItem(array []string, value string) []string {
+	list := make([]string, 0, len(array))
+	for _, item := range array {
+		if item != value {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func copyStrings(array []string) []string {
+	if array == nil {
+		return nil
+	}
+	list := make([]string, len(array))
+	copy(list, array)
+	return list
+}
+
+func hasMember(array []proxy.MemberInfo, user string) bool {
+	for _, item := range array {
+		if item.User == user {
+			return true
+		}
+	}
+	return false
+}
+
+func pullMember(array []proxy.MemberInfo, user string) []proxy.MemberInfo {
+	list := make([]proxy.MemberInfo, 0, len(array))
+	for _, item := range array {
+		if item.User != user {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func pullWait(array []proxy.WaitInfo, user string) []proxy.WaitInfo {
+	list := make([]proxy.WaitInfo, 0, len(array))
+	for _, item := range array {
+		if item.User != user {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// 顺序和元素都相同，nil与空数组相同
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func memberUsers(array []proxy.MemberInfo) []string {
+	list := make([]string, 0, len(array))
+	for _, item := range array {
+		list = append(list, item.User)
+	}
+	return list
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+type categoryStore struct {
+	table *collection[nosql.Category]
+}
+
+func newCategoryStore() *categoryStore {
+	return &categoryStore{table: newCollection(func(t *nosql.Category) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *categoryStore) CreateCategory(info *nosql.Category) error {
+	return mine.table.insert(info)
+}
+
+func (mine *categoryStore) GetOneCategory(uid string) (*nosql.Category, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *categoryStore) GetAllCategories() ([]*nosql.Category, error) {
+	return mine.table.findMany(func(t *nosql.Category) bool {
+		return t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *categoryStore) GetCategoryListByParent(parent string) ([]*nosql.Category, error) {
+	return mine.table.findMany(func(t *nosql.Category) bool {
+		return t.Parent == parent && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *categoryStore) GetCategoryListByOwner(owner, parent string) ([]*nosql.Category, error) {
+	return mine.table.findMany(func(t *nosql.Category) bool {
+		return t.Owner == owner && t.Parent == parent && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *categoryStore) UpdateCategoryOwner(uid, owner string) error {
+	return mine.table.update(uid, func(t *nosql.Category) {
+		t.Owner = owner
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *categoryStore) UpdateCategoryBase(uid, name, remark, quote, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Category) {
+		t.Name = name
+		t.Remark = remark
+		t.Quote = quote
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+// 目前只有weight一个整型字段
+func (mine *categoryStore) UpdateCategoryInt(filter, operator, uid string, value int64) error {
+	return mine.table.update(uid, func(t *nosql.Category) {
+		if filter == "weight" {
+			t.Weight = uint32(value)
+		}
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *categoryStore) DeleteCategory(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Category) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *categoryStore) QueryCategories(query *proxy.Query) ([]*nosql.Category, int64, error) {
+	return mine.table.query(query)
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"sort"
+	"time"
+)
+
+type coterieStore struct {
+	table *collection[nosql.Coterie]
+}
+
+func newCoterieStore() *coterieStore {
+	return &coterieStore{table: newCollection(func(t *nosql.Coterie) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *coterieStore) CreateCoterie(info *nosql.Coterie) error {
+	return mine.table.insert(info)
+}
+
+func (mine *coterieStore) GetCoterie(uid string) (*nosql.Coterie, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *coterieStore) GetCoterieByCreator(creator string) (*nosql.Coterie, error) {
+	return mine.table.findOne(func(t *nosql.Coterie) bool {
+		return t.Creator == creator && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *coterieStore) GetCoterieByCentre(centre string) (*nosql.Coterie, error) {
+	return mine.table.findOne(func(t *nosql.Coterie) bool {
+		return t.Centre == centre && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *coterieStore) GetActivitiesCount() int64 {
+	return mine.table.count(func(t *nosql.Coterie) bool {
+		return t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *coterieStore) GetAllCoteries(page, num int64) ([]*nosql.Coterie, error) {
+	list, err := mine.table.findMany(func(t *nosql.Coterie) bool {
+		return t.DeleteTime.IsZero()
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedTime.After(list[j].CreatedTime)
+	})
+	return pageOf(list, page, num), nil
+}
+
+func (mine *coterieStore) GetCoteriesByMember(user string) ([]*nosql.Coterie, error) {
+	return mine.table.findMany(func(t *nosql.Coterie) bool {
+		return hasMember(t.Members, user) && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *coterieStore) GetCoteriesByCreator(user string) ([]*nosql.Coterie, error) {
+	return mine.table.findMany(func(t *nosql.Coterie) bool {
+		return t.Creator == user && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *coterieStore) GetCoteriesByMaster(user string) ([]*nosql.Coterie, error) {
+	return mine.table.findMany(func(t *nosql.Coterie) bool {
+		return t.Master == user && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *coterieStore) UpdateCoterieBase(uid, name, remark, psw, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Name = name
+		t.Remark = remark
+		t.Passwords = psw
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) UpdateCoterieMaster(uid, master, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Master = master
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) UpdateCoterieStatus(uid, operator string, st uint8) error {
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Status = st
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) UpdateCoteriePasswords(uid, operator, psw string) error {
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Passwords = psw
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) UpdateCoterieTags(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Tags = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) UpdateCoterieAssistants(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Assistants = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) RemoveCoterie(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) AppendCoterieMember(uid string, invitee proxy.MemberInfo) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Members = append(t.Members, invitee)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) SubtractCoterieMember(uid, user string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		t.Members = pullMember(t.Members, user)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) AppendCoterieMembers(uid string, list []proxy.MemberInfo) (bool, error) {
+	joined := false
+	err := mine.table.update(uid, func(t *nosql.Coterie) {
+		for _, item := range list {
+			if hasMember(t.Members, item.User) {
+				return
+			}
+		}
+		t.Members = append(t.Members, list...)
+		t.UpdatedTime = time.Now()
+		joined = true
+	})
+	return joined, err
+}
+
+func (mine *coterieStore) SubtractCoterieMembers(uid string, users []string) error {
+	return mine.table.update(uid, func(t *nosql.Coterie) {
+		for _, user := range users {
+			t.Members = pullMember(t.Members, user)
+		}
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *coterieStore) ReplaceCoterieMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	num := mine.table.updateAll(func(t *nosql.Coterie) bool {
+		return t.UID == id && sameStrings(memberUsers(t.Members), old)
+	}, func(t *nosql.Coterie) {
+		t.Members = append(make([]proxy.MemberInfo, 0, len(list)), list...)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+	return num > 0, nil
+}
+
+func (mine *coterieStore) QueryCoteries(query *proxy.Query) ([]*nosql.Coterie, int64, error) {
+	return mine.table.query(query)
+}
@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+type familyStore struct {
+	table *collection[nosql.Family]
+}
+
+func newFamilyStore() *familyStore {
+	return &familyStore{table: newCollection(func(t *nosql.Family) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *familyStore) CreateFamily(info *nosql.Family) error {
+	return mine.table.insert(info)
+}
+
+func (mine *familyStore) GetFamily(uid string) (*nosql.Family, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *familyStore) GetFamilyByCreator(creator string) (*nosql.Family, error) {
+	return mine.table.findOne(func(t *nosql.Family) bool {
+		return t.Creator == creator
+	})
+}
+
+func (mine *familyStore) GetFamilyByMaster(master string) (*nosql.Family, error) {
+	return mine.table.findOne(func(t *nosql.Family) bool {
+		return t.Master == master && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *familyStore) GetFamilyByChild(entity string) (*nosql.Family, error) {
+	return mine.table.findOne(func(t *nosql.Family) bool {
+		return hasItem(t.Children, entity)
+	})
+}
+
+func (mine *familyStore) GetAllFamilies() ([]*nosql.Family, error) {
+	return mine.table.findMany(func(t *nosql.Family) bool {
+		return t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *familyStore) GetFamiliesByMember(user string) ([]*nosql.Family, error) {
+	return mine.table.findMany(func(t *nosql.Family) bool {
+		return hasMember(t.Members, user) && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *familyStore) GetFamiliesByRegion(region string) ([]*nosql.Family, error) {
+	return mine.table.findMany(func(t *nosql.Family) bool {
+		return t.Region == region && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *familyStore) GetFamiliesByAgent(agent string) ([]*nosql.Family, error) {
+	return mine.table.findMany(func(t *nosql.Family) bool {
+		return hasItem(t.Agents, agent) && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *familyStore) UpdateFamilyBase(uid, name, remark, psw, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Name = name
+		t.Remark = remark
+		t.Passwords = psw
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) UpdateFamilyMaster(uid, master, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Master = master
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) UpdateFamilyStatus(uid, operator string, st uint8) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Status = st
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) UpdateFamilyPasswords(uid, operator, psw string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Passwords = psw
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) UpdateFamilyTags(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Tags = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) UpdateFamilyAgents(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Agents = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) UpdateFamilyAssistants(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Assistants = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) UpdateFamilyChildren(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Children = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) RemoveFamily(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *familyStore) AppendFamilyMember(uid string, invitee proxy.MemberInfo) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Members = append(t.Members, invitee)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) SubtractFamilyMember(uid, user string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Family) {
+		t.Members = pullMember(t.Members, user)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) AppendFamilyMembers(uid string, list []proxy.MemberInfo) (bool, error) {
+	joined := false
+	err := mine.table.update(uid, func(t *nosql.Family) {
+		for _, item := range list {
+			if hasMember(t.Members, item.User) {
+				return
+			}
+		}
+		t.Members = append(t.Members, list...)
+		t.UpdatedTime = time.Now()
+		joined = true
+	})
+	return joined, err
+}
+
+func (mine *familyStore) SubtractFamilyMembers(uid string, users []string) error {
+	return mine.table.update(uid, func(t *nosql.Family) {
+		for _, user := range users {
+			t.Members = pullMember(t.Members, user)
+		}
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *familyStore) ReplaceFamilyMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	num := mine.table.updateAll(func(t *nosql.Family) bool {
+		return t.UID == id && sameStrings(memberUsers(t.Members), old)
+	}, func(t *nosql.Family) {
+		t.Members = append(make([]proxy.MemberInfo, 0, len(list)), list...)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+	return num > 0, nil
+}
+
+func (mine *familyStore) QueryFamilies(query *proxy.Query) ([]*nosql.Family, int64, error) {
+	return mine.table.query(query)
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+type invitationStore struct {
+	table *collection[nosql.Invitation]
+}
+
+func newInvitationStore() *invitationStore {
+	return &invitationStore{table: newCollection(func(t *nosql.Invitation) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *invitationStore) CreateInvitation(info *nosql.Invitation) error {
+	return mine.table.insert(info)
+}
+
+func (mine *invitationStore) GetInvitation(uid string) (*nosql.Invitation, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *invitationStore) GetInvitationByCode(code string) (*nosql.Invitation, error) {
+	return mine.table.findOne(func(t *nosql.Invitation) bool {
+		return t.Code == code && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *invitationStore) UseInvitation(uid string, now time.Time) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	num := mine.table.updateAll(func(t *nosql.Invitation) bool {
+		return t.UID == id && t.DeleteTime.IsZero() && t.Used < t.Limit && (t.Expired.IsZero() || t.Expired.After(now))
+	}, func(t *nosql.Invitation) {
+		t.Used += 1
+		t.UpdatedTime = now
+	})
+	return num > 0, nil
+}
+
+func (mine *invitationStore) ReleaseInvitation(uid string) error {
+	return mine.table.update(uid, func(t *nosql.Invitation) {
+		if t.Used > 0 {
+			t.Used -= 1
+		}
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *invitationStore) RemoveInvitation(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Invitation) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *invitationStore) QueryInvitations(query *proxy.Query) ([]*nosql.Invitation, int64, error) {
+	return mine.table.query(query)
+}
@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+type meetingStore struct {
+	table *collection[nosql.Meeting]
+}
+
+func newMeetingStore() *meetingStore {
+	return &meetingStore{table: newCollection(func(t *nosql.Meeting) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *meetingStore) CreateMeeting(info *nosql.Meeting) error {
+	return mine.table.insert(info)
+}
+
+func (mine *meetingStore) GetMeeting(uid string) (*nosql.Meeting, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *meetingStore) GetMeetingsByGroup(group string) ([]*nosql.Meeting, error) {
+	return mine.table.findMany(func(t *nosql.Meeting) bool {
+		return t.Group == group && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *meetingStore) GetMeetingsByScene(owner string) ([]*nosql.Meeting, error) {
+	return mine.table.findMany(func(t *nosql.Meeting) bool {
+		return t.Owner == owner && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingBase(uid, name, remark, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Name = name
+		t.Remark = remark
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingLocation(uid, location, operator string, kind uint8) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Type = kind
+		t.Location = location
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingGroup(uid, operator, group string) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Group = group
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingDate(uid, operator string, start, stop time.Time) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.StartTime = start
+		t.StopTime = stop
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingStop(uid, operator string, stop time.Time) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.StopTime = stop
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingStatus(uid string, status uint16) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Status = uint8(status)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) TransitMeeting(uid string, from, status uint8) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	num := mine.table.updateAll(func(t *nosql.Meeting) bool {
+		return t.UID == id && t.Status == from && t.DeleteTime.IsZero()
+	}, func(t *nosql.Meeting) {
+		t.Status = status
+		t.UpdatedTime = time.Now()
+	})
+	return num > 0, nil
+}
+
+func (mine *meetingStore) StopMeeting(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Status = 3
+		t.Operator = operator
+		t.StopTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) RemoveMeeting(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) AppendMeetingSign(uid, member, operator string) error {
+	if len(member) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Signs = append(t.Signs, member)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingSignRule(uid, operator string, radius, before, late int32) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Radius = radius
+		t.SignBefore = before
+		t.SignLate = late
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingRule(uid, operator, rule, series string, occurrence time.Time, span int64, revision uint32) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Rule = rule
+		t.Series = series
+		t.Occurrence = occurrence
+		t.Span = span
+		t.Revision = revision
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) UpdateMeetingExceptions(uid, operator string, list []proxy.ExceptionInfo) error {
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		t.Exceptions = list
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) SaveMeetingAttend(uid string, change *nosql.AttendChange) error {
+	if change == nil || len(change.User()) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	user := change.User()
+	return mine.table.update(uid, func(t *nosql.Meeting) {
+		if change.Attend != nil {
+			found := false
+			for i := range t.Attends {
+				if t.Attends[i].User == user {
+					t.Attends[i] = *change.Attend
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Attends = append(t.Attends, *change.Attend)
+			}
+		}
+		if change.Record != nil {
+			t.Records = append(t.Records, *change.Record)
+		}
+		switch change.Field {
+		case "signs":
+			if !hasItem(t.Signs, user) {
+				t.Signs = append(t.Signs, user)
+			}
+		case "submits":
+			if !hasItem(t.Submits, user) {
+				t.Submits = append(t.Submits, user)
+			}
+		case "notifies":
+			if !hasItem(t.Notifies, user) {
+				t.Notifies = append(t.Notifies, user)
+			}
+		}
+		t.Operator = change.Operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *meetingStore) QueryMeetings(query *proxy.Query) ([]*nosql.Meeting, int64, error) {
+	return mine.table.query(query)
+}
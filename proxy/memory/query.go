@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"sort"
+	"strings"
+	"time"
+)
+
+type queryEntry[T any] struct {
+	item *T
+	doc  bson.M
+}
+
+// 通用的列表查询，文档转换为bson之后按字段名匹配，语义与mongodb保持一致
+func (mine *collection[T]) query(query *proxy.Query) ([]*T, int64, error) {
+	if query == nil {
+		query = new(proxy.Query)
+	}
+	conditions := make([]proxy.Condition, 0, len(query.Conditions)+2)
+	for _, item := range query.Conditions {
+		value, err := normalize(item.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		conditions = append(conditions, proxy.Condition{Field: item.Field, Op: item.Op, Value: value})
+	}
+	if query.Alive {
+		conditions = append(conditions, proxy.Condition{Field: "deleteAt", Op: proxy.OpEqual, Value: primitive.NewDateTimeFromTime(time.Time{})})
+	}
+	var cursor primitive.ObjectID
+	if len(query.Cursor) > 0 {
+		uid, err := primitive.ObjectIDFromHex(query.Cursor)
+		if err != nil {
+			return nil, 0, errors.New("the cursor is invalid")
+		}
+		cursor = uid
+	}
+
+	mine.lock.RLock()
+	defer mine.lock.RUnlock()
+	list := make([]*queryEntry[T], 0, 10)
+	for _, item := range mine.items {
+		doc, err := toDocument(item)
+		if err != nil {
+			return nil, 0, err
+		}
+		matched := true
+		for _, condition := range conditions {
+			ok, err := matchCondition(doc, condition)
+			if err != nil {
+				return nil, 0, err
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			list = append(list, &queryEntry[T]{item: item, doc: doc})
+		}
+	}
+	total := int64(len(list))
+
+	field := query.Sort
+	if len(query.Cursor) > 0 {
+		field = ""
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		num := 0
+		if len(field) > 0 && field != "_id" {
+			num = compareFirst(list[i].doc, list[j].doc, field)
+		}
+		if num == 0 {
+			num = compareFirst(list[i].doc, list[j].doc, "_id")
+		}
+		if query.Desc {
+			return num > 0
+		}
+		return num < 0
+	})
+
+	skip := query.Skip
+	if len(query.Cursor) > 0 {
+		skip = 0
+		for i, item := range list {
+			id, _ := item.doc["_id"].(primitive.ObjectID)
+			num := strings.Compare(id.Hex(), cursor.Hex())
+			if (query.Desc && num < 0) || (!query.Desc && num > 0) {
+				break
+			}
+			skip = int64(i + 1)
+		}
+	}
+	page := pageOf(list, skip, query.Limit)
+	items := make([]*T, 0, len(page))
+	for _, entry := range page {
+		node, err := clone(entry.item)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, node)
+	}
+	return items, total, nil
+}
+
+func toDocument(item interface{}) (bson.M, error) {
+	bytes, err := bson.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	err = bson.Unmarshal(bytes, &doc)
+	return doc, err
+}
+
+// 条件的值经过bson编解码，保证与文档中的类型一致
+func normalize(value interface{}) (interface{}, error) {
+	if list, ok := value.([]uint8); ok {
+		// []uint8在bson中会被编码为二进制
+		arr := make(primitive.A, 0, len(list))
+		for _, item := range list {
+			arr = append(arr, int32(item))
+		}
+		return arr, nil
+	}
+	doc, err := toDocument(bson.M{"v": value})
+	if err != nil {
+		return nil, err
+	}
+	return doc["v"], nil
+}
+
+// 按照字段路径取值，遇到数组时展开，与mongodb的点语法一致
+func lookup(value interface{}, path []string) []interface{} {
+	switch val := value.(type) {
+	case primitive.A:
+		list := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			list = append(list, lookup(item, path)...)
+		}
+		return list
+	case primitive.M:
+		if len(path) < 1 {
+			return []interface{}{val}
+		}
+		return lookup(val[path[0]], path[1:])
+	case primitive.D:
+		if len(path) < 1 {
+			return []interface{}{val}
+		}
+		return lookup(val.Map()[path[0]], path[1:])
+	}
+	if len(path) > 0 || value == nil {
+		return nil
+	}
+	return []interface{}{value}
+}
+
+func matchCondition(doc bson.M, condition proxy.Condition) (bool, error) {
+	values := lookup(doc, strings.Split(condition.Field, "."))
+	for _, value := range values {
+		switch condition.Op {
+		case proxy.OpEqual:
+			if num, ok := compareValue(value, condition.Value); ok && num == 0 {
+				return true, nil
+			}
+		case proxy.OpIn:
+			array, ok := condition.Value.(primitive.A)
+			if !ok {
+				return false, errors.New("the value of in must be array: " + condition.Field)
+			}
+			for _, item := range array {
+				if num, ok := compareValue(value, item); ok && num == 0 {
+					return true, nil
+				}
+			}
+		case proxy.OpGreater:
+			if num, ok := compareValue(value, condition.Value); ok && num > 0 {
+				return true, nil
+			}
+		case proxy.OpLess:
+			if num, ok := compareValue(value, condition.Value); ok && num < 0 {
+				return true, nil
+			}
+		default:
+			return false, errors.New("the query operator is not supported: " + condition.Field)
+		}
+	}
+	return false, nil
+}
+
+// 比较两个文档中某个字段的第一个值，不存在的值最小
+func compareFirst(a, b bson.M, field string) int {
+	left := lookup(a, strings.Split(field, "."))
+	right := lookup(b, strings.Split(field, "."))
+	if len(left) < 1 || len(right) < 1 {
+		return len(left) - len(right)
+	}
+	num, _ := compareValue(left[0], right[0])
+	return num
+}
+
+// 比较bson解码后的值，类型不可比较时返回false
+func compareValue(a, b interface{}) (int, bool) {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			if x < y {
+				return -1, true
+			} else if x > y {
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case primitive.DateTime:
+		if y, ok := b.(primitive.DateTime); ok {
+			if x < y {
+				return -1, true
+			} else if x > y {
+				return 1, true
+			}
+			return 0, true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return strings.Compare(x.Hex(), y.Hex()), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch val := value.(type) {
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case float64:
+		return val, true
+	}
+	return 0, false
+}
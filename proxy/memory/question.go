@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+type questionStore struct {
+	table *collection[nosql.Question]
+}
+
+func newQuestionStore() *questionStore {
+	return &questionStore{table: newCollection(func(t *nosql.Question) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *questionStore) CreateQuestion(info *nosql.Question) error {
+	return mine.table.insert(info)
+}
+
+func (mine *questionStore) GetQuestion(uid string) (*nosql.Question, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *questionStore) GetQuestionsByName(title string) ([]*nosql.Question, error) {
+	return mine.table.findMany(func(t *nosql.Question) bool {
+		return t.Title == title && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *questionStore) GetQuestionsByQuote(quote string) ([]*nosql.Question, error) {
+	return mine.table.findMany(func(t *nosql.Question) bool {
+		return t.Quote == quote && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *questionStore) GetQuestionsByTitle(title, category string) ([]*nosql.Question, error) {
+	return mine.table.findMany(func(t *nosql.Question) bool {
+		return t.Title == title && t.Category == category && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *questionStore) GetQuestionsByCategory(category string) ([]*nosql.Question, error) {
+	return mine.table.findMany(func(t *nosql.Question) bool {
+		return t.Category == category && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *questionStore) GetQuestionCount(category string) uint32 {
+	num := mine.table.count(func(t *nosql.Question) bool {
+		return t.Category == category && t.DeleteTime.IsZero()
+	})
+	return uint32(num)
+}
+
+func (mine *questionStore) UpdateQuestionBase(uid, title, remark, operator, category string, cd uint16, answers []uint32, opts []proxy.PairInfo) error {
+	return mine.table.update(uid, func(t *nosql.Question) {
+		t.Title = title
+		t.Remark = remark
+		t.Cd = cd
+		t.Category = category
+		t.Answers = copyNumbers(answers)
+		t.Options = copyPairs(opts)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *questionStore) UpdateQuestionAnswers(uid, operator string, answers []uint32) error {
+	return mine.table.update(uid, func(t *nosql.Question) {
+		t.Answers = copyNumbers(answers)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *questionStore) UpdateQuestionAssets(uid, operator string, assets []string) error {
+	return mine.table.update(uid, func(t *nosql.Question) {
+		t.Assets = copyStrings(assets)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *questionStore) UpdateQuestionOptions(uid, operator string, list []proxy.PairInfo) error {
+	return mine.table.update(uid, func(t *nosql.Question) {
+		t.Options = copyPairs(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *questionStore) RemoveQuestion(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Question) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func copyNumbers(array []uint32) []uint32 {
+	if array == nil {
+		return nil
+	}
+	list := make([]uint32, len(array))
+	copy(list, array)
+	return list
+}
+
+func copyPairs(array []proxy.PairInfo) []proxy.PairInfo {
+	if array == nil {
+		return nil
+	}
+	list := make([]proxy.PairInfo, len(array))
+	copy(list, array)
+	return list
+}
+
+func (mine *questionStore) QueryQuestions(query *proxy.Query) ([]*nosql.Question, int64, error) {
+	return mine.table.query(query)
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"sort"
+	"sync"
+	"time"
+)
+
+type quizStore struct {
+	table *collection[nosql.QuizRecord]
+	lock  sync.Mutex
+	// 用户和题目对应的最后一次提交的时间
+	submits map[[2]string]time.Time
+}
+
+func newQuizStore() *quizStore {
+	return &quizStore{table: newCollection(func(t *nosql.QuizRecord) primitive.ObjectID { return t.UID }),
+		submits: make(map[[2]string]time.Time, 20)}
+}
+
+func (mine *quizStore) TrySubmitQuiz(user, question string, now time.Time, cd time.Duration) (bool, time.Time, error) {
+	key := [2]string{user, question}
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	last, ok := mine.submits[key]
+	if ok && last.After(now.Add(-cd)) {
+		return false, last, nil
+	}
+	mine.submits[key] = now
+	return true, time.Time{}, nil
+}
+
+func (mine *quizStore) CreateQuizRecord(info *nosql.QuizRecord) error {
+	return mine.table.insert(info)
+}
+
+func (mine *quizStore) GetQuizRecord(uid string) (*nosql.QuizRecord, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *quizStore) QueryQuizRecords(query *proxy.Query) ([]*nosql.QuizRecord, int64, error) {
+	return mine.table.query(query)
+}
+
+func (mine *quizStore) GetQuizRanking(category string, limit int64) ([]*proxy.QuizScore, error) {
+	dbs, err := mine.table.findMany(func(t *nosql.QuizRecord) bool {
+		return t.Category == category && t.DeleteTime.IsZero()
+	})
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]*proxy.QuizScore, 10)
+	list := make([]*proxy.QuizScore, 0, 10)
+	for _, db := range dbs {
+		score, ok := scores[db.User]
+		if !ok {
+			score = &proxy.QuizScore{User: db.User}
+			scores[db.User] = score
+			list = append(list, score)
+		}
+		score.Total += 1
+		if db.Correct {
+			score.Correct += 1
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Correct != list[j].Correct {
+			return list[i].Correct > list[j].Correct
+		}
+		if list[i].Total != list[j].Total {
+			return list[i].Total < list[j].Total
+		}
+		return list[i].User < list[j].User
+	})
+	if limit > 0 && int64(len(list)) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
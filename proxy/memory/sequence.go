@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+)
+
+type sequenceStore struct {
+	lock  sync.Mutex
+	items map[string]uint64
+}
+
+func newSequenceStore() *sequenceStore {
+	return &sequenceStore{items: make(map[string]uint64, 20)}
+}
+
+func (mine *sequenceStore) GetSequenceNext(name string) (uint64, error) {
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	mine.items[name] += 1
+	return mine.items[name], nil
+}
+
+func (mine *sequenceStore) ReserveSequence(name string, count uint64) (uint64, error) {
+	if count < 1 {
+		return 0, errors.New("the sequence increment must be more than 0")
+	}
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	first := mine.items[name] + 1
+	mine.items[name] += count
+	return first, nil
+}
+
+func (mine *sequenceStore) RepairSequence(name string, min uint64) (bool, error) {
+	mine.lock.Lock()
+	defer mine.lock.Unlock()
+	if mine.items[name] >= min {
+		return false, nil
+	}
+	mine.items[name] = min
+	return true, nil
+}
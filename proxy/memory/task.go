@@ -0,0 +1,314 @@
+package memory
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"sort"
+	"strings"
+	"time"
+)
+
+type taskStore struct {
+	table *collection[nosql.Task]
+}
+
+func newTaskStore() *taskStore {
+	return &taskStore{table: newCollection(func(t *nosql.Task) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *taskStore) CreateTask(info *nosql.Task) error {
+	return mine.table.insert(info)
+}
+
+func (mine *taskStore) GetTask(uid string) (*nosql.Task, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *taskStore) GetTasksByOwner(uid string, st uint8) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return t.Owner == uid && t.Status == st && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByOwner2(uid string) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return t.Owner == uid && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByType(owner string, tp uint8) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return t.Owner == owner && t.Type == tp && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByRegion(region string, st uint8) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return hasItem(t.Regions, region) && t.Status == st && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByRegion2(region string) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return hasItem(t.Regions, region) && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByAgent(agent string, st uint8) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return hasItem(t.Executors, agent) && t.Status == st && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByAgent2(agent string) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return hasItem(t.Executors, agent) && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByTarget(client string, st uint8) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return t.Target == client && t.Status == st && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByTarget2(client string) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return t.Target == client && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) GetTasksByPreTask(uid string) ([]*nosql.Task, error) {
+	return mine.table.findMany(func(t *nosql.Task) bool {
+		return hasItem(t.PreTasks, uid) && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *taskStore) SearchTasks(filter *proxy.TaskFilter) ([]*nosql.Task, int64, error) {
+	keyword := strings.ToLower(filter.Keyword)
+	list, err := mine.table.findMany(func(t *nosql.Task) bool {
+		if !t.DeleteTime.IsZero() {
+			return false
+		}
+		if len(filter.Owner) > 0 && t.Owner != filter.Owner {
+			return false
+		}
+		if len(filter.Region) > 0 && !hasItem(t.Regions, filter.Region) {
+			return false
+		}
+		if len(filter.Executor) > 0 && !hasItem(t.Executors, filter.Executor) {
+			return false
+		}
+		for _, tag := range filter.Tags {
+			if !hasItem(t.Tags, tag) {
+				return false
+			}
+		}
+		if len(filter.Types) > 0 && !hasNumber(filter.Types, t.Type) {
+			return false
+		}
+		if len(filter.States) > 0 && !hasNumber(filter.States, t.Status) {
+			return false
+		}
+		if len(keyword) > 0 && !strings.Contains(strings.ToLower(t.Name), keyword) &&
+			!strings.Contains(strings.ToLower(t.Remark), keyword) {
+			return false
+		}
+		if len(filter.End) > 0 && t.Duration.Begin > filter.End {
+			return false
+		}
+		if len(filter.Begin) > 0 && len(t.Duration.End) > 0 && t.Duration.End < filter.Begin {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	less, err := taskLess(filter.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if filter.Desc {
+			return less(list[j], list[i])
+		}
+		return less(list[i], list[j])
+	})
+	return pageOf(list, filter.Skip, filter.Limit), int64(len(list)), nil
+}
+
+func taskLess(field string) (func(a, b *nosql.Task) bool, error) {
+	switch field {
+	case "", "createdAt":
+		return func(a, b *nosql.Task) bool {
+			if a.CreatedTime.Equal(b.CreatedTime) {
+				return a.UID.Hex() < b.UID.Hex()
+			}
+			return a.CreatedTime.Before(b.CreatedTime)
+		}, nil
+	case "updatedAt":
+		return func(a, b *nosql.Task) bool { return a.UpdatedTime.Before(b.UpdatedTime) }, nil
+	case "id":
+		return func(a, b *nosql.Task) bool { return a.ID < b.ID }, nil
+	case "name":
+		return func(a, b *nosql.Task) bool { return a.Name < b.Name }, nil
+	case "status":
+		return func(a, b *nosql.Task) bool { return a.Status < b.Status }, nil
+	case "duration.begin":
+		return func(a, b *nosql.Task) bool { return a.Duration.Begin < b.Duration.Begin }, nil
+	}
+	return nil, errors.New("the sort field is not supported: " + field)
+}
+
+func (mine *taskStore) UpdateTaskBase(uid, name, remark, operator string, assets []string) error {
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Name = name
+		t.Remark = remark
+		t.Assets = copyStrings(assets)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) UpdateTaskTags(uid, operator string, tags []string) error {
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Tags = copyStrings(tags)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) UpdateTaskExecutors(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Executors = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) UpdateTaskType(uid, operator string, tp uint8) error {
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Type = tp
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) UpdateTaskPreTasks(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.PreTasks = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) TransitTask(uid, operator string, history proxy.StatusHistory) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	num := mine.table.updateAll(func(t *nosql.Task) bool {
+		return t.UID == id && t.Status == history.From && t.DeleteTime.IsZero()
+	}, func(t *nosql.Task) {
+		t.Status = history.To
+		t.Histories = append(t.Histories, history)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+	return num > 0, nil
+}
+
+func (mine *taskStore) RemoveTask(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *taskStore) AppendTaskRecord(uid string, data proxy.RecordInfo) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	record, err := clone(&data)
+	if err != nil {
+		return err
+	}
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Records = append(t.Records, *record)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) SubtractTaskRecord(uid, record string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Task) {
+		list := make([]proxy.RecordInfo, 0, len(t.Records))
+		for _, item := range t.Records {
+			if item.UID != record {
+				list = append(list, item)
+			}
+		}
+		t.Records = list
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) UpdateTaskRecord(uid string, data proxy.RecordInfo) error {
+	record, err := clone(&data)
+	if err != nil {
+		return err
+	}
+	return mine.table.update(uid, func(t *nosql.Task) {
+		for i, item := range t.Records {
+			if item.UID == record.UID {
+				t.Records[i] = *record
+				t.UpdatedTime = time.Now()
+				break
+			}
+		}
+	})
+}
+
+func (mine *taskStore) UpdateTaskRecords(uid string, list []proxy.RecordInfo) error {
+	array := make([]proxy.RecordInfo, 0, len(list))
+	for _, item := range list {
+		record, err := clone(&item)
+		if err != nil {
+			return err
+		}
+		array = append(array, *record)
+	}
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Records = array
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) AppendTaskExecutor(uid, user string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Executors = append(t.Executors, user)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) SubtractTaskExecutor(uid, user string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Task) {
+		t.Executors = pullItem(t.Executors, user)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *taskStore) QueryTasks(query *proxy.Query) ([]*nosql.Task, int64, error) {
+	return mine.table.query(query)
+}
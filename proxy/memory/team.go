@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+type teamStore struct {
+	table *collection[nosql.Team]
+}
+
+func newTeamStore() *teamStore {
+	return &teamStore{table: newCollection(func(t *nosql.Team) primitive.ObjectID { return t.UID })}
+}
+
+func (mine *teamStore) CreateTeam(info *nosql.Team) error {
+	return mine.table.insert(info)
+}
+
+func (mine *teamStore) GetTeam(uid string) (*nosql.Team, error) {
+	return mine.table.get(uid)
+}
+
+func (mine *teamStore) GetTeamByName(owner, name string) (*nosql.Team, error) {
+	return mine.table.findOne(func(t *nosql.Team) bool {
+		return t.Owner == owner && t.Name == name && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *teamStore) GetTeamsByOwner(owner string) ([]*nosql.Team, error) {
+	return mine.table.findMany(func(t *nosql.Team) bool {
+		return t.Owner == owner && t.DeleteTime.IsZero()
+	})
+}
+
+func (mine *teamStore) GetTeamsByMember(user string) ([]*nosql.Team, error) {
+	return mine.table.findMany(func(t *nosql.Team) bool {
+		return hasItem(t.Members, user)
+	})
+}
+
+func (mine *teamStore) UpdateTeamBase(uid, name, remark, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Name = name
+		t.Remark = remark
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) UpdateTeamAssistants(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Assistants = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) UpdateTeamTags(uid, operator string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Tags = copyStrings(list)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) UpdateTeamStatus(uid, operator string, st uint8) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Status = st
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) UpdateTeamRegion(uid, region, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Region = region
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) UpdateTeamParent(uid, parent, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Parent = parent
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) UpdateTeamMembers(uid, operator string, members []string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Members = copyStrings(members)
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) UpdateTeamMaster(uid, member, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Master = member
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) RemoveTeam(uid, operator string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Operator = operator
+		t.DeleteTime = time.Now()
+	})
+}
+
+func (mine *teamStore) AppendTeamMember(uid, member string) error {
+	if len(member) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Members = append(t.Members, member)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) SubtractTeamMember(uid string, member string) error {
+	if len(member) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Members = pullItem(t.Members, member)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) UpdateTeamLimit(uid, operator string, limit uint16) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.MaxNum = limit
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) JoinTeam(uid, member string, limit uint16) (bool, error) {
+	if len(member) < 1 {
+		return false, errors.New("the member uid is empty")
+	}
+	joined := false
+	err := mine.table.update(uid, func(t *nosql.Team) {
+		if hasItem(t.Members, member) || (limit > 0 && len(t.Members) >= int(limit)) {
+			return
+		}
+		t.Members = append(t.Members, member)
+		t.Waits = pullWait(t.Waits, member)
+		t.UpdatedTime = time.Now()
+		joined = true
+	})
+	return joined, err
+}
+
+func (mine *teamStore) AppendTeamWait(uid string, wait proxy.WaitInfo) error {
+	if len(wait.User) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Team) {
+		for _, item := range t.Waits {
+			if item.User == wait.User {
+				return
+			}
+		}
+		t.Waits = append(t.Waits, wait)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) SubtractTeamWait(uid, user string) error {
+	if len(user) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return mine.table.update(uid, func(t *nosql.Team) {
+		t.Waits = pullWait(t.Waits, user)
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) AppendTeamMembers(uid string, list []string, limit uint16) (bool, error) {
+	joined := false
+	err := mine.table.update(uid, func(t *nosql.Team) {
+		if limit > 0 && len(t.Members)+len(list) > int(limit) {
+			return
+		}
+		for _, member := range list {
+			if hasItem(t.Members, member) {
+				return
+			}
+		}
+		t.Members = append(t.Members, list...)
+		for _, member := range list {
+			t.Waits = pullWait(t.Waits, member)
+		}
+		t.UpdatedTime = time.Now()
+		joined = true
+	})
+	return joined, err
+}
+
+func (mine *teamStore) SubtractTeamMembers(uid string, list []string) error {
+	return mine.table.update(uid, func(t *nosql.Team) {
+		for _, member := range list {
+			t.Members = pullItem(t.Members, member)
+		}
+		t.UpdatedTime = time.Now()
+	})
+}
+
+func (mine *teamStore) ReplaceTeamMembers(uid, operator string, old, members []string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	num := mine.table.updateAll(func(t *nosql.Team) bool {
+		return t.UID == id && sameStrings(t.Members, old)
+	}, func(t *nosql.Team) {
+		t.Members = copyStrings(members)
+		for _, member := range members {
+			t.Waits = pullWait(t.Waits, member)
+		}
+		t.Operator = operator
+		t.UpdatedTime = time.Now()
+	})
+	return num > 0, nil
+}
+
+func (mine *teamStore) QueryTeams(query *proxy.Query) ([]*nosql.Team, int64, error) {
+	return mine.table.query(query)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"time"
 )
 
@@ -220,3 +221,7 @@ func SubtractAgentAttach(uid, scene string) error {
 	_, err := removeElement(TableAgent, uid, msg)
 	return err
 }
+
+func QueryAgents(query *proxy.Query) ([]*Agent, int64, error) {
+	return findPage[Agent](TableAgent, query)
+}
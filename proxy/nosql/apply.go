@@ -4,6 +4,7 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"time"
 )
 
@@ -29,6 +30,11 @@ type Apply struct {
 	Reason     string    `json:"reason" bson:"reason"`
 	Remark     string    `json:"remark" bson:"remark"`
 	SubmitTime time.Time `json:"submit" bson:"submit"`
+	//审核人以及审核时间
+	Decider     string    `json:"decider" bson:"decider"`
+	DecidedTime time.Time `json:"decided" bson:"decided"`
+	//待审核时提醒的时间
+	RemindTime time.Time `json:"remind" bson:"remind"`
 }
 
 func CreateApply(info *Apply) error {
@@ -153,7 +159,42 @@ func UpdateApply(uid, reason, operator string, status uint8) error {
 	return err
 }
 
+// 只有状态为from其中之一时才修改，返回是否修改了，避免并发的审核重复生效
+func DecideApply(uid, operator, reason string, from []uint8, status uint8) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	filter := bson.M{"_id": objID, "status": bson.M{"$in": toBsonValue(from)}, "deleteAt": new(time.Time)}
+	msg := bson.M{"status": status, "reason": reason, "operator": operator, "decider": operator,
+		"decided": now, "updatedAt": now}
+	num, err := updateOneBy(TableApply, filter, bson.M{"$set": msg})
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+// 没有提醒过时记录提醒的时间，返回是否记录了，多个服务同时检查时只提醒一次
+func RemindApply(uid string, now time.Time) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{"_id": objID, "remind": new(time.Time), "deleteAt": new(time.Time)}
+	num, err := updateOneBy(TableApply, filter, bson.M{"$set": bson.M{"remind": now}})
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
 func RemoveApply(uid, operator string) error {
 	_, err := removeOne(TableApply, uid, operator)
 	return err
 }
+
+func QueryApplies(query *proxy.Query) ([]*Apply, int64, error) {
+	return findPage[Apply](TableApply, query)
+}
@@ -0,0 +1,180 @@
+package nosql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"time"
+)
+
+/**
+备份文件为json lines格式，每个集合一个文件 <集合>.jsonl：
+1. 第一行为BackupHeader，记录格式的版本、集合名称和文档数量
+2. 之后每行一个文档，字段与集合的json标签一致，包括已删除的文档
+3. sequences集合的文档为Sequence，恢复后自增序号保持不变
+*/
+
+const BackupVersion = 1
+
+const backupTimeOut = 5 * time.Minute
+
+// BackupTables 需要备份的集合，也是恢复时的顺序
+var BackupTables = []string{TableSequence, TableTask, TableAgent, TableTeam, TableFamily, TableCoterie,
+	TableApply, TableInvitation, TableMeeting, TableCategory, TableQuestion, TableRecord}
+
+type BackupHeader struct {
+	Version int       `json:"version"`
+	Table   string    `json:"table"`
+	Count   int       `json:"count"`
+	Created time.Time `json:"created"`
+}
+
+// NewDocument 集合对应的文档类型
+func NewDocument(table string) (interface{}, error) {
+	switch table {
+	case TableSequence:
+		return new(Sequence), nil
+	case TableTask:
+		return new(Task), nil
+	case TableAgent:
+		return new(Agent), nil
+	case TableTeam:
+		return new(Team), nil
+	case TableFamily:
+		return new(Family), nil
+	case TableCoterie:
+		return new(Coterie), nil
+	case TableApply:
+		return new(Apply), nil
+	case TableInvitation:
+		return new(Invitation), nil
+	case TableMeeting:
+		return new(Meeting), nil
+	case TableCategory:
+		return new(Category), nil
+	case TableQuestion:
+		return new(Question), nil
+	case TableRecord:
+		return new(QuizRecord), nil
+	default:
+		return nil, errors.New("the table not support backup: " + table)
+	}
+}
+
+// WriteBackupFile 把集合的文档写入备份文件
+func WriteBackupFile(file, table string, list []interface{}) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	writer := bufio.NewWriter(f)
+	encoder := json.NewEncoder(writer)
+	header := &BackupHeader{Version: BackupVersion, Table: table, Count: len(list), Created: time.Now()}
+	err = encoder.Encode(header)
+	if err != nil {
+		return err
+	}
+	for _, item := range list {
+		err = encoder.Encode(item)
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// ReadBackupFile 读取备份文件，检查版本以及文档的数量
+func ReadBackupFile(file string) (*BackupHeader, []interface{}, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	header := new(BackupHeader)
+	err = decoder.Decode(header)
+	if err != nil {
+		return nil, nil, errors.New("read the backup header failed: " + err.Error())
+	}
+	if header.Version < 1 || header.Version > BackupVersion {
+		return nil, nil, errors.New(fmt.Sprintf("the backup version %d is not supported", header.Version))
+	}
+	list := make([]interface{}, 0, header.Count)
+	for decoder.More() {
+		doc, err := NewDocument(header.Table)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = decoder.Decode(doc)
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("read the document %d of %s failed: %s", len(list)+1, file, err.Error()))
+		}
+		list = append(list, doc)
+	}
+	if len(list) != header.Count {
+		return nil, nil, errors.New(fmt.Sprintf("the backup of %s is broken, the count should be %d but %d", header.Table, header.Count, len(list)))
+	}
+	return header, list, nil
+}
+
+// DumpTable 集合中的全部文档，包括已删除的
+func DumpTable(table string) ([]interface{}, error) {
+	if _, err := NewDocument(table); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeOut)
+	defer cancel()
+	cursor, err := noSql.Collection(table).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	list := make([]interface{}, 0, 100)
+	for cursor.Next(ctx) {
+		doc, _ := NewDocument(table)
+		err = cursor.Decode(doc)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, doc)
+	}
+	return list, cursor.Err()
+}
+
+// RestoreTable 清空集合后写入文档，保留集合的索引
+func RestoreTable(table string, list []interface{}) error {
+	if _, err := NewDocument(table); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeOut)
+	defer cancel()
+	c := noSql.Collection(table)
+	_, err := c.DeleteMany(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	if len(list) < 1 {
+		return nil
+	}
+	for _, item := range list {
+		// 其他数据库导出的序号没有uid
+		if seq, ok := item.(*Sequence); ok && seq.UID.IsZero() {
+			seq.UID = primitive.NewObjectID()
+		}
+	}
+	_, err = c.InsertMany(ctx, list)
+	if err != nil {
+		return err
+	}
+	return ensureArrays(table)
+}
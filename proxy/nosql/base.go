@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/labstack/gommon/log"
 	"github.com/tidwall/gjson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -11,7 +12,6 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"io/ioutil"
 	"mime/multipart"
-	"os"
 	"time"
 )
 
@@ -41,6 +41,10 @@ func initMongoDB(ip string, port string, db string) error {
 		return err
 	}
 	noSql = dbClient.Database(db)
+	err = ensureQuizSubmitIndex()
+	if err != nil {
+		return err
+	}
 
 	tables, _ := noSql.ListCollectionNames(ctx, nil)
 	for i := 0; i < len(tables); i++ {
@@ -49,21 +53,19 @@ func initMongoDB(ip string, port string, db string) error {
 	return nil
 }
 
-func initMysql() error {
-	/*uri := core.DBConf.User + ":" + core.DBConf.Password + "@tcp(" + core.DBConf.URL+":"+core.DBConf.Port + ")/" + core.DBConf.Name
-	db, err := gorm.Open(core.DBConf.Type, uri)
+// Migrate 修复旧的数据：合并重复的序号后建立唯一索引，把null的数组字段改为空数组；
+// 服务启动时执行，备份和恢复时不执行
+func Migrate() error {
+	err := ensureSequenceIndex()
 	if err != nil {
-		panic("failed to connect database!!!" + uri)
 		return err
 	}
-	dbSql = db
-	db.DB().SetMaxIdleConns(10)
-	db.DB().SetMaxOpenConns(100)
-
-	dbSql.LogMode(true)
-
-	warn("connect database success!!!")
-	initTeacherTable()*/
+	for table := range arrayFields {
+		err = ensureArrays(table)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -71,7 +73,7 @@ func InitDB(ip string, port string, db string, kind string) error {
 	if kind == "mongodb" {
 		return initMongoDB(ip, port, db)
 	} else {
-		return initMysql()
+		return errors.New("the database type is not supported: " + kind)
 	}
 }
 
@@ -92,74 +94,22 @@ func checkConnected() bool {
 	return true
 }
 
+// 导入的文档追加到集合中，uid重复时返回错误
 func analyticDataStructure(table string, data []gjson.Result) error {
-	return nil
-}
-
-func writeFile(path string, table string, list interface{}) error {
-	f, err := os.OpenFile(path+table+".json", os.O_WRONLY|os.O_CREATE, 0666)
-	defer f.Close()
-	if err != nil {
-		os.Remove(path + table + ".json")
-		return errors.New("open the database failed")
-	}
-	bytes, _ := json.Marshal(list)
-	_, err2 := f.Write(bytes)
-	if err2 != nil {
-		os.Remove(path + table + ".json")
-		return errors.New("write the database failed")
-	}
-	return nil
-}
-
-func readFile(path string, table string) error {
-	f, err := os.OpenFile(path+table+".json", os.O_RDWR, 0666)
-	defer f.Close()
-	if err != nil {
-		return errors.New("open the database failed")
-	}
-	body, err := ioutil.ReadAll(f)
-	if err != nil {
-		return errors.New("read the file failed")
-	}
-
-	dataJson := string(body)
-	result := gjson.Parse(dataJson)
-	data := result.Array()
-
-	return analyticDataStructure(table, data)
-}
-
-func BackupDatabase() string {
-	/*timeStr := time.Now().Format("20060102150405")
-	path := "db/"+timeStr+"/"
-	bl := tool.FileIsExist(path)
-	if !bl {
-		err := os.MkdirAll(path, 0666)
+	for i, item := range data {
+		doc, err := NewDocument(table)
 		if err != nil {
-			warn("backup database failed!!!")
+			return err
 		}
-	}
-
-	tables, _ := noSql.ListCollectionNames(context.Background(), nil)
-	for i := 0; i < len(tables); i++ {
-		if tables[i] == TableAdmin {
-			//cursor,_ := findAll(TableAdmin)
-			//writeFile(path,tables[i],cursor.)
-		}else if tables[i] == TableScene {
-
-			//writeFile(path,tables[i],list)
+		err = json.Unmarshal([]byte(item.Raw), doc)
+		if err != nil {
+			return errors.New(fmt.Sprintf("the document %d format is error: %s", i+1, err.Error()))
+		}
+		_, err = insertOne(table, doc)
+		if err != nil {
+			return err
 		}
 	}
-	return timeStr*/
-	return ""
-}
-
-func RecoveryDatabase(timeStr string) error {
-	//path := "db/" + timeStr + "/"
-	//
-	//tables, _ := noSql.ListCollectionNames(context.Background(), nil)
-
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"time"
 )
 
@@ -197,3 +198,7 @@ func DeleteCategory(uid, operator string) error {
 	_, err := removeOne(TableCategory, uid, operator)
 	return err
 }
+
+func QueryCategories(query *proxy.Query) ([]*Category, int64, error) {
+	return findPage[Category](TableCategory, query)
+}
@@ -7,6 +7,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"omo.msa.assignment/proxy"
 	"time"
 )
 
@@ -120,7 +121,7 @@ func hadOne(collection string, filter bson.M) (bool, error) {
 	defer cancel()
 	result := c.FindOne(ctx, filter)
 	if result.Err() != nil {
-		return false, result.Err()
+		return false, notFound(result.Err())
 	}
 	return true, nil
 }
@@ -200,7 +201,8 @@ func removeElement(collection string, uid string, data bson.M) (int64, error) {
 	return result.ModifiedCount, nil
 }
 
-func updateOneBy(collection string, filter bson.M, update bson.M) (int64, error) {
+// update可以是更新的操作符或者聚合管道
+func updateOneBy(collection string, filter bson.M, update interface{}) (int64, error) {
 	if len(collection) < 1 {
 		return 0, errors.New("the collection is empty")
 	}
@@ -217,6 +219,14 @@ func updateOneBy(collection string, filter bson.M, update bson.M) (int64, error)
 	return result.ModifiedCount, nil
 }
 
+// 没有找到时统一返回proxy.ErrNotFound，和其他的存储一致
+func notFound(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return proxy.ErrNotFound
+	}
+	return err
+}
+
 func findOne(collection string, uid string) (*mongo.SingleResult, error) {
 	if len(collection) < 1 {
 		return nil, errors.New("the collection is empty")
@@ -234,7 +244,7 @@ func findOne(collection string, uid string) (*mongo.SingleResult, error) {
 	filter := bson.M{"_id": objID}
 	result := c.FindOne(ctx, filter)
 	if result.Err() != nil {
-		return nil, result.Err()
+		return nil, notFound(result.Err())
 	}
 	return result, nil
 }
@@ -251,7 +261,7 @@ func findOneBy(collection string, filter bson.M) (*mongo.SingleResult, error) {
 	defer cancel()
 	result := c.FindOne(ctx, filter)
 	if result.Err() != nil {
-		return nil, result.Err()
+		return nil, notFound(result.Err())
 	}
 	return result, nil
 }
@@ -273,7 +283,7 @@ func findOneOfField(collection string, uid string, selector bson.M) (*mongo.Sing
 	filter := bson.M{"_id": objID}
 	result := c.FindOne(ctx, filter, options.FindOne().SetProjection(selector))
 	if result.Err() != nil {
-		return nil, result.Err()
+		return nil, notFound(result.Err())
 	}
 	return result, nil
 }
@@ -290,7 +300,7 @@ func findOneByOpt(collection string, filter bson.M, selector bson.M) (*mongo.Sin
 	defer cancel()
 	result := c.FindOne(ctx, filter, options.FindOne().SetProjection(selector))
 	if result.Err() != nil {
-		return nil, result.Err()
+		return nil, notFound(result.Err())
 	}
 	return result, nil
 }
@@ -411,3 +421,37 @@ func copyOne(collection string) (*mongo.Collection, error) {
 	}
 	return tmp, nil
 }
+
+// 聚合管道中的数组字段，为空时作为空数组
+func arrayOf(field string) bson.M {
+	return bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}}
+}
+
+// 需要$push或者$pull的数组字段，保存为null时mongodb不能修改
+var arrayFields = map[string][]string{
+	TableTask:    {"records", "histories", "executors"},
+	TableTeam:    {"members", "waits"},
+	TableMeeting: {"signs", "records", "attends", "notifies", "submits"},
+}
+
+// 把集合中为null或者不存在的数组字段改为空数组，启动和恢复备份后调用
+func ensureArrays(collection string) error {
+	fields, ok := arrayFields[collection]
+	if !ok {
+		return nil
+	}
+	c := noSql.Collection(collection)
+	if c == nil {
+		return errors.New("can not found the collection of" + collection)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	for _, field := range fields {
+		filter := bson.M{field: bson.M{"$not": bson.M{"$type": "array"}}}
+		_, err := c.UpdateMany(ctx, filter, bson.M{"$set": bson.M{field: bson.A{}}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -300,3 +300,53 @@ func SubtractFamilyMember(uid, user string) error {
 	_, err := removeElement(TableFamily, uid, msg)
 	return err
 }
+
+// AppendFamilyMembers 一次加入多个成员，有成员已经存在时都不加入，返回是否加入
+func AppendFamilyMembers(uid string, list []proxy.MemberInfo) (bool, error) {
+	if len(list) < 1 {
+		return true, nil
+	}
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	users := make([]string, 0, len(list))
+	for _, item := range list {
+		users = append(users, item.User)
+	}
+	filter := bson.M{"_id": objID, "members.user": bson.M{"$nin": users}}
+	update := bson.M{"$push": bson.M{"members": bson.M{"$each": list}}, "$set": bson.M{"updatedAt": time.Now()}}
+	num, err := updateOneBy(TableFamily, filter, update)
+	return num > 0, err
+}
+
+func SubtractFamilyMembers(uid string, users []string) error {
+	if len(users) < 1 {
+		return nil
+	}
+	msg := bson.M{"members": bson.M{"user": bson.M{"$in": users}}}
+	_, err := removeElement(TableFamily, uid, msg)
+	return err
+}
+
+// ReplaceFamilyMembers 成员的用户与old相同时才替换
+func ReplaceFamilyMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	if old == nil {
+		old = make([]string, 0)
+	}
+	filter := bson.M{"_id": objID, "$expr": bson.M{"$eq": bson.A{arrayOf("members.user"), old}}}
+	update := bson.M{"$set": bson.M{"members": list, "operator": operator, "updatedAt": time.Now()}}
+	num, err := updateOneBy(TableFamily, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+func QueryFamilies(query *proxy.Query) ([]*Family, int64, error) {
+	return findPage[Family](TableFamily, query)
+}
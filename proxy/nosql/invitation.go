@@ -0,0 +1,105 @@
+package nosql
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"time"
+)
+
+// Invitation 加入圈子或者家庭的邀请码，可以使用limit次，撤销即删除
+type Invitation struct {
+	UID         primitive.ObjectID `bson:"_id"`
+	ID          uint64             `json:"id" bson:"id"`
+	Name        string             `json:"name" bson:"name"`
+	CreatedTime time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedTime time.Time          `json:"updatedAt" bson:"updatedAt"`
+	DeleteTime  time.Time          `json:"deleteAt" bson:"deleteAt"`
+	Creator     string             `json:"creator" bson:"creator"`
+	Operator    string             `json:"operator" bson:"operator"`
+
+	Code string `json:"code" bson:"code"`
+	//与申请的类型一致
+	Type  uint8  `json:"type" bson:"type"`
+	Scene string `json:"scene" bson:"scene"`
+	Group string `json:"group" bson:"group"`
+	//预设的成员备注
+	Remark string `json:"remark" bson:"remark"`
+	Limit  uint32 `json:"limit" bson:"limit"`
+	Used   uint32 `json:"used" bson:"used"`
+	//过期时间，零值表示不过期
+	Expired time.Time `json:"expired" bson:"expired"`
+}
+
+func CreateInvitation(info *Invitation) error {
+	_, err := insertOne(TableInvitation, info)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func GetInvitation(uid string) (*Invitation, error) {
+	result, err := findOne(TableInvitation, uid)
+	if err != nil {
+		return nil, err
+	}
+	model := new(Invitation)
+	err1 := result.Decode(model)
+	if err1 != nil {
+		return nil, err1
+	}
+	return model, nil
+}
+
+func GetInvitationByCode(code string) (*Invitation, error) {
+	msg := bson.M{"code": code, "deleteAt": new(time.Time)}
+	result, err := findOneBy(TableInvitation, msg)
+	if err != nil {
+		return nil, err
+	}
+	model := new(Invitation)
+	err1 := result.Decode(model)
+	if err1 != nil {
+		return nil, err1
+	}
+	return model, nil
+}
+
+// UseInvitation 未过期并且还有次数时使用次数加一，返回是否使用成功，检查和修改在一次操作中完成
+func UseInvitation(uid string, now time.Time) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{"_id": objID, "deleteAt": new(time.Time),
+		"$expr": bson.M{"$lt": bson.A{"$used", "$limit"}},
+		"$or":   bson.A{bson.M{"expired": new(time.Time)}, bson.M{"expired": bson.M{"$gt": now}}}}
+	update := bson.M{"$inc": bson.M{"used": 1}, "$set": bson.M{"updatedAt": now}}
+	num, err := updateOneBy(TableInvitation, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+// ReleaseInvitation 使用次数减一，不会小于0
+func ReleaseInvitation(uid string) error {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": objID, "used": bson.M{"$gt": 0}}
+	update := bson.M{"$inc": bson.M{"used": -1}, "$set": bson.M{"updatedAt": time.Now()}}
+	_, err = updateOneBy(TableInvitation, filter, update)
+	return err
+}
+
+func RemoveInvitation(uid, operator string) error {
+	_, err := removeOne(TableInvitation, uid, operator)
+	return err
+}
+
+func QueryInvitations(query *proxy.Query) ([]*Invitation, int64, error) {
+	return findPage[Invitation](TableInvitation, query)
+}
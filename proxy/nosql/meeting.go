@@ -5,6 +5,7 @@ import (
 	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"time"
 )
 
@@ -38,6 +39,42 @@ type Meeting struct {
 	Signs     []string `json:"signs" bson:"signs"`
 	Submits   []string `json:"submits" bson:"submits"`
 	Notifies  []string `json:"notifies" bson:"notifies"`
+
+	//签到的范围(米)，签到开始的时间(开始前的秒数)以及迟到的时间(开始后的秒数)，0表示使用默认值，小于0表示不限制
+	Radius     int32            `json:"radius" bson:"radius"`
+	SignBefore int32            `json:"signBefore" bson:"signBefore"`
+	SignLate   int32            `json:"signLate" bson:"signLate"`
+	Records    []proxy.SignInfo `json:"records" bson:"records"`
+	//每个成员的签到、提交、通知以及回复
+	Attends []proxy.AttendInfo `json:"attends" bson:"attends"`
+
+	//重复的规则(RRULE)，只保存在系列的第一次会议上，series为第一次会议，occurrence为按照规则计算的开始时间，span为每次的时长(秒)，
+	//revision为设置规则的次数，生成的会议的uid与其相关
+	Rule       string                `json:"rule" bson:"rule"`
+	Series     string                `json:"series" bson:"series"`
+	Occurrence time.Time             `json:"occurrence" bson:"occurrence"`
+	Span       int64                 `json:"span" bson:"span"`
+	Revision   uint32                `json:"revision" bson:"revision"`
+	Exceptions []proxy.ExceptionInfo `json:"exceptions" bson:"exceptions"`
+}
+
+// AttendChange 成员的一次签到、提交、邀请或者回复，Attend为空时不修改参加情况，Record为签到的记录，
+// Field为需要加入成员的数组(signs、submits或者notifies)
+type AttendChange struct {
+	Attend   *proxy.AttendInfo
+	Record   *proxy.SignInfo
+	Field    string
+	Operator string
+}
+
+func (mine *AttendChange) User() string {
+	if mine.Attend != nil {
+		return mine.Attend.User
+	}
+	if mine.Record != nil {
+		return mine.Record.User
+	}
+	return ""
 }
 
 func CreateMeeting(info *Meeting) error {
@@ -142,6 +179,21 @@ func UpdateMeetingStatus(uid string, status uint16) error {
 	return err
 }
 
+// TransitMeeting 状态为from时才修改为status，返回是否修改成功，避免覆盖已经关闭的会议
+func TransitMeeting(uid string, from, status uint8) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{"_id": objID, "status": from, "deleteAt": new(time.Time)}
+	msg := bson.M{"status": status, "updatedAt": time.Now()}
+	num, err := updateOneBy(TableMeeting, filter, bson.M{"$set": msg})
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
 func StopMeeting(uid, operator string) error {
 	msg := bson.M{"status": 3, "operator": operator, "stopAt": time.Now()}
 	_, err := updateOne(TableMeeting, uid, msg)
@@ -175,20 +227,57 @@ func AppendMeetingSign(uid, member, operator string) error {
 	return err
 }
 
-func AppendMeetingNotify(uid, member, operator string) error {
-	if len(member) < 1 {
-		return errors.New("the member uid is empty")
-	}
-	msg := bson.M{"notifies": member}
-	_, err := appendElement(TableMeeting, uid, msg)
+func UpdateMeetingSignRule(uid, operator string, radius, before, late int32) error {
+	msg := bson.M{"radius": radius, "signBefore": before, "signLate": late, "operator": operator, "updatedAt": time.Now()}
+	_, err := updateOne(TableMeeting, uid, msg)
 	return err
 }
 
-func AppendMeetingSubmit(uid, member, operator string) error {
-	if len(member) < 1 {
+func UpdateMeetingRule(uid, operator, rule, series string, occurrence time.Time, span int64, revision uint32) error {
+	msg := bson.M{"rule": rule, "series": series, "occurrence": occurrence, "span": span, "revision": revision, "operator": operator, "updatedAt": time.Now()}
+	_, err := updateOne(TableMeeting, uid, msg)
+	return err
+}
+
+func UpdateMeetingExceptions(uid, operator string, list []proxy.ExceptionInfo) error {
+	msg := bson.M{"exceptions": list, "operator": operator, "updatedAt": time.Now()}
+	_, err := updateOne(TableMeeting, uid, msg)
+	return err
+}
+
+// SaveMeetingAttend 在一次更新中替换成员的参加情况(不存在时追加)，同时追加签到记录以及把成员加入对应的数组
+func SaveMeetingAttend(uid string, change *AttendChange) error {
+	if change == nil || len(change.User()) < 1 {
 		return errors.New("the member uid is empty")
 	}
-	msg := bson.M{"submits": member}
-	_, err := appendElement(TableMeeting, uid, msg)
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return err
+	}
+	user := change.User()
+	set := bson.M{"operator": change.Operator, "updatedAt": time.Now()}
+	if change.Attend != nil {
+		attend := bson.M{"$literal": change.Attend}
+		attends := arrayOf("attends")
+		set["attends"] = bson.M{"$cond": bson.A{
+			bson.M{"$in": bson.A{user, bson.M{"$map": bson.M{"input": attends, "as": "a", "in": "$$a.user"}}}},
+			bson.M{"$map": bson.M{"input": attends, "as": "a",
+				"in": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$a.user", user}}, attend, "$$a"}}}},
+			bson.M{"$concatArrays": bson.A{attends, bson.A{attend}}},
+		}}
+	}
+	if change.Record != nil {
+		set["records"] = bson.M{"$concatArrays": bson.A{arrayOf("records"), bson.A{bson.M{"$literal": change.Record}}}}
+	}
+	if len(change.Field) > 0 {
+		list := arrayOf(change.Field)
+		set[change.Field] = bson.M{"$cond": bson.A{bson.M{"$in": bson.A{user, list}}, list,
+			bson.M{"$concatArrays": bson.A{list, bson.A{user}}}}}
+	}
+	_, err = updateOneBy(TableMeeting, bson.M{"_id": objID}, bson.A{bson.M{"$set": set}})
 	return err
 }
+
+func QueryMeetings(query *proxy.Query) ([]*Meeting, int64, error) {
+	return findPage[Meeting](TableMeeting, query)
+}
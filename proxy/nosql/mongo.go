@@ -0,0 +1,794 @@
+package nosql
+
+import (
+	"omo.msa.assignment/proxy"
+	"time"
+)
+
+/**
+mongodb的存储实现，直接转发到本包的数据库操作函数
+*/
+
+func NewStores() *Stores {
+	return &Stores{
+		Task:       new(mongoTask),
+		Agent:      new(mongoAgent),
+		Team:       new(mongoTeam),
+		Family:     new(mongoFamily),
+		Coterie:    new(mongoCoterie),
+		Apply:      new(mongoApply),
+		Invitation: new(mongoInvitation),
+		Meeting:    new(mongoMeeting),
+		Question:   new(mongoQuestion),
+		Category:   new(mongoCategory),
+		Quiz:       new(mongoQuiz),
+		Sequence:   new(mongoSequence),
+		Backup:     new(mongoBackup),
+	}
+}
+
+type mongoTask struct{}
+
+func (mine *mongoTask) QueryTasks(query *proxy.Query) ([]*Task, int64, error) {
+	return QueryTasks(query)
+}
+
+func (mine *mongoTask) CreateTask(info *Task) error {
+	return CreateTask(info)
+}
+
+func (mine *mongoTask) GetTask(uid string) (*Task, error) {
+	return GetTask(uid)
+}
+
+func (mine *mongoTask) GetTasksByOwner(uid string, st uint8) ([]*Task, error) {
+	return GetTasksByOwner(uid, st)
+}
+
+func (mine *mongoTask) GetTasksByOwner2(uid string) ([]*Task, error) {
+	return GetTasksByOwner2(uid)
+}
+
+func (mine *mongoTask) GetTasksByType(owner string, tp uint8) ([]*Task, error) {
+	return GetTasksByType(owner, tp)
+}
+
+func (mine *mongoTask) GetTasksByRegion(region string, st uint8) ([]*Task, error) {
+	return GetTasksByRegion(region, st)
+}
+
+func (mine *mongoTask) GetTasksByRegion2(region string) ([]*Task, error) {
+	return GetTasksByRegion2(region)
+}
+
+func (mine *mongoTask) GetTasksByAgent(agent string, st uint8) ([]*Task, error) {
+	return GetTasksByAgent(agent, st)
+}
+
+func (mine *mongoTask) GetTasksByAgent2(agent string) ([]*Task, error) {
+	return GetTasksByAgent2(agent)
+}
+
+func (mine *mongoTask) GetTasksByTarget(client string, st uint8) ([]*Task, error) {
+	return GetTasksByTarget(client, st)
+}
+
+func (mine *mongoTask) GetTasksByTarget2(client string) ([]*Task, error) {
+	return GetTasksByTarget2(client)
+}
+
+func (mine *mongoTask) GetTasksByPreTask(uid string) ([]*Task, error) {
+	return GetTasksByPreTask(uid)
+}
+
+func (mine *mongoTask) SearchTasks(filter *proxy.TaskFilter) ([]*Task, int64, error) {
+	return SearchTasks(filter)
+}
+
+func (mine *mongoTask) UpdateTaskBase(uid, name, remark, operator string, assets []string) error {
+	return UpdateTaskBase(uid, name, remark, operator, assets)
+}
+
+func (mine *mongoTask) UpdateTaskTags(uid, operator string, tags []string) error {
+	return UpdateTaskTags(uid, operator, tags)
+}
+
+func (mine *mongoTask) UpdateTaskExecutors(uid, operator string, list []string) error {
+	return UpdateTaskExecutors(uid, operator, list)
+}
+
+func (mine *mongoTask) UpdateTaskType(uid, operator string, tp uint8) error {
+	return UpdateTaskType(uid, operator, tp)
+}
+
+func (mine *mongoTask) UpdateTaskPreTasks(uid, operator string, list []string) error {
+	return UpdateTaskPreTasks(uid, operator, list)
+}
+
+func (mine *mongoTask) TransitTask(uid, operator string, history proxy.StatusHistory) (bool, error) {
+	return TransitTask(uid, operator, history)
+}
+
+func (mine *mongoTask) RemoveTask(uid, operator string) error {
+	return RemoveTask(uid, operator)
+}
+
+func (mine *mongoTask) AppendTaskRecord(uid string, data proxy.RecordInfo) error {
+	return AppendTaskRecord(uid, data)
+}
+
+func (mine *mongoTask) SubtractTaskRecord(uid, record string) error {
+	return SubtractTaskRecord(uid, record)
+}
+
+func (mine *mongoTask) UpdateTaskRecord(uid string, data proxy.RecordInfo) error {
+	return UpdateTaskRecord(uid, data)
+}
+
+func (mine *mongoTask) UpdateTaskRecords(uid string, list []proxy.RecordInfo) error {
+	return UpdateTaskRecords(uid, list)
+}
+
+func (mine *mongoTask) AppendTaskExecutor(uid, user string) error {
+	return AppendTaskExecutor(uid, user)
+}
+
+func (mine *mongoTask) SubtractTaskExecutor(uid, user string) error {
+	return SubtractTaskExecutor(uid, user)
+}
+
+type mongoAgent struct{}
+
+func (mine *mongoAgent) QueryAgents(query *proxy.Query) ([]*Agent, int64, error) {
+	return QueryAgents(query)
+}
+
+func (mine *mongoAgent) CreateAgent(info *Agent) error {
+	return CreateAgent(info)
+}
+
+func (mine *mongoAgent) GetAgent(uid string) (*Agent, error) {
+	return GetAgent(uid)
+}
+
+func (mine *mongoAgent) GetAgentByUser(user string) (*Agent, error) {
+	return GetAgentByUser(user)
+}
+
+func (mine *mongoAgent) GetAgentsByOwner(owner string) ([]*Agent, error) {
+	return GetAgentsByOwner(owner)
+}
+
+func (mine *mongoAgent) GetAgentsByAttach(scene string) ([]*Agent, error) {
+	return GetAgentsByAttach(scene)
+}
+
+func (mine *mongoAgent) GetAgentsByRegion(region string) ([]*Agent, error) {
+	return GetAgentsByRegion(region)
+}
+
+func (mine *mongoAgent) GetAgentsByWay(owner, way string) ([]*Agent, error) {
+	return GetAgentsByWay(owner, way)
+}
+
+func (mine *mongoAgent) UpdateAgentBase(uid, name, remark, operator string) error {
+	return UpdateAgentBase(uid, name, remark, operator)
+}
+
+func (mine *mongoAgent) UpdateAgentEntity(uid, entity, operator string) error {
+	return UpdateAgentEntity(uid, entity, operator)
+}
+
+func (mine *mongoAgent) UpdateAgentStatus(uid, operator string, st uint8) error {
+	return UpdateAgentStatus(uid, operator, st)
+}
+
+func (mine *mongoAgent) UpdateAgentTags(uid, operator string, tags []string) error {
+	return UpdateAgentTags(uid, operator, tags)
+}
+
+func (mine *mongoAgent) UpdateAgentRegions(uid, operator string, list []string) error {
+	return UpdateAgentRegions(uid, operator, list)
+}
+
+func (mine *mongoAgent) UpdateAgentAttaches(uid, operator string, list []string) error {
+	return UpdateAgentAttaches(uid, operator, list)
+}
+
+func (mine *mongoAgent) RemoveAgent(uid, operator string) error {
+	return RemoveAgent(uid, operator)
+}
+
+func (mine *mongoAgent) AppendAgentAttach(uid, scene string) error {
+	return AppendAgentAttach(uid, scene)
+}
+
+func (mine *mongoAgent) SubtractAgentAttach(uid, scene string) error {
+	return SubtractAgentAttach(uid, scene)
+}
+
+type mongoTeam struct{}
+
+func (mine *mongoTeam) QueryTeams(query *proxy.Query) ([]*Team, int64, error) {
+	return QueryTeams(query)
+}
+
+func (mine *mongoTeam) CreateTeam(info *Team) error {
+	return CreateTeam(info)
+}
+
+func (mine *mongoTeam) GetTeam(uid string) (*Team, error) {
+	return GetTeam(uid)
+}
+
+func (mine *mongoTeam) GetTeamByName(owner, name string) (*Team, error) {
+	return GetTeamByName(owner, name)
+}
+
+func (mine *mongoTeam) GetTeamsByOwner(owner string) ([]*Team, error) {
+	return GetTeamsByOwner(owner)
+}
+
+func (mine *mongoTeam) GetTeamsByMember(user string) ([]*Team, error) {
+	return GetTeamsByMember(user)
+}
+
+func (mine *mongoTeam) UpdateTeamBase(uid, name, remark, operator string) error {
+	return UpdateTeamBase(uid, name, remark, operator)
+}
+
+func (mine *mongoTeam) UpdateTeamAssistants(uid, operator string, list []string) error {
+	return UpdateTeamAssistants(uid, operator, list)
+}
+
+func (mine *mongoTeam) UpdateTeamTags(uid, operator string, list []string) error {
+	return UpdateTeamTags(uid, operator, list)
+}
+
+func (mine *mongoTeam) UpdateTeamStatus(uid, operator string, st uint8) error {
+	return UpdateTeamStatus(uid, operator, st)
+}
+
+func (mine *mongoTeam) UpdateTeamRegion(uid, region, operator string) error {
+	return UpdateTeamRegion(uid, region, operator)
+}
+
+func (mine *mongoTeam) UpdateTeamParent(uid, parent, operator string) error {
+	return UpdateTeamParent(uid, parent, operator)
+}
+
+func (mine *mongoTeam) UpdateTeamMembers(uid, operator string, members []string) error {
+	return UpdateTeamMembers(uid, operator, members)
+}
+
+func (mine *mongoTeam) UpdateTeamMaster(uid, member, operator string) error {
+	return UpdateTeamMaster(uid, member, operator)
+}
+
+func (mine *mongoTeam) RemoveTeam(uid, operator string) error {
+	return RemoveTeam(uid, operator)
+}
+
+func (mine *mongoTeam) AppendTeamMember(uid, member string) error {
+	return AppendTeamMember(uid, member)
+}
+
+func (mine *mongoTeam) SubtractTeamMember(uid string, member string) error {
+	return SubtractTeamMember(uid, member)
+}
+
+func (mine *mongoTeam) UpdateTeamLimit(uid, operator string, limit uint16) error {
+	return UpdateTeamLimit(uid, operator, limit)
+}
+
+func (mine *mongoTeam) JoinTeam(uid, member string, limit uint16) (bool, error) {
+	return JoinTeam(uid, member, limit)
+}
+
+func (mine *mongoTeam) AppendTeamWait(uid string, wait proxy.WaitInfo) error {
+	return AppendTeamWait(uid, wait)
+}
+
+func (mine *mongoTeam) SubtractTeamWait(uid, user string) error {
+	return SubtractTeamWait(uid, user)
+}
+
+func (mine *mongoTeam) AppendTeamMembers(uid string, list []string, limit uint16) (bool, error) {
+	return AppendTeamMembers(uid, list, limit)
+}
+
+func (mine *mongoTeam) SubtractTeamMembers(uid string, list []string) error {
+	return SubtractTeamMembers(uid, list)
+}
+
+func (mine *mongoTeam) ReplaceTeamMembers(uid, operator string, old, members []string) (bool, error) {
+	return ReplaceTeamMembers(uid, operator, old, members)
+}
+
+type mongoFamily struct{}
+
+func (mine *mongoFamily) QueryFamilies(query *proxy.Query) ([]*Family, int64, error) {
+	return QueryFamilies(query)
+}
+
+func (mine *mongoFamily) CreateFamily(info *Family) error {
+	return CreateFamily(info)
+}
+
+func (mine *mongoFamily) GetFamily(uid string) (*Family, error) {
+	return GetFamily(uid)
+}
+
+func (mine *mongoFamily) GetFamilyByCreator(creator string) (*Family, error) {
+	return GetFamilyByCreator(creator)
+}
+
+func (mine *mongoFamily) GetFamilyByMaster(master string) (*Family, error) {
+	return GetFamilyByMaster(master)
+}
+
+func (mine *mongoFamily) GetFamilyByChild(entity string) (*Family, error) {
+	return GetFamilyByChild(entity)
+}
+
+func (mine *mongoFamily) GetAllFamilies() ([]*Family, error) {
+	return GetAllFamilies()
+}
+
+func (mine *mongoFamily) GetFamiliesByMember(user string) ([]*Family, error) {
+	return GetFamiliesByMember(user)
+}
+
+func (mine *mongoFamily) GetFamiliesByRegion(region string) ([]*Family, error) {
+	return GetFamiliesByRegion(region)
+}
+
+func (mine *mongoFamily) GetFamiliesByAgent(agent string) ([]*Family, error) {
+	return GetFamiliesByAgent(agent)
+}
+
+func (mine *mongoFamily) UpdateFamilyBase(uid, name, remark, psw, operator string) error {
+	return UpdateFamilyBase(uid, name, remark, psw, operator)
+}
+
+func (mine *mongoFamily) UpdateFamilyMaster(uid, master, operator string) error {
+	return UpdateFamilyMaster(uid, master, operator)
+}
+
+func (mine *mongoFamily) UpdateFamilyStatus(uid, operator string, st uint8) error {
+	return UpdateFamilyStatus(uid, operator, st)
+}
+
+func (mine *mongoFamily) UpdateFamilyPasswords(uid, operator, psw string) error {
+	return UpdateFamilyPasswords(uid, operator, psw)
+}
+
+func (mine *mongoFamily) UpdateFamilyTags(uid, operator string, list []string) error {
+	return UpdateFamilyTags(uid, operator, list)
+}
+
+func (mine *mongoFamily) UpdateFamilyAgents(uid, operator string, list []string) error {
+	return UpdateFamilyAgents(uid, operator, list)
+}
+
+func (mine *mongoFamily) UpdateFamilyAssistants(uid, operator string, list []string) error {
+	return UpdateFamilyAssistants(uid, operator, list)
+}
+
+func (mine *mongoFamily) UpdateFamilyChildren(uid, operator string, list []string) error {
+	return UpdateFamilyChildren(uid, operator, list)
+}
+
+func (mine *mongoFamily) RemoveFamily(uid, operator string) error {
+	return RemoveFamily(uid, operator)
+}
+
+func (mine *mongoFamily) AppendFamilyMember(uid string, invitee proxy.MemberInfo) error {
+	return AppendFamilyMember(uid, invitee)
+}
+
+func (mine *mongoFamily) SubtractFamilyMember(uid, user string) error {
+	return SubtractFamilyMember(uid, user)
+}
+
+func (mine *mongoFamily) AppendFamilyMembers(uid string, list []proxy.MemberInfo) (bool, error) {
+	return AppendFamilyMembers(uid, list)
+}
+
+func (mine *mongoFamily) SubtractFamilyMembers(uid string, users []string) error {
+	return SubtractFamilyMembers(uid, users)
+}
+
+func (mine *mongoFamily) ReplaceFamilyMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error) {
+	return ReplaceFamilyMembers(uid, operator, old, list)
+}
+
+type mongoCoterie struct{}
+
+func (mine *mongoCoterie) QueryCoteries(query *proxy.Query) ([]*Coterie, int64, error) {
+	return QueryCoteries(query)
+}
+
+func (mine *mongoCoterie) CreateCoterie(info *Coterie) error {
+	return CreateCoterie(info)
+}
+
+func (mine *mongoCoterie) GetCoterie(uid string) (*Coterie, error) {
+	return GetCoterie(uid)
+}
+
+func (mine *mongoCoterie) GetCoterieByCreator(creator string) (*Coterie, error) {
+	return GetCoterieByCreator(creator)
+}
+
+func (mine *mongoCoterie) GetCoterieByCentre(centre string) (*Coterie, error) {
+	return GetCoterieByCentre(centre)
+}
+
+func (mine *mongoCoterie) GetActivitiesCount() int64 {
+	return GetActivitiesCount()
+}
+
+func (mine *mongoCoterie) GetAllCoteries(page, num int64) ([]*Coterie, error) {
+	return GetAllCoteries(page, num)
+}
+
+func (mine *mongoCoterie) GetCoteriesByMember(user string) ([]*Coterie, error) {
+	return GetCoteriesByMember(user)
+}
+
+func (mine *mongoCoterie) GetCoteriesByCreator(user string) ([]*Coterie, error) {
+	return GetCoteriesByCreator(user)
+}
+
+func (mine *mongoCoterie) GetCoteriesByMaster(user string) ([]*Coterie, error) {
+	return GetCoteriesByMaster(user)
+}
+
+func (mine *mongoCoterie) UpdateCoterieBase(uid, name, remark, psw, operator string) error {
+	return UpdateCoterieBase(uid, name, remark, psw, operator)
+}
+
+func (mine *mongoCoterie) UpdateCoterieMaster(uid, master, operator string) error {
+	return UpdateCoterieMaster(uid, master, operator)
+}
+
+func (mine *mongoCoterie) UpdateCoterieStatus(uid, operator string, st uint8) error {
+	return UpdateCoterieStatus(uid, operator, st)
+}
+
+func (mine *mongoCoterie) UpdateCoteriePasswords(uid, operator, psw string) error {
+	return UpdateCoteriePasswords(uid, operator, psw)
+}
+
+func (mine *mongoCoterie) UpdateCoterieTags(uid, operator string, list []string) error {
+	return UpdateCoterieTags(uid, operator, list)
+}
+
+func (mine *mongoCoterie) UpdateCoterieAssistants(uid, operator string, list []string) error {
+	return UpdateCoterieAssistants(uid, operator, list)
+}
+
+func (mine *mongoCoterie) RemoveCoterie(uid, operator string) error {
+	return RemoveCoterie(uid, operator)
+}
+
+func (mine *mongoCoterie) AppendCoterieMember(uid string, invitee proxy.MemberInfo) error {
+	return AppendCoterieMember(uid, invitee)
+}
+
+func (mine *mongoCoterie) SubtractCoterieMember(uid, user string) error {
+	return SubtractCoterieMember(uid, user)
+}
+
+func (mine *mongoCoterie) AppendCoterieMembers(uid string, list []proxy.MemberInfo) (bool, error) {
+	return AppendCoterieMembers(uid, list)
+}
+
+func (mine *mongoCoterie) SubtractCoterieMembers(uid string, users []string) error {
+	return SubtractCoterieMembers(uid, users)
+}
+
+func (mine *mongoCoterie) ReplaceCoterieMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error) {
+	return ReplaceCoterieMembers(uid, operator, old, list)
+}
+
+type mongoInvitation struct{}
+
+func (mine *mongoInvitation) CreateInvitation(info *Invitation) error {
+	return CreateInvitation(info)
+}
+
+func (mine *mongoInvitation) GetInvitation(uid string) (*Invitation, error) {
+	return GetInvitation(uid)
+}
+
+func (mine *mongoInvitation) GetInvitationByCode(code string) (*Invitation, error) {
+	return GetInvitationByCode(code)
+}
+
+func (mine *mongoInvitation) UseInvitation(uid string, now time.Time) (bool, error) {
+	return UseInvitation(uid, now)
+}
+
+func (mine *mongoInvitation) ReleaseInvitation(uid string) error {
+	return ReleaseInvitation(uid)
+}
+
+func (mine *mongoInvitation) RemoveInvitation(uid, operator string) error {
+	return RemoveInvitation(uid, operator)
+}
+
+func (mine *mongoInvitation) QueryInvitations(query *proxy.Query) ([]*Invitation, int64, error) {
+	return QueryInvitations(query)
+}
+
+type mongoApply struct{}
+
+func (mine *mongoApply) QueryApplies(query *proxy.Query) ([]*Apply, int64, error) {
+	return QueryApplies(query)
+}
+
+func (mine *mongoApply) CreateApply(info *Apply) error {
+	return CreateApply(info)
+}
+
+func (mine *mongoApply) GetApply(uid string) (*Apply, error) {
+	return GetApply(uid)
+}
+
+func (mine *mongoApply) GetAppliesByGroup(group string) ([]*Apply, error) {
+	return GetAppliesByGroup(group)
+}
+
+func (mine *mongoApply) GetAppliesByScene(scene string, tp uint8) ([]*Apply, error) {
+	return GetAppliesByScene(scene, tp)
+}
+
+func (mine *mongoApply) GetAppliesByScene1(scene string) ([]*Apply, error) {
+	return GetAppliesByScene1(scene)
+}
+
+func (mine *mongoApply) GetAppliesByApplicant(user string) ([]*Apply, error) {
+	return GetAppliesByApplicant(user)
+}
+
+func (mine *mongoApply) GetAppliesByCreator(user string) ([]*Apply, error) {
+	return GetAppliesByCreator(user)
+}
+
+func (mine *mongoApply) UpdateApply(uid, reason, operator string, status uint8) error {
+	return UpdateApply(uid, reason, operator, status)
+}
+
+func (mine *mongoApply) DecideApply(uid, operator, reason string, from []uint8, status uint8) (bool, error) {
+	return DecideApply(uid, operator, reason, from, status)
+}
+
+func (mine *mongoApply) RemindApply(uid string, now time.Time) (bool, error) {
+	return RemindApply(uid, now)
+}
+
+func (mine *mongoApply) RemoveApply(uid, operator string) error {
+	return RemoveApply(uid, operator)
+}
+
+type mongoMeeting struct{}
+
+func (mine *mongoMeeting) QueryMeetings(query *proxy.Query) ([]*Meeting, int64, error) {
+	return QueryMeetings(query)
+}
+
+func (mine *mongoMeeting) CreateMeeting(info *Meeting) error {
+	return CreateMeeting(info)
+}
+
+func (mine *mongoMeeting) GetMeeting(uid string) (*Meeting, error) {
+	return GetMeeting(uid)
+}
+
+func (mine *mongoMeeting) GetMeetingsByGroup(group string) ([]*Meeting, error) {
+	return GetMeetingsByGroup(group)
+}
+
+func (mine *mongoMeeting) GetMeetingsByScene(owner string) ([]*Meeting, error) {
+	return GetMeetingsByScene(owner)
+}
+
+func (mine *mongoMeeting) UpdateMeetingBase(uid, name, remark, operator string) error {
+	return UpdateMeetingBase(uid, name, remark, operator)
+}
+
+func (mine *mongoMeeting) UpdateMeetingLocation(uid, location, operator string, kind uint8) error {
+	return UpdateMeetingLocation(uid, location, operator, kind)
+}
+
+func (mine *mongoMeeting) UpdateMeetingGroup(uid, operator, group string) error {
+	return UpdateMeetingGroup(uid, operator, group)
+}
+
+func (mine *mongoMeeting) UpdateMeetingDate(uid, operator string, start, stop time.Time) error {
+	return UpdateMeetingDate(uid, operator, start, stop)
+}
+
+func (mine *mongoMeeting) UpdateMeetingStop(uid, operator string, t time.Time) error {
+	return UpdateMeetingStop(uid, operator, t)
+}
+
+func (mine *mongoMeeting) UpdateMeetingStatus(uid string, status uint16) error {
+	return UpdateMeetingStatus(uid, status)
+}
+
+func (mine *mongoMeeting) TransitMeeting(uid string, from, status uint8) (bool, error) {
+	return TransitMeeting(uid, from, status)
+}
+
+func (mine *mongoMeeting) StopMeeting(uid, operator string) error {
+	return StopMeeting(uid, operator)
+}
+
+func (mine *mongoMeeting) RemoveMeeting(uid, operator string) error {
+	return RemoveMeeting(uid, operator)
+}
+
+func (mine *mongoMeeting) AppendMeetingSign(uid, member, operator string) error {
+	return AppendMeetingSign(uid, member, operator)
+}
+
+func (mine *mongoMeeting) UpdateMeetingSignRule(uid, operator string, radius, before, late int32) error {
+	return UpdateMeetingSignRule(uid, operator, radius, before, late)
+}
+
+func (mine *mongoMeeting) UpdateMeetingRule(uid, operator, rule, series string, occurrence time.Time, span int64, revision uint32) error {
+	return UpdateMeetingRule(uid, operator, rule, series, occurrence, span, revision)
+}
+
+func (mine *mongoMeeting) UpdateMeetingExceptions(uid, operator string, list []proxy.ExceptionInfo) error {
+	return UpdateMeetingExceptions(uid, operator, list)
+}
+
+func (mine *mongoMeeting) SaveMeetingAttend(uid string, change *AttendChange) error {
+	return SaveMeetingAttend(uid, change)
+}
+
+type mongoQuestion struct{}
+
+func (mine *mongoQuestion) QueryQuestions(query *proxy.Query) ([]*Question, int64, error) {
+	return QueryQuestions(query)
+}
+
+func (mine *mongoQuestion) CreateQuestion(info *Question) error {
+	return CreateQuestion(info)
+}
+
+func (mine *mongoQuestion) GetQuestion(uid string) (*Question, error) {
+	return GetQuestion(uid)
+}
+
+func (mine *mongoQuestion) GetQuestionsByName(title string) ([]*Question, error) {
+	return GetQuestionsByName(title)
+}
+
+func (mine *mongoQuestion) GetQuestionsByQuote(quote string) ([]*Question, error) {
+	return GetQuestionsByQuote(quote)
+}
+
+func (mine *mongoQuestion) GetQuestionsByTitle(title, category string) ([]*Question, error) {
+	return GetQuestionsByTitle(title, category)
+}
+
+func (mine *mongoQuestion) GetQuestionsByCategory(category string) ([]*Question, error) {
+	return GetQuestionsByCategory(category)
+}
+
+func (mine *mongoQuestion) GetQuestionCount(category string) uint32 {
+	return GetQuestionCount(category)
+}
+
+func (mine *mongoQuestion) UpdateQuestionBase(uid, title, remark, operator, category string, cd uint16, answers []uint32, opts []proxy.PairInfo) error {
+	return UpdateQuestionBase(uid, title, remark, operator, category, cd, answers, opts)
+}
+
+func (mine *mongoQuestion) UpdateQuestionAnswers(uid, operator string, answers []uint32) error {
+	return UpdateQuestionAnswers(uid, operator, answers)
+}
+
+func (mine *mongoQuestion) UpdateQuestionAssets(uid, operator string, assets []string) error {
+	return UpdateQuestionAssets(uid, operator, assets)
+}
+
+func (mine *mongoQuestion) UpdateQuestionOptions(uid, operator string, list []proxy.PairInfo) error {
+	return UpdateQuestionOptions(uid, operator, list)
+}
+
+func (mine *mongoQuestion) RemoveQuestion(uid, operator string) error {
+	return RemoveQuestion(uid, operator)
+}
+
+type mongoCategory struct{}
+
+func (mine *mongoCategory) QueryCategories(query *proxy.Query) ([]*Category, int64, error) {
+	return QueryCategories(query)
+}
+
+func (mine *mongoCategory) CreateCategory(info *Category) error {
+	return CreateCategory(info)
+}
+
+func (mine *mongoCategory) GetOneCategory(uid string) (*Category, error) {
+	return GetOneCategory(uid)
+}
+
+func (mine *mongoCategory) GetAllCategories() ([]*Category, error) {
+	return GetAllCategories()
+}
+
+func (mine *mongoCategory) GetCategoryListByParent(parent string) ([]*Category, error) {
+	return GetCategoryListByParent(parent)
+}
+
+func (mine *mongoCategory) GetCategoryListByOwner(owner, parent string) ([]*Category, error) {
+	return GetCategoryListByOwner(owner, parent)
+}
+
+func (mine *mongoCategory) UpdateCategoryOwner(uid, owner string) error {
+	return UpdateCategoryOwner(uid, owner)
+}
+
+func (mine *mongoCategory) UpdateCategoryBase(uid, name, remark, quote, operator string) error {
+	return UpdateCategoryBase(uid, name, remark, quote, operator)
+}
+
+func (mine *mongoCategory) UpdateCategoryInt(filter, operator, uid string, value int64) error {
+	return UpdateCategoryInt(filter, operator, uid, value)
+}
+
+func (mine *mongoCategory) DeleteCategory(uid, operator string) error {
+	return DeleteCategory(uid, operator)
+}
+
+type mongoQuiz struct{}
+
+func (mine *mongoQuiz) CreateQuizRecord(info *QuizRecord) error {
+	return CreateQuizRecord(info)
+}
+
+func (mine *mongoQuiz) GetQuizRecord(uid string) (*QuizRecord, error) {
+	return GetQuizRecord(uid)
+}
+
+func (mine *mongoQuiz) QueryQuizRecords(query *proxy.Query) ([]*QuizRecord, int64, error) {
+	return QueryQuizRecords(query)
+}
+
+func (mine *mongoQuiz) GetQuizRanking(category string, limit int64) ([]*proxy.QuizScore, error) {
+	return GetQuizRanking(category, limit)
+}
+
+func (mine *mongoQuiz) TrySubmitQuiz(user, question string, now time.Time, cd time.Duration) (bool, time.Time, error) {
+	return TrySubmitQuiz(user, question, now, cd)
+}
+
+type mongoSequence struct{}
+
+func (mine *mongoSequence) GetSequenceNext(name string) (uint64, error) {
+	return getSequenceNext(name)
+}
+
+func (mine *mongoSequence) ReserveSequence(name string, count uint64) (uint64, error) {
+	return reserveSequence(name, count)
+}
+
+func (mine *mongoSequence) RepairSequence(name string, min uint64) (bool, error) {
+	return repairSequence(name, min)
+}
+
+type mongoBackup struct{}
+
+func (mine *mongoBackup) DumpTable(table string) ([]interface{}, error) {
+	return DumpTable(table)
+}
+
+func (mine *mongoBackup) RestoreTable(table string, list []interface{}) error {
+	return RestoreTable(table, list)
+}
@@ -0,0 +1,127 @@
+package nosql
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"omo.msa.assignment/proxy"
+	"reflect"
+	"time"
+)
+
+// 把通用的查询条件转换为bson，cursor为false时忽略游标，用于统计总数
+func queryFilter(query *proxy.Query, cursor bool) (bson.M, error) {
+	list := make(bson.A, 0, len(query.Conditions)+2)
+	for _, item := range query.Conditions {
+		value := toBsonValue(item.Value)
+		switch item.Op {
+		case proxy.OpEqual:
+			list = append(list, bson.M{item.Field: value})
+		case proxy.OpIn:
+			array, ok := value.(bson.A)
+			if !ok {
+				return nil, errors.New("the value of in must be array: " + item.Field)
+			}
+			list = append(list, bson.M{item.Field: bson.M{"$in": array}})
+		case proxy.OpGreater:
+			list = append(list, bson.M{item.Field: bson.M{"$gt": value}})
+		case proxy.OpLess:
+			list = append(list, bson.M{item.Field: bson.M{"$lt": value}})
+		default:
+			return nil, errors.New("the query operator is not supported: " + item.Field)
+		}
+	}
+	if query.Alive {
+		list = append(list, bson.M{"deleteAt": new(time.Time)})
+	}
+	if cursor && len(query.Cursor) > 0 {
+		uid, err := primitive.ObjectIDFromHex(query.Cursor)
+		if err != nil {
+			return nil, errors.New("the cursor is invalid")
+		}
+		if query.Desc {
+			list = append(list, bson.M{"_id": bson.M{"$lt": uid}})
+		} else {
+			list = append(list, bson.M{"_id": bson.M{"$gt": uid}})
+		}
+	}
+	if len(list) < 1 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": list}, nil
+}
+
+// 切片统一转换为bson.A，[]uint8在bson中会被编码为二进制
+func toBsonValue(value interface{}) interface{} {
+	val := reflect.ValueOf(value)
+	if val.Kind() != reflect.Slice {
+		return value
+	}
+	arr := make(bson.A, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		item := val.Index(i)
+		if item.Kind() == reflect.Uint8 {
+			arr = append(arr, int32(item.Uint()))
+		} else {
+			arr = append(arr, item.Interface())
+		}
+	}
+	return arr
+}
+
+func queryOptions(query *proxy.Query) *options.FindOptions {
+	direction := 1
+	if query.Desc {
+		direction = -1
+	}
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if len(query.Sort) > 0 && query.Sort != "_id" && len(query.Cursor) < 1 {
+		sort = bson.D{{Key: query.Sort, Value: direction}, {Key: "_id", Value: direction}}
+	}
+	opts := options.Find().SetSort(sort)
+	if query.Skip > 0 && len(query.Cursor) < 1 {
+		opts.SetSkip(query.Skip)
+	}
+	if query.Limit > 0 {
+		opts.SetLimit(query.Limit)
+	}
+	return opts
+}
+
+// 分页查询，返回当前页的数据以及满足条件的总数
+func findPage[T any](collection string, query *proxy.Query) ([]*T, int64, error) {
+	if query == nil {
+		query = new(proxy.Query)
+	}
+	filter, err := queryFilter(query, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := getCountBy(collection, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(query.Cursor) > 0 {
+		filter, err = queryFilter(query, true)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	cursor, err := findManyByOpts(collection, filter, queryOptions(query))
+	if err != nil {
+		return nil, 0, err
+	}
+	var items = make([]*T, 0, 10)
+	defer cursor.Close(context.Background())
+	for cursor.Next(context.Background()) {
+		var node = new(T)
+		if err := cursor.Decode(node); err != nil {
+			return nil, 0, err
+		} else {
+			items = append(items, node)
+		}
+	}
+	return items, total, nil
+}
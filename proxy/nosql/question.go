@@ -200,3 +200,7 @@ func RemoveQuestion(uid, operator string) error {
 //	}
 //	return items, nil
 //}
+
+func QueryQuestions(query *proxy.Query) ([]*Question, int64, error) {
+	return findPage[Question](TableQuestion, query)
+}
@@ -0,0 +1,146 @@
+package nosql
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"omo.msa.assignment/proxy"
+	"time"
+)
+
+// QuizRecord 用户答题的记录，每次提交保存一条
+type QuizRecord struct {
+	UID         primitive.ObjectID `bson:"_id"`
+	ID          uint64             `json:"id" bson:"id"`
+	CreatedTime time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedTime time.Time          `json:"updatedAt" bson:"updatedAt"`
+	DeleteTime  time.Time          `json:"deleteAt" bson:"deleteAt"`
+	Creator     string             `json:"creator" bson:"creator"`
+	Operator    string             `json:"operator" bson:"operator"`
+
+	User     string   `json:"user" bson:"user"`
+	Owner    string   `json:"owner" bson:"owner"`
+	Question string   `json:"question" bson:"question"`
+	Category string   `json:"category" bson:"category"`
+	Answers  []uint32 `json:"answers" bson:"answers"`
+	Correct  bool     `json:"correct" bson:"correct"`
+}
+
+// QuizSubmit 用户最后一次提交某道题的时间，用户和题目唯一
+type QuizSubmit struct {
+	UID        primitive.ObjectID `bson:"_id"`
+	User       string             `json:"user" bson:"user"`
+	Question   string             `json:"question" bson:"question"`
+	SubmitTime time.Time          `json:"submitAt" bson:"submitAt"`
+}
+
+func ensureQuizSubmitIndex() error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	_, err := noSql.Collection(TableQuizSubmit).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user", Value: 1}, {Key: "question", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_user_question"),
+	})
+	return err
+}
+
+// TrySubmitQuiz 上一次提交的时间早于now-cd(或者没有提交过)时记录本次提交并返回true，
+// 否则返回false以及上一次提交的时间；时间条件和更新在一次操作中完成，并发的提交只有一个成功
+func TrySubmitQuiz(user, question string, now time.Time, cd time.Duration) (bool, time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	c := noSql.Collection(TableQuizSubmit)
+	filter := bson.M{"user": user, "question": question, "submitAt": bson.M{"$lte": now.Add(-cd)}}
+	update := bson.M{"$set": bson.M{"submitAt": now}, "$setOnInsert": bson.M{"_id": primitive.NewObjectID()}}
+	_, err := c.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err == nil {
+		return true, time.Time{}, nil
+	}
+	// 时间条件不满足时upsert会因为唯一索引冲突而失败
+	if !isDuplicateKey(err) {
+		return false, time.Time{}, err
+	}
+	model := new(QuizSubmit)
+	err = c.FindOne(ctx, bson.M{"user": user, "question": question}).Decode(model)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	return false, model.SubmitTime, nil
+}
+
+func isDuplicateKey(err error) bool {
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, item := range we.WriteErrors {
+			if item.Code == 11000 {
+				return true
+			}
+		}
+	}
+	var ce mongo.CommandError
+	if errors.As(err, &ce) {
+		return ce.Code == 11000
+	}
+	return false
+}
+
+func CreateQuizRecord(info *QuizRecord) error {
+	_, err := insertOne(TableRecord, info)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func GetQuizRecord(uid string) (*QuizRecord, error) {
+	result, err := findOne(TableRecord, uid)
+	if err != nil {
+		return nil, err
+	}
+	model := new(QuizRecord)
+	err1 := result.Decode(model)
+	if err1 != nil {
+		return nil, err1
+	}
+	return model, nil
+}
+
+func QueryQuizRecords(query *proxy.Query) ([]*QuizRecord, int64, error) {
+	return findPage[QuizRecord](TableRecord, query)
+}
+
+// GetQuizRanking 按照分类统计每个用户答对的次数，答对次数相同时答题次数少的在前
+func GetQuizRanking(category string, limit int64) ([]*proxy.QuizScore, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"category": category, "deleteAt": new(time.Time)}},
+		bson.M{"$group": bson.M{"_id": "$user", "total": bson.M{"$sum": 1},
+			"correct": bson.M{"$sum": bson.M{"$cond": bson.A{"$correct", 1, 0}}}}},
+		bson.M{"$sort": bson.D{{Key: "correct", Value: -1}, {Key: "total", Value: 1}, {Key: "_id", Value: 1}}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": limit})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	cursor, err := noSql.Collection(TableRecord).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var items = make([]*proxy.QuizScore, 0, 10)
+	for cursor.Next(ctx) {
+		var node struct {
+			User    string `bson:"_id"`
+			Total   uint32 `bson:"total"`
+			Correct uint32 `bson:"correct"`
+		}
+		if err = cursor.Decode(&node); err != nil {
+			return nil, err
+		}
+		items = append(items, &proxy.QuizScore{User: node.User, Total: node.Total, Correct: node.Correct})
+	}
+	return items, nil
+}
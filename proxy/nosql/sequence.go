@@ -1,8 +1,12 @@
 package nosql
 
 import (
+	"context"
+	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"time"
 )
 
@@ -15,31 +19,95 @@ type Sequence struct {
 	Count       uint64             `json:"count" bson:"count"`
 }
 
-func createSequence(name string) error {
-	var db = new(Sequence)
-	db.Count = 0
-	db.UID = primitive.NewObjectID()
-	db.Name = name
-	db.CreatedTime = time.Now()
-	_, err := insertOne(TableSequence, db)
+// 序号的名称唯一，建立索引前先合并以前并发创建的重复文档，保留最大的序号
+func ensureSequenceIndex() error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	c := noSql.Collection(TableSequence)
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$name"}, {Key: "keep", Value: bson.D{{Key: "$first", Value: "$_id"}}},
+			{Key: "num", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+		{{Key: "$match", Value: bson.D{{Key: "num", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+	}
+	cursor, err := c.Aggregate(ctx, pipeline)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		item := struct {
+			Name string             `bson:"_id"`
+			Keep primitive.ObjectID `bson:"keep"`
+		}{}
+		err = cursor.Decode(&item)
+		if err != nil {
+			return err
+		}
+		_, err = c.DeleteMany(ctx, bson.M{"name": item.Name, "_id": bson.M{"$ne": item.Keep}})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_name"),
+	})
+	return err
 }
 
-func getSequenceNext(name string) (uint64, error) {
-	num, _ := getSequenceCount(name)
+// 增加序号并返回增加后的值，不存在时创建，查找和增加在一次操作中完成
+func incSequence(name string, num uint64) (uint64, error) {
 	if num < 1 {
-		_ = createSequence(name)
+		return 0, errors.New("the sequence increment must be more than 0")
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	now := time.Now()
 	filter := bson.M{"name": name}
-	update := bson.M{"$inc": bson.M{"count": 1}, "$set": bson.M{"updatedAt": time.Now()}}
-	_, err := updateOneBy(TableSequence, filter, update)
+	update := bson.M{
+		"$inc":         bson.M{"count": int64(num)},
+		"$set":         bson.M{"updatedAt": now},
+		"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "createdAt": now, "deleteAt": time.Time{}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	result := noSql.Collection(TableSequence).FindOneAndUpdate(ctx, filter, update, opts)
+	model := new(Sequence)
+	err := result.Decode(model)
+	if err != nil {
+		return 0, err
+	}
+	return model.Count, nil
+}
+
+func getSequenceNext(name string) (uint64, error) {
+	return incSequence(name, 1)
+}
+
+// 预留连续的count个序号，返回第一个
+func reserveSequence(name string, count uint64) (uint64, error) {
+	last, err := incSequence(name, count)
 	if err != nil {
 		return 0, err
 	}
-	return num + 1, nil
+	return last - count + 1, nil
+}
+
+// 序号小于min时修改为min，返回是否修改了
+func repairSequence(name string, min uint64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	now := time.Now()
+	filter := bson.M{"name": name}
+	update := bson.M{
+		"$max":         bson.M{"count": int64(min)},
+		"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "createdAt": now, "updatedAt": now, "deleteAt": time.Time{}},
+	}
+	result, err := noSql.Collection(TableSequence).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0 || result.UpsertedCount > 0, nil
 }
 
 func getSequenceCount(name string) (uint64, error) {
@@ -0,0 +1,261 @@
+package nosql
+
+import (
+	"omo.msa.assignment/proxy"
+	"time"
+)
+
+/**
+存储层接口，cache层只通过这些接口访问数据，具体实现可以是mongodb或者内存
+*/
+
+type TaskStore interface {
+	CreateTask(info *Task) error
+	GetTask(uid string) (*Task, error)
+	GetTasksByOwner(uid string, st uint8) ([]*Task, error)
+	GetTasksByOwner2(uid string) ([]*Task, error)
+	GetTasksByType(owner string, tp uint8) ([]*Task, error)
+	GetTasksByRegion(region string, st uint8) ([]*Task, error)
+	GetTasksByRegion2(region string) ([]*Task, error)
+	GetTasksByAgent(agent string, st uint8) ([]*Task, error)
+	GetTasksByAgent2(agent string) ([]*Task, error)
+	GetTasksByTarget(client string, st uint8) ([]*Task, error)
+	GetTasksByTarget2(client string) ([]*Task, error)
+	GetTasksByPreTask(uid string) ([]*Task, error)
+	SearchTasks(filter *proxy.TaskFilter) ([]*Task, int64, error)
+	UpdateTaskBase(uid, name, remark, operator string, assets []string) error
+	UpdateTaskTags(uid, operator string, tags []string) error
+	UpdateTaskExecutors(uid, operator string, list []string) error
+	UpdateTaskType(uid, operator string, tp uint8) error
+	UpdateTaskPreTasks(uid, operator string, list []string) error
+	TransitTask(uid, operator string, history proxy.StatusHistory) (bool, error)
+	RemoveTask(uid, operator string) error
+	AppendTaskRecord(uid string, data proxy.RecordInfo) error
+	SubtractTaskRecord(uid, record string) error
+	UpdateTaskRecord(uid string, data proxy.RecordInfo) error
+	UpdateTaskRecords(uid string, list []proxy.RecordInfo) error
+	AppendTaskExecutor(uid, user string) error
+	SubtractTaskExecutor(uid, user string) error
+	QueryTasks(query *proxy.Query) ([]*Task, int64, error)
+}
+
+type AgentStore interface {
+	CreateAgent(info *Agent) error
+	GetAgent(uid string) (*Agent, error)
+	GetAgentByUser(user string) (*Agent, error)
+	GetAgentsByOwner(owner string) ([]*Agent, error)
+	GetAgentsByAttach(scene string) ([]*Agent, error)
+	GetAgentsByRegion(region string) ([]*Agent, error)
+	GetAgentsByWay(owner, way string) ([]*Agent, error)
+	UpdateAgentBase(uid, name, remark, operator string) error
+	UpdateAgentEntity(uid, entity, operator string) error
+	UpdateAgentStatus(uid, operator string, st uint8) error
+	UpdateAgentTags(uid, operator string, tags []string) error
+	UpdateAgentRegions(uid, operator string, list []string) error
+	UpdateAgentAttaches(uid, operator string, list []string) error
+	RemoveAgent(uid, operator string) error
+	AppendAgentAttach(uid, scene string) error
+	SubtractAgentAttach(uid, scene string) error
+	QueryAgents(query *proxy.Query) ([]*Agent, int64, error)
+}
+
+type TeamStore interface {
+	CreateTeam(info *Team) error
+	GetTeam(uid string) (*Team, error)
+	GetTeamByName(owner, name string) (*Team, error)
+	GetTeamsByOwner(owner string) ([]*Team, error)
+	GetTeamsByMember(user string) ([]*Team, error)
+	UpdateTeamBase(uid, name, remark, operator string) error
+	UpdateTeamAssistants(uid, operator string, list []string) error
+	UpdateTeamTags(uid, operator string, list []string) error
+	UpdateTeamStatus(uid, operator string, st uint8) error
+	UpdateTeamRegion(uid, region, operator string) error
+	UpdateTeamMembers(uid, operator string, members []string) error
+	UpdateTeamParent(uid, parent, operator string) error
+	UpdateTeamMaster(uid, member, operator string) error
+	RemoveTeam(uid, operator string) error
+	AppendTeamMember(uid, member string) error
+	SubtractTeamMember(uid string, member string) error
+	UpdateTeamLimit(uid, operator string, limit uint16) error
+	JoinTeam(uid, member string, limit uint16) (bool, error)
+	AppendTeamWait(uid string, wait proxy.WaitInfo) error
+	SubtractTeamWait(uid, user string) error
+	AppendTeamMembers(uid string, list []string, limit uint16) (bool, error)
+	SubtractTeamMembers(uid string, list []string) error
+	// ReplaceTeamMembers 成员仍然为old时替换为members，并从候补中移除，返回是否替换成功
+	ReplaceTeamMembers(uid, operator string, old, members []string) (bool, error)
+	QueryTeams(query *proxy.Query) ([]*Team, int64, error)
+}
+
+type FamilyStore interface {
+	CreateFamily(info *Family) error
+	GetFamily(uid string) (*Family, error)
+	GetFamilyByCreator(creator string) (*Family, error)
+	GetFamilyByMaster(master string) (*Family, error)
+	GetFamilyByChild(entity string) (*Family, error)
+	GetAllFamilies() ([]*Family, error)
+	GetFamiliesByMember(user string) ([]*Family, error)
+	GetFamiliesByRegion(region string) ([]*Family, error)
+	GetFamiliesByAgent(agent string) ([]*Family, error)
+	UpdateFamilyBase(uid, name, remark, psw, operator string) error
+	UpdateFamilyMaster(uid, master, operator string) error
+	UpdateFamilyStatus(uid, operator string, st uint8) error
+	UpdateFamilyPasswords(uid, operator, psw string) error
+	UpdateFamilyTags(uid, operator string, list []string) error
+	UpdateFamilyAgents(uid, operator string, list []string) error
+	UpdateFamilyAssistants(uid, operator string, list []string) error
+	UpdateFamilyChildren(uid, operator string, list []string) error
+	RemoveFamily(uid, operator string) error
+	AppendFamilyMember(uid string, invitee proxy.MemberInfo) error
+	SubtractFamilyMember(uid, user string) error
+	AppendFamilyMembers(uid string, list []proxy.MemberInfo) (bool, error)
+	SubtractFamilyMembers(uid string, users []string) error
+	// ReplaceFamilyMembers 成员仍然为old时替换为list，返回是否替换成功
+	ReplaceFamilyMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error)
+	QueryFamilies(query *proxy.Query) ([]*Family, int64, error)
+}
+
+type CoterieStore interface {
+	CreateCoterie(info *Coterie) error
+	GetCoterie(uid string) (*Coterie, error)
+	GetCoterieByCreator(creator string) (*Coterie, error)
+	GetCoterieByCentre(centre string) (*Coterie, error)
+	GetActivitiesCount() int64
+	GetAllCoteries(page, num int64) ([]*Coterie, error)
+	GetCoteriesByMember(user string) ([]*Coterie, error)
+	GetCoteriesByCreator(user string) ([]*Coterie, error)
+	GetCoteriesByMaster(user string) ([]*Coterie, error)
+	UpdateCoterieBase(uid, name, remark, psw, operator string) error
+	UpdateCoterieMaster(uid, master, operator string) error
+	UpdateCoterieStatus(uid, operator string, st uint8) error
+	UpdateCoteriePasswords(uid, operator, psw string) error
+	UpdateCoterieTags(uid, operator string, list []string) error
+	UpdateCoterieAssistants(uid, operator string, list []string) error
+	RemoveCoterie(uid, operator string) error
+	AppendCoterieMember(uid string, invitee proxy.MemberInfo) error
+	SubtractCoterieMember(uid, user string) error
+	AppendCoterieMembers(uid string, list []proxy.MemberInfo) (bool, error)
+	SubtractCoterieMembers(uid string, users []string) error
+	// ReplaceCoterieMembers 成员仍然为old时替换为list，返回是否替换成功
+	ReplaceCoterieMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error)
+	QueryCoteries(query *proxy.Query) ([]*Coterie, int64, error)
+}
+
+type ApplyStore interface {
+	CreateApply(info *Apply) error
+	GetApply(uid string) (*Apply, error)
+	GetAppliesByGroup(group string) ([]*Apply, error)
+	GetAppliesByScene(scene string, tp uint8) ([]*Apply, error)
+	GetAppliesByScene1(scene string) ([]*Apply, error)
+	GetAppliesByApplicant(user string) ([]*Apply, error)
+	GetAppliesByCreator(user string) ([]*Apply, error)
+	UpdateApply(uid, reason, operator string, status uint8) error
+	DecideApply(uid, operator, reason string, from []uint8, status uint8) (bool, error)
+	RemindApply(uid string, now time.Time) (bool, error)
+	RemoveApply(uid, operator string) error
+	QueryApplies(query *proxy.Query) ([]*Apply, int64, error)
+}
+
+type InvitationStore interface {
+	CreateInvitation(info *Invitation) error
+	GetInvitation(uid string) (*Invitation, error)
+	GetInvitationByCode(code string) (*Invitation, error)
+	// UseInvitation 未过期并且还有次数时使用次数加一，返回是否使用成功
+	UseInvitation(uid string, now time.Time) (bool, error)
+	// ReleaseInvitation 使用之后加入失败时使用次数减一
+	ReleaseInvitation(uid string) error
+	RemoveInvitation(uid, operator string) error
+	QueryInvitations(query *proxy.Query) ([]*Invitation, int64, error)
+}
+
+type MeetingStore interface {
+	CreateMeeting(info *Meeting) error
+	GetMeeting(uid string) (*Meeting, error)
+	GetMeetingsByGroup(group string) ([]*Meeting, error)
+	GetMeetingsByScene(owner string) ([]*Meeting, error)
+	UpdateMeetingBase(uid, name, remark, operator string) error
+	UpdateMeetingLocation(uid, location, operator string, kind uint8) error
+	UpdateMeetingGroup(uid, operator, group string) error
+	UpdateMeetingDate(uid, operator string, start, stop time.Time) error
+	UpdateMeetingStop(uid, operator string, t time.Time) error
+	UpdateMeetingStatus(uid string, status uint16) error
+	TransitMeeting(uid string, from, status uint8) (bool, error)
+	StopMeeting(uid, operator string) error
+	RemoveMeeting(uid, operator string) error
+	AppendMeetingSign(uid, member, operator string) error
+	UpdateMeetingSignRule(uid, operator string, radius, before, late int32) error
+	UpdateMeetingRule(uid, operator, rule, series string, occurrence time.Time, span int64, revision uint32) error
+	UpdateMeetingExceptions(uid, operator string, list []proxy.ExceptionInfo) error
+	SaveMeetingAttend(uid string, change *AttendChange) error
+	QueryMeetings(query *proxy.Query) ([]*Meeting, int64, error)
+}
+
+type QuestionStore interface {
+	CreateQuestion(info *Question) error
+	GetQuestion(uid string) (*Question, error)
+	GetQuestionsByName(title string) ([]*Question, error)
+	GetQuestionsByQuote(quote string) ([]*Question, error)
+	GetQuestionsByTitle(title, category string) ([]*Question, error)
+	GetQuestionsByCategory(category string) ([]*Question, error)
+	GetQuestionCount(category string) uint32
+	UpdateQuestionBase(uid, title, remark, operator, category string, cd uint16, answers []uint32, opts []proxy.PairInfo) error
+	UpdateQuestionAnswers(uid, operator string, answers []uint32) error
+	UpdateQuestionAssets(uid, operator string, assets []string) error
+	UpdateQuestionOptions(uid, operator string, list []proxy.PairInfo) error
+	RemoveQuestion(uid, operator string) error
+	QueryQuestions(query *proxy.Query) ([]*Question, int64, error)
+}
+
+type CategoryStore interface {
+	CreateCategory(info *Category) error
+	GetOneCategory(uid string) (*Category, error)
+	GetAllCategories() ([]*Category, error)
+	GetCategoryListByParent(parent string) ([]*Category, error)
+	GetCategoryListByOwner(owner, parent string) ([]*Category, error)
+	UpdateCategoryOwner(uid, owner string) error
+	UpdateCategoryBase(uid, name, remark, quote, operator string) error
+	UpdateCategoryInt(filter, operator, uid string, value int64) error
+	DeleteCategory(uid, operator string) error
+	QueryCategories(query *proxy.Query) ([]*Category, int64, error)
+}
+
+type QuizStore interface {
+	CreateQuizRecord(info *QuizRecord) error
+	GetQuizRecord(uid string) (*QuizRecord, error)
+	QueryQuizRecords(query *proxy.Query) ([]*QuizRecord, int64, error)
+	GetQuizRanking(category string, limit int64) ([]*proxy.QuizScore, error)
+	// TrySubmitQuiz 距离上一次提交超过cd时记录本次提交并返回true，否则返回false以及上一次提交的时间
+	TrySubmitQuiz(user, question string, now time.Time, cd time.Duration) (bool, time.Time, error)
+}
+
+type SequenceStore interface {
+	GetSequenceNext(name string) (uint64, error)
+	// ReserveSequence 预留连续的count个序号，返回第一个，用于批量导入
+	ReserveSequence(name string, count uint64) (uint64, error)
+	// RepairSequence 序号小于min时修改为min，返回是否修改了
+	RepairSequence(name string, min uint64) (bool, error)
+}
+
+// BackupStore 备份和恢复，文档的类型由NewDocument决定
+type BackupStore interface {
+	// DumpTable 集合中的全部文档，包括已删除的
+	DumpTable(table string) ([]interface{}, error)
+	// RestoreTable 清空集合后写入文档
+	RestoreTable(table string, list []interface{}) error
+}
+
+type Stores struct {
+	Task       TaskStore
+	Agent      AgentStore
+	Team       TeamStore
+	Family     FamilyStore
+	Coterie    CoterieStore
+	Apply      ApplyStore
+	Invitation InvitationStore
+	Meeting    MeetingStore
+	Question   QuestionStore
+	Category   CategoryStore
+	Quiz       QuizStore
+	Sequence   SequenceStore
+	Backup     BackupStore
+}
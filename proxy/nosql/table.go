@@ -6,13 +6,14 @@ const (
 	*/
 	TableSequence = "sequences"
 
-	TableTask    = "tasks"
-	TableAgent   = "agents"
-	TableTeam    = "teams"
-	TableFamily  = "families"
-	TableCoterie = "coteries"
-	TableApply   = "applies"
-	TableMeeting = "meetings"
+	TableTask       = "tasks"
+	TableAgent      = "agents"
+	TableTeam       = "teams"
+	TableFamily     = "families"
+	TableCoterie    = "coteries"
+	TableApply      = "applies"
+	TableMeeting    = "meetings"
+	TableInvitation = "invitations"
 
 	/**
 	知识题库
@@ -20,4 +21,6 @@ const (
 	TableQuestion = "lore_questions"
 	TableRecord   = "lore_records"
 	TableCategory = "lore_category"
+	// 用户最后一次提交题目的时间
+	TableQuizSubmit = "lore_submits"
 )
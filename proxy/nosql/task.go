@@ -5,7 +5,9 @@ import (
 	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"omo.msa.assignment/proxy"
+	"regexp"
 	"time"
 )
 
@@ -24,15 +26,16 @@ type Task struct {
 	Remark string `json:"remark" bson:"remark"`
 	Target string `json:"target" bson:"target"`
 
-	Owner     string             `json:"owner" bson:"owner"`
-	Way       string             `json:"way" bson:"way"`
-	Duration  proxy.DateInfo     `json:"duration" bson:"duration"`
-	Executors []string           `json:"executors" bson:"executors"`
-	PreTasks  []string           `json:"preTasks" bson:"preTasks"`
-	Regions   []string           `json:"regions" bson:"regions"`
-	Tags      []string           `json:"tags" bson:"tags"`
-	Assets    []string           `json:"assets" bson:"assets"`
-	Records   []proxy.RecordInfo `json:"records" bson:"records"`
+	Owner     string                `json:"owner" bson:"owner"`
+	Way       string                `json:"way" bson:"way"`
+	Duration  proxy.DateInfo        `json:"duration" bson:"duration"`
+	Executors []string              `json:"executors" bson:"executors"`
+	PreTasks  []string              `json:"preTasks" bson:"preTasks"`
+	Regions   []string              `json:"regions" bson:"regions"`
+	Tags      []string              `json:"tags" bson:"tags"`
+	Assets    []string              `json:"assets" bson:"assets"`
+	Records   []proxy.RecordInfo    `json:"records" bson:"records"`
+	Histories []proxy.StatusHistory `json:"histories" bson:"histories"`
 }
 
 func CreateTask(info *Task) error {
@@ -80,6 +83,85 @@ func GetTasksByOwner(uid string, st uint8) ([]*Task, error) {
 	return items, nil
 }
 
+func SearchTasks(filter *proxy.TaskFilter) ([]*Task, int64, error) {
+	msg := bson.M{"deleteAt": new(time.Time)}
+	and := bson.A{}
+	if len(filter.Owner) > 0 {
+		msg["owner"] = filter.Owner
+	}
+	if len(filter.Region) > 0 {
+		msg["regions"] = filter.Region
+	}
+	if len(filter.Executor) > 0 {
+		msg["executors"] = filter.Executor
+	}
+	if len(filter.Tags) > 0 {
+		msg["tags"] = bson.M{"$all": filter.Tags}
+	}
+	if len(filter.Types) > 0 {
+		msg["type"] = bson.M{"$in": toIntArray(filter.Types)}
+	}
+	if len(filter.States) > 0 {
+		msg["status"] = bson.M{"$in": toIntArray(filter.States)}
+	}
+	if len(filter.Keyword) > 0 {
+		reg := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Keyword), Options: "i"}
+		and = append(and, bson.M{"$or": bson.A{bson.M{"name": reg}, bson.M{"remark": reg}}})
+	}
+	if len(filter.End) > 0 {
+		msg["duration.begin"] = bson.M{"$lte": filter.End}
+	}
+	if len(filter.Begin) > 0 {
+		and = append(and, bson.M{"$or": bson.A{bson.M{"duration.end": bson.M{"$gte": filter.Begin}}, bson.M{"duration.end": ""}}})
+	}
+	if len(and) > 0 {
+		msg["$and"] = and
+	}
+	total, err := getCountBy(TableTask, msg)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort := filter.Sort
+	if len(sort) < 1 {
+		sort = "createdAt"
+	}
+	direction := 1
+	if filter.Desc {
+		direction = -1
+	}
+	opts := options.Find().SetSort(bson.D{{Key: sort, Value: direction}, {Key: "_id", Value: direction}})
+	if filter.Skip > 0 {
+		opts.SetSkip(filter.Skip)
+	}
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+	cursor, err1 := findManyByOpts(TableTask, msg, opts)
+	if err1 != nil {
+		return nil, 0, err1
+	}
+	var items = make([]*Task, 0, 100)
+	defer cursor.Close(context.Background())
+	for cursor.Next(context.Background()) {
+		var node = new(Task)
+		if err := cursor.Decode(node); err != nil {
+			return nil, 0, err
+		} else {
+			items = append(items, node)
+		}
+	}
+	return items, total, nil
+}
+
+// []uint8在bson中会被编码为二进制，查询前需要转换
+func toIntArray(list []uint8) bson.A {
+	arr := make(bson.A, 0, len(list))
+	for _, item := range list {
+		arr = append(arr, int32(item))
+	}
+	return arr
+}
+
 func GetTasksByOwner2(uid string) ([]*Task, error) {
 	msg := bson.M{"owner": uid, "deleteAt": new(time.Time)}
 	cursor, err1 := findMany(TableTask, msg, 0)
@@ -256,6 +338,25 @@ func UpdateTaskBase(uid, name, remark, operator string, assets []string) error {
 	return err
 }
 
+func GetTasksByPreTask(uid string) ([]*Task, error) {
+	msg := bson.M{"preTasks": uid, "deleteAt": new(time.Time)}
+	cursor, err1 := findMany(TableTask, msg, 0)
+	if err1 != nil {
+		return nil, err1
+	}
+	var items = make([]*Task, 0, 10)
+	defer cursor.Close(context.Background())
+	for cursor.Next(context.Background()) {
+		var node = new(Task)
+		if err := cursor.Decode(node); err != nil {
+			return nil, err
+		} else {
+			items = append(items, node)
+		}
+	}
+	return items, nil
+}
+
 func UpdateTaskTags(uid, operator string, tags []string) error {
 	msg := bson.M{"tags": tags, "operator": operator, "updatedAt": time.Now()}
 	_, err := updateOne(TableTask, uid, msg)
@@ -268,18 +369,34 @@ func UpdateTaskExecutors(uid, operator string, list []string) error {
 	return err
 }
 
-func UpdateTaskType(uid, operator string, tp uint8) error {
-	msg := bson.M{"type": tp, "operator": operator, "updatedAt": time.Now()}
+func UpdateTaskPreTasks(uid, operator string, list []string) error {
+	msg := bson.M{"preTasks": list, "operator": operator, "updatedAt": time.Now()}
 	_, err := updateOne(TableTask, uid, msg)
 	return err
 }
 
-func UpdateTaskStatus(uid string, status uint8, operator string) error {
-	msg := bson.M{"status": status, "operator": operator, "updatedAt": time.Now()}
+func UpdateTaskType(uid, operator string, tp uint8) error {
+	msg := bson.M{"type": tp, "operator": operator, "updatedAt": time.Now()}
 	_, err := updateOne(TableTask, uid, msg)
 	return err
 }
 
+// TransitTask 状态为history.From时才修改为history.To并记录历史，返回是否修改成功，避免并发的状态变更都成功
+func TransitTask(uid, operator string, history proxy.StatusHistory) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{"_id": objID, "status": history.From, "deleteAt": new(time.Time)}
+	msg := bson.M{"$set": bson.M{"status": history.To, "operator": operator, "updatedAt": time.Now()},
+		"$push": bson.M{"histories": history}}
+	num, err := updateOneBy(TableTask, filter, msg)
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
 func RemoveTask(uid, operator string) error {
 	_, err := removeOne(TableTask, uid, operator)
 	return err
@@ -303,6 +420,24 @@ func SubtractTaskRecord(uid, record string) error {
 	return err
 }
 
+// UpdateTaskRecord 按照记录的uid替换数组中的元素
+func UpdateTaskRecord(uid string, data proxy.RecordInfo) error {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": objID, "records.uid": data.UID}
+	msg := bson.M{"$set": bson.M{"records.$": data, "updatedAt": time.Now()}}
+	_, err = updateOneBy(TableTask, filter, msg)
+	return err
+}
+
+func UpdateTaskRecords(uid string, list []proxy.RecordInfo) error {
+	msg := bson.M{"records": list, "updatedAt": time.Now()}
+	_, err := updateOne(TableTask, uid, msg)
+	return err
+}
+
 func AppendTaskExecutor(uid, user string) error {
 	if len(uid) < 1 {
 		return errors.New("the uid is empty")
@@ -320,3 +455,7 @@ func SubtractTaskExecutor(uid, user string) error {
 	_, err := removeElement(TableTask, uid, msg)
 	return err
 }
+
+func QueryTasks(query *proxy.Query) ([]*Task, int64, error) {
+	return findPage[Task](TableTask, query)
+}
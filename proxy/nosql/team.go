@@ -3,8 +3,10 @@ package nosql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
 	"time"
 )
 
@@ -28,6 +30,8 @@ type Team struct {
 	Assistants []string `json:"assistants" bson:"assistants"`
 	Tags       []string `json:"tags" bson:"tags"`
 	Members    []string `json:"members" bson:"members"`
+
+	Waits []proxy.WaitInfo `json:"waits" bson:"waits"`
 }
 
 func CreateTeam(info *Team) error {
@@ -177,6 +181,12 @@ func UpdateTeamRegion(uid, region, operator string) error {
 	return err
 }
 
+func UpdateTeamParent(uid, parent, operator string) error {
+	msg := bson.M{"operator": operator, "parent": parent, "updatedAt": time.Now()}
+	_, err := updateOne(TableTeam, uid, msg)
+	return err
+}
+
 func UpdateTeamMembers(uid, operator string, members []string) error {
 	msg := bson.M{"operator": operator, "members": members, "updatedAt": time.Now()}
 	_, err := updateOne(TableTeam, uid, msg)
@@ -206,3 +216,105 @@ func SubtractTeamMember(uid string, member string) error {
 	_, err := removeElement(TableTeam, uid, msg)
 	return err
 }
+
+func UpdateTeamLimit(uid, operator string, limit uint16) error {
+	msg := bson.M{"limit": limit, "operator": operator, "updatedAt": time.Now()}
+	_, err := updateOne(TableTeam, uid, msg)
+	return err
+}
+
+// JoinTeam 不是成员并且没有满员(limit为0时不限制)时加入，同时从候补中移除，返回是否加入
+func JoinTeam(uid, member string, limit uint16) (bool, error) {
+	if len(member) < 1 {
+		return false, errors.New("the member uid is empty")
+	}
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{"_id": objID, "members": bson.M{"$ne": member}}
+	if limit > 0 {
+		filter[fmt.Sprintf("members.%d", limit-1)] = bson.M{"$exists": false}
+	}
+	update := bson.M{"$push": bson.M{"members": member}, "$pull": bson.M{"waits": bson.M{"user": member}},
+		"$set": bson.M{"updatedAt": time.Now()}}
+	num, err := updateOneBy(TableTeam, filter, update)
+	return num > 0, err
+}
+
+// AppendTeamWait 加入候补，已经在候补中时不修改
+func AppendTeamWait(uid string, wait proxy.WaitInfo) error {
+	if len(wait.User) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": objID, "waits.user": bson.M{"$ne": wait.User}}
+	_, err = updateOneBy(TableTeam, filter, bson.M{"$push": bson.M{"waits": wait}, "$set": bson.M{"updatedAt": time.Now()}})
+	return err
+}
+
+func SubtractTeamWait(uid, user string) error {
+	if len(user) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	msg := bson.M{"waits": bson.M{"user": user}}
+	_, err := removeElement(TableTeam, uid, msg)
+	return err
+}
+
+// AppendTeamMembers 一次加入多个成员，有成员已经存在或者加入后超过limit(为0时不限制)时都不加入，返回是否加入
+func AppendTeamMembers(uid string, list []string, limit uint16) (bool, error) {
+	if len(list) < 1 {
+		return true, nil
+	}
+	if limit > 0 && len(list) > int(limit) {
+		return false, nil
+	}
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{"_id": objID, "members": bson.M{"$nin": list}}
+	if limit > 0 {
+		filter[fmt.Sprintf("members.%d", int(limit)-len(list))] = bson.M{"$exists": false}
+	}
+	update := bson.M{"$addToSet": bson.M{"members": bson.M{"$each": list}},
+		"$pull": bson.M{"waits": bson.M{"user": bson.M{"$in": list}}}, "$set": bson.M{"updatedAt": time.Now()}}
+	num, err := updateOneBy(TableTeam, filter, update)
+	return num > 0, err
+}
+
+func SubtractTeamMembers(uid string, list []string) error {
+	if len(list) < 1 {
+		return nil
+	}
+	msg := bson.M{"members": bson.M{"$in": list}}
+	_, err := removeElement(TableTeam, uid, msg)
+	return err
+}
+
+// ReplaceTeamMembers 成员与old相同时才替换，同时从候补中移除新的成员
+func ReplaceTeamMembers(uid, operator string, old, members []string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return false, err
+	}
+	if old == nil {
+		old = make([]string, 0)
+	}
+	filter := bson.M{"_id": objID, "$expr": bson.M{"$eq": bson.A{arrayOf("members"), old}}}
+	update := bson.M{"$set": bson.M{"members": members, "operator": operator, "updatedAt": time.Now()},
+		"$pull": bson.M{"waits": bson.M{"user": bson.M{"$in": members}}}}
+	num, err := updateOneBy(TableTeam, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+func QueryTeams(query *proxy.Query) ([]*Team, int64, error) {
+	return findPage[Team](TableTeam, query)
+}
@@ -0,0 +1,55 @@
+package proxy
+
+const (
+	// OpEqual 等于，数组字段表示包含该值，结构体数组使用 数组.字段 的格式
+	OpEqual uint8 = 0
+	// OpIn 等于其中一个值
+	OpIn uint8 = 1
+	// OpGreater 大于
+	OpGreater uint8 = 2
+	// OpLess 小于
+	OpLess uint8 = 3
+)
+
+// Condition 单个查询条件，Field为bson字段名
+type Condition struct {
+	Field string
+	Op    uint8
+	Value interface{}
+}
+
+// Query 列表查询，数据库中完成过滤、排序以及分页，多个条件之间为AND
+type Query struct {
+	Conditions []Condition
+	// 只查询未删除的数据
+	Alive bool
+	// 排序的bson字段名，为空时按_id排序
+	Sort string
+	Desc bool
+	Skip int64
+	// 为0时不限制数量
+	Limit int64
+	// 游标，即上一页最后一条数据的uid，不为空时忽略Skip，并且只能按_id排序
+	Cursor string
+}
+
+func Equal(field string, value interface{}) Condition {
+	return Condition{Field: field, Op: OpEqual, Value: value}
+}
+
+// In list必须为切片
+func In(field string, list interface{}) Condition {
+	return Condition{Field: field, Op: OpIn, Value: list}
+}
+
+func Greater(field string, value interface{}) Condition {
+	return Condition{Field: field, Op: OpGreater, Value: value}
+}
+
+func Less(field string, value interface{}) Condition {
+	return Condition{Field: field, Op: OpLess, Value: value}
+}
+
+func NewQuery(alive bool, list ...Condition) *Query {
+	return &Query{Conditions: list, Alive: alive}
+}
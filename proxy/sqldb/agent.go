@@ -0,0 +1,86 @@
+package sqldb
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var agents = newTable[nosql.Agent](nosql.TableAgent)
+
+type agentStore struct{}
+
+func (mine *agentStore) CreateAgent(info *nosql.Agent) error {
+	return agents.insert(info)
+}
+
+func (mine *agentStore) GetAgent(uid string) (*nosql.Agent, error) {
+	return agents.get(uid)
+}
+
+func (mine *agentStore) GetAgentByUser(user string) (*nosql.Agent, error) {
+	return agents.findOne(eq("user", user))
+}
+
+func (mine *agentStore) GetAgentsByOwner(owner string) ([]*nosql.Agent, error) {
+	return agents.findMany(eq("owner", owner), alive())
+}
+
+func (mine *agentStore) GetAgentsByAttach(scene string) ([]*nosql.Agent, error) {
+	return agents.findMany(has("attaches", scene), alive())
+}
+
+func (mine *agentStore) GetAgentsByRegion(region string) ([]*nosql.Agent, error) {
+	return agents.findMany(has("regions", region), alive())
+}
+
+func (mine *agentStore) GetAgentsByWay(owner, way string) ([]*nosql.Agent, error) {
+	return agents.findMany(eq("owner", owner), eq("way", way), alive())
+}
+
+func (mine *agentStore) UpdateAgentBase(uid, name, remark, operator string) error {
+	return agents.update(uid, values{"name": name, "remark": remark, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *agentStore) UpdateAgentEntity(uid, entity, operator string) error {
+	return agents.update(uid, values{"entity": entity, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *agentStore) UpdateAgentStatus(uid, operator string, st uint8) error {
+	return agents.update(uid, values{"status": st, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *agentStore) UpdateAgentTags(uid, operator string, tags []string) error {
+	return agents.update(uid, values{"tags": tags, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *agentStore) UpdateAgentRegions(uid, operator string, list []string) error {
+	return agents.update(uid, values{"regions": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *agentStore) UpdateAgentAttaches(uid, operator string, list []string) error {
+	return agents.update(uid, values{"attaches": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *agentStore) RemoveAgent(uid, operator string) error {
+	return agents.removeOne(uid, operator)
+}
+
+func (mine *agentStore) AppendAgentAttach(uid, scene string) error {
+	if len(scene) < 1 {
+		return errors.New("the attach uid is empty")
+	}
+	return agents.appendElement(uid, "attaches", scene)
+}
+
+func (mine *agentStore) SubtractAgentAttach(uid, scene string) error {
+	if len(scene) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return agents.removeElement(uid, "attaches", scene)
+}
+
+func (mine *agentStore) QueryAgents(query *proxy.Query) ([]*nosql.Agent, int64, error) {
+	return agents.query(query)
+}
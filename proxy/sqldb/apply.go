@@ -0,0 +1,69 @@
+package sqldb
+
+import (
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var applies = newTable[nosql.Apply](nosql.TableApply)
+
+type applyStore struct{}
+
+func (mine *applyStore) CreateApply(info *nosql.Apply) error {
+	return applies.insert(info)
+}
+
+func (mine *applyStore) GetApply(uid string) (*nosql.Apply, error) {
+	return applies.get(uid)
+}
+
+func (mine *applyStore) GetAppliesByGroup(group string) ([]*nosql.Apply, error) {
+	return applies.findMany(eq("group", group), alive())
+}
+
+func (mine *applyStore) GetAppliesByScene(scene string, tp uint8) ([]*nosql.Apply, error) {
+	return applies.findMany(eq("scene", scene), eq("type", tp), alive())
+}
+
+func (mine *applyStore) GetAppliesByScene1(scene string) ([]*nosql.Apply, error) {
+	return applies.findMany(eq("scene", scene), alive())
+}
+
+func (mine *applyStore) GetAppliesByApplicant(user string) ([]*nosql.Apply, error) {
+	return applies.findMany(eq("applicant", user), alive())
+}
+
+func (mine *applyStore) GetAppliesByCreator(user string) ([]*nosql.Apply, error) {
+	return applies.findMany(eq("creator", user), alive())
+}
+
+func (mine *applyStore) UpdateApply(uid, reason, operator string, status uint8) error {
+	return applies.update(uid, values{"status": status, "reason": reason, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *applyStore) DecideApply(uid, operator, reason string, from []uint8, status uint8) (bool, error) {
+	now := time.Now()
+	num, err := applies.updateBy(values{"status": status, "reason": reason, "operator": operator, "decider": operator,
+		"decided": now, "updatedAt": now}, eq("uid", uid), in("status", from), alive())
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+func (mine *applyStore) RemindApply(uid string, now time.Time) (bool, error) {
+	num, err := applies.updateBy(values{"remind": now}, eq("uid", uid), eq("remind", time.Time{}), alive())
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+func (mine *applyStore) RemoveApply(uid, operator string) error {
+	return applies.removeOne(uid, operator)
+}
+
+func (mine *applyStore) QueryApplies(query *proxy.Query) ([]*nosql.Apply, int64, error) {
+	return applies.query(query)
+}
@@ -0,0 +1,163 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+const backupTimeOut = 5 * time.Minute
+
+type backupStore struct{}
+
+func (mine *table[T]) dump() ([]interface{}, error) {
+	list, err := mine.findMany()
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		docs = append(docs, item)
+	}
+	return docs, nil
+}
+
+// 在一个事务中清空主表和子表后写入，失败时保持原来的数据
+func (mine *table[T]) restore(docs []interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+quote(mine.meta.name))
+	if err != nil {
+		return err
+	}
+	for _, array := range mine.meta.arrays {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+quote(array.table))
+		if err != nil {
+			return err
+		}
+	}
+	for _, item := range docs {
+		doc, ok := item.(*T)
+		if !ok {
+			return errors.New("the document type is not matched")
+		}
+		err = mine.insertRow(ctx, tx, doc)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (mine *backupStore) table(name string) (interface {
+	dump() ([]interface{}, error)
+	restore(docs []interface{}) error
+}, error) {
+	switch name {
+	case nosql.TableTask:
+		return tasks, nil
+	case nosql.TableAgent:
+		return agents, nil
+	case nosql.TableTeam:
+		return teams, nil
+	case nosql.TableFamily:
+		return families, nil
+	case nosql.TableCoterie:
+		return coteries, nil
+	case nosql.TableApply:
+		return applies, nil
+	case nosql.TableInvitation:
+		return invitations, nil
+	case nosql.TableMeeting:
+		return meetings, nil
+	case nosql.TableCategory:
+		return categories, nil
+	case nosql.TableQuestion:
+		return questions, nil
+	case nosql.TableRecord:
+		return quizzes, nil
+	default:
+		return nil, errors.New("the table not support backup: " + name)
+	}
+}
+
+func (mine *backupStore) DumpTable(table string) ([]interface{}, error) {
+	if table == TableSequence {
+		return dumpSequences()
+	}
+	item, err := mine.table(table)
+	if err != nil {
+		return nil, err
+	}
+	return item.dump()
+}
+
+func (mine *backupStore) RestoreTable(table string, list []interface{}) error {
+	if table == TableSequence {
+		return restoreSequences(list)
+	}
+	item, err := mine.table(table)
+	if err != nil {
+		return err
+	}
+	return item.restore(list)
+}
+
+func dumpSequences() ([]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	query := fmt.Sprintf("SELECT %s, %s, %s FROM %s ORDER BY %s", quote("name"), quote("count"), quote("updatedAt"),
+		quote(TableSequence), quote("name"))
+	rows, err := dbConn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]interface{}, 0, 20)
+	for rows.Next() {
+		var name string
+		var count int64
+		var updated sql.NullInt64
+		err = rows.Scan(&name, &count, &updated)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, &nosql.Sequence{Name: name, Count: uint64(count), UpdatedTime: fromMillisecond(updated.Int64)})
+	}
+	return list, rows.Err()
+}
+
+func restoreSequences(list []interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+quote(TableSequence))
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?)",
+		quote(TableSequence), quote("name"), quote("count"), quote("updatedAt"))
+	for _, item := range list {
+		seq, ok := item.(*nosql.Sequence)
+		if !ok {
+			return errors.New("the document type is not matched")
+		}
+		_, err = tx.ExecContext(ctx, rebind(query), seq.Name, int64(seq.Count), toMillisecond(seq.UpdatedTime))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
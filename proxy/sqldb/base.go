@@ -0,0 +1,118 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+	"net/url"
+	"omo.msa.assignment/proxy/nosql"
+	"strings"
+	"time"
+)
+
+/**
+关系型数据库存储实现(mysql/postgres/sqlite)，与mongodb实现语义保持一致：
+1. 每个集合对应一张主表，标量字段为列，时间保存为毫秒时间戳(零值为0)
+2. 数组字段保存在子表 <主表>_<字段> 中，结构体元素以json保存，第一个字段作为索引键
+3. 启动时自动建表，并为已有表补充缺少的列
+*/
+
+const (
+	KindMysql    = "mysql"
+	KindPostgres = "postgres"
+	KindSqlite   = "sqlite"
+)
+
+const timeOut = 10 * time.Second
+
+var dbConn *sql.DB
+var dbKind string
+
+func IsSupported(kind string) bool {
+	return kind == KindMysql || kind == KindPostgres || kind == KindSqlite
+}
+
+func InitDB(kind, ip, port, db, user, psw string) error {
+	if !IsSupported(kind) {
+		return errors.New("the database type is not supported: " + kind)
+	}
+	driver, dsn := dataSource(kind, ip, port, db, user, psw)
+	conn, err := sql.Open(driver, dsn)
+	if err != nil {
+		return err
+	}
+	if kind == KindSqlite {
+		// sqlite只允许一个写连接，避免database is locked
+		conn.SetMaxOpenConns(1)
+	} else {
+		conn.SetMaxIdleConns(10)
+		conn.SetMaxOpenConns(100)
+	}
+	err = conn.Ping()
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	dbConn = conn
+	dbKind = kind
+	return migrate()
+}
+
+func NewStores() *nosql.Stores {
+	return &nosql.Stores{
+		Task:       new(taskStore),
+		Agent:      new(agentStore),
+		Team:       new(teamStore),
+		Family:     new(familyStore),
+		Coterie:    new(coterieStore),
+		Apply:      new(applyStore),
+		Invitation: new(invitationStore),
+		Meeting:    new(meetingStore),
+		Question:   new(questionStore),
+		Category:   new(categoryStore),
+		Quiz:       new(quizStore),
+		Sequence:   new(sequenceStore),
+		Backup:     new(backupStore),
+	}
+}
+
+func dataSource(kind, ip, port, db, user, psw string) (string, string) {
+	switch kind {
+	case KindMysql:
+		return "mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4", user, psw, ip, port, db)
+	case KindPostgres:
+		u := url.URL{Scheme: "postgres", User: url.UserPassword(user, psw), Host: ip + ":" + port, Path: db, RawQuery: "sslmode=disable"}
+		return "postgres", u.String()
+	default:
+		// sqlite的库名即文件路径
+		return "sqlite", db
+	}
+}
+
+func quote(name string) string {
+	if dbKind == KindMysql {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// 统一使用?作为占位符，postgres需要转换为$n
+func rebind(query string) string {
+	if dbKind != KindPostgres {
+		return query
+	}
+	var builder strings.Builder
+	num := 0
+	for _, ch := range query {
+		if ch == '?' {
+			num += 1
+			builder.WriteString(fmt.Sprintf("$%d", num))
+		} else {
+			builder.WriteRune(ch)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,51 @@
+package sqldb
+
+import (
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var categories = newTable[nosql.Category](nosql.TableCategory)
+
+type categoryStore struct{}
+
+func (mine *categoryStore) CreateCategory(info *nosql.Category) error {
+	return categories.insert(info)
+}
+
+func (mine *categoryStore) GetOneCategory(uid string) (*nosql.Category, error) {
+	return categories.get(uid)
+}
+
+func (mine *categoryStore) GetAllCategories() ([]*nosql.Category, error) {
+	return categories.findMany(alive())
+}
+
+func (mine *categoryStore) GetCategoryListByParent(parent string) ([]*nosql.Category, error) {
+	return categories.findMany(eq("parent", parent), alive())
+}
+
+func (mine *categoryStore) GetCategoryListByOwner(owner, parent string) ([]*nosql.Category, error) {
+	return categories.findMany(eq("owner", owner), eq("parent", parent), alive())
+}
+
+func (mine *categoryStore) UpdateCategoryOwner(uid, owner string) error {
+	return categories.update(uid, values{"owner": owner, "updatedAt": time.Now()})
+}
+
+func (mine *categoryStore) UpdateCategoryBase(uid, name, remark, quote, operator string) error {
+	return categories.update(uid, values{"name": name, "remark": remark, "quote": quote, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *categoryStore) UpdateCategoryInt(filter, operator, uid string, value int64) error {
+	return categories.update(uid, values{filter: value, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *categoryStore) DeleteCategory(uid, operator string) error {
+	return categories.removeOne(uid, operator)
+}
+
+func (mine *categoryStore) QueryCategories(query *proxy.Query) ([]*nosql.Category, int64, error) {
+	return categories.query(query)
+}
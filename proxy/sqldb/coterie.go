@@ -0,0 +1,108 @@
+package sqldb
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var coteries = newTable[nosql.Coterie](nosql.TableCoterie)
+
+type coterieStore struct{}
+
+func (mine *coterieStore) CreateCoterie(info *nosql.Coterie) error {
+	return coteries.insert(info)
+}
+
+func (mine *coterieStore) GetCoterie(uid string) (*nosql.Coterie, error) {
+	return coteries.get(uid)
+}
+
+func (mine *coterieStore) GetCoterieByCreator(creator string) (*nosql.Coterie, error) {
+	return coteries.findOne(eq("creator", creator), alive())
+}
+
+func (mine *coterieStore) GetCoterieByCentre(centre string) (*nosql.Coterie, error) {
+	return coteries.findOne(eq("centre", centre), alive())
+}
+
+func (mine *coterieStore) GetActivitiesCount() int64 {
+	num, _ := coteries.count(alive())
+	return num
+}
+
+func (mine *coterieStore) GetAllCoteries(page, num int64) ([]*nosql.Coterie, error) {
+	return coteries.findList(quote("createdAt")+" DESC", page, num, alive())
+}
+
+func (mine *coterieStore) GetCoteriesByMember(user string) ([]*nosql.Coterie, error) {
+	return coteries.findMany(has("members", user), alive())
+}
+
+func (mine *coterieStore) GetCoteriesByCreator(user string) ([]*nosql.Coterie, error) {
+	return coteries.findMany(eq("creator", user), alive())
+}
+
+func (mine *coterieStore) GetCoteriesByMaster(user string) ([]*nosql.Coterie, error) {
+	return coteries.findMany(eq("master", user), alive())
+}
+
+func (mine *coterieStore) UpdateCoterieBase(uid, name, remark, psw, operator string) error {
+	return coteries.update(uid, values{"name": name, "remark": remark, "passwords": psw, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *coterieStore) UpdateCoterieMaster(uid, master, operator string) error {
+	return coteries.update(uid, values{"master": master, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *coterieStore) UpdateCoterieStatus(uid, operator string, st uint8) error {
+	return coteries.update(uid, values{"status": st, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *coterieStore) UpdateCoteriePasswords(uid, operator, psw string) error {
+	return coteries.update(uid, values{"passwords": psw, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *coterieStore) UpdateCoterieTags(uid, operator string, list []string) error {
+	return coteries.update(uid, values{"tags": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *coterieStore) UpdateCoterieAssistants(uid, operator string, list []string) error {
+	return coteries.update(uid, values{"assistants": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *coterieStore) RemoveCoterie(uid, operator string) error {
+	return coteries.removeOne(uid, operator)
+}
+
+func (mine *coterieStore) AppendCoterieMember(uid string, invitee proxy.MemberInfo) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return coteries.appendElement(uid, "members", invitee)
+}
+
+func (mine *coterieStore) SubtractCoterieMember(uid, user string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return coteries.removeElement(uid, "members", user)
+}
+
+func (mine *coterieStore) AppendCoterieMembers(uid string, list []proxy.MemberInfo) (bool, error) {
+	return coteries.appendElements(uid, "members", list, 0, "")
+}
+
+func (mine *coterieStore) SubtractCoterieMembers(uid string, users []string) error {
+	return coteries.removeElements(uid, "members", users)
+}
+
+func (mine *coterieStore) ReplaceCoterieMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error) {
+	fields := values{"operator": operator, "updatedAt": time.Now()}
+	return coteries.replaceElements(uid, "members", old, list, fields, "")
+}
+
+func (mine *coterieStore) QueryCoteries(query *proxy.Query) ([]*nosql.Coterie, int64, error) {
+	return coteries.query(query)
+}
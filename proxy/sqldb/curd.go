@@ -0,0 +1,965 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"omo.msa.assignment/proxy"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	opEqual = iota
+	opHas
+	opIn
+	opLess
+	opGreater
+	opLike
+	opOr
+	opLessThan
+	opGreaterThan
+)
+
+// 查询条件，多个条件之间为AND
+type condition struct {
+	name  string
+	op    uint8
+	value interface{}
+	or    []condition
+}
+
+// 更新的字段，键为bson的字段名
+type values map[string]interface{}
+
+func eq(name string, value interface{}) condition {
+	return condition{name: name, op: opEqual, value: value}
+}
+
+// 数组字段包含某个元素，结构体数组按第一个字段匹配
+func has(name string, value interface{}) condition {
+	return condition{name: name, op: opHas, value: value}
+}
+
+// 字段等于其中一个值，list为切片
+func in(name string, list interface{}) condition {
+	return condition{name: name, op: opIn, value: list}
+}
+
+// 小于等于
+func lte(name string, value interface{}) condition {
+	return condition{name: name, op: opLess, value: value}
+}
+
+// 大于等于
+func gte(name string, value interface{}) condition {
+	return condition{name: name, op: opGreater, value: value}
+}
+
+// 小于
+func lt(name string, value interface{}) condition {
+	return condition{name: name, op: opLessThan, value: value}
+}
+
+// 大于
+func gt(name string, value interface{}) condition {
+	return condition{name: name, op: opGreaterThan, value: value}
+}
+
+// 字段包含某段文字，不区分大小写
+func like(name string, text string) condition {
+	return condition{name: name, op: opLike, value: text}
+}
+
+func or(list ...condition) condition {
+	return condition{op: opOr, or: list}
+}
+
+// 未删除的数据
+func alive() condition {
+	return eq("deleteAt", time.Time{})
+}
+
+type table[T any] struct {
+	meta *tableMeta
+}
+
+func newTable[T any](name string) *table[T] {
+	return &table[T]{meta: newTableMeta(name, reflect.TypeOf(new(T)).Elem())}
+}
+
+func (mine *table[T]) where(conditions []condition) (string, []interface{}, error) {
+	if len(conditions) < 1 {
+		return "", nil, nil
+	}
+	list := make([]string, 0, len(conditions))
+	args := make([]interface{}, 0, len(conditions))
+	for _, item := range conditions {
+		exp, params, err := mine.expression(item)
+		if err != nil {
+			return "", nil, err
+		}
+		list = append(list, exp)
+		args = append(args, params...)
+	}
+	return " WHERE " + strings.Join(list, " AND "), args, nil
+}
+
+func (mine *table[T]) expression(item condition) (string, []interface{}, error) {
+	if item.op == opOr {
+		list := make([]string, 0, len(item.or))
+		args := make([]interface{}, 0, len(item.or))
+		for _, sub := range item.or {
+			exp, params, err := mine.expression(sub)
+			if err != nil {
+				return "", nil, err
+			}
+			list = append(list, exp)
+			args = append(args, params...)
+		}
+		return "(" + strings.Join(list, " OR ") + ")", args, nil
+	}
+	if item.op == opHas {
+		array := mine.meta.array(item.name)
+		if array == nil {
+			return "", nil, errors.New("the array field is not existed: " + item.name)
+		}
+		key, _, err := encodeElement(reflect.ValueOf(item.value))
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s = ?)",
+			quote("uid"), quote("owner"), quote(array.table), quote("item")), []interface{}{key}, nil
+	}
+	column := mine.meta.column(item.name)
+	if column == nil {
+		return "", nil, errors.New("the field is not existed: " + item.name)
+	}
+	switch item.op {
+	case opIn:
+		array := reflect.ValueOf(item.value)
+		if array.Len() < 1 {
+			return "1 = 0", nil, nil
+		}
+		args := make([]interface{}, 0, array.Len())
+		for i := 0; i < array.Len(); i++ {
+			val, err := toColumn(column.kind, array.Index(i).Interface())
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, val)
+		}
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+		return fmt.Sprintf("%s IN (%s)", quote(column.field()), marks), args, nil
+	case opLike:
+		text := strings.ToLower(fmt.Sprint(item.value))
+		// 不用反斜杠转义，mysql的字符串里反斜杠本身也需要转义
+		replacer := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+		return fmt.Sprintf("LOWER(%s) LIKE ? ESCAPE '!'", quote(column.field())), []interface{}{"%" + replacer.Replace(text) + "%"}, nil
+	}
+	val, err := toColumn(column.kind, item.value)
+	if err != nil {
+		return "", nil, err
+	}
+	operator := "="
+	if item.op == opLess {
+		operator = "<="
+	} else if item.op == opGreater {
+		operator = ">="
+	} else if item.op == opLessThan {
+		operator = "<"
+	} else if item.op == opGreaterThan {
+		operator = ">"
+	}
+	return fmt.Sprintf("%s %s ?", quote(column.field()), operator), []interface{}{val}, nil
+}
+
+// 排序语句，按字段排序后再按uid排序保证分页稳定
+func (mine *table[T]) order(name string, desc bool) (string, error) {
+	column := mine.meta.column(name)
+	if column == nil {
+		return "", errors.New("the field is not existed: " + name)
+	}
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+	return quote(column.field()) + direction + ", " + quote("uid") + direction, nil
+}
+
+func (mine *table[T]) selectColumns() string {
+	list := make([]string, 0, len(mine.meta.columns))
+	for _, item := range mine.meta.columns {
+		list = append(list, quote(item.field()))
+	}
+	return strings.Join(list, ", ")
+}
+
+func (mine *table[T]) insert(info *T) error {
+	if info == nil {
+		return errors.New("the document is nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = mine.insertRow(ctx, tx, info)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 在事务中写入主表的一行以及子表的数组元素
+func (mine *table[T]) insertRow(ctx context.Context, tx *sql.Tx, info *T) error {
+	val := reflect.ValueOf(info).Elem()
+	names := make([]string, 0, len(mine.meta.columns))
+	marks := make([]string, 0, len(mine.meta.columns))
+	args := make([]interface{}, 0, len(mine.meta.columns))
+	var uid string
+	for _, item := range mine.meta.columns {
+		arg, err := toColumn(item.kind, val.FieldByIndex(item.path).Interface())
+		if err != nil {
+			return err
+		}
+		if item.name == "uid" {
+			uid = arg.(string)
+		}
+		names = append(names, quote(item.field()))
+		marks = append(marks, "?")
+		args = append(args, arg)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quote(mine.meta.name), strings.Join(names, ", "), strings.Join(marks, ", "))
+	_, err := tx.ExecContext(ctx, rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	for _, array := range mine.meta.arrays {
+		err = insertElements(ctx, tx, array, uid, val.Field(array.index), 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertElements(ctx context.Context, tx *sql.Tx, array *arrayMeta, uid string, list reflect.Value, start int64) error {
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s) VALUES (?, ?, ?, ?)",
+		quote(array.table), quote("owner"), quote("seq"), quote("item"), quote("value"))
+	for i := 0; i < list.Len(); i++ {
+		key, value, err := encodeElement(list.Index(i))
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, rebind(query), uid, start+int64(i)+1, key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 一条语句中IN的参数数量上限，sqlite默认最多999个参数，postgres和mysql最多65535个
+const maxInArgs = 500
+
+// 参数按照maxInArgs分段，每段调用一次fun，marks为这一段的占位符
+func eachChunk(args []interface{}, fun func(marks string, chunk []interface{}) error) error {
+	for start := 0; start < len(args); start += maxInArgs {
+		end := start + maxInArgs
+		if end > len(args) {
+			end = len(args)
+		}
+		chunk := args[start:end]
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+		err := fun(marks, chunk)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 删除数组中索引键为items中任意一个的元素
+func deleteItems(ctx context.Context, tx *sql.Tx, array *arrayMeta, uid string, items []interface{}) error {
+	return eachChunk(items, func(marks string, chunk []interface{}) error {
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s IN (%s)", quote(array.table), quote("owner"), quote("item"), marks)
+		_, err := tx.ExecContext(ctx, rebind(query), append([]interface{}{uid}, chunk...)...)
+		return err
+	})
+}
+
+func (mine *table[T]) get(uid string) (*T, error) {
+	_, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return nil, err
+	}
+	return mine.findOne(eq("uid", uid))
+}
+
+func (mine *table[T]) findOne(conditions ...condition) (*T, error) {
+	list, err := mine.findList("", 0, 1, conditions...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) < 1 {
+		return nil, proxy.ErrNotFound
+	}
+	return list[0], nil
+}
+
+func (mine *table[T]) findMany(conditions ...condition) ([]*T, error) {
+	return mine.findList("", 0, 0, conditions...)
+}
+
+// 按条件分页查询，sort为空时按创建时间排序，limit为0表示不限制
+func (mine *table[T]) findList(sort string, skip, limit int64, conditions ...condition) ([]*T, error) {
+	where, args, err := mine.where(conditions)
+	if err != nil {
+		return nil, err
+	}
+	if len(sort) < 1 {
+		sort = quote("createdAt") + ", " + quote("uid")
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s", mine.selectColumns(), quote(mine.meta.name), where, sort)
+	if skip > 0 || limit > 0 {
+		if limit < 1 {
+			limit = math.MaxInt64
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, skip)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	rows, err := dbConn.QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]*T, 0, 10)
+	uids := make([]interface{}, 0, 10)
+	for rows.Next() {
+		holders := make([]interface{}, 0, len(mine.meta.columns))
+		for _, item := range mine.meta.columns {
+			holders = append(holders, newHolder(item.kind))
+		}
+		err = rows.Scan(holders...)
+		if err != nil {
+			return nil, err
+		}
+		node := new(T)
+		val := reflect.ValueOf(node).Elem()
+		for i, item := range mine.meta.columns {
+			err = fromHolder(item.kind, holders[i], val.FieldByIndex(item.path))
+			if err != nil {
+				return nil, err
+			}
+		}
+		list = append(list, node)
+		uids = append(uids, holders[0].(*sql.NullString).String)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	if len(list) < 1 {
+		return list, nil
+	}
+	for _, array := range mine.meta.arrays {
+		err = mine.loadElements(ctx, array, uids, list)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (mine *table[T]) loadElements(ctx context.Context, array *arrayMeta, uids []interface{}, list []*T) error {
+	kind := reflect.SliceOf(array.elem)
+	elements := make(map[string]reflect.Value, len(uids))
+	err := eachChunk(uids, func(marks string, chunk []interface{}) error {
+		query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s) ORDER BY %s, %s",
+			quote("owner"), quote("value"), quote(array.table), quote("owner"), marks, quote("owner"), quote("seq"))
+		rows, err := dbConn.QueryContext(ctx, rebind(query), chunk...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var owner string
+			var value sql.NullString
+			err = rows.Scan(&owner, &value)
+			if err != nil {
+				return err
+			}
+			element, err := decodeElement(array.elem, value.String)
+			if err != nil {
+				return err
+			}
+			items, ok := elements[owner]
+			if !ok {
+				items = reflect.MakeSlice(kind, 0, 5)
+			}
+			elements[owner] = reflect.Append(items, element)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return err
+	}
+	for i, node := range list {
+		field := reflect.ValueOf(node).Elem().Field(array.index)
+		if items, ok := elements[uids[i].(string)]; ok {
+			field.Set(items)
+		} else {
+			field.Set(reflect.MakeSlice(kind, 0, 0))
+		}
+	}
+	return nil
+}
+
+func (mine *table[T]) count(conditions ...condition) (int64, error) {
+	where, args, err := mine.where(conditions)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", quote(mine.meta.name), where)
+	var num int64
+	err = dbConn.QueryRowContext(ctx, rebind(query), args...).Scan(&num)
+	return num, err
+}
+
+// 按uid修改字段，数组字段整体替换，与mongodb的updateOne一样，找不到数据时不报错
+func (mine *table[T]) update(uid string, fields values) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	num, err := mine.updateColumns(ctx, tx, uid, fields)
+	if err != nil {
+		return err
+	}
+	if num < 1 {
+		return nil
+	}
+	for name, value := range fields {
+		array := mine.meta.array(name)
+		if array == nil {
+			continue
+		}
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", quote(array.table), quote("owner"))
+		_, err = tx.ExecContext(ctx, rebind(query), uid)
+		if err != nil {
+			return err
+		}
+		list := reflect.ValueOf(value)
+		if !list.IsValid() {
+			continue
+		}
+		err = insertElements(ctx, tx, array, uid, list, 0)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// 修改标量字段，返回匹配的数据条数
+func (mine *table[T]) updateColumns(ctx context.Context, tx *sql.Tx, uid string, fields values) (int64, error) {
+	sets := make([]string, 0, len(fields))
+	args := make([]interface{}, 0, len(fields)+1)
+	for name, value := range fields {
+		if mine.meta.array(name) != nil {
+			continue
+		}
+		columns, items, err := mine.meta.expand(name, value)
+		if err != nil {
+			return 0, err
+		}
+		for i, column := range columns {
+			arg, err := toColumn(column.kind, items[i])
+			if err != nil {
+				return 0, err
+			}
+			sets = append(sets, quote(column.field())+" = ?")
+			args = append(args, arg)
+		}
+	}
+	if len(sets) < 1 {
+		// 只修改数组的时候确认数据存在
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", quote(mine.meta.name), quote("uid"))
+		var num int64
+		err := tx.QueryRowContext(ctx, rebind(query), uid).Scan(&num)
+		return num, err
+	}
+	args = append(args, uid)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", quote(mine.meta.name), strings.Join(sets, ", "), quote("uid"))
+	result, err := tx.ExecContext(ctx, rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	// mysql在值没有变化时返回0，这里再确认一次数据是否存在
+	num, err := result.RowsAffected()
+	if err == nil && num > 0 {
+		return num, nil
+	}
+	query = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", quote(mine.meta.name), quote("uid"))
+	err = tx.QueryRowContext(ctx, rebind(query), uid).Scan(&num)
+	return num, err
+}
+
+// 按条件修改标量字段，对应mongodb带过滤条件的updateOne，返回修改的数据条数
+func (mine *table[T]) updateBy(fields values, conditions ...condition) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	return mine.updateWhere(ctx, dbConn, fields, conditions)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (mine *table[T]) updateWhere(ctx context.Context, db execer, fields values, conditions []condition) (int64, error) {
+	sets := make([]string, 0, len(fields))
+	args := make([]interface{}, 0, len(fields)+len(conditions))
+	for name, value := range fields {
+		if mine.meta.array(name) != nil {
+			return 0, errors.New("the array field can not update by conditions: " + name)
+		}
+		columns, items, err := mine.meta.expand(name, value)
+		if err != nil {
+			return 0, err
+		}
+		for i, column := range columns {
+			arg, err := toColumn(column.kind, items[i])
+			if err != nil {
+				return 0, err
+			}
+			sets = append(sets, quote(column.field())+" = ?")
+			args = append(args, arg)
+		}
+	}
+	where, params, err := mine.where(conditions)
+	if err != nil {
+		return 0, err
+	}
+	args = append(args, params...)
+	query := fmt.Sprintf("UPDATE %s SET %s%s", quote(mine.meta.name), strings.Join(sets, ", "), where)
+	result, err := db.ExecContext(ctx, rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// 按条件修改标量字段并追加元素，对应mongodb带过滤条件的updateOne中同时$set和$push，返回是否修改
+func (mine *table[T]) appendElementBy(uid, name string, value interface{}, fields values, conditions ...condition) (bool, error) {
+	array := mine.meta.array(name)
+	if array == nil {
+		return false, errors.New("the array field is not existed: " + name)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	conditions = append(conditions, eq("uid", uid))
+	num, err := mine.updateWhere(ctx, tx, fields, conditions)
+	if err != nil || num < 1 {
+		return false, err
+	}
+	err = pushElement(ctx, tx, array, uid, value, false)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// 对应mongodb的$push
+func (mine *table[T]) appendElement(uid, name string, value interface{}) error {
+	array := mine.meta.array(name)
+	if array == nil {
+		return errors.New("the array field is not existed: " + name)
+	}
+	return mine.withRow(uid, values{"updatedAt": time.Now()}, func(ctx context.Context, tx *sql.Tx) error {
+		return pushElement(ctx, tx, array, uid, value, false)
+	})
+}
+
+// 在一个事务中修改标量字段后执行fun，数据不存在时不执行
+func (mine *table[T]) withRow(uid string, fields values, fun func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	num, err := mine.updateColumns(ctx, tx, uid, fields)
+	if err != nil {
+		return err
+	}
+	if num < 1 {
+		return nil
+	}
+	err = fun(ctx, tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 在事务中追加元素，unique为true时索引键相等的元素已经存在则不追加
+func pushElement(ctx context.Context, tx *sql.Tx, array *arrayMeta, uid string, value interface{}, unique bool) error {
+	if unique {
+		item, _, err := encodeElement(reflect.ValueOf(value))
+		if err != nil {
+			return err
+		}
+		var had int64
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ? AND %s = ?", quote(array.table), quote("owner"), quote("item"))
+		err = tx.QueryRowContext(ctx, rebind(query), uid, item).Scan(&had)
+		if err != nil || had > 0 {
+			return err
+		}
+	}
+	var last int64
+	query := fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) FROM %s WHERE %s = ?", quote("seq"), quote(array.table), quote("owner"))
+	err := tx.QueryRowContext(ctx, rebind(query), uid).Scan(&last)
+	if err != nil {
+		return err
+	}
+	list := reflect.MakeSlice(reflect.SliceOf(array.elem), 0, 1)
+	list = reflect.Append(list, reflect.ValueOf(value))
+	return insertElements(ctx, tx, array, uid, list, last)
+}
+
+// 在事务中替换索引键相等的元素，不存在时追加
+func putElement(ctx context.Context, tx *sql.Tx, array *arrayMeta, uid string, value interface{}) error {
+	item, data, err := encodeElement(reflect.ValueOf(value))
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ? AND %s = ?", quote(array.table), quote("value"), quote("owner"), quote("item"))
+	result, err := tx.ExecContext(ctx, rebind(query), data, uid, item)
+	if err != nil {
+		return err
+	}
+	num, err := result.RowsAffected()
+	if err != nil || num > 0 {
+		return err
+	}
+	return pushElement(ctx, tx, array, uid, value, false)
+}
+
+// 对应mongodb带条件的$push，索引键相等的元素已经存在或者数量达到limit(为0时不限制)时不追加，
+// 追加时同时从pull数组中移除索引键相等的元素，返回是否追加
+func (mine *table[T]) appendLimit(uid, name string, value interface{}, limit int64, pull string) (bool, error) {
+	list := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(value)), 0, 1)
+	list = reflect.Append(list, reflect.ValueOf(value))
+	return mine.appendElements(uid, name, list.Interface(), limit, pull)
+}
+
+// 对应mongodb带条件的$push $each，有索引键相等的元素已经存在或者追加后超过limit(为0时不限制)时都不追加，
+// 追加时同时从pull数组中移除索引键相等的元素，返回是否追加
+func (mine *table[T]) appendElements(uid, name string, array interface{}, limit int64, pull string) (bool, error) {
+	meta := mine.meta.array(name)
+	if meta == nil {
+		return false, errors.New("the array field is not existed: " + name)
+	}
+	var other *arrayMeta
+	if len(pull) > 0 {
+		other = mine.meta.array(pull)
+		if other == nil {
+			return false, errors.New("the array field is not existed: " + pull)
+		}
+	}
+	list := reflect.ValueOf(array)
+	if list.Len() < 1 {
+		return true, nil
+	}
+	items := make([]interface{}, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		item, _, err := encodeElement(list.Index(i))
+		if err != nil {
+			return false, err
+		}
+		items = append(items, item)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	// 先修改主表锁住这一行，并发追加时数量的检查不会失效
+	num, err := mine.updateColumns(ctx, tx, uid, values{"updatedAt": time.Now()})
+	if err != nil || num < 1 {
+		return false, err
+	}
+	var had, total, last int64
+	err = eachChunk(items, func(marks string, chunk []interface{}) error {
+		var num int64
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ? AND %s IN (%s)", quote(meta.table), quote("owner"), quote("item"), marks)
+		err := tx.QueryRowContext(ctx, rebind(query), append([]interface{}{uid}, chunk...)...).Scan(&num)
+		had += num
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	query := fmt.Sprintf("SELECT COUNT(*), COALESCE(MAX(%s), 0) FROM %s WHERE %s = ?", quote("seq"), quote(meta.table), quote("owner"))
+	err = tx.QueryRowContext(ctx, rebind(query), uid).Scan(&total, &last)
+	if err != nil {
+		return false, err
+	}
+	if had > 0 || (limit > 0 && total+int64(len(items)) > limit) {
+		return false, nil
+	}
+	err = insertElements(ctx, tx, meta, uid, list, last)
+	if err != nil {
+		return false, err
+	}
+	if other != nil {
+		err = deleteItems(ctx, tx, other, uid, items)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// 数组元素的索引键仍然为old时整体替换为array，对应mongodb带条件的$set；pull不为空时同时从该数组中移除替换后的元素，
+// 返回是否替换成功
+func (mine *table[T]) replaceElements(uid, name string, old []string, array interface{}, fields values, pull string) (bool, error) {
+	meta := mine.meta.array(name)
+	if meta == nil {
+		return false, errors.New("the array field is not existed: " + name)
+	}
+	var other *arrayMeta
+	if len(pull) > 0 {
+		other = mine.meta.array(pull)
+		if other == nil {
+			return false, errors.New("the array field is not existed: " + pull)
+		}
+	}
+	list := reflect.ValueOf(array)
+	items := make([]interface{}, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		item, _, err := encodeElement(list.Index(i))
+		if err != nil {
+			return false, err
+		}
+		items = append(items, item)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	// 先修改主表锁住这一行，比较之后不会再被其他人修改
+	num, err := mine.updateColumns(ctx, tx, uid, fields)
+	if err != nil || num < 1 {
+		return false, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? ORDER BY %s", quote("item"), quote(meta.table), quote("owner"), quote("seq"))
+	rows, err := tx.QueryContext(ctx, rebind(query), uid)
+	if err != nil {
+		return false, err
+	}
+	current := make([]string, 0, len(old))
+	for rows.Next() {
+		var item string
+		err = rows.Scan(&item)
+		if err != nil {
+			rows.Close()
+			return false, err
+		}
+		current = append(current, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+	if len(current) != len(old) {
+		return false, nil
+	}
+	for i, key := range old {
+		if current[i] != toItem(key) {
+			return false, nil
+		}
+	}
+	query = fmt.Sprintf("DELETE FROM %s WHERE %s = ?", quote(meta.table), quote("owner"))
+	_, err = tx.ExecContext(ctx, rebind(query), uid)
+	if err != nil {
+		return false, err
+	}
+	err = insertElements(ctx, tx, meta, uid, list, 0)
+	if err != nil {
+		return false, err
+	}
+	if other != nil {
+		err = deleteItems(ctx, tx, other, uid, items)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// 对应mongodb的$pull $in，移除索引键等于其中任意一个的元素
+func (mine *table[T]) removeElements(uid, name string, keys []string) error {
+	array := mine.meta.array(name)
+	if array == nil {
+		return errors.New("the array field is not existed: " + name)
+	}
+	if len(keys) < 1 {
+		return nil
+	}
+	items := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, toItem(key))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	num, err := mine.updateColumns(ctx, tx, uid, values{"updatedAt": time.Now()})
+	if err != nil || num < 1 {
+		return err
+	}
+	err = deleteItems(ctx, tx, array, uid, items)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 对应mongodb的$pull，移除所有索引键相等的元素
+func (mine *table[T]) removeElement(uid, name string, key interface{}) error {
+	array := mine.meta.array(name)
+	if array == nil {
+		return errors.New("the array field is not existed: " + name)
+	}
+	item, _, err := encodeElement(reflect.ValueOf(key))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	num, err := mine.updateColumns(ctx, tx, uid, values{"updatedAt": time.Now()})
+	if err != nil {
+		return err
+	}
+	if num < 1 {
+		return nil
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s = ?", quote(array.table), quote("owner"), quote("item"))
+	_, err = tx.ExecContext(ctx, rebind(query), uid, item)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 对应mongodb的 数组.$ 的修改，替换索引键相等的元素
+func (mine *table[T]) replaceElement(uid, name string, value interface{}) error {
+	array := mine.meta.array(name)
+	if array == nil {
+		return errors.New("the array field is not existed: " + name)
+	}
+	item, data, err := encodeElement(reflect.ValueOf(value))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	num, err := mine.updateColumns(ctx, tx, uid, values{"updatedAt": time.Now()})
+	if err != nil {
+		return err
+	}
+	if num < 1 {
+		return nil
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ? AND %s = ?", quote(array.table), quote("value"), quote("owner"), quote("item"))
+	_, err = tx.ExecContext(ctx, rebind(query), data, uid, item)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 替换索引键相等的元素，不存在时追加
+func (mine *table[T]) upsertElement(uid, name string, value interface{}) error {
+	array := mine.meta.array(name)
+	if array == nil {
+		return errors.New("the array field is not existed: " + name)
+	}
+	return mine.withRow(uid, values{"updatedAt": time.Now()}, func(ctx context.Context, tx *sql.Tx) error {
+		return putElement(ctx, tx, array, uid, value)
+	})
+}
+
+// 数组的一次修改，put为true时替换索引键相等的元素，unique为true时索引键相等的元素已经存在则不追加
+type elementChange struct {
+	name   string
+	value  interface{}
+	put    bool
+	unique bool
+}
+
+// 在一个事务中修改标量字段以及多个数组，对应mongodb的一次更新
+func (mine *table[T]) changeElements(uid string, fields values, changes ...elementChange) error {
+	for _, change := range changes {
+		if mine.meta.array(change.name) == nil {
+			return errors.New("the array field is not existed: " + change.name)
+		}
+	}
+	return mine.withRow(uid, fields, func(ctx context.Context, tx *sql.Tx) error {
+		for _, change := range changes {
+			array := mine.meta.array(change.name)
+			var err error
+			if change.put {
+				err = putElement(ctx, tx, array, uid, change.value)
+			} else {
+				err = pushElement(ctx, tx, array, uid, change.value, change.unique)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (mine *table[T]) removeOne(uid, operator string) error {
+	return mine.update(uid, values{"deleteAt": time.Now(), "operator": operator})
+}
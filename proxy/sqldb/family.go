@@ -0,0 +1,115 @@
+package sqldb
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var families = newTable[nosql.Family](nosql.TableFamily)
+
+type familyStore struct{}
+
+func (mine *familyStore) CreateFamily(info *nosql.Family) error {
+	return families.insert(info)
+}
+
+func (mine *familyStore) GetFamily(uid string) (*nosql.Family, error) {
+	return families.get(uid)
+}
+
+func (mine *familyStore) GetFamilyByCreator(creator string) (*nosql.Family, error) {
+	return families.findOne(eq("creator", creator))
+}
+
+func (mine *familyStore) GetFamilyByMaster(master string) (*nosql.Family, error) {
+	return families.findOne(eq("master", master), alive())
+}
+
+func (mine *familyStore) GetFamilyByChild(entity string) (*nosql.Family, error) {
+	return families.findOne(has("children", entity))
+}
+
+func (mine *familyStore) GetAllFamilies() ([]*nosql.Family, error) {
+	return families.findMany(alive())
+}
+
+func (mine *familyStore) GetFamiliesByMember(user string) ([]*nosql.Family, error) {
+	return families.findMany(has("members", user), alive())
+}
+
+func (mine *familyStore) GetFamiliesByRegion(region string) ([]*nosql.Family, error) {
+	return families.findMany(eq("region", region), alive())
+}
+
+func (mine *familyStore) GetFamiliesByAgent(agent string) ([]*nosql.Family, error) {
+	return families.findMany(has("agents", agent), alive())
+}
+
+func (mine *familyStore) UpdateFamilyBase(uid, name, remark, psw, operator string) error {
+	return families.update(uid, values{"name": name, "remark": remark, "passwords": psw, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *familyStore) UpdateFamilyMaster(uid, master, operator string) error {
+	return families.update(uid, values{"master": master, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *familyStore) UpdateFamilyStatus(uid, operator string, st uint8) error {
+	return families.update(uid, values{"status": st, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *familyStore) UpdateFamilyPasswords(uid, operator, psw string) error {
+	return families.update(uid, values{"passwords": psw, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *familyStore) UpdateFamilyTags(uid, operator string, list []string) error {
+	return families.update(uid, values{"tags": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *familyStore) UpdateFamilyAgents(uid, operator string, list []string) error {
+	return families.update(uid, values{"agents": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *familyStore) UpdateFamilyAssistants(uid, operator string, list []string) error {
+	return families.update(uid, values{"assistants": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *familyStore) UpdateFamilyChildren(uid, operator string, list []string) error {
+	return families.update(uid, values{"children": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *familyStore) RemoveFamily(uid, operator string) error {
+	return families.removeOne(uid, operator)
+}
+
+func (mine *familyStore) AppendFamilyMember(uid string, invitee proxy.MemberInfo) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return families.appendElement(uid, "members", invitee)
+}
+
+func (mine *familyStore) SubtractFamilyMember(uid, user string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return families.removeElement(uid, "members", user)
+}
+
+func (mine *familyStore) AppendFamilyMembers(uid string, list []proxy.MemberInfo) (bool, error) {
+	return families.appendElements(uid, "members", list, 0, "")
+}
+
+func (mine *familyStore) SubtractFamilyMembers(uid string, users []string) error {
+	return families.removeElements(uid, "members", users)
+}
+
+func (mine *familyStore) ReplaceFamilyMembers(uid, operator string, old []string, list []proxy.MemberInfo) (bool, error) {
+	fields := values{"operator": operator, "updatedAt": time.Now()}
+	return families.replaceElements(uid, "members", old, list, fields, "")
+}
+
+func (mine *familyStore) QueryFamilies(query *proxy.Query) ([]*nosql.Family, int64, error) {
+	return families.query(query)
+}
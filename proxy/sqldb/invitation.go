@@ -0,0 +1,64 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var invitations = newTable[nosql.Invitation](nosql.TableInvitation)
+
+type invitationStore struct{}
+
+func (mine *invitationStore) CreateInvitation(info *nosql.Invitation) error {
+	return invitations.insert(info)
+}
+
+func (mine *invitationStore) GetInvitation(uid string) (*nosql.Invitation, error) {
+	return invitations.get(uid)
+}
+
+func (mine *invitationStore) GetInvitationByCode(code string) (*nosql.Invitation, error) {
+	return invitations.findOne(eq("code", code), alive())
+}
+
+// 在一条update语句中检查次数和过期时间
+func (mine *invitationStore) UseInvitation(uid string, now time.Time) (bool, error) {
+	where, args, err := invitations.where([]condition{eq("uid", uid), alive(),
+		or(eq("expired", time.Time{}), gt("expired", now))})
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	query := fmt.Sprintf("UPDATE %s SET %s = %s + 1, %s = ?%s AND %s < %s", quote(nosql.TableInvitation),
+		quote("used"), quote("used"), quote("updatedAt"), where, quote("used"), quote("limit"))
+	result, err := dbConn.ExecContext(ctx, rebind(query), append([]interface{}{toMillisecond(now)}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+func (mine *invitationStore) ReleaseInvitation(uid string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	query := fmt.Sprintf("UPDATE %s SET %s = %s - 1, %s = ? WHERE %s = ? AND %s > 0", quote(nosql.TableInvitation),
+		quote("used"), quote("used"), quote("updatedAt"), quote("uid"), quote("used"))
+	_, err := dbConn.ExecContext(ctx, rebind(query), toMillisecond(time.Now()), uid)
+	return err
+}
+
+func (mine *invitationStore) RemoveInvitation(uid, operator string) error {
+	return invitations.removeOne(uid, operator)
+}
+
+func (mine *invitationStore) QueryInvitations(query *proxy.Query) ([]*nosql.Invitation, int64, error) {
+	return invitations.query(query)
+}
@@ -0,0 +1,109 @@
+package sqldb
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var meetings = newTable[nosql.Meeting](nosql.TableMeeting)
+
+type meetingStore struct{}
+
+func (mine *meetingStore) CreateMeeting(info *nosql.Meeting) error {
+	return meetings.insert(info)
+}
+
+func (mine *meetingStore) GetMeeting(uid string) (*nosql.Meeting, error) {
+	return meetings.get(uid)
+}
+
+func (mine *meetingStore) GetMeetingsByGroup(group string) ([]*nosql.Meeting, error) {
+	return meetings.findMany(eq("group", group), alive())
+}
+
+func (mine *meetingStore) GetMeetingsByScene(owner string) ([]*nosql.Meeting, error) {
+	return meetings.findMany(eq("owner", owner), alive())
+}
+
+func (mine *meetingStore) UpdateMeetingBase(uid, name, remark, operator string) error {
+	return meetings.update(uid, values{"name": name, "remark": remark, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) UpdateMeetingLocation(uid, location, operator string, kind uint8) error {
+	return meetings.update(uid, values{"type": kind, "location": location, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) UpdateMeetingGroup(uid, operator, group string) error {
+	return meetings.update(uid, values{"group": group, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) UpdateMeetingDate(uid, operator string, start, stop time.Time) error {
+	return meetings.update(uid, values{"startAt": start, "stopAt": stop, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) UpdateMeetingStop(uid, operator string, t time.Time) error {
+	return meetings.update(uid, values{"stopAt": t, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) UpdateMeetingStatus(uid string, status uint16) error {
+	return meetings.update(uid, values{"status": status, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) TransitMeeting(uid string, from, status uint8) (bool, error) {
+	num, err := meetings.updateBy(values{"status": status, "updatedAt": time.Now()}, eq("uid", uid), eq("status", from), alive())
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+func (mine *meetingStore) StopMeeting(uid, operator string) error {
+	return meetings.update(uid, values{"status": 3, "operator": operator, "stopAt": time.Now()})
+}
+
+func (mine *meetingStore) RemoveMeeting(uid, operator string) error {
+	return meetings.removeOne(uid, operator)
+}
+
+func (mine *meetingStore) AppendMeetingSign(uid, member, operator string) error {
+	if len(member) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return meetings.appendElement(uid, "signs", member)
+}
+
+func (mine *meetingStore) UpdateMeetingSignRule(uid, operator string, radius, before, late int32) error {
+	return meetings.update(uid, values{"radius": radius, "signBefore": before, "signLate": late, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) UpdateMeetingRule(uid, operator, rule, series string, occurrence time.Time, span int64, revision uint32) error {
+	return meetings.update(uid, values{"rule": rule, "series": series, "occurrence": occurrence, "span": span, "revision": revision,
+		"operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) UpdateMeetingExceptions(uid, operator string, list []proxy.ExceptionInfo) error {
+	return meetings.update(uid, values{"exceptions": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *meetingStore) SaveMeetingAttend(uid string, change *nosql.AttendChange) error {
+	if change == nil || len(change.User()) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	changes := make([]elementChange, 0, 3)
+	if change.Attend != nil {
+		changes = append(changes, elementChange{name: "attends", value: *change.Attend, put: true})
+	}
+	if change.Record != nil {
+		changes = append(changes, elementChange{name: "records", value: *change.Record})
+	}
+	if len(change.Field) > 0 {
+		changes = append(changes, elementChange{name: change.Field, value: change.User(), unique: true})
+	}
+	return meetings.changeElements(uid, values{"operator": change.Operator, "updatedAt": time.Now()}, changes...)
+}
+
+func (mine *meetingStore) QueryMeetings(query *proxy.Query) ([]*nosql.Meeting, int64, error) {
+	return meetings.query(query)
+}
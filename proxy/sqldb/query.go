@@ -0,0 +1,109 @@
+package sqldb
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"omo.msa.assignment/proxy"
+	"reflect"
+	"strings"
+)
+
+// 通用的列表查询，总数不受游标影响
+func (mine *table[T]) query(query *proxy.Query) ([]*T, int64, error) {
+	if query == nil {
+		query = new(proxy.Query)
+	}
+	conditions, err := mine.toConditions(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := mine.count(conditions...)
+	if err != nil {
+		return nil, 0, err
+	}
+	field := query.Sort
+	skip := query.Skip
+	if len(query.Cursor) > 0 {
+		_, err = primitive.ObjectIDFromHex(query.Cursor)
+		if err != nil {
+			return nil, 0, errors.New("the cursor is invalid")
+		}
+		if query.Desc {
+			conditions = append(conditions, lt("uid", query.Cursor))
+		} else {
+			conditions = append(conditions, gt("uid", query.Cursor))
+		}
+		field = ""
+		skip = 0
+	}
+	var order string
+	if len(field) < 1 || field == "_id" {
+		order = quote("uid") + " ASC"
+		if query.Desc {
+			order = quote("uid") + " DESC"
+		}
+	} else {
+		order, err = mine.order(field, query.Desc)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	list, err := mine.findList(order, skip, query.Limit, conditions...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// 数组字段以及 数组.字段 的条件转换为子表查询，结构体数组按第一个字段匹配
+func (mine *table[T]) toConditions(query *proxy.Query) ([]condition, error) {
+	list := make([]condition, 0, len(query.Conditions)+1)
+	for _, item := range query.Conditions {
+		name := item.Field
+		if name == "_id" {
+			name = "uid"
+		}
+		array := mine.meta.array(strings.Split(name, ".")[0])
+		if array != nil {
+			switch item.Op {
+			case proxy.OpEqual:
+				list = append(list, has(array.name, item.Value))
+			case proxy.OpIn:
+				values := reflect.ValueOf(item.Value)
+				if values.Kind() != reflect.Slice {
+					return nil, errors.New("the value of in must be array: " + item.Field)
+				}
+				arr := make([]condition, 0, values.Len())
+				for i := 0; i < values.Len(); i++ {
+					arr = append(arr, has(array.name, values.Index(i).Interface()))
+				}
+				if len(arr) < 1 {
+					arr = append(arr, in("uid", []string{}))
+				}
+				list = append(list, or(arr...))
+			default:
+				return nil, errors.New("the query operator is not supported for array: " + item.Field)
+			}
+			continue
+		}
+		switch item.Op {
+		case proxy.OpEqual:
+			list = append(list, eq(name, item.Value))
+		case proxy.OpIn:
+			if reflect.ValueOf(item.Value).Kind() != reflect.Slice {
+				return nil, errors.New("the value of in must be array: " + item.Field)
+			}
+			list = append(list, in(name, item.Value))
+		case proxy.OpGreater:
+			list = append(list, gt(name, item.Value))
+		case proxy.OpLess:
+			list = append(list, lt(name, item.Value))
+		default:
+			return nil, errors.New("the query operator is not supported: " + item.Field)
+		}
+	}
+	if query.Alive {
+		list = append(list, alive())
+	}
+	return list, nil
+}
@@ -0,0 +1,65 @@
+package sqldb
+
+import (
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var questions = newTable[nosql.Question](nosql.TableQuestion)
+
+type questionStore struct{}
+
+func (mine *questionStore) CreateQuestion(info *nosql.Question) error {
+	return questions.insert(info)
+}
+
+func (mine *questionStore) GetQuestion(uid string) (*nosql.Question, error) {
+	return questions.get(uid)
+}
+
+func (mine *questionStore) GetQuestionsByName(title string) ([]*nosql.Question, error) {
+	return questions.findMany(eq("title", title), alive())
+}
+
+func (mine *questionStore) GetQuestionsByQuote(quote string) ([]*nosql.Question, error) {
+	return questions.findMany(eq("quote", quote), alive())
+}
+
+func (mine *questionStore) GetQuestionsByTitle(title, category string) ([]*nosql.Question, error) {
+	return questions.findMany(eq("title", title), eq("category", category), alive())
+}
+
+func (mine *questionStore) GetQuestionsByCategory(category string) ([]*nosql.Question, error) {
+	return questions.findMany(eq("category", category), alive())
+}
+
+func (mine *questionStore) GetQuestionCount(category string) uint32 {
+	num, _ := questions.count(eq("category", category), alive())
+	return uint32(num)
+}
+
+func (mine *questionStore) UpdateQuestionBase(uid, title, remark, operator, category string, cd uint16, answers []uint32, opts []proxy.PairInfo) error {
+	return questions.update(uid, values{"title": title, "remark": remark, "cd": cd, "category": category,
+		"answers": answers, "options": opts, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *questionStore) UpdateQuestionAnswers(uid, operator string, answers []uint32) error {
+	return questions.update(uid, values{"answers": answers, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *questionStore) UpdateQuestionAssets(uid, operator string, assets []string) error {
+	return questions.update(uid, values{"assets": assets, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *questionStore) UpdateQuestionOptions(uid, operator string, list []proxy.PairInfo) error {
+	return questions.update(uid, values{"options": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *questionStore) RemoveQuestion(uid, operator string) error {
+	return questions.removeOne(uid, operator)
+}
+
+func (mine *questionStore) QueryQuestions(query *proxy.Query) ([]*nosql.Question, int64, error) {
+	return questions.query(query)
+}
@@ -0,0 +1,96 @@
+package sqldb
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var quizzes = newTable[nosql.QuizRecord](nosql.TableRecord)
+
+type quizStore struct{}
+
+func (mine *quizStore) CreateQuizRecord(info *nosql.QuizRecord) error {
+	return quizzes.insert(info)
+}
+
+func (mine *quizStore) GetQuizRecord(uid string) (*nosql.QuizRecord, error) {
+	return quizzes.get(uid)
+}
+
+func (mine *quizStore) QueryQuizRecords(query *proxy.Query) ([]*nosql.QuizRecord, int64, error) {
+	return quizzes.query(query)
+}
+
+func (mine *quizStore) GetQuizRanking(category string, limit int64) ([]*proxy.QuizScore, error) {
+	where, args, err := quizzes.where([]condition{eq("category", category), alive()})
+	if err != nil {
+		return nil, err
+	}
+	user := quote("user")
+	correct := fmt.Sprintf("SUM(%s)", quote("correct"))
+	query := fmt.Sprintf("SELECT %s, COUNT(*), %s FROM %s%s GROUP BY %s ORDER BY %s DESC, COUNT(*) ASC, %s ASC",
+		user, correct, quote(quizzes.meta.name), where, user, correct, user)
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	rows, err := dbConn.QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]*proxy.QuizScore, 0, 10)
+	for rows.Next() {
+		score := new(proxy.QuizScore)
+		var total, num int64
+		err = rows.Scan(&score.User, &total, &num)
+		if err != nil {
+			return nil, err
+		}
+		score.Total = uint32(total)
+		score.Correct = uint32(num)
+		list = append(list, score)
+	}
+	return list, rows.Err()
+}
+
+// 上一次提交早于now-cd时通过带条件的UPDATE记录本次提交，没有提交过时插入，
+// 并发插入时主键冲突的一方失败，读取已经存在的提交时间
+func (mine *quizStore) TrySubmitQuiz(user, question string, now time.Time, cd time.Duration) (bool, time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	// 主键为用户和题目的md5，长度与其他表的主键一致
+	sum := md5.Sum([]byte(user + "\n" + question))
+	key := hex.EncodeToString(sum[:])
+	table := quote(TableQuizSubmit)
+	query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ? AND %s <= ?", table, quote("submitAt"), quote("name"), quote("submitAt"))
+	result, err := dbConn.ExecContext(ctx, rebind(query), toMillisecond(now), key, toMillisecond(now.Add(-cd)))
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if num > 0 {
+		return true, time.Time{}, nil
+	}
+	query = fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s) VALUES (?, ?, ?, ?)",
+		table, quote("name"), quote("user"), quote("question"), quote("submitAt"))
+	_, err = dbConn.ExecContext(ctx, rebind(query), key, user, question, toMillisecond(now))
+	if err == nil {
+		return true, time.Time{}, nil
+	}
+	var last int64
+	query = fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", quote("submitAt"), table, quote("name"))
+	if er := dbConn.QueryRowContext(ctx, rebind(query), key).Scan(&last); er != nil {
+		return false, time.Time{}, err
+	}
+	return false, fromMillisecond(last), nil
+}
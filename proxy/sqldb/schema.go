@@ -0,0 +1,445 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	kindString = iota
+	kindInt
+	kindUint
+	kindTime
+	kindObjectID
+	kindJson
+)
+
+// 子表索引键的最大长度，兼容mysql的索引长度限制
+const maxItemLength = 191
+
+const TableSequence = "sequences"
+
+// TableQuizSubmit 用户最后一次提交题目的时间
+const TableQuizSubmit = "lore_submits"
+
+type columnMeta struct {
+	// bson的字段路径，嵌套结构体为 duration.begin
+	name string
+	kind uint8
+	path []int
+}
+
+type arrayMeta struct {
+	name  string
+	table string
+	index int
+	elem  reflect.Type
+}
+
+type tableMeta struct {
+	name    string
+	columns []*columnMeta
+	arrays  []*arrayMeta
+}
+
+var schemas = make([]*tableMeta, 0, 10)
+
+var (
+	typeTime     = reflect.TypeOf(time.Time{})
+	typeObjectID = reflect.TypeOf(primitive.ObjectID{})
+)
+
+func newTableMeta(name string, kind reflect.Type) *tableMeta {
+	meta := &tableMeta{name: name, columns: make([]*columnMeta, 0, 20), arrays: make([]*arrayMeta, 0, 5)}
+	meta.parseFields(kind, "", nil)
+	schemas = append(schemas, meta)
+	return meta
+}
+
+// 嵌套的结构体展开为多个列
+func (mine *tableMeta) parseFields(kind reflect.Type, prefix string, parent []int) {
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		tag := strings.Split(field.Tag.Get("bson"), ",")[0]
+		if len(tag) < 1 || tag == "-" {
+			continue
+		}
+		if tag == "_id" {
+			tag = "uid"
+		}
+		path := append(append(make([]int, 0, len(parent)+1), parent...), i)
+		if field.Type.Kind() == reflect.Slice && len(prefix) < 1 {
+			mine.arrays = append(mine.arrays, &arrayMeta{name: tag, table: mine.name + "_" + snakeName(tag), index: i, elem: field.Type.Elem()})
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type != typeTime && field.Type != typeObjectID {
+			mine.parseFields(field.Type, prefix+tag+".", path)
+			continue
+		}
+		column := &columnMeta{name: prefix + tag, kind: columnKind(field.Type), path: path}
+		if column.name == "uid" {
+			// 主键总是第一列
+			mine.columns = append([]*columnMeta{column}, mine.columns...)
+		} else {
+			mine.columns = append(mine.columns, column)
+		}
+	}
+}
+
+func columnKind(kind reflect.Type) uint8 {
+	switch kind {
+	case typeTime:
+		return kindTime
+	case typeObjectID:
+		return kindObjectID
+	}
+	switch kind.Kind() {
+	case reflect.String:
+		return kindString
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return kindInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindUint
+	default:
+		return kindJson
+	}
+}
+
+func snakeName(name string) string {
+	var builder strings.Builder
+	for _, ch := range name {
+		if unicode.IsUpper(ch) {
+			builder.WriteRune('_')
+			builder.WriteRune(unicode.ToLower(ch))
+		} else {
+			builder.WriteRune(ch)
+		}
+	}
+	return builder.String()
+}
+
+func (mine *tableMeta) column(name string) *columnMeta {
+	for _, item := range mine.columns {
+		if item.name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// 嵌套结构体的全部列
+func (mine *tableMeta) group(name string) []*columnMeta {
+	list := make([]*columnMeta, 0, 2)
+	for _, item := range mine.columns {
+		if strings.HasPrefix(item.name, name+".") {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// 把要修改的字段转换为列，嵌套结构体展开为多个列
+func (mine *tableMeta) expand(name string, value interface{}) ([]*columnMeta, []interface{}, error) {
+	column := mine.column(name)
+	if column != nil {
+		return []*columnMeta{column}, []interface{}{value}, nil
+	}
+	list := mine.group(name)
+	if len(list) < 1 {
+		return nil, nil, errors.New("the field is not existed: " + name)
+	}
+	val := reflect.ValueOf(value)
+	if val.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("the value of %s is not struct", name)
+	}
+	depth := strings.Count(name, ".") + 1
+	items := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		items = append(items, val.FieldByIndex(item.path[depth:]).Interface())
+	}
+	return list, items, nil
+}
+
+// 数据库中的列名
+func (mine *columnMeta) field() string {
+	return strings.ReplaceAll(mine.name, ".", "_")
+}
+
+func (mine *tableMeta) array(name string) *arrayMeta {
+	for _, item := range mine.arrays {
+		if item.name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+func columnType(kind uint8) string {
+	switch kind {
+	case kindObjectID:
+		return "VARCHAR(32)"
+	case kindInt, kindUint, kindTime:
+		return "BIGINT"
+	default:
+		return "TEXT"
+	}
+}
+
+func migrate() error {
+	for _, meta := range schemas {
+		err := migrateTable(meta.name, meta.columns)
+		if err != nil {
+			return err
+		}
+		for _, array := range meta.arrays {
+			err = migrateArray(array.table)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	sequence := []*columnMeta{{name: "name", kind: kindObjectID}, {name: "count", kind: kindUint}, {name: "updatedAt", kind: kindTime}}
+	err := migrateTable(TableSequence, sequence)
+	if err != nil {
+		return err
+	}
+	submit := []*columnMeta{{name: "name", kind: kindObjectID}, {name: "user", kind: kindString},
+		{name: "question", kind: kindString}, {name: "submitAt", kind: kindTime}}
+	return migrateTable(TableQuizSubmit, submit)
+}
+
+// 建表，已经存在的表则补充缺少的列
+func migrateTable(table string, columns []*columnMeta) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	list := make([]string, 0, len(columns))
+	for i, item := range columns {
+		define := quote(item.field()) + " " + columnType(item.kind)
+		if i == 0 {
+			define += " NOT NULL PRIMARY KEY"
+		}
+		list = append(list, define)
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quote(table), strings.Join(list, ", "))
+	_, err := dbConn.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	exists, err := tableColumns(ctx, table)
+	if err != nil {
+		return err
+	}
+	for _, item := range columns {
+		if _, ok := exists[strings.ToLower(item.field())]; ok {
+			continue
+		}
+		query = fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quote(table), quote(item.field()), columnType(item.kind))
+		_, err = dbConn.ExecContext(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateArray(table string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	columns := fmt.Sprintf("%s VARCHAR(32) NOT NULL, %s BIGINT NOT NULL, %s VARCHAR(%d) NOT NULL, %s TEXT",
+		quote("owner"), quote("seq"), quote("item"), maxItemLength, quote("value"))
+	if dbKind == KindMysql {
+		columns += fmt.Sprintf(", INDEX %s (%s), INDEX %s (%s)", quote("idx_owner"), quote("owner"), quote("idx_item"), quote("item"))
+	}
+	_, err := dbConn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quote(table), columns))
+	if err != nil {
+		return err
+	}
+	if dbKind == KindMysql {
+		return nil
+	}
+	for _, key := range []string{"owner", "item"} {
+		query := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", quote(table+"_"+key), quote(table), quote(key))
+		_, err = dbConn.ExecContext(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tableColumns(ctx context.Context, table string) (map[string]bool, error) {
+	rows, err := dbConn.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", quote(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(names))
+	for _, name := range names {
+		exists[strings.ToLower(name)] = true
+	}
+	return exists, nil
+}
+
+func toMillisecond(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromMillisecond(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
+
+// 把go的值转换为列的值
+func toColumn(kind uint8, value interface{}) (interface{}, error) {
+	val := reflect.ValueOf(value)
+	switch kind {
+	case kindTime:
+		t, ok := value.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("the value %v is not time", value)
+		}
+		return toMillisecond(t), nil
+	case kindObjectID:
+		if id, ok := value.(primitive.ObjectID); ok {
+			return id.Hex(), nil
+		}
+		return fmt.Sprint(value), nil
+	case kindInt, kindUint:
+		switch val.Kind() {
+		case reflect.Bool:
+			if val.Bool() {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return val.Int(), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(val.Uint()), nil
+		}
+		return nil, fmt.Errorf("the value %v is not number", value)
+	case kindJson:
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(bytes), nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+// 列的扫描容器
+func newHolder(kind uint8) interface{} {
+	switch kind {
+	case kindInt, kindUint, kindTime:
+		return new(sql.NullInt64)
+	default:
+		return new(sql.NullString)
+	}
+}
+
+func fromHolder(kind uint8, holder interface{}, field reflect.Value) error {
+	switch kind {
+	case kindTime:
+		field.Set(reflect.ValueOf(fromMillisecond(holder.(*sql.NullInt64).Int64)))
+	case kindInt:
+		if field.Kind() == reflect.Bool {
+			field.SetBool(holder.(*sql.NullInt64).Int64 != 0)
+		} else {
+			field.SetInt(holder.(*sql.NullInt64).Int64)
+		}
+	case kindUint:
+		field.SetUint(uint64(holder.(*sql.NullInt64).Int64))
+	case kindObjectID:
+		id, err := primitive.ObjectIDFromHex(holder.(*sql.NullString).String)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(id))
+	case kindJson:
+		str := holder.(*sql.NullString).String
+		if len(str) > 0 {
+			return json.Unmarshal([]byte(str), field.Addr().Interface())
+		}
+	default:
+		field.SetString(holder.(*sql.NullString).String)
+	}
+	return nil
+}
+
+// 数组元素转换为子表的索引键和值
+func encodeElement(val reflect.Value) (string, string, error) {
+	switch val.Kind() {
+	case reflect.String:
+		return toItem(val.String()), val.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		str := strconv.FormatInt(val.Int(), 10)
+		return str, str, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		str := strconv.FormatUint(val.Uint(), 10)
+		return str, str, nil
+	case reflect.Struct:
+		bytes, err := json.Marshal(val.Interface())
+		if err != nil {
+			return "", "", err
+		}
+		key := ""
+		if val.NumField() > 0 {
+			key = fmt.Sprint(val.Field(0).Interface())
+		}
+		return toItem(key), string(bytes), nil
+	}
+	return "", "", fmt.Errorf("the element type %s is not supported", val.Type())
+}
+
+func decodeElement(kind reflect.Type, value string) (reflect.Value, error) {
+	val := reflect.New(kind).Elem()
+	switch kind.Kind() {
+	case reflect.String:
+		val.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return val, err
+		}
+		val.SetInt(num)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return val, err
+		}
+		val.SetUint(num)
+	default:
+		err := json.Unmarshal([]byte(value), val.Addr().Interface())
+		if err != nil {
+			return val, err
+		}
+	}
+	return val, nil
+}
+
+func toItem(key string) string {
+	runes := []rune(key)
+	if len(runes) > maxItemLength {
+		return string(runes[:maxItemLength])
+	}
+	return key
+}
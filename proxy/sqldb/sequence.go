@@ -0,0 +1,105 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type sequenceStore struct{}
+
+func (mine *sequenceStore) GetSequenceNext(name string) (uint64, error) {
+	return incSequence(name, 1)
+}
+
+func (mine *sequenceStore) ReserveSequence(name string, count uint64) (uint64, error) {
+	if count < 1 {
+		return 0, errors.New("the sequence increment must be more than 0")
+	}
+	last, err := incSequence(name, count)
+	if err != nil {
+		return 0, err
+	}
+	return last - count + 1, nil
+}
+
+func (mine *sequenceStore) RepairSequence(name string, min uint64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	now := toMillisecond(time.Now())
+	query := fmt.Sprintf("UPDATE %s SET %s = ?, %s = ? WHERE %s = ? AND %s < ?",
+		quote(TableSequence), quote("count"), quote("updatedAt"), quote("name"), quote("count"))
+	result, err := dbConn.ExecContext(ctx, rebind(query), int64(min), now, name, int64(min))
+	if err != nil {
+		return false, err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if num > 0 {
+		return true, nil
+	}
+	// 序号不存在时创建，已经存在(不需要修改)时插入会因为主键冲突而失败
+	_, err = incSequence(name, 0)
+	if err != nil {
+		return false, err
+	}
+	result, err = dbConn.ExecContext(ctx, rebind(query), int64(min), now, name, int64(min))
+	if err != nil {
+		return false, err
+	}
+	num, err = result.RowsAffected()
+	return num > 0, err
+}
+
+// 在同一个事务里自增并读取，数据库的行锁保证并发时不会拿到重复的序号；
+// 两个并发的请求同时创建序号时，主键冲突的一方重试一次即可自增已经存在的行
+func incSequence(name string, num uint64) (uint64, error) {
+	count, err := tryIncSequence(name, num)
+	if err != nil {
+		count, err = tryIncSequence(name, num)
+	}
+	return count, err
+}
+
+func tryIncSequence(name string, num uint64) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	now := toMillisecond(time.Now())
+	query := fmt.Sprintf("UPDATE %s SET %s = %s + ?, %s = ? WHERE %s = ?",
+		quote(TableSequence), quote("count"), quote("count"), quote("updatedAt"), quote("name"))
+	result, err := tx.ExecContext(ctx, rebind(query), int64(num), now, name)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected < 1 {
+		query = fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?)",
+			quote(TableSequence), quote("name"), quote("count"), quote("updatedAt"))
+		_, err = tx.ExecContext(ctx, rebind(query), name, int64(num), now)
+		if err != nil {
+			return 0, err
+		}
+	}
+	var count int64
+	query = fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", quote("count"), quote(TableSequence), quote("name"))
+	err = tx.QueryRowContext(ctx, rebind(query), name).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(count), nil
+}
@@ -0,0 +1,180 @@
+package sqldb
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var tasks = newTable[nosql.Task](nosql.TableTask)
+
+type taskStore struct{}
+
+func (mine *taskStore) CreateTask(info *nosql.Task) error {
+	return tasks.insert(info)
+}
+
+func (mine *taskStore) GetTask(uid string) (*nosql.Task, error) {
+	return tasks.get(uid)
+}
+
+func (mine *taskStore) GetTasksByOwner(uid string, st uint8) ([]*nosql.Task, error) {
+	return tasks.findMany(eq("owner", uid), eq("status", st), alive())
+}
+
+func (mine *taskStore) GetTasksByOwner2(uid string) ([]*nosql.Task, error) {
+	return tasks.findMany(eq("owner", uid), alive())
+}
+
+func (mine *taskStore) GetTasksByType(owner string, tp uint8) ([]*nosql.Task, error) {
+	return tasks.findMany(eq("owner", owner), eq("type", tp), alive())
+}
+
+func (mine *taskStore) GetTasksByRegion(region string, st uint8) ([]*nosql.Task, error) {
+	return tasks.findMany(has("regions", region), eq("status", st), alive())
+}
+
+func (mine *taskStore) GetTasksByRegion2(region string) ([]*nosql.Task, error) {
+	return tasks.findMany(has("regions", region), alive())
+}
+
+func (mine *taskStore) GetTasksByAgent(agent string, st uint8) ([]*nosql.Task, error) {
+	return tasks.findMany(has("executors", agent), eq("status", st), alive())
+}
+
+func (mine *taskStore) GetTasksByAgent2(agent string) ([]*nosql.Task, error) {
+	return tasks.findMany(has("executors", agent), alive())
+}
+
+func (mine *taskStore) GetTasksByTarget(client string, st uint8) ([]*nosql.Task, error) {
+	return tasks.findMany(eq("target", client), eq("status", st), alive())
+}
+
+func (mine *taskStore) GetTasksByTarget2(client string) ([]*nosql.Task, error) {
+	return tasks.findMany(eq("target", client), alive())
+}
+
+func (mine *taskStore) GetTasksByPreTask(uid string) ([]*nosql.Task, error) {
+	return tasks.findMany(has("preTasks", uid), alive())
+}
+
+func (mine *taskStore) SearchTasks(filter *proxy.TaskFilter) ([]*nosql.Task, int64, error) {
+	conditions := []condition{alive()}
+	if len(filter.Owner) > 0 {
+		conditions = append(conditions, eq("owner", filter.Owner))
+	}
+	if len(filter.Region) > 0 {
+		conditions = append(conditions, has("regions", filter.Region))
+	}
+	if len(filter.Executor) > 0 {
+		conditions = append(conditions, has("executors", filter.Executor))
+	}
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, has("tags", tag))
+	}
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, in("type", filter.Types))
+	}
+	if len(filter.States) > 0 {
+		conditions = append(conditions, in("status", filter.States))
+	}
+	if len(filter.Keyword) > 0 {
+		conditions = append(conditions, or(like("name", filter.Keyword), like("remark", filter.Keyword)))
+	}
+	if len(filter.End) > 0 {
+		conditions = append(conditions, lte("duration.begin", filter.End))
+	}
+	if len(filter.Begin) > 0 {
+		conditions = append(conditions, or(gte("duration.end", filter.Begin), eq("duration.end", "")))
+	}
+	total, err := tasks.count(conditions...)
+	if err != nil {
+		return nil, 0, err
+	}
+	field := filter.Sort
+	if len(field) < 1 {
+		field = "createdAt"
+	}
+	sort, err := tasks.order(field, filter.Desc)
+	if err != nil {
+		return nil, 0, err
+	}
+	list, err := tasks.findList(sort, filter.Skip, filter.Limit, conditions...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+func (mine *taskStore) UpdateTaskBase(uid, name, remark, operator string, assets []string) error {
+	return tasks.update(uid, values{"name": name, "remark": remark, "assets": assets, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *taskStore) UpdateTaskTags(uid, operator string, tags []string) error {
+	return tasks.update(uid, values{"tags": tags, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *taskStore) UpdateTaskExecutors(uid, operator string, list []string) error {
+	return tasks.update(uid, values{"executors": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *taskStore) UpdateTaskType(uid, operator string, tp uint8) error {
+	return tasks.update(uid, values{"type": tp, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *taskStore) UpdateTaskPreTasks(uid, operator string, list []string) error {
+	return tasks.update(uid, values{"preTasks": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *taskStore) TransitTask(uid, operator string, history proxy.StatusHistory) (bool, error) {
+	fields := values{"status": history.To, "operator": operator, "updatedAt": time.Now()}
+	return tasks.appendElementBy(uid, "histories", history, fields, eq("status", history.From), alive())
+}
+
+func (mine *taskStore) RemoveTask(uid, operator string) error {
+	return tasks.removeOne(uid, operator)
+}
+
+func (mine *taskStore) AppendTaskRecord(uid string, data proxy.RecordInfo) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return tasks.appendElement(uid, "records", data)
+}
+
+func (mine *taskStore) SubtractTaskRecord(uid, record string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return tasks.removeElement(uid, "records", record)
+}
+
+func (mine *taskStore) UpdateTaskRecord(uid string, data proxy.RecordInfo) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return tasks.replaceElement(uid, "records", data)
+}
+
+func (mine *taskStore) UpdateTaskRecords(uid string, list []proxy.RecordInfo) error {
+	return tasks.update(uid, values{"records": list, "updatedAt": time.Now()})
+}
+
+func (mine *taskStore) AppendTaskExecutor(uid, user string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return tasks.appendElement(uid, "executors", user)
+}
+
+func (mine *taskStore) SubtractTaskExecutor(uid, user string) error {
+	if len(uid) < 1 {
+		return errors.New("the uid is empty")
+	}
+	return tasks.removeElement(uid, "executors", user)
+}
+
+func (mine *taskStore) QueryTasks(query *proxy.Query) ([]*nosql.Task, int64, error) {
+	return tasks.query(query)
+}
@@ -0,0 +1,125 @@
+package sqldb
+
+import (
+	"errors"
+	"omo.msa.assignment/proxy"
+	"omo.msa.assignment/proxy/nosql"
+	"time"
+)
+
+var teams = newTable[nosql.Team](nosql.TableTeam)
+
+type teamStore struct{}
+
+func (mine *teamStore) CreateTeam(info *nosql.Team) error {
+	return teams.insert(info)
+}
+
+func (mine *teamStore) GetTeam(uid string) (*nosql.Team, error) {
+	return teams.get(uid)
+}
+
+func (mine *teamStore) GetTeamByName(owner, name string) (*nosql.Team, error) {
+	return teams.findOne(eq("owner", owner), eq("name", name), alive())
+}
+
+func (mine *teamStore) GetTeamsByOwner(owner string) ([]*nosql.Team, error) {
+	return teams.findMany(eq("owner", owner), alive())
+}
+
+func (mine *teamStore) GetTeamsByMember(user string) ([]*nosql.Team, error) {
+	return teams.findMany(has("members", user))
+}
+
+func (mine *teamStore) UpdateTeamBase(uid, name, remark, operator string) error {
+	return teams.update(uid, values{"name": name, "remark": remark, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) UpdateTeamAssistants(uid, operator string, list []string) error {
+	return teams.update(uid, values{"assistants": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) UpdateTeamTags(uid, operator string, list []string) error {
+	return teams.update(uid, values{"tags": list, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) UpdateTeamStatus(uid, operator string, st uint8) error {
+	return teams.update(uid, values{"status": st, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) UpdateTeamRegion(uid, region, operator string) error {
+	return teams.update(uid, values{"region": region, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) UpdateTeamParent(uid, parent, operator string) error {
+	return teams.update(uid, values{"parent": parent, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) UpdateTeamMembers(uid, operator string, members []string) error {
+	return teams.update(uid, values{"members": members, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) UpdateTeamMaster(uid, member, operator string) error {
+	return teams.update(uid, values{"master": member, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) RemoveTeam(uid, operator string) error {
+	return teams.removeOne(uid, operator)
+}
+
+func (mine *teamStore) AppendTeamMember(uid, member string) error {
+	if len(member) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return teams.appendElement(uid, "members", member)
+}
+
+func (mine *teamStore) SubtractTeamMember(uid string, member string) error {
+	if len(member) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return teams.removeElement(uid, "members", member)
+}
+
+func (mine *teamStore) UpdateTeamLimit(uid, operator string, limit uint16) error {
+	return teams.update(uid, values{"limit": limit, "operator": operator, "updatedAt": time.Now()})
+}
+
+func (mine *teamStore) JoinTeam(uid, member string, limit uint16) (bool, error) {
+	if len(member) < 1 {
+		return false, errors.New("the member uid is empty")
+	}
+	return teams.appendLimit(uid, "members", member, int64(limit), "waits")
+}
+
+func (mine *teamStore) AppendTeamWait(uid string, wait proxy.WaitInfo) error {
+	if len(wait.User) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	_, err := teams.appendLimit(uid, "waits", wait, 0, "")
+	return err
+}
+
+func (mine *teamStore) SubtractTeamWait(uid, user string) error {
+	if len(user) < 1 {
+		return errors.New("the member uid is empty")
+	}
+	return teams.removeElement(uid, "waits", user)
+}
+
+func (mine *teamStore) AppendTeamMembers(uid string, list []string, limit uint16) (bool, error) {
+	return teams.appendElements(uid, "members", list, int64(limit), "waits")
+}
+
+func (mine *teamStore) SubtractTeamMembers(uid string, list []string) error {
+	return teams.removeElements(uid, "members", list)
+}
+
+func (mine *teamStore) ReplaceTeamMembers(uid, operator string, old, members []string) (bool, error) {
+	fields := values{"operator": operator, "updatedAt": time.Now()}
+	return teams.replaceElements(uid, "members", old, members, fields, "waits")
+}
+
+func (mine *teamStore) QueryTeams(query *proxy.Query) ([]*nosql.Team, int64, error) {
+	return teams.query(query)
+}
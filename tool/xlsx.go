@@ -0,0 +1,220 @@
+package tool
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 只支持单个工作表的纯文本读写，满足表格导入导出的需要
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
+
+type xlsxText struct {
+	Text string `xml:",chardata"`
+}
+
+type xlsxInline struct {
+	T xlsxText   `xml:"t"`
+	R []xlsxText `xml:"r>t"`
+}
+
+type xlsxCell struct {
+	Ref    string     `xml:"r,attr"`
+	Type   string     `xml:"t,attr"`
+	Value  string     `xml:"v"`
+	Inline xlsxInline `xml:"is"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxShared struct {
+	Items []xlsxInline `xml:"si"`
+}
+
+func (mine xlsxInline) String() string {
+	if len(mine.R) < 1 {
+		return mine.T.Text
+	}
+	var builder strings.Builder
+	builder.WriteString(mine.T.Text)
+	for _, item := range mine.R {
+		builder.WriteString(item.Text)
+	}
+	return builder.String()
+}
+
+// WriteXLSX 把所有的行写入只有一个工作表的xlsx文件，单元格都是文本
+func WriteXLSX(w io.Writer, sheet string, rows [][]string) error {
+	if len(sheet) < 1 {
+		sheet = "Sheet1"
+	}
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	body.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		body.WriteString(`<row r="` + strconv.Itoa(i+1) + `">`)
+		for j, cell := range row {
+			body.WriteString(`<c r="` + xlsxColumn(j) + strconv.Itoa(i+1) + `" t="inlineStr"><is><t xml:space="preserve">`)
+			err := xml.EscapeText(&body, []byte(cell))
+			if err != nil {
+				return err
+			}
+			body.WriteString(`</t></is></c>`)
+		}
+		body.WriteString(`</row>`)
+	}
+	body.WriteString(`</sheetData></worksheet>`)
+
+	var name bytes.Buffer
+	err := xml.EscapeText(&name, []byte(sheet))
+	if err != nil {
+		return err
+	}
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"[Content_Types].xml", []byte(xlsxContentTypes)},
+		{"_rels/.rels", []byte(xlsxRootRels)},
+		{"xl/workbook.xml", []byte(workbook)},
+		{"xl/_rels/workbook.xml.rels", []byte(xlsxWorkbookRels)},
+		{"xl/worksheets/sheet1.xml", body.Bytes()},
+	}
+	writer := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := writer.Create(file.name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(file.data)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// ReadXLSX 读取第一个工作表的所有行，空白的单元格为空字符串
+func ReadXLSX(data []byte) ([][]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(reader.File))
+	sheets := make([]string, 0, 1)
+	for _, f := range reader.File {
+		files[f.Name] = f
+		if strings.HasPrefix(f.Name, "xl/worksheets/") && strings.HasSuffix(f.Name, ".xml") {
+			sheets = append(sheets, f.Name)
+		}
+	}
+	if len(sheets) < 1 {
+		return nil, errors.New("not found the worksheet in xlsx")
+	}
+	sheetName := "xl/worksheets/sheet1.xml"
+	if _, ok := files[sheetName]; !ok {
+		sort.Strings(sheets)
+		sheetName = sheets[0]
+	}
+	shared := new(xlsxShared)
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		err = readXMLFile(f, shared)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sheet := new(xlsxSheet)
+	err = readXMLFile(files[sheetName], sheet)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		cells := make([]string, 0, len(row.Cells))
+		for i, cell := range row.Cells {
+			index := i
+			if len(cell.Ref) > 0 {
+				index = xlsxColumnIndex(cell.Ref)
+			}
+			for len(cells) < index {
+				cells = append(cells, "")
+			}
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				num, err := strconv.Atoi(cell.Value)
+				if err != nil || num < 0 || num >= len(shared.Items) {
+					return nil, errors.New("the shared string index is error: " + cell.Value)
+				}
+				value = shared.Items[num].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			}
+			cells = append(cells, value)
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+func readXMLFile(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// 列的序号转换为字母，0为A
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// 单元格的引用(例如AB12)转换为列的序号
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		index = index*26 + int(c-'A') + 1
+	}
+	return index - 1
+}